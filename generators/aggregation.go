@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+/**************************************************************************************************
+** TScoreAdjustment is a boost, or a penalty when Score is negative, added to the score of the
+** tokens for which AppliesTo returns true. The token is the one merged from all the lists.
+**************************************************************************************************/
+type TScoreAdjustment struct {
+	Name      string
+	Score     float64
+	AppliesTo func(token models.TokenListToken) bool
+}
+
+/**************************************************************************************************
+** TAggregationRules decides which tokens of the generated lists make an aggregated list.
+** - The score of a token on a chain is the sum of the weights of the lists it is in, plus the
+**   adjustments which apply to it. A list has the weight set in SourceWeights, or DefaultWeight.
+**   A weight of 0 ignores the list.
+** - The threshold of a chain is Threshold, or the one in ThresholdForChainID, times the sum of the
+**   weights of the lists having at least one token on this chain. A token is included if its
+**   score reaches the threshold of its chain.
+** - The tokens of the PinnedSources lists and the extra tokens of the chains are always included.
+**************************************************************************************************/
+type TAggregationRules struct {
+	DefaultWeight       float64
+	SourceWeights       map[string]float64
+	PinnedSources       []string
+	Threshold           float64
+	ThresholdForChainID map[uint64]float64
+	Adjustments         []TScoreAdjustment
+}
+
+// TScoreExplanation explains why a token is or is not part of an aggregated list
+type TScoreExplanation struct {
+	ChainID     uint64             `json:"chainId"`
+	Address     string             `json:"address"`
+	Sources     []string           `json:"sources"`
+	Adjustments map[string]float64 `json:"adjustments,omitempty"`
+	Score       float64            `json:"score"`
+	Threshold   float64            `json:"threshold"`
+	Included    bool               `json:"included"`
+	Reason      string             `json:"reason"`
+}
+
+/**************************************************************************************************
+** TAggregation is the output of aggregateLists, per chainID and address: the tokens found in the
+** lists with their fields resolved from all of them, the source of each field, the fields the
+** lists disagree on, and the explanation of the score of the tokens.
+**************************************************************************************************/
+type TAggregation struct {
+	Tokens       map[uint64]map[string]models.TokenListToken
+	Provenance   map[uint64]map[string]TFieldProvenance
+	Conflicts    map[uint64]map[string][]TFieldConflict
+	Explanations map[uint64]map[string]TScoreExplanation
+}
+
+/**************************************************************************************************
+** ICON_SCORE_ADJUSTMENT boosts the tokens with an icon. It is not one of the default adjustments:
+** almost every token gets an icon from smolAssets or the icon store, and half a list is enough to
+** tip a token found in too few lists over a threshold like 1.5, loosening the occurrence rule.
+**************************************************************************************************/
+var ICON_SCORE_ADJUSTMENT = TScoreAdjustment{
+	Name:  `icon`,
+	Score: 0.5,
+	AppliesTo: func(token models.TokenListToken) bool {
+		return helpers.HasIcon(token.LogoURI)
+	},
+}
+
+/**************************************************************************************************
+** DEFAULT_SCORE_ADJUSTMENTS are the adjustments shared by the aggregated lists. They only penalize
+** tokens, so with the default weights a token is included if it is in at least half of the lists
+** of its chain, rounded up, like the occurrence rule the aggregated lists always had.
+**************************************************************************************************/
+var DEFAULT_SCORE_ADJUSTMENTS = []TScoreAdjustment{
+	{
+		Name:  `suspiciousName`,
+		Score: -100,
+		AppliesTo: func(token models.TokenListToken) bool {
+			return helpers.SPAM_URL_REGEX.MatchString(token.Name) || helpers.SPAM_URL_REGEX.MatchString(token.Symbol)
+		},
+	},
+}
+
+// AGGREGATION_RULES are the rules of each aggregated list
+var AGGREGATION_RULES = map[string]TAggregationRules{
+	`tokenlistooor`: {
+		DefaultWeight:       1,
+		SourceWeights:       map[string]float64{},
+		PinnedSources:       []string{`yearn`, `smolAssets`},
+		Threshold:           0.5,
+		ThresholdForChainID: map[uint64]float64{},
+		Adjustments:         DEFAULT_SCORE_ADJUSTMENTS,
+	},
+	`popular`: {
+		DefaultWeight:       1,
+		SourceWeights:       map[string]float64{},
+		Threshold:           0.5,
+		ThresholdForChainID: map[uint64]float64{},
+		Adjustments:         DEFAULT_SCORE_ADJUSTMENTS,
+	},
+}
+
+// weightOf returns the weight of a list for the rules
+func (rules TAggregationRules) weightOf(name string) float64 {
+	if weight, ok := rules.SourceWeights[name]; ok {
+		return weight
+	}
+	return rules.DefaultWeight
+}
+
+// thresholdFor returns the share of the total weight of a chain a token must reach
+func (rules TAggregationRules) thresholdFor(chainID uint64) float64 {
+	if threshold, ok := rules.ThresholdForChainID[chainID]; ok {
+		return threshold
+	}
+	return rules.Threshold
+}
+
+// isExtraToken returns true if the address is one of the extra tokens of the chain
+func isExtraToken(chainID uint64, address common.Address) bool {
+	for _, extraToken := range chains.CHAINS[chainID].ExtraTokens {
+		if address == extraToken {
+			return true
+		}
+	}
+	return false
+}
+
+/**************************************************************************************************
+** aggregateLists reads the token lists of all the generators, except the pool lists and the lists
+** built from the aggregated lists, see DependsOn, and scores each token found in them with the
+** rules. The fields of a token found in several lists are resolved with resolveToken.
+**************************************************************************************************/
+func aggregateLists(rules TAggregationRules) TAggregation {
+	aggregation := TAggregation{
+		Tokens:       make(map[uint64]map[string]models.TokenListToken),
+		Provenance:   make(map[uint64]map[string]TFieldProvenance),
+		Conflicts:    make(map[uint64]map[string][]TFieldConflict),
+		Explanations: make(map[uint64]map[string]TScoreExplanation),
+	}
+	sourcesPerToken := make(map[uint64]map[string][]string)
+	votesPerToken := make(map[uint64]map[string]*tTokenVotes)
+	weightPerChain := make(map[uint64]float64)
+
+	names := []string{}
+	for _, name := range generatorNames() {
+		if GENERATORS[name].GeneratorType == GeneratorPool || len(GENERATORS[name].DependsOn) > 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	/**************************************************************************
+	** Collect the fields of the tokens in all the lists, and keep the lists
+	** each token is in and the total weight of the lists of each chain.
+	**************************************************************************/
+	for _, name := range names {
+		weight := rules.weightOf(name)
+		if weight == 0 {
+			continue
+		}
+		chainsOfList := make(map[uint64]bool)
+		tokenList, err := helpers.ReadTokenListFromJsonFile(name + `.json`)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logs.Warning(`Ignoring the list ` + name + `: ` + err.Error())
+		}
+		for _, token := range tokenList.Tokens {
+			if !chains.IsChainIDSupported(token.ChainID) {
+				continue
+			}
+			if !chainsOfList[token.ChainID] {
+				chainsOfList[token.ChainID] = true
+				weightPerChain[token.ChainID] += weight
+			}
+			if _, ok := sourcesPerToken[token.ChainID]; !ok {
+				sourcesPerToken[token.ChainID] = make(map[string][]string)
+				votesPerToken[token.ChainID] = make(map[string]*tTokenVotes)
+			}
+
+			address := helpers.ToAddress(token.Address)
+			if helpers.Includes(sourcesPerToken[token.ChainID][address], name) {
+				continue
+			}
+			sourcesPerToken[token.ChainID][address] = append(sourcesPerToken[token.ChainID][address], name)
+			if _, ok := votesPerToken[token.ChainID][address]; !ok {
+				votesPerToken[token.ChainID][address] = newTokenVotes()
+			}
+			votesPerToken[token.ChainID][address].add(name, token)
+		}
+	}
+
+	/**************************************************************************
+	** Resolve the fields of each token from the values given by the lists.
+	**************************************************************************/
+	for chainID, votesPerAddress := range votesPerToken {
+		aggregation.Tokens[chainID] = make(map[string]models.TokenListToken)
+		aggregation.Provenance[chainID] = make(map[string]TFieldProvenance)
+		for address, votes := range votesPerAddress {
+			token, provenance, conflicts := resolveToken(chainID, address, votes)
+			token.Occurrence = len(sourcesPerToken[chainID][address])
+			aggregation.Tokens[chainID][address] = token
+			aggregation.Provenance[chainID][address] = provenance
+			if len(conflicts) > 0 {
+				if _, ok := aggregation.Conflicts[chainID]; !ok {
+					aggregation.Conflicts[chainID] = make(map[string][]TFieldConflict)
+				}
+				aggregation.Conflicts[chainID][address] = conflicts
+			}
+		}
+	}
+
+	/**************************************************************************
+	** Score the tokens against the threshold of their chain.
+	**************************************************************************/
+	for chainID, tokens := range aggregation.Tokens {
+		aggregation.Explanations[chainID] = make(map[string]TScoreExplanation)
+		threshold := weightPerChain[chainID] * rules.thresholdFor(chainID)
+		for address, token := range tokens {
+			explanation := TScoreExplanation{
+				ChainID:   chainID,
+				Address:   address,
+				Sources:   sourcesPerToken[chainID][address],
+				Threshold: threshold,
+			}
+			for _, source := range explanation.Sources {
+				explanation.Score += rules.weightOf(source)
+			}
+			for _, adjustment := range rules.Adjustments {
+				if adjustment.AppliesTo(token) {
+					if explanation.Adjustments == nil {
+						explanation.Adjustments = make(map[string]float64)
+					}
+					explanation.Adjustments[adjustment.Name] = adjustment.Score
+					explanation.Score += adjustment.Score
+				}
+			}
+
+			pinnedSource := firstOf(explanation.Sources, rules.PinnedSources)
+			switch {
+			case explanation.Score >= threshold:
+				explanation.Included = true
+				explanation.Reason = `score reaches the threshold`
+			case isExtraToken(chainID, common.HexToAddress(address)):
+				explanation.Included = true
+				explanation.Reason = `extra token of the chain`
+			case pinnedSource != ``:
+				explanation.Included = true
+				explanation.Reason = `in the pinned list ` + pinnedSource
+			default:
+				explanation.Reason = `score below the threshold`
+			}
+			aggregation.Explanations[chainID][address] = explanation
+		}
+	}
+	return aggregation
+}
+
+// firstOf returns the first element of the slice which is one of the values, or an empty string
+func firstOf(slice []string, values []string) string {
+	for _, item := range slice {
+		if helpers.Includes(values, item) {
+			return item
+		}
+	}
+	return ``
+}
+
+// IncludedTokens returns the tokens included in the aggregated list
+func (aggregation TAggregation) IncludedTokens() []models.TokenListToken {
+	tokens := []models.TokenListToken{}
+	for chainID, explanations := range aggregation.Explanations {
+		for address, explanation := range explanations {
+			if explanation.Included {
+				tokens = append(tokens, aggregation.Tokens[chainID][address])
+			}
+		}
+	}
+	return tokens
+}
+
+// getExplanationsPath returns the file of the explanations of the scores of an aggregated list, read
+// by the explain command
+func getExplanationsPath(name string) string {
+	return helpers.BASE_PATH + `/data/aggregation/explanations/` + name + `.json`
+}
+
+// loadExplanations reads the explanations saved for an aggregated list, per chainID and address
+func loadExplanations(name string) (map[uint64]map[string]TScoreExplanation, error) {
+	explanations := make(map[uint64]map[string]TScoreExplanation)
+	content, err := os.ReadFile(getExplanationsPath(name))
+	if err != nil {
+		return explanations, err
+	}
+	err = json.Unmarshal(content, &explanations)
+	return explanations, err
+}
+
+/**************************************************************************************************
+** saveExplanations writes the explanations of the scores of an aggregated list in
+** getExplanationsPath, for the explain command. Only the chains of this run are replaced: the
+** explanations of the chains left out by --chains are kept from the previous run.
+**************************************************************************************************/
+func (aggregation TAggregation) saveExplanations(name string) error {
+	if helpers.DRY_RUN {
+		return nil
+	}
+	explanations, err := loadExplanations(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logs.Warning(`Replacing the invalid explanations of ` + name + `: ` + err.Error())
+		explanations = make(map[uint64]map[string]TScoreExplanation)
+	}
+	for chainID := range explanations {
+		if chains.IsChainIDSupported(chainID) {
+			delete(explanations, chainID)
+		}
+	}
+	for chainID, explanationsOfChain := range aggregation.Explanations {
+		explanations[chainID] = explanationsOfChain
+	}
+	return helpers.SaveJSONFile(getExplanationsPath(name), explanations)
+}
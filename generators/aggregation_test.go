@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+func TestAggregateListsDoesNotCreateTheMissingLists(t *testing.T) {
+	listPath := helpers.BASE_PATH + `/lists/consensys.json`
+	if err := os.Remove(listPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	aggregateLists(AGGREGATION_RULES[`tokenlistooor`])
+	if _, err := os.Stat(listPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf(`the missing list was created: %v`, err)
+	}
+}
+
+func TestSaveExplanationsKeepsTheOtherChains(t *testing.T) {
+	const otherChainID = uint64(10)
+	address := `0x6B175474E89094C44Da98b954EedeAC495271d0F`
+	previous := map[uint64]map[string]TScoreExplanation{
+		TEST_CHAIN_ID: {address: {ChainID: TEST_CHAIN_ID, Address: address, Score: 1, Reason: `previous`}},
+		otherChainID:  {address: {ChainID: otherChainID, Address: address, Score: 2, Reason: `other chain`}},
+	}
+	if err := helpers.SaveJSONFile(getExplanationsPath(`test`), previous); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(getExplanationsPath(`test`)) })
+
+	aggregation := TAggregation{Explanations: map[uint64]map[string]TScoreExplanation{
+		TEST_CHAIN_ID: {address: {ChainID: TEST_CHAIN_ID, Address: address, Score: 3, Included: true, Reason: `current`}},
+	}}
+	if err := aggregation.saveExplanations(`test`); err != nil {
+		t.Fatal(err)
+	}
+	explanations, err := loadExplanations(`test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := explanations[TEST_CHAIN_ID][address]; got.Reason != `current` || !got.Included {
+		t.Errorf(`got %+v on the chain of the run`, got)
+	}
+	if got := explanations[otherChainID][address]; got.Reason != `other chain` {
+		t.Errorf(`got %+v on the chain left out of the run`, got)
+	}
+}
+
+func TestAggregateListsSkipsTheDependentLists(t *testing.T) {
+	address := `0x6B175474E89094C44Da98b954EedeAC495271d0F`
+	listPath := helpers.BASE_PATH + `/lists/ajna-static.json`
+	content := `{"tokens":[{"address":"` + address + `","chainId":1,"name":"Dai Stablecoin","symbol":"DAI","decimals":18}]}`
+	if err := os.WriteFile(listPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(listPath) })
+
+	aggregation := aggregateLists(AGGREGATION_RULES[`tokenlistooor`])
+	for _, source := range aggregation.Explanations[TEST_CHAIN_ID][address].Sources {
+		if source == `ajna-static` {
+			t.Error(`the aggregated list reads ajna-static, which is built from it`)
+		}
+	}
+}
+
+// testAddress returns a checksummed address made of the number n
+func testAddress(n int64) string {
+	return common.BigToAddress(big.NewInt(n)).Hex()
+}
+
+// testToken returns a token of chainID with the given address, and the logo when withIcon is set
+func testToken(chainID uint64, address string, name string, withIcon bool) models.TokenListToken {
+	token := models.TokenListToken{Address: address, ChainID: chainID, Name: name, Symbol: `TKN`, Decimals: 18}
+	if withIcon {
+		token.LogoURI = `https://assets.smold.app/api/token/1/` + address + `/logo-128.png`
+	}
+	return token
+}
+
+// withAggregationSources writes the lists, per generator name, in a temporary lists folder read by
+// aggregateLists until the end of the test
+func withAggregationSources(t *testing.T, lists map[string][]models.TokenListToken) {
+	t.Helper()
+	basePath, previousBasePath := t.TempDir(), helpers.BASE_PATH
+	helpers.BASE_PATH = basePath
+	t.Cleanup(func() { helpers.BASE_PATH = previousBasePath })
+	if err := os.MkdirAll(basePath+`/lists`, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, tokens := range lists {
+		content, err := json.Marshal(models.TokenListData[models.TokenListToken]{Name: name, Tokens: tokens})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(basePath+`/lists/`+name+`.json`, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAggregateListsScoresTheTokens(t *testing.T) {
+	const otherChainID = uint64(10)
+	if err := chains.SetChainFilter([]uint64{TEST_CHAIN_ID, otherChainID}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { chains.SetChainFilter([]uint64{TEST_CHAIN_ID}) })
+
+	belowThreshold, withIcon, spam := testAddress(1), testAddress(2), testAddress(3)
+	ignoredSource, pinned, reachesThreshold := testAddress(4), testAddress(5), testAddress(6)
+	extraToken := chains.CHAINS[TEST_CHAIN_ID].ExtraTokens[0].Hex()
+	otherChain := testAddress(7)
+	withAggregationSources(t, map[string][]models.TokenListToken{
+		`coingecko`: {
+			testToken(TEST_CHAIN_ID, belowThreshold, `Below`, false),
+			testToken(TEST_CHAIN_ID, withIcon, `Icon`, true),
+			testToken(TEST_CHAIN_ID, spam, `Visit www.spam.example`, false),
+			testToken(TEST_CHAIN_ID, reachesThreshold, `Reaches`, false),
+		},
+		`1inch`: {
+			testToken(TEST_CHAIN_ID, spam, `Visit www.spam.example`, false),
+			testToken(TEST_CHAIN_ID, ignoredSource, `Ignored`, false),
+			testToken(TEST_CHAIN_ID, extraToken, `Extra`, false),
+			testToken(TEST_CHAIN_ID, reachesThreshold, `Reaches`, false),
+			testToken(otherChainID, otherChain, `Other chain`, false),
+		},
+		`uniswap`: {
+			testToken(TEST_CHAIN_ID, spam, `Visit www.spam.example`, false),
+			testToken(otherChainID, testAddress(8), `Other chain only in uniswap`, false),
+		},
+		`paraswap`: {testToken(TEST_CHAIN_ID, ignoredSource, `Ignored`, false)},
+		`yearn`:    {testToken(TEST_CHAIN_ID, pinned, `Pinned`, false)},
+	})
+
+	// The weights on the chain of the test are 2 + 1 + 1 + 1, paraswap being ignored, and on the
+	// other chain 1 + 1
+	rules := TAggregationRules{
+		DefaultWeight:       1,
+		SourceWeights:       map[string]float64{`coingecko`: 2, `paraswap`: 0},
+		PinnedSources:       []string{`yearn`},
+		Threshold:           0.5,
+		ThresholdForChainID: map[uint64]float64{otherChainID: 0.25},
+		Adjustments:         append([]TScoreAdjustment{ICON_SCORE_ADJUSTMENT}, DEFAULT_SCORE_ADJUSTMENTS...),
+	}
+	aggregation := aggregateLists(rules)
+
+	for _, test := range []struct {
+		name        string
+		chainID     uint64
+		address     string
+		sources     []string
+		adjustments map[string]float64
+		score       float64
+		threshold   float64
+		included    bool
+		reason      string
+	}{
+		{`weighted source below the threshold`, TEST_CHAIN_ID, belowThreshold, []string{`coingecko`}, nil, 2, 2.5, false, `score below the threshold`},
+		{`icon reaching the threshold`, TEST_CHAIN_ID, withIcon, []string{`coingecko`}, map[string]float64{`icon`: 0.5}, 2.5, 2.5, true, `score reaches the threshold`},
+		{`suspicious name`, TEST_CHAIN_ID, spam, []string{`1inch`, `coingecko`, `uniswap`}, map[string]float64{`suspiciousName`: -100}, -96, 2.5, false, `score below the threshold`},
+		{`source of weight 0`, TEST_CHAIN_ID, ignoredSource, []string{`1inch`}, nil, 1, 2.5, false, `score below the threshold`},
+		{`pinned source`, TEST_CHAIN_ID, pinned, []string{`yearn`}, nil, 1, 2.5, true, `in the pinned list yearn`},
+		{`extra token`, TEST_CHAIN_ID, extraToken, []string{`1inch`}, nil, 1, 2.5, true, `extra token of the chain`},
+		{`several sources`, TEST_CHAIN_ID, reachesThreshold, []string{`1inch`, `coingecko`}, nil, 3, 2.5, true, `score reaches the threshold`},
+		{`threshold of the chain`, otherChainID, otherChain, []string{`1inch`}, nil, 1, 0.5, true, `score reaches the threshold`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			explanation, ok := aggregation.Explanations[test.chainID][test.address]
+			if !ok {
+				t.Fatal(`the token has no explanation`)
+			}
+			if !reflect.DeepEqual(explanation.Sources, test.sources) || !reflect.DeepEqual(explanation.Adjustments, test.adjustments) {
+				t.Errorf(`got the sources %v and the adjustments %v`, explanation.Sources, explanation.Adjustments)
+			}
+			if explanation.Score != test.score || explanation.Threshold != test.threshold {
+				t.Errorf(`got the score %v and the threshold %v, want %v and %v`, explanation.Score, explanation.Threshold, test.score, test.threshold)
+			}
+			if explanation.Included != test.included || explanation.Reason != test.reason {
+				t.Errorf(`got included %v because %q, want %v because %q`, explanation.Included, explanation.Reason, test.included, test.reason)
+			}
+		})
+	}
+}
+
+func TestAggregateListsKeepsTheOccurrenceRuleByDefault(t *testing.T) {
+	sources := []string{`1inch`, `coingecko`, `cowswap`, `paraswap`, `uniswap`}
+	for listCount := 1; listCount <= len(sources); listCount++ {
+		// The token k is in the k first lists without an icon, and the token 100+k is in the k
+		// first lists with an icon, like almost every token of the generated lists
+		lists := map[string][]models.TokenListToken{}
+		for occurrence := 1; occurrence <= listCount; occurrence++ {
+			for _, name := range sources[:occurrence] {
+				lists[name] = append(lists[name],
+					testToken(TEST_CHAIN_ID, testAddress(int64(occurrence)), `Token`, false),
+					testToken(TEST_CHAIN_ID, testAddress(int64(100+occurrence)), `Token with an icon`, true),
+				)
+			}
+		}
+		withAggregationSources(t, lists)
+
+		for _, name := range []string{`tokenlistooor`, `popular`} {
+			aggregation := aggregateLists(AGGREGATION_RULES[name])
+			for occurrence := 1; occurrence <= listCount; occurrence++ {
+				wasIncluded := occurrence >= int(math.Ceil(float64(listCount)*0.5))
+				for _, address := range []string{testAddress(int64(occurrence)), testAddress(int64(100 + occurrence))} {
+					explanation := aggregation.Explanations[TEST_CHAIN_ID][address]
+					if explanation.Included != wasIncluded {
+						t.Errorf(`%s: the token %s in %d of %d lists is included: %v, want %v`, name, address, occurrence, listCount, explanation.Included, wasIncluded)
+					}
+				}
+			}
+		}
+	}
+}
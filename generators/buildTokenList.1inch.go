@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
@@ -29,31 +31,42 @@ var APIURIFor1Inch = map[uint64]string{
 	43114: `https://api.1inch.io/v5.0/43114/tokens`,
 }
 
-func fetch1InchTokenList() []models.TokenListToken {
+func fetch1InchTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	tokenList := []models.TokenListToken{}
+	chainErrors := &helpers.TChainErrors{}
 
 	for chainID, uri := range APIURIFor1Inch {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 
-		list := helpers.FetchJSON[T1InchList](uri)
+		list, err := helpers.FetchJSON[T1InchList](ctx, uri)
+		chainErrors.Add(chainID, err)
+		if err != nil {
+			continue
+		}
 		tokenAddresses := []common.Address{}
 		for _, token := range list.Tokens {
 			tokenAddresses = append(tokenAddresses, common.HexToAddress(token.Address))
 		}
-		tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+		tokenList = append(tokenList, helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)...)
 		tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	}
 
-	return tokenList
+	return tokenList, chainErrors.OrNil()
 }
 
-func build1InchTokenList() {
+func build1InchTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`1inch.json`)
 	tokenList.Name = "1inch Token List"
 	tokenList.LogoURI = "https://app.1inch.io/assets/images/logo.png"
 
-	tokens := fetch1InchTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `1inch.json`, helpers.SavingMethodStandard)
+	tokens, err := fetch1InchTokenList(ctx)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `1inch.json`, helpers.SavingMethodStandard)
 }
@@ -1,36 +1,109 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 	"github.com/migratooor/tokenLists/generators/static"
 )
 
-func handleAjnaStaticTokenList(chainID uint64, tokens []static.TStaticElement) []models.TokenListToken {
+// AJNA_STATIC_SOURCES are the lists whose tokens are candidates for the Ajna static list, along
+// with the tokens curated by the Ajna team. The generator depends on them, so it reads the lists
+// saved by the run.
+var AJNA_STATIC_SOURCES = []string{`ajna`, `tokenlistooor`}
+
+// AJNA_UNSAFE_TAGS are the behaviours the Ajna pools do not support
+var AJNA_UNSAFE_TAGS = []string{models.TagFeeOnTransfer, models.TagRebasing}
+
+// isAjnaUnsafe returns true if one of the tags is in AJNA_UNSAFE_TAGS
+func isAjnaUnsafe(tags []string) bool {
+	for _, tag := range tags {
+		if helpers.Includes(AJNA_UNSAFE_TAGS, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+/**************************************************************************************************
+** loadAjnaStaticSources returns the addresses of the tokens of the AJNA_STATIC_SOURCES lists, per
+** chainID, without the ones tagged as suspicious.
+**************************************************************************************************/
+func loadAjnaStaticSources() map[uint64][]common.Address {
+	addresses := make(map[uint64][]common.Address)
+	for _, name := range AJNA_STATIC_SOURCES {
+		tokenList, err := helpers.ReadTokenListFromJsonFile(name + `.json`)
+		if err != nil {
+			logs.Warning(`Failed to read the source ` + name + ` of the Ajna static list: ` + err.Error())
+		}
+		for _, token := range tokenList.Tokens {
+			if helpers.Includes(token.Tags, models.TagSuspicious) {
+				continue
+			}
+			addresses[token.ChainID] = append(addresses[token.ChainID], common.HexToAddress(token.Address))
+		}
+	}
+	return addresses
+}
+
+/**************************************************************************************************
+** fetchAjnaStaticTokenList probes the tokens curated by the Ajna team for a chain and the ones of
+** the sources, see ethereum.ProbeTokens, and keeps the ones Ajna supports, tagged with their
+** behaviours:
+** - a token taking a fee on transfer or rebasing is dropped,
+** - a token of the sources is dropped if the probe could not simulate a transfer of it, while a
+**   curated token is kept, as it was checked by hand.
+**************************************************************************************************/
+func fetchAjnaStaticTokenList(ctx context.Context, chainID uint64, sources map[uint64][]common.Address) []models.TokenListToken {
+	if !chains.IsChainIDSupported(chainID) {
+		return []models.TokenListToken{}
+	}
+
+	isCurated := make(map[common.Address]bool)
+	candidates := []common.Address{}
+	for _, token := range static.AJNA_STATIC_TOKENLIST[chainID] {
+		isCurated[token.Address] = true
+		candidates = append(candidates, token.Address)
+	}
+	isCandidate := make(map[common.Address]bool)
+	for _, address := range sources[chainID] {
+		if !isCurated[address] && !isCandidate[address] {
+			isCandidate[address] = true
+			candidates = append(candidates, address)
+		}
+	}
+
+	behaviours := helpers.RetrieveTokenBehaviours(ctx, chainID, candidates)
 	tokenAddresses := []common.Address{}
-	for _, token := range tokens {
-		tokenAddresses = append(tokenAddresses, token.Address)
+	for _, address := range candidates {
+		behaviour, ok := behaviours[address.Hex()]
+		if isAjnaUnsafe(behaviour.Tags()) || (!isCurated[address] && (!ok || !behaviour.Simulated)) {
+			continue
+		}
+		tokenAddresses = append(tokenAddresses, address)
+	}
+
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
+	for i, token := range tokenList {
+		tokenList[i].Tags = behaviours[common.HexToAddress(token.Address).Hex()].Tags()
 	}
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	return tokenList
 }
 
-func fetchAjnaStaticTokenList(chainID uint64) []models.TokenListToken {
-	tokens := static.AJNA_STATIC_TOKENLIST
-	return handleAjnaStaticTokenList(chainID, tokens[chainID])
-}
-
-func buildAjnaStaticTokenList() {
+func buildAjnaStaticTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`ajna-static.json`)
-	tokenList.Name = `Ajna (Static)`
+	tokenList.Name = `Ajna Static`
 	tokenList.LogoURI = `https://www.ajna.finance/static/tokens/ajna.png`
 	tokenList.Keywords = []string{`Ajna`}
+	sources := loadAjnaStaticSources()
 	tokens := []models.TokenListToken{}
-	tokens = append(tokens, fetchAjnaStaticTokenList(1)...)
-	tokens = append(tokens, fetchAjnaStaticTokenList(5)...)
-	tokens = append(tokens, fetchAjnaStaticTokenList(137)...)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `ajna-static.json`, helpers.SavingMethodStandard)
+	tokens = append(tokens, fetchAjnaStaticTokenList(ctx, 1, sources)...)
+	tokens = append(tokens, fetchAjnaStaticTokenList(ctx, 5, sources)...)
+	tokens = append(tokens, fetchAjnaStaticTokenList(ctx, 137, sources)...)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `ajna-static.json`, helpers.SavingMethodStandard)
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/contracts"
@@ -10,28 +13,37 @@ import (
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func handleAjnaTokenList(chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+func handleAjnaTokenList(ctx context.Context, chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	return tokenList
 }
 
-func fetchAjnaTokenList(chainID uint64, sugarAddress common.Address) []models.TokenListToken {
+// AJNA_POOL_FACTORIES are the pool factories of Ajna, per chainID
+var AJNA_POOL_FACTORIES = map[uint64]common.Address{
+	1:     common.HexToAddress(`0x6146DD43C5622bB6D12A5240ab9CF4de14eDC625`),
+	5:     common.HexToAddress(`0xDB61f8aD0B3ed0c5522b8FE71b80023fe9188e9e`),
+	10:    common.HexToAddress(`0x609C4e8804fafC07c96bE81A8a98d0AdCf2b7Dfa`),
+	100:   common.HexToAddress(`0x87578E357358163FCAb1711c62AcDB5BBFa1C9ef`),
+	137:   common.HexToAddress(`0x1f172F881eBa06Aa7a991651780527C173783Cf6`),
+	8453:  common.HexToAddress(`0x214f62B5836D83f3D6c4f71F174209097B1A779C`),
+	42161: common.HexToAddress(`0xA3A1e968Bd6C578205E11256c8e6929f21742aAF`),
+}
+
+func fetchAjnaTokenList(ctx context.Context, chainID uint64, sugarAddress common.Address) ([]models.TokenListToken, error) {
 	client := ethereum.GetRPC(chainID)
 	ajnaPoolFactory, err := contracts.NewAjnaPoolFactoryCaller(sugarAddress, client)
 	if err != nil {
-		logs.Error(err)
-		return []models.TokenListToken{}
+		return nil, err
 	}
 	/**************************************************************************
 	** We first fetch all the pools deployed on Ajna. This will allow us to
 	** retrieve the collateral and quote tokens for each pool in a second
 	** step.
 	**************************************************************************/
-	allPools, err := ajnaPoolFactory.GetDeployedPoolsList(nil)
+	allPools, err := ajnaPoolFactory.GetDeployedPoolsList(ethereum.CallOpts(ctx, chainID))
 	if err != nil {
-		logs.Error(err)
-		return []models.TokenListToken{}
+		return nil, errors.New(`failed to read the pools of Ajna: ` + err.Error())
 	}
 
 	/**************************************************************************
@@ -41,16 +53,19 @@ func fetchAjnaTokenList(chainID uint64, sugarAddress common.Address) []models.To
 	**************************************************************************/
 	addressesMap := make(map[common.Address]bool)
 	for _, pool := range allPools {
+		if ctx.Err() != nil {
+			break
+		}
 		ajnaPool, err := contracts.NewAjnaPoolCaller(pool, client)
 		if err != nil {
 			logs.Error(err)
 			continue
 		}
-		collateralAddress, errCollateral := ajnaPool.CollateralAddress(nil)
+		collateralAddress, errCollateral := ajnaPool.CollateralAddress(ethereum.CallOpts(ctx, chainID))
 		if errCollateral == nil {
 			addressesMap[collateralAddress] = true
 		}
-		quoteTokenAddress, errQuoteToken := ajnaPool.QuoteTokenAddress(nil)
+		quoteTokenAddress, errQuoteToken := ajnaPool.QuoteTokenAddress(ethereum.CallOpts(ctx, chainID))
 		if errQuoteToken == nil {
 			addressesMap[quoteTokenAddress] = true
 		}
@@ -64,22 +79,31 @@ func fetchAjnaTokenList(chainID uint64, sugarAddress common.Address) []models.To
 	for address := range addressesMap {
 		addressesSlice = append(addressesSlice, address)
 	}
-	return handleAjnaTokenList(chainID, addressesSlice)
+	return handleAjnaTokenList(ctx, chainID, addressesSlice), nil
 }
 
-func buildAjnaTokenList() {
+func buildAjnaTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`ajna.json`)
 	tokenList.Name = `Ajna`
 	tokenList.LogoURI = `https://www.ajna.finance/static/tokens/ajna.png`
 	tokenList.Keywords = []string{`Ajna`}
-	tokens := []models.TokenListToken{}
-	tokens = append(tokens, fetchAjnaTokenList(1, common.HexToAddress(`0x6146DD43C5622bB6D12A5240ab9CF4de14eDC625`))...)
-	tokens = append(tokens, fetchAjnaTokenList(5, common.HexToAddress(`0xDB61f8aD0B3ed0c5522b8FE71b80023fe9188e9e`))...)
-	tokens = append(tokens, fetchAjnaTokenList(10, common.HexToAddress(`0x609C4e8804fafC07c96bE81A8a98d0AdCf2b7Dfa`))...)
-	tokens = append(tokens, fetchAjnaTokenList(100, common.HexToAddress(`0x87578E357358163FCAb1711c62AcDB5BBFa1C9ef`))...)
-	tokens = append(tokens, fetchAjnaTokenList(137, common.HexToAddress(`0x1f172F881eBa06Aa7a991651780527C173783Cf6`))...)
-	tokens = append(tokens, fetchAjnaTokenList(8453, common.HexToAddress(`0x214f62B5836D83f3D6c4f71F174209097B1A779C`))...)
-	tokens = append(tokens, fetchAjnaTokenList(42161, common.HexToAddress(`0xA3A1e968Bd6C578205E11256c8e6929f21742aAF`))...)
 
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `ajna.json`, helpers.SavingMethodStandard)
+	tokens := []models.TokenListToken{}
+	chainErrors := &helpers.TChainErrors{}
+	for chainID, factory := range AJNA_POOL_FACTORIES {
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		chainTokens, err := fetchAjnaTokenList(ctx, chainID, factory)
+		chainErrors.Add(chainID, err)
+		tokens = append(tokens, chainTokens...)
+	}
+	tokens, err := helpers.KeepTokensOfFailedChains(tokenList, tokens, chainErrors.OrNil())
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `ajna.json`, helpers.SavingMethodStandard)
 }
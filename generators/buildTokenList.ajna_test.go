@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// tokensByAddress indexes the tokens of a list by address
+func tokensByAddress(tokens []models.TokenListToken) map[string]models.TokenListToken {
+	byAddress := make(map[string]models.TokenListToken)
+	for _, token := range tokens {
+		byAddress[token.Address] = token
+	}
+	return byAddress
+}
+
+func TestFetchAjnaTokenList(t *testing.T) {
+	collateral := deployERC20(t, `Ajna Collateral`, `AJC`)
+	quoteToken := deployERC20(t, `Ajna Quote`, `AJQ`)
+	otherCollateral := deployERC20(t, `Other Collateral`, `OTC`)
+	factory, err := simulatedChain(t).DeployAjnaFactory([]simulated.TAjnaPool{
+		{Collateral: collateral, QuoteToken: quoteToken},
+		{Collateral: otherCollateral, QuoteToken: quoteToken},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := fetchAjnaTokenList(context.Background(), TEST_CHAIN_ID, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := tokensByAddress(list)
+	for address, symbol := range map[common.Address]string{
+		collateral:      `AJC`,
+		quoteToken:      `AJQ`,
+		otherCollateral: `OTC`,
+	} {
+		token, ok := tokens[address.Hex()]
+		if !ok {
+			t.Errorf(`%s is missing from the list`, symbol)
+			continue
+		}
+		if token.Symbol != symbol || token.Decimals != 18 || token.ChainID != TEST_CHAIN_ID {
+			t.Errorf(`%s: got %s with %d decimals on chain %d`, symbol, token.Symbol, token.Decimals, token.ChainID)
+		}
+	}
+	if len(tokens) != 4 {
+		t.Errorf(`got %d tokens, want the 3 tokens of the pools and the coin of the chain`, len(tokens))
+	}
+
+	// A factory which cannot be read fails the chain, rather than giving an empty list
+	if list, err := fetchAjnaTokenList(context.Background(), TEST_CHAIN_ID, common.HexToAddress(`0xdead`)); err == nil {
+		t.Errorf(`got the tokens %v and no error without a factory`, list)
+	}
+}
+
+func TestFetchAjnaStaticTokenListDropsTheFeeAndRebasingTokens(t *testing.T) {
+	previous := ethereum.PROBE_SETTINGS.RebaseBlocks
+	ethereum.PROBE_SETTINGS.RebaseBlocks = 1 // The simulated chain only has a few blocks
+	t.Cleanup(func() { ethereum.PROBE_SETTINGS.RebaseBlocks = previous })
+
+	deploy := func(token simulated.TNonStandardERC20) common.Address {
+		t.Helper()
+		address, err := simulatedChain(t).DeployNonStandardERC20(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+	standard := deploy(simulated.TNonStandardERC20{Name: `Static Standard`, Symbol: `STS`, Decimals: 18})
+	fee := deploy(simulated.TNonStandardERC20{Name: `Static Fee`, Symbol: `STF`, Decimals: 18, FeeBps: 100})
+	rebasing := deploy(simulated.TNonStandardERC20{Name: `Static Rebasing`, Symbol: `STR`, Decimals: 18, Rebasing: true})
+	deployERC20(t, `Static Block`, `STB`) // Mines a block after the rebasing token, to read its drift
+
+	sources := map[uint64][]common.Address{TEST_CHAIN_ID: {standard, fee, rebasing}}
+	tokens := tokensByAddress(fetchAjnaStaticTokenList(context.Background(), TEST_CHAIN_ID, sources))
+	if token, ok := tokens[standard.Hex()]; !ok || token.Symbol != `STS` {
+		t.Errorf(`got the standard token %+v, want it in the list`, token)
+	}
+	for address, symbol := range map[common.Address]string{fee: `STF`, rebasing: `STR`} {
+		if _, ok := tokens[address.Hex()]; ok {
+			t.Errorf(`%s is in the list, the Ajna pools do not support it`, symbol)
+		}
+	}
+}
+
+func TestAjnaStaticRunsAfterItsSources(t *testing.T) {
+	names, dependentNames := splitDependentGenerators([]string{`ajna`, `ajna-static`, `tokenlistooor`})
+	if len(names) != 2 || len(dependentNames) != 1 || dependentNames[0] != `ajna-static` {
+		t.Fatalf(`got %v then %v, want ajna-static to run last`, names, dependentNames)
+	}
+
+	results := runDependentGenerators(context.Background(), dependentNames, []TGeneratorResult{
+		{Name: `ajna`, Status: GeneratorStatusFailed},
+	}, TSchedulerOptions{})
+	if len(results) != 1 || results[0].Name != `ajna-static` || results[0].Status != GeneratorStatusFailed {
+		t.Fatalf(`got %+v, want ajna-static to fail without running`, results)
+	}
+
+	for _, name := range AJNA_STATIC_SOURCES {
+		if !helpers.Includes(GENERATORS[`ajna-static`].DependsOn, name) {
+			t.Errorf(`ajna-static does not depend on its source %s`, name)
+		}
+	}
+}
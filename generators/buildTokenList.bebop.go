@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
@@ -51,27 +54,77 @@ func bebopMapNetworkChainIDToName(chainID uint64) string {
 	return `ethereum`
 }
 
-func fetchbebopTokenList() []models.TokenListToken {
+/**************************************************************************************************
+** bebopTags returns the tags of a token of the Bebop list which are part of models.TAGS, matched by
+** their identifier or by the name given to them in the tags of the list. The other tags are only
+** meaningful to Bebop and are dropped.
+**************************************************************************************************/
+func bebopTags(tokenTags []string, listTags map[string]models.TTag) []string {
+	tags := []string{}
+	for _, tokenTag := range tokenTags {
+		if tag, ok := models.ToTag(tokenTag); ok {
+			tags = append(tags, tag)
+		} else if tag, ok := models.ToTag(listTags[tokenTag].Name); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return models.SortTags(tags)
+}
+
+/**************************************************************************************************
+** bebopExtensions returns the color and displayDecimals extensions of a token of the Bebop list,
+** leaving out the ones Bebop does not set: the schema refuses the empty strings, and an empty
+** color would prevent the whole list from being saved.
+**************************************************************************************************/
+func bebopExtensions(color string, displayDecimals *int) map[string]interface{} {
+	extensions := map[string]interface{}{}
+	if color != `` {
+		extensions[`color`] = color
+	}
+	if displayDecimals != nil {
+		extensions[`displayDecimals`] = *displayDecimals
+	}
+	if len(extensions) == 0 {
+		return nil
+	}
+	return extensions
+}
+
+func fetchbebopTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	supportedChainID := []uint64{1, 137, 42161}
 	tokens := []models.TokenListToken{}
+	chainErrors := &helpers.TChainErrors{}
 
 	type TBebopTokenListToken struct {
 		models.TokenListToken
 		Tags       []string `json:"tags"`
 		Extensions struct {
 			Color           string `json:"color"`
-			DisplayDecimals int    `json:"displayDecimals"`
+			DisplayDecimals *int   `json:"displayDecimals"`
 		} `json:"extensions"`
 	}
 
-	tokenList := helpers.FetchJSON[models.TokenListData[TBebopTokenListToken]](`https://api.bebop.xyz/token_list`)
+	bebopTokenList, err := helpers.FetchJSON[models.TokenListData[TBebopTokenListToken]](ctx, `https://api.bebop.xyz/token_list`)
+	if err != nil {
+		return tokens, err
+	}
 	tokenMap := map[string]TBebopTokenListToken{}
-	for _, token := range tokenList.Tokens {
+	for _, token := range bebopTokenList.Tokens {
 		tokenMap[token.Address] = token
 	}
 
 	for _, chainID := range supportedChainID {
-		list := helpers.FetchJSON[TBebopList](`https://api.bebop.xyz/` + bebopMapNetworkChainIDToName(chainID) + `/v2/token-info`)
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		list, err := helpers.FetchJSON[TBebopList](ctx, `https://api.bebop.xyz/`+bebopMapNetworkChainIDToName(chainID)+`/v2/token-info`)
+		chainErrors.Add(chainID, err)
+		if err != nil {
+			continue
+		}
 
 		tokenList := []common.Address{}
 		for _, token := range list.Tokens {
@@ -84,7 +137,7 @@ func fetchbebopTokenList() []models.TokenListToken {
 			tokenList = append(tokenList, common.HexToAddress(token.Address))
 		}
 
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokenList)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokenList)
 		for _, existingToken := range list.Tokens {
 			if !existingToken.Availability.IsAvailable {
 				continue
@@ -107,25 +160,25 @@ func fetchbebopTokenList() []models.TokenListToken {
 					int(token.Decimals),
 				); err == nil {
 					if tokenFromList, ok := tokenMap[common.HexToAddress(existingToken.Address).Hex()]; ok {
-						newToken.Metadata = map[string]any{
-							`tags`:            tokenFromList.Tags,
-							`color`:           tokenFromList.Extensions.Color,
-							`displayDecimals`: tokenFromList.Extensions.DisplayDecimals,
-						}
+						newToken.Tags = bebopTags(tokenFromList.Tags, bebopTokenList.Tags)
+						newToken.Extensions = bebopExtensions(tokenFromList.Extensions.Color, tokenFromList.Extensions.DisplayDecimals)
 					}
 					tokens = append(tokens, newToken)
 				}
 			}
 		}
 	}
-	return tokens
+	return tokens, chainErrors.OrNil()
 }
 
-func buildBebopTokenList() {
+func buildBebopTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`bebop.json`)
 	tokenList.Name = "Bebop"
 	tokenList.LogoURI = "https://bebop-public-images.s3.eu-west-2.amazonaws.com/bebop-logo.png"
 
-	tokens := fetchbebopTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `bebop.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchbebopTokenList(ctx)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `bebop.json`, helpers.SavingMethodStandard)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+func TestSaveBebopTokenWithoutColor(t *testing.T) {
+	if err := os.WriteFile(helpers.BASE_PATH+`/lists/bebop.json`, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(helpers.BASE_PATH + `/lists/bebop.json`)
+		os.RemoveAll(helpers.BASE_PATH + `/lists/1`)
+		os.RemoveAll(helpers.BASE_PATH + `/lists/changelogs`)
+	})
+
+	displayDecimals := 2
+	tokens := []models.TokenListToken{
+		{
+			Address: `0x6B175474E89094C44Da98b954EedeAC495271d0F`, Name: `Dai Stablecoin`, Symbol: `DAI`, Decimals: 18, ChainID: TEST_CHAIN_ID,
+			Extensions: bebopExtensions(``, &displayDecimals),
+		},
+		{
+			Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`, Decimals: 6, ChainID: TEST_CHAIN_ID,
+			Extensions: bebopExtensions(``, nil),
+		},
+		{Address: `0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2`, Name: `Wrapped Ether`, Symbol: `WETH`, Decimals: 18, ChainID: TEST_CHAIN_ID},
+		{Address: `0xdAC17F958D2ee523a2206206994597C13D831ec7`, Name: `Tether USD`, Symbol: `USDT`, Decimals: 6, ChainID: TEST_CHAIN_ID},
+	}
+	tokenList := helpers.LoadTokenListFromJsonFile(`bebop.json`)
+	tokenList.Name = `Bebop`
+	tokenList.LogoURI = `https://bebop-public-images.s3.eu-west-2.amazonaws.com/bebop-logo.png`
+	if _, err := helpers.SaveTokenListInJsonFile(context.Background(), tokenList, tokens, `bebop.json`, helpers.SavingMethodStandard); err != nil {
+		t.Fatal(err)
+	}
+
+	savedList := helpers.LoadTokenListFromJsonFile(`bebop.json`)
+	if len(savedList.Tokens) != 4 {
+		t.Fatalf(`got %d tokens, want 4`, len(savedList.Tokens))
+	}
+	for _, token := range savedList.Tokens {
+		if _, ok := token.Extensions[`color`]; ok {
+			t.Errorf(`%s has an empty color extension`, token.Symbol)
+		}
+	}
+	if savedList.Tokens[0].Extensions[`displayDecimals`] != float64(2) {
+		t.Errorf(`got the extensions %v for DAI`, savedList.Tokens[0].Extensions)
+	}
+	if savedList.Tokens[1].Extensions != nil {
+		t.Errorf(`got the extensions %v for USDC`, savedList.Tokens[1].Extensions)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
@@ -27,13 +28,13 @@ var BLOCKSCOUTV6_URI = map[uint64]string{
 	7777777: `https://explorer.zora.energy/`,
 }
 
-func handleBlockScoutTokenList(chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+func handleBlockScoutTokenList(ctx context.Context, chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	return tokenList
 }
 
-func fetchBlockScoutV5TokenList(chainID uint64) []models.TokenListToken {
+func fetchBlockScoutV5TokenList(ctx context.Context, chainID uint64) ([]models.TokenListToken, error) {
 	type TBlockScoutAPIResponse struct {
 		Items    []string `json:"items"`
 		NextPage string   `json:"next_page_path"`
@@ -44,7 +45,10 @@ func fetchBlockScoutV5TokenList(chainID uint64) []models.TokenListToken {
 	tokens := []common.Address{}
 
 	for i := 0; i < 20; i++ {
-		response := helpers.FetchJSON[TBlockScoutAPIResponse](explorerBaseURI + nextPageURI)
+		response, err := helpers.FetchJSON[TBlockScoutAPIResponse](ctx, explorerBaseURI+nextPageURI)
+		if err != nil {
+			return nil, err
+		}
 		for _, token := range response.Items {
 			dataIdentifierHash := strings.Split(token, "data-identifier-hash=\"")[1]
 			dataIdentifierHash = strings.Split(dataIdentifierHash, "\"")[0]
@@ -53,10 +57,10 @@ func fetchBlockScoutV5TokenList(chainID uint64) []models.TokenListToken {
 		nextPageURI = response.NextPage + `&type=JSON`
 	}
 
-	return handleBlockScoutTokenList(chainID, tokens)
+	return handleBlockScoutTokenList(ctx, chainID, tokens), nil
 }
 
-func fetchBlockScoutV6TokenList(chainID uint64) []models.TokenListToken {
+func fetchBlockScoutV6TokenList(ctx context.Context, chainID uint64) ([]models.TokenListToken, error) {
 	type TBlockScoutAPIResponse struct {
 		Items []struct {
 			Address string `json:"address"`
@@ -79,7 +83,10 @@ func fetchBlockScoutV6TokenList(chainID uint64) []models.TokenListToken {
 	tokens := []common.Address{}
 
 	for i := 0; i < 40; i++ {
-		response := helpers.FetchJSON[TBlockScoutAPIResponse](explorerBaseURI + nextPageURI)
+		response, err := helpers.FetchJSON[TBlockScoutAPIResponse](ctx, explorerBaseURI+nextPageURI)
+		if err != nil {
+			return nil, err
+		}
 		for _, token := range response.Items {
 			if token.Type == `ERC-721` || token.Type == `ERC-1155` {
 				continue
@@ -90,21 +97,42 @@ func fetchBlockScoutV6TokenList(chainID uint64) []models.TokenListToken {
 		nextPageURI = strings.ReplaceAll(nextPageURI, ` `, `%20`)
 	}
 
-	return handleBlockScoutTokenList(chainID, tokens)
+	return handleBlockScoutTokenList(ctx, chainID, tokens), nil
 }
 
-func buildBlockScoutTokenList() {
+func buildBlockScoutTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`blockscout.json`)
 	tokenList.Name = `Blockscout`
 	tokenList.LogoURI = `https://2383309224-files.gitbook.io/~/files/v0/b/gitbook-x-prod.appspot.com/o/spaces%2F-Lq1XoWGmy8zggj_u2fM%2Ficon%2FyFkt6mPJJvjKiSBBOppe%2FBS_logo_slack.png?alt=media`
 	tokenList.Keywords = []string{`explorer`, `blockscout`}
 
 	tokens := []models.TokenListToken{}
+	chainErrors := &helpers.TChainErrors{}
 	for chainID := range BLOCKSCOUTV5_URI {
-		tokens = append(tokens, fetchBlockScoutV5TokenList(chainID)...)
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		chainTokens, err := fetchBlockScoutV5TokenList(ctx, chainID)
+		chainErrors.Add(chainID, err)
+		tokens = append(tokens, chainTokens...)
 	}
 	for chainID := range BLOCKSCOUTV6_URI {
-		tokens = append(tokens, fetchBlockScoutV6TokenList(chainID)...)
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		chainTokens, err := fetchBlockScoutV6TokenList(ctx, chainID)
+		chainErrors.Add(chainID, err)
+		tokens = append(tokens, chainTokens...)
+	}
+	tokens, err := helpers.KeepTokensOfFailedChains(tokenList, tokens, chainErrors.OrNil())
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
 	}
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `blockscout.json`, helpers.SavingMethodStandard)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `blockscout.json`, helpers.SavingMethodStandard)
 }
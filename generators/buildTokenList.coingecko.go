@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"strconv"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
@@ -39,9 +41,12 @@ func coingeckoMapNetworkNameToChainID(network string) uint64 {
 	return 0
 }
 
-func fetchCoingeckoLegacyListLogoURI() map[string]string {
+func fetchCoingeckoLegacyListLogoURI(ctx context.Context) map[string]string {
 	logoURIList := make(map[string]string)
-	list := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](`https://tokens.coingecko.com/uniswap/all.json`)
+	list, err := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](ctx, `https://tokens.coingecko.com/uniswap/all.json`)
+	if err != nil {
+		logs.Warning(`Could not load the CoinGecko logos, using the fallback ones: ` + err.Error())
+	}
 	for _, v := range list.Tokens {
 		chainIDStr := strconv.FormatInt(int64(v.ChainID), 10)
 		logoURIList[chainIDStr+`_`+common.HexToAddress(v.Address).Hex()] = v.LogoURI
@@ -49,8 +54,8 @@ func fetchCoingeckoLegacyListLogoURI() map[string]string {
 	return logoURIList
 }
 
-func handleCoingeckoTokenList(tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
-	logoURIs := fetchCoingeckoLegacyListLogoURI()
+func handleCoingeckoTokenList(ctx context.Context, tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
+	logoURIs := fetchCoingeckoLegacyListLogoURI(ctx)
 	tokensForChainIDSyncMap := helpers.InitSyncMap(tokensPerChainID)
 
 	// Fetch the basic informations for all the tokens for all the chains
@@ -62,7 +67,7 @@ func handleCoingeckoTokenList(tokensPerChainID map[uint64][]common.Address) []mo
 			syncMapRaw, _ := tokensForChainIDSyncMap.Load(chainID)
 			syncMap := syncMapRaw.([]models.TokenListToken)
 
-			tokensInfo := helpers.RetrieveBasicInformations(chainID, list)
+			tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, list)
 			for _, address := range list {
 				if token, ok := tokensInfo[address.Hex()]; ok {
 					if newToken, err := helpers.SetToken(
@@ -85,9 +90,12 @@ func handleCoingeckoTokenList(tokensPerChainID map[uint64][]common.Address) []mo
 	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
 }
 
-func fetchCoingeckoTokenList() []models.TokenListToken {
+func fetchCoingeckoTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	tokensPerChainID := make(map[uint64][]common.Address)
-	list := helpers.FetchJSON[[]TCoingeckoList](`https://api.coingecko.com/api/v3/coins/list?include_platform=true`)
+	list, err := helpers.FetchJSON[[]TCoingeckoList](ctx, `https://api.coingecko.com/api/v3/coins/list?include_platform=true`)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, v := range list {
 		if len(v.Platforms) == 0 {
@@ -108,15 +116,18 @@ func fetchCoingeckoTokenList() []models.TokenListToken {
 			tokensPerChainID[chainID] = append(tokensPerChainID[chainID], common.HexToAddress(addressOnPlatform))
 		}
 	}
-	return handleCoingeckoTokenList(tokensPerChainID)
+	return handleCoingeckoTokenList(ctx, tokensPerChainID), nil
 }
 
-func buildCoingeckoTokenList() {
+func buildCoingeckoTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`coingecko.json`)
 	tokenList.Name = "CoinGecko"
 	tokenList.Keywords = []string{"coingecko", "defi"}
 	tokenList.LogoURI = "https://static.coingecko.com/s/about/gecko-1b23cd303298d7474345b1938c21fdb20c71f4f399eefa8637ad243b8ac5dbf5.png"
 
-	tokens := fetchCoingeckoTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `coingecko.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchCoingeckoTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `coingecko.json`, helpers.SavingMethodStandard)
 }
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func buildConsensysTokenList() {
+func buildConsensysTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`consensys.json`)
-	originalTokenList := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](
+	originalTokenList, err := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](
+		ctx,
 		`https://raw.githubusercontent.com/Consensys/linea-token-list/main/json/linea-mainnet-token-shortlist.json`,
 	)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 	tokenList.Name = originalTokenList.Name
 	tokenList.LogoURI = `https://avatars.githubusercontent.com/u/10818037?s=200&v=4`
 	tokenList.Keywords = originalTokenList.Keywords
@@ -27,7 +33,7 @@ func buildConsensysTokenList() {
 			continue
 		}
 
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokensForChain)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokensForChain)
 		for _, existingToken := range tokensForChain {
 			if token, ok := tokensInfo[existingToken.Hex()]; ok {
 				if newToken, err := helpers.SetToken(
@@ -57,6 +63,6 @@ func buildConsensysTokenList() {
 		tokenList.NextTokensMap[key] = token
 	}
 
-	tokens := helpers.GetTokensFromList(tokenList.Tokens)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `consensys.json`, helpers.SavingMethodStandard)
+	tokens := helpers.GetTokensFromList(ctx, tokenList.Tokens)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `consensys.json`, helpers.SavingMethodStandard)
 }
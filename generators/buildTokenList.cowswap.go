@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func buildCowswapTokenList() {
+func buildCowswapTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`cowswap.json`)
-	originalTokenList := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](
+	originalTokenList, err := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](
+		ctx,
 		`https://raw.githubusercontent.com/cowprotocol/token-lists/main/src/public/CowSwap.json`,
 	)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 	tokenList.Name = originalTokenList.Name
 	tokenList.LogoURI = `https://raw.githubusercontent.com/cowprotocol/cowswap/c5974fb8a45d678029ecb013dab33722e152daaa/src/assets/cow-swap/cow_v2.svg`
 	tokenList.Keywords = originalTokenList.Keywords
@@ -27,7 +33,7 @@ func buildCowswapTokenList() {
 			continue
 		}
 
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokensForChain)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokensForChain)
 		for _, existingToken := range tokensForChain {
 			if token, ok := tokensInfo[existingToken.Hex()]; ok {
 				if newToken, err := helpers.SetToken(
@@ -57,6 +63,6 @@ func buildCowswapTokenList() {
 		tokenList.NextTokensMap[key] = token
 	}
 
-	tokens := helpers.GetTokensFromList(tokenList.Tokens)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `cowswap.json`, helpers.SavingMethodStandard)
+	tokens := helpers.GetTokensFromList(ctx, tokenList.Tokens)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `cowswap.json`, helpers.SavingMethodStandard)
 }
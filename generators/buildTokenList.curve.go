@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -15,6 +17,7 @@ type TCurveTokenData struct {
 	LpTokenAddress string   `json:"lpTokenAddress"`
 	LpTokenName    string   `json:"name"`
 	LpTokenSymbol  string   `json:"symbol"`
+	PoolType       string   `json:"-"`
 }
 type TCurveList struct {
 	Data struct {
@@ -59,7 +62,24 @@ var APIURIForCurve = map[uint64][]string{
 	},
 }
 
-func handleCurveTokenList(listPerChainID map[uint64][]TCurveTokenData) []models.TokenListToken {
+/**************************************************************************************************
+** curvePoolMetadata returns the TPoolMetadata of the LP token of a Curve pool. The pool type is
+** the registry of the pool in the Curve API (main, crypto, factory, factory-crypto). Old pools
+** have an LP token distinct from the pool contract, whose address is then kept in Pool.
+**************************************************************************************************/
+func curvePoolMetadata(token TCurveTokenData) models.TPoolMetadata {
+	coins := []string{}
+	for _, coinAddress := range token.CoinsAddresses {
+		coins = append(coins, common.HexToAddress(coinAddress).Hex())
+	}
+	pool := models.NewPoolMetadata(`curve`, token.PoolType, coins)
+	if common.HexToAddress(token.Address) != common.HexToAddress(token.LpTokenAddress) {
+		pool.Pool = common.HexToAddress(token.Address).Hex()
+	}
+	return pool
+}
+
+func handleCurveTokenList(ctx context.Context, listPerChainID map[uint64][]TCurveTokenData) []models.TokenListToken {
 	tokensForChainIDSyncMap := helpers.InitSyncMap(listPerChainID)
 
 	// Fetch the basic informations for all the tokens for all the chains
@@ -72,12 +92,14 @@ func handleCurveTokenList(listPerChainID map[uint64][]TCurveTokenData) []models.
 			syncMap := syncMapRaw.([]models.TokenListToken)
 
 			listOfAddresses := []common.Address{}
+			poolOfLpToken := map[string]models.TPoolMetadata{}
 			for _, token := range list {
 				if !chains.IsTokenIgnored(chainID, common.HexToAddress(token.Address)) {
 					listOfAddresses = append(listOfAddresses, common.HexToAddress(token.Address))
 				}
 				if !chains.IsTokenIgnored(chainID, common.HexToAddress(token.LpTokenAddress)) {
 					listOfAddresses = append(listOfAddresses, common.HexToAddress(token.LpTokenAddress))
+					poolOfLpToken[common.HexToAddress(token.LpTokenAddress).Hex()] = curvePoolMetadata(token)
 				}
 				for _, coinAddress := range token.CoinsAddresses {
 					if !chains.IsTokenIgnored(chainID, common.HexToAddress(coinAddress)) {
@@ -86,7 +108,7 @@ func handleCurveTokenList(listPerChainID map[uint64][]TCurveTokenData) []models.
 				}
 			}
 
-			tokensInfo := helpers.RetrieveBasicInformations(chainID, listOfAddresses)
+			tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, listOfAddresses)
 			for _, address := range listOfAddresses {
 				if token, ok := tokensInfo[address.Hex()]; ok {
 					if newToken, err := helpers.SetToken(
@@ -97,6 +119,10 @@ func handleCurveTokenList(listPerChainID map[uint64][]TCurveTokenData) []models.
 						chainID,
 						int(token.Decimals),
 					); err == nil {
+						if pool, ok := poolOfLpToken[address.Hex()]; ok {
+							newToken.Tags = []string{models.TagLPToken}
+							newToken.Metadata = pool.ToMetadata()
+						}
 						syncMap = append(syncMap, newToken)
 						tokensForChainIDSyncMap.Store(chainID, syncMap)
 					}
@@ -109,28 +135,48 @@ func handleCurveTokenList(listPerChainID map[uint64][]TCurveTokenData) []models.
 	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
 }
 
-func fetchCurveTokenList() []models.TokenListToken {
+func fetchCurveTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	listPerChainID := make(map[uint64][]TCurveTokenData)
+	chainErrors := &helpers.TChainErrors{}
 
 	for chainID, uris := range APIURIForCurve {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 
+		var chainErr error
 		for _, uri := range uris {
-			list := helpers.FetchJSON[TCurveList](uri)
-			listPerChainID[chainID] = append(listPerChainID[chainID], list.Data.PoolData...)
+			list, err := helpers.FetchJSON[TCurveList](ctx, uri)
+			if err != nil {
+				chainErr = err
+				break
+			}
+			poolType := uri[strings.LastIndex(uri, `/`)+1:]
+			for _, pool := range list.Data.PoolData {
+				pool.PoolType = poolType
+				listPerChainID[chainID] = append(listPerChainID[chainID], pool)
+			}
 		}
+		if chainErr != nil {
+			delete(listPerChainID, chainID)
+		}
+		chainErrors.Add(chainID, chainErr)
 	}
 
-	return handleCurveTokenList(listPerChainID)
+	return handleCurveTokenList(ctx, listPerChainID), chainErrors.OrNil()
 }
 
-func buildCurveTokenList() {
+func buildCurveTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`curve.json`)
 	tokenList.Name = "Curve Token List"
 	tokenList.LogoURI = "https://classic.curve.fi/logo.png"
 
-	tokens := fetchCurveTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `curve.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchCurveTokenList(ctx)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `curve.json`, helpers.SavingMethodStandard)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
@@ -37,8 +39,11 @@ func defillamaMapNetworkNameToChainID(network string) uint64 {
 	return 0
 }
 
-func fetchDefillamaTokenList() []models.TokenListToken {
-	list := helpers.FetchJSON[[]TDefillamaList](`https://defillama-datasets.llama.fi/tokenlist/all.json`)
+func fetchDefillamaTokenList(ctx context.Context) ([]models.TokenListToken, error) {
+	list, err := helpers.FetchJSON[[]TDefillamaList](ctx, `https://defillama-datasets.llama.fi/tokenlist/all.json`)
+	if err != nil {
+		return nil, err
+	}
 	listPerChainID := []models.TokenListToken{}
 	for _, v := range list {
 		if len(v.Platforms) == 0 {
@@ -62,14 +67,17 @@ func fetchDefillamaTokenList() []models.TokenListToken {
 			})
 		}
 	}
-	return helpers.GetTokensFromList(listPerChainID)
+	return helpers.GetTokensFromList(ctx, listPerChainID), nil
 }
 
-func buildDefillamaTokenList() {
+func buildDefillamaTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`defillama.json`)
 	tokenList.Name = "DefiLlama"
 	tokenList.LogoURI = "https://wiki.defillama.com/w/resources/assets/wiki.png?88de1"
 
-	tokens := fetchDefillamaTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `defillama.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchDefillamaTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `defillama.json`, helpers.SavingMethodStandard)
 }
@@ -1,8 +1,7 @@
 package main
 
 import (
-	"io/ioutil"
-	"net/http"
+	"context"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -11,33 +10,30 @@ import (
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func handleLedgerTokenList(tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
+func handleLedgerTokenList(ctx context.Context, tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
 	tokenList := []models.TokenListToken{}
 
 	for chainID, list := range tokensPerChainID {
-		tokenList := helpers.GetTokensFromAddresses(chainID, list)
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		tokenList = append(tokenList, helpers.GetTokensFromAddresses(ctx, chainID, list)...)
 		tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	}
 
 	return tokenList
 }
 
-func fetchLedgerTokenList() []models.TokenListToken {
+/**************************************************************************************************
+** parseLedgerCryptoAssets extracts the token addresses from the markdown table of the Ledger
+** cryptoassets file, where each row is `| Parent currency | Ticker | Address | ... |`.
+**************************************************************************************************/
+func parseLedgerCryptoAssets(body []byte) map[uint64][]common.Address {
 	tokensPerChainID := map[uint64][]common.Address{}
 	tokensPerChainID[1] = []common.Address{}
 	tokensPerChainID[56] = []common.Address{}
 	tokensPerChainID[137] = []common.Address{}
 
-	resp, err := http.Get(`https://raw.githubusercontent.com/LedgerHQ/ledger-live/develop/apps/ledger-live-desktop/cryptoassets.md`)
-	if err != nil {
-		return []models.TokenListToken{}
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []models.TokenListToken{}
-	}
-
 	lines := strings.Split(string(body), "\n")
 	index := 0
 	for i, line := range lines {
@@ -72,15 +68,26 @@ func fetchLedgerTokenList() []models.TokenListToken {
 			}
 		}
 	}
-	return handleLedgerTokenList(tokensPerChainID)
+	return tokensPerChainID
 }
 
-func buildLedgersTokenList() {
+func fetchLedgerTokenList(ctx context.Context) ([]models.TokenListToken, error) {
+	body, err := helpers.FetchBody(ctx, `https://raw.githubusercontent.com/LedgerHQ/ledger-live/develop/apps/ledger-live-desktop/cryptoassets.md`)
+	if err != nil {
+		return nil, err
+	}
+	return handleLedgerTokenList(ctx, parseLedgerCryptoAssets(body)), nil
+}
+
+func buildLedgersTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`ledger.json`)
 	tokenList.Name = "Ledger"
 	tokenList.Keywords = []string{"Ledger"}
 	tokenList.LogoURI = "https://www.ledger.com/wp-content/uploads/2021/11/Ledger_favicon.png"
 
-	tokens := fetchLedgerTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `ledger.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchLedgerTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `ledger.json`, helpers.SavingMethodStandard)
 }
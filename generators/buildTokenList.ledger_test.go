@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/fixtures"
+)
+
+func TestParseLedgerCryptoAssets(t *testing.T) {
+	fixturePaths, _ := filepath.Glob(filepath.Join(REPO_PATH, `testdata`, `fixtures`, `ledger`, `*.json`))
+	if len(fixturePaths) != 1 {
+		t.Fatalf(`got %d fixtures for ledger, want the cryptoassets file`, len(fixturePaths))
+	}
+	content, err := os.ReadFile(fixturePaths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := fixtures.TFixture{}
+	if err := json.Unmarshal(content, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	// The coins listed before the table of the tokens and the zero address are skipped
+	tokensPerChainID := parseLedgerCryptoAssets([]byte(fixture.Body))
+	for chainID, expected := range map[uint64][]string{
+		1: {
+			`0xdAC17F958D2ee523a2206206994597C13D831ec7`,
+			`0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`,
+			`0x6B175474E89094C44Da98b954EedeAC495271d0F`,
+			`0x00000000000000000000000000000000DeaDBeef`,
+		},
+		56:  {`0xe9e7CEA3DedcA5984780Bafc599bD69ADd087D56`},
+		137: {`0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174`},
+	} {
+		addresses := tokensPerChainID[chainID]
+		if len(addresses) != len(expected) {
+			t.Errorf(`chain %d: got %d addresses, want %d`, chainID, len(addresses), len(expected))
+			continue
+		}
+		for i, address := range expected {
+			if addresses[i] != common.HexToAddress(address) {
+				t.Errorf(`chain %d: got %s at %d, want %s`, chainID, addresses[i].Hex(), i, address)
+			}
+		}
+	}
+	if len(tokensPerChainID) != 3 {
+		t.Errorf(`got the chains %v, want 1, 56 and 137`, tokensPerChainID)
+	}
+}
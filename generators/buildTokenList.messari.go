@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"strconv"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
@@ -46,14 +46,20 @@ func messariMapNetworkNameToChainID(network string) uint64 {
 	return 0
 }
 
-func fetchMessariTokenList() []models.TokenListToken {
+func fetchMessariTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	limit := 500
 	page := 1
 	allTokens := []models.TokenListToken{}
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		uri := `https://data.messari.io/api/v2/assets?fields=name,symbol,contract_addresses,id&sort=id&limit=` + strconv.FormatInt(int64(limit), 10) + `&page=` + strconv.FormatInt(int64(page), 10)
-		list := helpers.FetchJSON[TMessariList](uri)
+		list, err := helpers.FetchJSON[TMessariList](ctx, uri)
+		if err != nil {
+			return nil, err
+		}
 
 		if list.Tokens == nil || len(list.Tokens) == 0 {
 			break
@@ -81,18 +87,19 @@ func fetchMessariTokenList() []models.TokenListToken {
 			}
 		}
 		page++
-		//20 requests per minutes, so 3 seconds between each request and we should be fine
-		time.Sleep(3 * time.Second)
 	}
 
-	return helpers.GetTokensFromList(allTokens)
+	return helpers.GetTokensFromList(ctx, allTokens), nil
 }
 
-func buildMessariTokenList() {
+func buildMessariTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`messari.json`)
 	tokenList.Name = "Messari Token List"
 	tokenList.LogoURI = "https://messari.io/images/logo_tcr-check.svg"
 
-	tokens := fetchMessariTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `messari.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchMessariTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `messari.json`, helpers.SavingMethodStandard)
 }
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func buildOptimismTokenList() {
+func buildOptimismTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`optimism.json`)
-	originalTokenList := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](`https://raw.githubusercontent.com/ethereum-optimism/ethereum-optimism.github.io/master/optimism.tokenlist.json`)
+	originalTokenList, err := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](ctx, `https://raw.githubusercontent.com/ethereum-optimism/ethereum-optimism.github.io/master/optimism.tokenlist.json`)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 	tokenList.Name = helpers.SafeString(originalTokenList.Name, `Optimism Token List`)
 	tokenList.LogoURI = helpers.SafeString(originalTokenList.LogoURI, `https://ethereum-optimism.github.io/optimism.svg`)
 	tokenList.Keywords = originalTokenList.Keywords
@@ -25,7 +30,7 @@ func buildOptimismTokenList() {
 			continue
 		}
 
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokensForChain)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokensForChain)
 		for _, existingToken := range tokensForChain {
 			if token, ok := tokensInfo[existingToken.Hex()]; ok {
 				if newToken, err := helpers.SetToken(
@@ -55,6 +60,6 @@ func buildOptimismTokenList() {
 		tokenList.NextTokensMap[key] = token
 	}
 
-	tokens := helpers.GetTokensFromList(tokenList.Tokens)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `optimism.json`, helpers.SavingMethodStandard)
+	tokens := helpers.GetTokensFromList(ctx, tokenList.Tokens)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `optimism.json`, helpers.SavingMethodStandard)
 }
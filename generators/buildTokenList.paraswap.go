@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
@@ -27,20 +29,28 @@ var APIURIForParaswap = map[uint64]string{
 	43114: `https://apiv5.paraswap.io/tokens/43114`,
 }
 
-func fetchParaswapTokenList() []models.TokenListToken {
+func fetchParaswapTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	tokens := []models.TokenListToken{}
+	chainErrors := &helpers.TChainErrors{}
 
 	for chainID, uri := range APIURIForParaswap {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 
 		tokenAddresses := []common.Address{}
-		list := helpers.FetchJSON[TParaswapList](uri)
+		list, err := helpers.FetchJSON[TParaswapList](ctx, uri)
+		chainErrors.Add(chainID, err)
+		if err != nil {
+			continue
+		}
 		for _, v := range list.Tokens {
 			tokenAddresses = append(tokenAddresses, common.HexToAddress(v.Address))
 		}
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokenAddresses)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokenAddresses)
 
 		for _, existingToken := range list.Tokens {
 			if token, ok := tokensInfo[common.HexToAddress(existingToken.Address).Hex()]; ok {
@@ -63,14 +73,17 @@ func fetchParaswapTokenList() []models.TokenListToken {
 		}
 	}
 
-	return tokens
+	return tokens, chainErrors.OrNil()
 }
 
-func buildParaswapTokenList() {
+func buildParaswapTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`paraswap.json`)
 	tokenList.Name = "Paraswap Token List"
 	tokenList.LogoURI = "https://app.paraswap.io/psp_logo.svg"
 
-	tokens := fetchParaswapTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `paraswap.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchParaswapTokenList(ctx)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `paraswap.json`, helpers.SavingMethodStandard)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -52,14 +53,20 @@ func portalsMapNetworkNameToChainID(network string) uint64 {
 	return 0
 }
 
-func fetchPortalsTokenList() []models.TokenListToken {
+func fetchPortalsTokenList(ctx context.Context) ([]models.TokenListToken, error) {
 	limit := 250
 	page := 0
 	tokens := []models.TokenListToken{}
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		uri := `https://api.portals.fi/v2/tokens?limit=` + strconv.FormatInt(int64(limit), 10) + `&page=` + strconv.FormatInt(int64(page), 10)
-		list := helpers.FetchJSON[TPortalList](uri)
+		list, err := helpers.FetchJSON[TPortalList](ctx, uri)
+		if err != nil {
+			return nil, err
+		}
 
 		for _, token := range list.Tokens {
 			logoURI := ``
@@ -82,14 +89,17 @@ func fetchPortalsTokenList() []models.TokenListToken {
 		}
 		page++
 	}
-	return helpers.GetTokensFromList(tokens)
+	return helpers.GetTokensFromList(ctx, tokens), nil
 }
 
-func buildPortalsTokenList() {
+func buildPortalsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`portals.json`)
 	tokenList.Name = "Portals Token List"
 	tokenList.LogoURI = "https://portals-assets-bucket.s3.amazonaws.com/logo.png"
 
-	tokens := fetchPortalsTokenList()
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `portals.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchPortalsTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `portals.json`, helpers.SavingMethodStandard)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
@@ -11,8 +13,8 @@ var ROUTESCAN_URI = map[uint64]string{
 	81457: `https://cdn.routescan.io/api/evm/all/erc20`,
 }
 
-func handleRouteScanTokenList(chainID uint64, tokenAddresses []common.Address, logos map[common.Address]string) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+func handleRouteScanTokenList(ctx context.Context, chainID uint64, tokenAddresses []common.Address, logos map[common.Address]string) []models.TokenListToken {
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 
 	for i, token := range tokenList {
@@ -26,7 +28,7 @@ func handleRouteScanTokenList(chainID uint64, tokenAddresses []common.Address, l
 	return tokenList
 }
 
-func fetchRouteScanTokenList(chainID uint64) []models.TokenListToken {
+func fetchRouteScanTokenList(ctx context.Context, chainID uint64) ([]models.TokenListToken, error) {
 	type TRoutescanAPIResponse struct {
 		Items []struct {
 			Address string `json:"address"`
@@ -46,7 +48,10 @@ func fetchRouteScanTokenList(chainID uint64) []models.TokenListToken {
 	nextPageURI := `?count=false&includedChainIds=81457&limit=1000&sort=marketCap%2Cdesc`
 	tokens := []common.Address{}
 	logos := map[common.Address]string{}
-	response := helpers.FetchJSON[TRoutescanAPIResponse](explorerBaseURI + nextPageURI)
+	response, err := helpers.FetchJSON[TRoutescanAPIResponse](ctx, explorerBaseURI+nextPageURI)
+	if err != nil {
+		return nil, err
+	}
 	for _, token := range response.Items {
 		if token.Detail.Type == `ERC-721` || token.Detail.Type == `ERC-1155` {
 			continue
@@ -64,18 +69,31 @@ func fetchRouteScanTokenList(chainID uint64) []models.TokenListToken {
 		logos[common.HexToAddress(token.Address)] = token.Detail.Icon
 	}
 
-	return handleRouteScanTokenList(chainID, tokens, logos)
+	return handleRouteScanTokenList(ctx, chainID, tokens, logos), nil
 }
 
-func buildRouteScanTokenList() {
+func buildRouteScanTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`routescan.json`)
 	tokenList.Name = `RouteScan`
 	tokenList.LogoURI = `https://cms-cdn.avascan.com/cms2/routescan.432df9c80dd7.svg`
 	tokenList.Keywords = []string{`explorer`, `routescan`}
 
 	tokens := []models.TokenListToken{}
+	chainErrors := &helpers.TChainErrors{}
 	for chainID := range ROUTESCAN_URI {
-		tokens = append(tokens, fetchRouteScanTokenList(chainID)...)
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		chainTokens, err := fetchRouteScanTokenList(ctx, chainID)
+		chainErrors.Add(chainID, err)
+		tokens = append(tokens, chainTokens...)
+	}
+	tokens, err := helpers.KeepTokensOfFailedChains(tokenList, tokens, chainErrors.OrNil())
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
 	}
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `routescan.json`, helpers.SavingMethodStandard)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `routescan.json`, helpers.SavingMethodStandard)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -75,19 +77,23 @@ var BASE_EXPLORERS_URI = map[uint64]etherscanSASExplorers{
 	},
 }
 
-func handleScanTokenList(chainID uint64, tokenAddresses []common.Address, imageURI []string) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+// SCAN_CHAIN_IDS are the chains of BASE_EXPLORERS_URI the etherscan list is built from, in order
+var SCAN_CHAIN_IDS = []uint64{1, 10, 56, 100, 137, 250, 1101, 8453, 42161, 81457}
+
+func handleScanTokenList(ctx context.Context, chainID uint64, tokenAddresses []common.Address, imageURI []string) []models.TokenListToken {
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	return tokenList
 }
 
-func fetchScanTokenListForL2(chainID uint64, currentPage uint8) []models.TokenListToken {
+func fetchScanTokenListForL2(ctx context.Context, chainID uint64, currentPage uint8) ([]models.TokenListToken, error) {
 	explorerBaseUri := BASE_EXPLORERS_URI[chainID].BaseURL
 	imageURI := []string{}
 	tokens := []common.Address{}
 	c := colly.NewCollector(
 		colly.UserAgent(`Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36`),
 	)
+	c.WithTransport(helpers.ContextTransport(ctx))
 
 	c.OnHTML("div.media", func(e *colly.HTMLElement) {
 		e.ForEach("img.u-xs-avatar", func(i int, h *colly.HTMLElement) {
@@ -104,14 +110,16 @@ func fetchScanTokenListForL2(chainID uint64, currentPage uint8) []models.TokenLi
 		logs.Error(`Error fetching token list for chainID: ` + strconv.Itoa(int(chainID)) + ` - ` + e.Error() + ` on page: ` + explorerBaseUri + `/tokens?p=` + strconv.Itoa(int(currentPage)))
 	})
 
-	for currentPage < 20 {
-		c.Visit(explorerBaseUri + `/tokens?p=` + strconv.Itoa(int(currentPage)))
+	for currentPage < 20 && ctx.Err() == nil {
+		if err := c.Visit(explorerBaseUri + `/tokens?p=` + strconv.Itoa(int(currentPage))); err != nil {
+			return nil, errors.New(`failed to scrape the page ` + strconv.Itoa(int(currentPage)) + ` of ` + explorerBaseUri + `: ` + err.Error())
+		}
 		currentPage++
 	}
-	return handleScanTokenList(chainID, tokens, imageURI)
+	return handleScanTokenList(ctx, chainID, tokens, imageURI), nil
 }
 
-func fetchScanTokenListForL1(chainID uint64, currentPage uint8) []models.TokenListToken {
+func fetchScanTokenListForL1(ctx context.Context, chainID uint64, currentPage uint8) ([]models.TokenListToken, error) {
 	explorerBaseUri := BASE_EXPLORERS_URI[chainID].BaseURL
 	imageURI := []string{}
 	tokens := []common.Address{}
@@ -119,6 +127,7 @@ func fetchScanTokenListForL1(chainID uint64, currentPage uint8) []models.TokenLi
 		colly.UserAgent(`Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36`),
 	)
 	c.IgnoreRobotsTxt = true
+	c.WithTransport(helpers.ContextTransport(ctx))
 
 	c.OnHTML("a.d-flex.align-items-center.gap-1.link-dark", func(e *colly.HTMLElement) {
 		e.ForEach("img.rounded-circle", func(i int, h *colly.HTMLElement) {
@@ -133,36 +142,44 @@ func fetchScanTokenListForL1(chainID uint64, currentPage uint8) []models.TokenLi
 		logs.Error(`Error fetching token list for chainID: ` + strconv.Itoa(int(chainID)) + ` - ` + e.Error() + ` on page: ` + explorerBaseUri + `/tokens?p=` + strconv.Itoa(int(currentPage)))
 	})
 
-	for currentPage < 20 {
-		c.Visit(explorerBaseUri + `/tokens?p=` + strconv.Itoa(int(currentPage)))
+	for currentPage < 20 && ctx.Err() == nil {
+		if err := c.Visit(explorerBaseUri + `/tokens?p=` + strconv.Itoa(int(currentPage))); err != nil {
+			return nil, errors.New(`failed to scrape the page ` + strconv.Itoa(int(currentPage)) + ` of ` + explorerBaseUri + `: ` + err.Error())
+		}
 		currentPage++
 	}
-	return handleScanTokenList(chainID, tokens, imageURI)
+	return handleScanTokenList(ctx, chainID, tokens, imageURI), nil
 }
 
-func fetchScanTokenList(chainID uint64) []models.TokenListToken {
+func fetchScanTokenList(ctx context.Context, chainID uint64) ([]models.TokenListToken, error) {
 	explorerBaseType := BASE_EXPLORERS_URI[chainID].Type
 	if explorerBaseType == L1 {
-		return fetchScanTokenListForL1(chainID, 1)
+		return fetchScanTokenListForL1(ctx, chainID, 1)
 	}
-	return fetchScanTokenListForL2(chainID, 1)
+	return fetchScanTokenListForL2(ctx, chainID, 1)
 }
 
-func buildScanTokenList() {
+func buildScanTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`etherscan.json`)
 	tokenList.Name = `Etherscan`
 	tokenList.LogoURI = `https://etherscan.io/images/brandassets/etherscan-logo-circle.svg`
 	tokenList.Keywords = []string{`ethereum`, `etherscan`}
 	tokens := []models.TokenListToken{}
-	tokens = append(tokens, fetchScanTokenList(1)...)
-	tokens = append(tokens, fetchScanTokenList(10)...)
-	tokens = append(tokens, fetchScanTokenList(56)...)
-	tokens = append(tokens, fetchScanTokenList(100)...)
-	tokens = append(tokens, fetchScanTokenList(137)...)
-	tokens = append(tokens, fetchScanTokenList(250)...)
-	tokens = append(tokens, fetchScanTokenList(1101)...)
-	tokens = append(tokens, fetchScanTokenList(8453)...)
-	tokens = append(tokens, fetchScanTokenList(42161)...)
-	tokens = append(tokens, fetchScanTokenList(81457)...)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `etherscan.json`, helpers.SavingMethodStandard)
+	chainErrors := &helpers.TChainErrors{}
+	for _, chainID := range SCAN_CHAIN_IDS {
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		chainTokens, err := fetchScanTokenList(ctx, chainID)
+		chainErrors.Add(chainID, err)
+		tokens = append(tokens, chainTokens...)
+	}
+	tokens, err := helpers.KeepTokensOfFailedChains(tokenList, tokens, chainErrors.OrNil())
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `etherscan.json`, helpers.SavingMethodStandard)
 }
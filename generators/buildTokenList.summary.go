@@ -3,11 +3,13 @@ package main
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"sort"
 	"strconv"
 	"time"
 
 	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
@@ -55,16 +57,26 @@ func listSupportedChains(list []models.TokenListToken) []int {
 	return detectedChains
 }
 
+// readTokenListForSummary reads a list for the summary, which never changes the lists: a missing
+// list is left missing and shows as empty
+func readTokenListForSummary(filePath string) models.TokenListData[models.TokenListToken] {
+	tokenList, err := helpers.ReadTokenListFromJsonFile(filePath)
+	if err != nil {
+		logs.Warning(`Failed to read ` + filePath + ` for the summary: ` + err.Error())
+	}
+	return tokenList
+}
+
 func buildSummary() {
 	tokenListSummary := TTokenListSummary{}
 	tokenListSummary.Name = `Tokenlistooor summary`
 	tokenListSummary.LogoURI = BASE_URI + `.github/tokenlistooor.svg`
-	tokenListSummary.Timestamp = time.Now().UTC().Unix()
-	for name, data := range GENERATORS {
+	for _, name := range generatorNames() {
 		if name == `yearn-min` {
 			continue
 		}
-		tokenList := helpers.LoadTokenListFromJsonFile(name + `.json`)
+		data := GENERATORS[name]
+		tokenList := readTokenListForSummary(name + `.json`)
 		listElement := TMinTokenListData{
 			Name:        tokenList.Name,
 			Timestamp:   tokenList.Timestamp,
@@ -90,7 +102,7 @@ func buildSummary() {
 
 	//Also add the tokenListooor list
 	{
-		tokenListooorList := helpers.LoadTokenListFromJsonFile(`tokenlistooor.json`)
+		tokenListooorList := readTokenListForSummary(`tokenlistooor.json`)
 		listElement := TMinTokenListData{
 			Name:        tokenListooorList.Name,
 			Timestamp:   tokenListooorList.Timestamp,
@@ -113,13 +125,16 @@ func buildSummary() {
 		}
 		tokenListSummary.Lists = append(tokenListSummary.Lists, listElement)
 	}
-	sort.Slice(tokenListSummary.Lists, func(i, j int) bool {
+	sort.SliceStable(tokenListSummary.Lists, func(i, j int) bool {
+		if tokenListSummary.Lists[i].Name == tokenListSummary.Lists[j].Name {
+			return tokenListSummary.Lists[i].URI < tokenListSummary.Lists[j].URI
+		}
 		return tokenListSummary.Lists[i].Name < tokenListSummary.Lists[j].Name
 	})
 
 	// Also add the popular list
 	{
-		popular := helpers.LoadTokenListFromJsonFile(`popular.json`)
+		popular := readTokenListForSummary(`popular.json`)
 		listElement := TMinTokenListData{
 			Name:        popular.Name,
 			Timestamp:   popular.Timestamp,
@@ -144,6 +159,21 @@ func buildSummary() {
 		tokenListSummary.Lists = append([]TMinTokenListData{listElement}, tokenListSummary.Lists...)
 	}
 
+	/**************************************************************************
+	** The timestamp of the summary is only moved when one of the lists
+	** changed, so running it again on the same lists gives the same file.
+	**************************************************************************/
+	previousSummary := TTokenListSummary{}
+	if content, err := os.ReadFile(helpers.BASE_PATH + `/lists/summary.json`); err == nil {
+		json.Unmarshal(content, &previousSummary)
+	}
+	previousLists, _ := json.Marshal(previousSummary.Lists)
+	nextLists, _ := json.Marshal(tokenListSummary.Lists)
+	if string(previousLists) == string(nextLists) {
+		return
+	}
+	tokenListSummary.Timestamp = time.Now().UTC().Unix()
+
 	jsonData, _ := json.MarshalIndent(tokenListSummary, "", "  ")
 	ioutil.WriteFile(helpers.BASE_PATH+`/lists/summary.json`, jsonData, 0644)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+)
+
+// readSummary returns the content of lists/summary.json and the summary it holds
+func readSummary(t *testing.T) ([]byte, TTokenListSummary) {
+	t.Helper()
+	content, err := os.ReadFile(helpers.BASE_PATH + `/lists/summary.json`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := TTokenListSummary{}
+	if err := json.Unmarshal(content, &summary); err != nil {
+		t.Fatal(err)
+	}
+	return content, summary
+}
+
+func TestBuildSummaryIsReproducible(t *testing.T) {
+	buildSummary()
+	content, summary := readSummary(t)
+	if len(summary.Lists) == 0 || summary.Lists[0].URI != BASE_URI+`lists/popular.json` {
+		t.Fatalf(`the popular list is not the first of the summary`)
+	}
+	if !sort.SliceIsSorted(summary.Lists[1:], func(i, j int) bool {
+		a, b := summary.Lists[1+i], summary.Lists[1+j]
+		return a.Name < b.Name || (a.Name == b.Name && a.URI < b.URI)
+	}) {
+		t.Error(`the lists of the summary are not sorted by name`)
+	}
+
+	for i := 0; i < 3; i++ {
+		buildSummary()
+		if nextContent, _ := readSummary(t); !bytes.Equal(nextContent, content) {
+			t.Fatalf("the summary changed on the same lists:\n%s", nextContent)
+		}
+	}
+}
+
+func TestBuildSummaryOnlyMovesTheTimestampWithTheLists(t *testing.T) {
+	buildSummary()
+	_, summary := readSummary(t)
+	summary.Timestamp = 1
+	if err := helpers.SaveJSONFile(helpers.BASE_PATH+`/lists/summary.json`, summary); err != nil {
+		t.Fatal(err)
+	}
+	buildSummary()
+	if _, summary = readSummary(t); summary.Timestamp != 1 {
+		t.Errorf(`the timestamp moved to %d while the lists did not change`, summary.Timestamp)
+	}
+
+	tokenList := helpers.LoadTokenListFromJsonFile(`cowswap.json`)
+	tokenList.Name = `CowSwap`
+	tokenList.Version.Major++
+	if err := helpers.SaveJSONFile(helpers.BASE_PATH+`/lists/cowswap.json`, tokenList); err != nil {
+		t.Fatal(err)
+	}
+	buildSummary()
+	if _, summary = readSummary(t); summary.Timestamp == 1 {
+		t.Error(`the timestamp did not move with the lists`)
+	}
+}
+
+func TestGeneratorNamesAreSorted(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		names := generatorNames()
+		if len(names) != len(GENERATORS) || !sort.StringsAreSorted(names) {
+			t.Fatalf(`got the generators %v`, names)
+		}
+		for _, name := range names {
+			if _, ok := GENERATORS[name]; !ok {
+				t.Errorf(`%s is not a generator`, name)
+			}
+		}
+	}
+}
@@ -2,14 +2,11 @@ package main
 
 import (
 	"context"
-	"strconv"
 	"sync"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/contracts"
-	"github.com/migratooor/tokenLists/generators/common/ethereum"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
@@ -17,7 +14,7 @@ import (
 
 var SUSHI_PAIR_THRESHOLD = 3
 
-func handleSushiswapPairsTokenList(tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
+func handleSushiswapPairsTokenList(ctx context.Context, tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
 	tokensForChainIDSyncMap := helpers.InitSyncMap(tokensPerChainID)
 
 	// Fetch the basic informations for all the tokens for all the chains
@@ -29,7 +26,7 @@ func handleSushiswapPairsTokenList(tokensPerChainID map[uint64][]common.Address)
 			syncMapRaw, _ := tokensForChainIDSyncMap.Load(chainID)
 			syncMap := syncMapRaw.([]models.TokenListToken)
 
-			tokensInfo := helpers.RetrieveBasicInformations(chainID, list)
+			tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, list)
 			for _, address := range list {
 				if token, ok := tokensInfo[address.Hex()]; ok {
 					if token.Name == `` || token.Symbol == `` {
@@ -55,10 +52,14 @@ func handleSushiswapPairsTokenList(tokensPerChainID map[uint64][]common.Address)
 	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
 }
 
-func fetchSushiswapPairsTokenList(extra map[string]interface{}) ([]models.TokenListToken, map[uint64]string) {
+func fetchSushiswapPairsTokenList(ctx context.Context, metadata map[string]interface{}, state *helpers.TIndexerState) ([]models.TokenListToken, error) {
 	tokensPerChainID := make(map[uint64][]common.Address)
 	allTokens := make(map[string]int)
-	lastBlockSync := make(map[uint64]string)
+	chainErrors := &helpers.TChainErrors{}
+	countTokens := func(event *contracts.UniV2FactoryPairCreated) {
+		allTokens[event.Token0.Hex()]++
+		allTokens[event.Token1.Hex()]++
+	}
 
 	/**************************************************************************
 	** Looping through all the Sushiswap contracts per chainID to read the logs
@@ -67,65 +68,31 @@ func fetchSushiswapPairsTokenList(extra map[string]interface{}) ([]models.TokenL
 	** least 10 different pairs.
 	**************************************************************************/
 	for chainID, sushiContract := range SushiswapContractsPerChainID {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 		tokensPerChainID[chainID] = []common.Address{}
 
 		/**********************************************************************
-		** Init the RPC and get the current block number to know where to start
-		** and end the logs fetching
+		** For each registered Sushiswap contract, we will index the creation
+		** events to count the pairs of each token, starting where the
+		** previous run stopped
 		**********************************************************************/
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		lastBlockSyncForChainID := uint64(0)
-		if sync, ok := extra[`lastBlockSyncFor_`+chainIDStr]; ok {
-			lastBlockSyncForChainID, _ = strconv.ParseUint(sync.(string), 10, 64)
-		}
-		client := ethereum.GetRPC(chainID)
-		currentBlockNumber, _ := client.BlockNumber(context.Background())
-		threshold := uint64(100_000)
-		if chainID == 56 {
-			threshold = uint64(5_000)
-		}
-
+		var chainErr error
 		for _, sushiContract := range sushiContract {
-			start := sushiContract.BlockNumber.Uint64()
-			if (lastBlockSyncForChainID != 0) && (lastBlockSyncForChainID > start) {
-				start = lastBlockSyncForChainID
+			start := factoryStartBlock(sushiContract.BlockNumber.Uint64(), metadata, chainID)
+			err := indexPairCreated(ctx, state, chainID, sushiContract.ContractAddress, start, countTokens)
+			if err != nil {
+				logs.Error("Error fetching all tokens from sushiswap factory contract: ", err)
+				chainErr = err
 			}
-			sushiV2Factory, _ := contracts.NewSushiV2Factory(sushiContract.ContractAddress, client)
-			for startBlockToTest := start; startBlockToTest <= currentBlockNumber; startBlockToTest += threshold {
-				end := startBlockToTest + threshold
-				if end > currentBlockNumber {
-					end = currentBlockNumber
-				}
-				options := &bind.FilterOpts{
-					Start:   startBlockToTest,
-					End:     &end,
-					Context: nil,
-				}
-				logs.Info(`v2 - start: `, startBlockToTest, ` end: `, end, ` total: `, len(allTokens), ` current block: `, currentBlockNumber, ` chainID: `, chainIDStr)
-				if log, err := sushiV2Factory.FilterPairCreated(options, nil, nil); err == nil {
-					for log.Next() {
-						if log.Error() != nil {
-							continue
-						}
-						if _, ok := allTokens[log.Event.Token0.Hex()]; !ok {
-							allTokens[log.Event.Token0.Hex()] = 0
-						}
-						if _, ok := allTokens[log.Event.Token1.Hex()]; !ok {
-							allTokens[log.Event.Token1.Hex()] = 0
-						}
-
-						allTokens[log.Event.Token0.Hex()]++
-						allTokens[log.Event.Token1.Hex()]++
-					}
-				} else {
-					logs.Error("Error fetching all tokens from sushiswap factory contract: ", err)
-					startBlockToTest -= threshold
-				}
-			}
-			lastBlockSync[chainID] = strconv.FormatUint(currentBlockNumber, 10)
+		}
+		chainErrors.Add(chainID, chainErr)
+		if chainErr == nil {
+			dropLegacyLastBlockSync(metadata, chainID)
 		}
 
 		/**********************************************************************
@@ -142,22 +109,22 @@ func fetchSushiswapPairsTokenList(extra map[string]interface{}) ([]models.TokenL
 		}
 	}
 
-	return handleSushiswapPairsTokenList(tokensPerChainID), lastBlockSync
+	return handleSushiswapPairsTokenList(ctx, tokensPerChainID), chainErrors.OrNil()
 }
 
-func buildSushiswapPairsTokenList() {
+func buildSushiswapPairsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`sushiswap-pairs.json`)
 	tokenList.Name = "SushiSwap Token Pairs"
 	tokenList.LogoURI = "https://raw.githubusercontent.com/sushiswap/art/master/sushi/logo-256x256.png"
 
-	tokens, lastBlockSync := fetchSushiswapPairsTokenList(tokenList.Metadata)
-	if tokenList.Metadata == nil {
-		tokenList.Metadata = make(map[string]interface{})
+	state := helpers.LoadIndexerState(`sushiswap-pairs`)
+	tokens, err := fetchSushiswapPairsTokenList(ctx, tokenList.Metadata, state)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
 	}
-	for chainID, blockNumber := range lastBlockSync {
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		tokenList.Metadata[`lastBlockSyncFor_`+chainIDStr] = blockNumber
+	result, err := helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `sushiswap-pairs.json`, helpers.SavingMethodAppend)
+	if err != nil {
+		return result, err
 	}
-
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `sushiswap-pairs.json`, helpers.SavingMethodAppend)
+	return result, state.Save()
 }
@@ -2,15 +2,11 @@ package main
 
 import (
 	"context"
-	"strconv"
-	"strings"
 	"sync"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/contracts"
-	"github.com/migratooor/tokenLists/generators/common/ethereum"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
@@ -18,7 +14,7 @@ import (
 
 var SUSHI_POOL_THRESHOLD = 3
 
-func handleSushiswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address, allPoolsPerChainID map[uint64]map[string]string) []models.TokenListToken {
+func handleSushiswapPoolsTokenList(ctx context.Context, tokensPerChainID map[uint64][]common.Address, allPoolsPerChainID map[uint64]map[string]contracts.UniV2FactoryPairCreated) []models.TokenListToken {
 	tokensForChainIDSyncMap := helpers.InitSyncMap(tokensPerChainID)
 
 	// Fetch the basic informations for all the tokens for all the chains
@@ -35,20 +31,16 @@ func handleSushiswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address,
 			** underlying tokens and use their name and symbol to build the pair name.
 			** The first step is to fetch the data for all the underlying tokens.
 			**************************************************************************/
-			underlyingTokenInfo := helpers.RetrieveBasicInformations(chainID, list)
+			underlyingTokenInfo := helpers.RetrieveBasicInformations(ctx, chainID, list)
 
 			/**************************************************************************
 			** Once we have the data for all the underlying tokens, we can loop over
-			** the pairs and build the name and symbol.
+			** the pairs and build the name and symbol. The underlying tokens, the
+			** factory and the creation block of the pair are kept in the metadata.
 			**************************************************************************/
-			for pool, tokens := range allPoolsPerChainID[chainID] {
-				splittedTokens := strings.Split(tokens, `_`)
-				if len(splittedTokens) != 2 {
-					continue
-				}
-
-				token1, ok1 := underlyingTokenInfo[helpers.ToAddress(splittedTokens[0])]
-				token2, ok2 := underlyingTokenInfo[helpers.ToAddress(splittedTokens[1])]
+			for pool, event := range allPoolsPerChainID[chainID] {
+				token1, ok1 := underlyingTokenInfo[event.Token0.Hex()]
+				token2, ok2 := underlyingTokenInfo[event.Token1.Hex()]
 				if !ok1 || !ok2 {
 					continue
 				}
@@ -59,8 +51,10 @@ func handleSushiswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address,
 					`SLP `+token1.Symbol+` + `+token2.Symbol,
 					``,
 					chainID,
-					18,
+					POOL_TOKEN_DECIMALS,
 				); err == nil {
+					newToken.Tags = []string{models.TagLPToken}
+					newToken.Metadata = pairMetadata(`sushiswap`, event).ToMetadata()
 					syncMap = append(syncMap, newToken)
 					tokensForChainIDSyncMap.Store(chainID, syncMap)
 				}
@@ -72,119 +66,85 @@ func handleSushiswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address,
 	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
 }
 
-func fetchSushiswapPoolsTokenList(extra map[string]interface{}) ([]models.TokenListToken, map[uint64]string) {
+func fetchSushiswapPoolsTokenList(ctx context.Context, metadata map[string]interface{}, state *helpers.TIndexerState) ([]models.TokenListToken, error) {
 	tokensPerChainID := make(map[uint64][]common.Address)
-	poolsPerChainID := make(map[uint64]map[string]string)
+	poolsPerChainID := make(map[uint64]map[string]contracts.UniV2FactoryPairCreated)
 	allTokens := make(map[string]int)
-	allPools := make(map[string]string)
-	lastBlockSync := make(map[uint64]string)
+	allPools := make(map[string]contracts.UniV2FactoryPairCreated)
+	chainErrors := &helpers.TChainErrors{}
 
 	/**************************************************************************
-	** Looping through all the Uniswap contracts per chainID to read the logs
+	** Looping through all the Sushiswap contracts per chainID to read the logs
 	** and see the pairs and tokens that are being used.
 	** In order to be included, a PAIR must have tokens that are both in at
 	** least 3 different pairs.
 	**************************************************************************/
 	for chainID, sushiContract := range SushiswapContractsPerChainID {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 		tokensPerChainID[chainID] = []common.Address{}
-		poolsPerChainID[chainID] = make(map[string]string)
-
-		/**********************************************************************
-		** Init the RPC and get the current block number to know where to start
-		** and end the logs fetching
-		**********************************************************************/
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		lastBlockSyncForChainID := uint64(0)
-		if sync, ok := extra[`lastBlockSyncFor_`+chainIDStr]; ok {
-			lastBlockSyncForChainID, _ = strconv.ParseUint(sync.(string), 10, 64)
-		}
-		client := ethereum.GetRPC(chainID)
-		currentBlockNumber, _ := client.BlockNumber(context.Background())
-		threshold := uint64(100_000)
-		if chainID == 56 {
-			threshold = uint64(5_000)
-		}
+		poolsPerChainID[chainID] = make(map[string]contracts.UniV2FactoryPairCreated)
 
 		/**********************************************************************
-		** For each registered Sushiswap contract, we will fetch the logs to
-		** get the pairs and tokens
+		** For each registered Sushiswap contract, we will index the PairCreated
+		** events to get the pairs and tokens, starting where the previous run
+		** stopped
 		**********************************************************************/
+		var chainErr error
 		for _, sushiContract := range sushiContract {
-			start := sushiContract.BlockNumber.Uint64()
-			if (lastBlockSyncForChainID != 0) && (lastBlockSyncForChainID > start) {
-				start = lastBlockSyncForChainID
-			}
-			sushiV2Factory, _ := contracts.NewSushiV2Factory(sushiContract.ContractAddress, client)
-			for startBlockToTest := start; startBlockToTest <= currentBlockNumber; startBlockToTest += threshold {
-				end := startBlockToTest + threshold
-				if end > currentBlockNumber {
-					end = currentBlockNumber
-				}
-				options := &bind.FilterOpts{
-					Start:   startBlockToTest,
-					End:     &end,
-					Context: nil,
-				}
-				logs.Info(`v2 - start: `, startBlockToTest, ` end: `, end, ` total: `, len(allPools), ` current block: `, currentBlockNumber, ` chainID: `, chainIDStr)
-				if log, err := sushiV2Factory.FilterPairCreated(options, nil, nil); err == nil {
-					for log.Next() {
-						if log.Error() != nil {
-							continue
-						}
-						if _, ok := allTokens[log.Event.Token0.Hex()]; !ok {
-							allTokens[log.Event.Token0.Hex()] = 0
-						}
-						if _, ok := allTokens[log.Event.Token1.Hex()]; !ok {
-							allTokens[log.Event.Token1.Hex()] = 0
-						}
-						allTokens[log.Event.Token0.Hex()]++
-						allTokens[log.Event.Token1.Hex()]++
-						allPools[log.Event.Pair.Hex()] = log.Event.Token0.Hex() + `_` + log.Event.Token1.Hex()
-					}
-				} else {
-					logs.Error("Error fetching all tokens from sushiswap factory contract: ", err)
-					startBlockToTest -= threshold
-				}
+			start := factoryStartBlock(sushiContract.BlockNumber.Uint64(), metadata, chainID)
+			err := indexPairCreated(ctx, state, chainID, sushiContract.ContractAddress, start, func(event *contracts.UniV2FactoryPairCreated) {
+				allTokens[event.Token0.Hex()]++
+				allTokens[event.Token1.Hex()]++
+				allPools[event.Pair.Hex()] = *event
+			})
+			if err != nil {
+				logs.Error("Error fetching all tokens from sushiswap factory contract: ", err)
+				chainErr = err
 			}
-			lastBlockSync[chainID] = strconv.FormatUint(currentBlockNumber, 10)
 		}
+		chainErrors.Add(chainID, chainErr)
+		if chainErr == nil {
+			dropLegacyLastBlockSync(metadata, chainID)
+		}
+
 		/**********************************************************************
 		** Adding the pairs that have at least SUSHI_POOL_THRESHOLD tokens in
 		** common
 		**********************************************************************/
-		for pool, tokensInPool := range allPools {
-			tokens := strings.Split(tokensInPool, `_`)
-			if (allTokens[tokens[0]] >= SUSHI_POOL_THRESHOLD) && (allTokens[tokens[1]] >= SUSHI_POOL_THRESHOLD) {
-				if chains.IsTokenIgnored(chainID, common.HexToAddress(tokens[0])) ||
-					chains.IsTokenIgnored(chainID, common.HexToAddress(tokens[1])) {
+		for pool, event := range allPools {
+			if (allTokens[event.Token0.Hex()] >= SUSHI_POOL_THRESHOLD) && (allTokens[event.Token1.Hex()] >= SUSHI_POOL_THRESHOLD) {
+				if chains.IsTokenIgnored(chainID, event.Token0) ||
+					chains.IsTokenIgnored(chainID, event.Token1) {
 					continue
 				}
-				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], common.HexToAddress(tokens[0]))
-				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], common.HexToAddress(tokens[1]))
-				poolsPerChainID[chainID][pool] = tokensInPool
+				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], event.Token0)
+				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], event.Token1)
+				poolsPerChainID[chainID][pool] = event
 			}
 		}
 	}
 
-	return handleSushiswapPoolsTokenList(tokensPerChainID, poolsPerChainID), lastBlockSync
+	return handleSushiswapPoolsTokenList(ctx, tokensPerChainID, poolsPerChainID), chainErrors.OrNil()
 }
 
-func buildSushiswapPoolsTokenList() {
+func buildSushiswapPoolsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`sushiswap-pools.json`)
 	tokenList.Name = "SushiSwap Token Pools"
 	tokenList.LogoURI = "https://raw.githubusercontent.com/sushiswap/art/master/sushi/logo-256x256.png"
 
-	tokens, lastBlockSync := fetchSushiswapPoolsTokenList(tokenList.Metadata)
-	if tokenList.Metadata == nil {
-		tokenList.Metadata = make(map[string]interface{})
+	state := helpers.LoadIndexerState(`sushiswap-pools`)
+	tokens, err := fetchSushiswapPoolsTokenList(ctx, tokenList.Metadata, state)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
 	}
-	for chainID, blockNumber := range lastBlockSync {
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		tokenList.Metadata[`lastBlockSyncFor_`+chainIDStr] = blockNumber
+	result, err := helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `sushiswap-pools.json`, helpers.SavingMethodAppend)
+	if err != nil {
+		return result, err
 	}
-
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `sushiswap-pools.json`, helpers.SavingMethodAppend)
+	return result, state.Save()
 }
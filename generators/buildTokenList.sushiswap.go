@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -59,9 +60,12 @@ var SushiswapContractsPerChainID = map[uint64][]TSushiContracts{
 	},
 }
 
-func buildSushiswapTokenList() {
+func buildSushiswapTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`sushiswap.json`)
-	originalTokenList := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](`https://token-list.sushi.com/`)
+	originalTokenList, err := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](ctx, `https://token-list.sushi.com/`)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 	tokenList.Name = originalTokenList.Name
 	tokenList.LogoURI = originalTokenList.LogoURI
 	tokenList.Keywords = originalTokenList.Keywords
@@ -77,7 +81,7 @@ func buildSushiswapTokenList() {
 			continue
 		}
 
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokensForChain)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokensForChain)
 		for _, existingToken := range tokensForChain {
 			if token, ok := tokensInfo[existingToken.Hex()]; ok {
 				if newToken, err := helpers.SetToken(
@@ -107,6 +111,6 @@ func buildSushiswapTokenList() {
 		tokenList.NextTokensMap[key] = token
 	}
 
-	tokens := helpers.GetTokensFromList(tokenList.Tokens)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `sushiswap.json`, helpers.SavingMethodStandard)
+	tokens := helpers.GetTokensFromList(ctx, tokenList.Tokens)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `sushiswap.json`, helpers.SavingMethodStandard)
 }
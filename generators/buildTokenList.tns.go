@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"strings"
 
@@ -23,11 +24,11 @@ var chainIDToBIP44 = map[string]uint64{
 	`0x8000a86a`: 43114, // Avalanche
 }
 
-func fetchTNSTokeList() []models.TokenListToken {
+func fetchTNSTokeList(ctx context.Context) ([]models.TokenListToken, error) {
 	listPerChainID := []models.TokenListToken{}
 	client := graphql.NewClient(
 		`https://api.thegraph.com/subgraphs/name/mike-data-nexus/tkn-_sg`,
-		nil,
+		helpers.HTTP_CLIENT,
 	)
 	var query struct {
 		Domains []struct {
@@ -40,10 +41,8 @@ func fetchTNSTokeList() []models.TokenListToken {
 			}
 		} `graphql:"domains(where: {name_ends_with: \".tkn.eth\"}, first: 1000)"`
 	}
-	err := client.Query(context.Background(), &query, nil)
-	if err != nil {
-		logs.Error(err)
-		return listPerChainID
+	if err := client.Query(ctx, &query, nil); err != nil {
+		return nil, errors.New(`failed to query the TNS subgraph: ` + err.Error())
 	}
 
 	for _, domain := range query.Domains {
@@ -66,16 +65,18 @@ func fetchTNSTokeList() []models.TokenListToken {
 		}
 	}
 
-	return helpers.GetTokensFromList(listPerChainID)
+	return helpers.GetTokensFromList(ctx, listPerChainID), nil
 }
 
-func buildTNSTokenList() {
+func buildTNSTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`tns.json`)
 	tokenList.Name = `Token Name Service`
 	tokenList.LogoURI = `https://logo.assets.tkn.eth.limo/`
 	tokenList.Keywords = []string{`tns`, `token`, `tokendao`, `tkn`, `tkr`}
 	tokenList.Description = `Token Name Service is a decentralized naming service for tokens on Ethereum.`
-	tokens := fetchTNSTokeList()
-
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `tns.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchTNSTokeList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `tns.json`, helpers.SavingMethodStandard)
 }
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func handleSmolAssetsTokenList(chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+func handleSmolAssetsTokenList(ctx context.Context, chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
 	if len(tokenList) == 0 {
 		return tokenList
 	}
@@ -16,16 +18,16 @@ func handleSmolAssetsTokenList(chainID uint64, tokenAddresses []common.Address)
 	return tokenList
 }
 
-func fetchSmolAssetsTokenList(chainID uint64) []models.TokenListToken {
+func fetchSmolAssetsTokenList(ctx context.Context, chainID uint64) []models.TokenListToken {
 	smolAssets := helpers.GetSmolAssetsPerChain(chainID)
 	allTokensToAdd := []common.Address{}
 	for _, token := range smolAssets {
 		allTokensToAdd = append(allTokensToAdd, common.HexToAddress(token))
 	}
-	return handleSmolAssetsTokenList(chainID, allTokensToAdd)
+	return handleSmolAssetsTokenList(ctx, chainID, allTokensToAdd)
 }
 
-func buildSmolAssetsTokenList() {
+func buildSmolAssetsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`smolAssets.json`)
 	tokenList.Name = `SmolAssets`
 	tokenList.Description = `A list of tokens supported by Smoldapp Token Assets repository`
@@ -33,7 +35,10 @@ func buildSmolAssetsTokenList() {
 	tokenList.Keywords = []string{`smol`, `tokenAssets`}
 	tokens := []models.TokenListToken{}
 	for _, chainID := range chains.SUPPORTED_CHAIN_IDS {
-		tokens = append(tokens, fetchSmolAssetsTokenList(chainID)...)
+		if ctx.Err() != nil {
+			break
+		}
+		tokens = append(tokens, fetchSmolAssetsTokenList(ctx, chainID)...)
 	}
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `smolAssets.json`, helpers.SavingMethodStandard)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `smolAssets.json`, helpers.SavingMethodStandard)
 }
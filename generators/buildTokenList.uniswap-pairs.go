@@ -2,20 +2,15 @@ package main
 
 import (
 	"context"
-	"strconv"
 	"sync"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
-	"github.com/migratooor/tokenLists/generators/common/contracts"
-	"github.com/migratooor/tokenLists/generators/common/ethereum"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
-	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func handleUniswapPairsTokenList(tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
+func handleUniswapPairsTokenList(ctx context.Context, tokensPerChainID map[uint64][]common.Address) []models.TokenListToken {
 	tokensForChainIDSyncMap := helpers.InitSyncMap(tokensPerChainID)
 
 	// Fetch the basic informations for all the tokens for all the chains
@@ -27,7 +22,7 @@ func handleUniswapPairsTokenList(tokensPerChainID map[uint64][]common.Address) [
 			syncMapRaw, _ := tokensForChainIDSyncMap.Load(chainID)
 			syncMap := syncMapRaw.([]models.TokenListToken)
 
-			tokensInfo := helpers.RetrieveBasicInformations(chainID, list)
+			tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, list)
 			for _, address := range list {
 				if token, ok := tokensInfo[address.Hex()]; ok {
 					if token.Name == `` || token.Symbol == `` {
@@ -54,115 +49,33 @@ func handleUniswapPairsTokenList(tokensPerChainID map[uint64][]common.Address) [
 	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
 }
 
-func fetchUniswapPairsTokenList(extra map[string]interface{}) ([]models.TokenListToken, map[uint64]string) {
+func fetchUniswapPairsTokenList(ctx context.Context, metadata map[string]interface{}, state *helpers.TIndexerState) ([]models.TokenListToken, error) {
 	tokensPerChainID := make(map[uint64][]common.Address)
-	allTokens := make(map[string]int)
-	lastBlockSync := make(map[uint64]string)
+	chainErrors := &helpers.TChainErrors{}
 
 	/**************************************************************************
 	** Looping through all the Uniswap contracts per chainID to read the logs
 	** and see the pairs and tokens that are being used.
-	** In order to be included, a PAIR must have tokens that are both in at
-	** least 10 different pairs.
+	** In order to be included, a token must be in at least
+	** UNI_POOL_THRESHOLD_FOR_CHAINID V2 pairs and V3 pools.
 	**************************************************************************/
-	for chainID, uniContract := range UniswapContractsPerChainID {
+	for chainID := range UniswapContractsPerChainID {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 		tokensPerChainID[chainID] = []common.Address{}
 
-		/**********************************************************************
-		** Init the RPC and get the current block number to know where to start
-		** and end the logs fetching
-		**********************************************************************/
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		lastBlockSyncForChainID := uint64(0)
-		if sync, ok := extra[`lastBlockSyncFor_`+chainIDStr]; ok {
-			lastBlockSyncForChainID, _ = strconv.ParseUint(sync.(string), 10, 64)
-		}
-		client := ethereum.GetRPC(chainID)
-		currentBlockNumber, _ := client.BlockNumber(context.Background())
-		threshold := uint64(100_000)
-		if chainID == 56 {
-			threshold = uint64(5_000)
-		}
-
-		for _, uniContract := range uniContract {
-			start := uniContract.BlockNumber.Uint64()
-			if (lastBlockSyncForChainID != 0) && (lastBlockSyncForChainID > start) {
-				start = lastBlockSyncForChainID
-			}
-			if uniContract.Type == 2 {
-				uniV2Factory, _ := contracts.NewUniV2Factory(uniContract.ContractAddress, client)
-				for startBlockToTest := start; startBlockToTest <= currentBlockNumber; startBlockToTest += threshold {
-					end := startBlockToTest + threshold
-					if end > currentBlockNumber {
-						end = currentBlockNumber
-					}
-					options := &bind.FilterOpts{
-						Start:   startBlockToTest,
-						End:     &end,
-						Context: nil,
-					}
-					logs.Info(`v2 - start: `, startBlockToTest, ` end: `, end, ` total: `, len(allTokens), ` current block: `, currentBlockNumber, ` chainID: `, chainIDStr)
-					if log, err := uniV2Factory.FilterPairCreated(options, nil, nil); err == nil {
-						for log.Next() {
-							if log.Error() != nil {
-								continue
-							}
-							if _, ok := allTokens[log.Event.Token0.Hex()]; !ok {
-								allTokens[log.Event.Token0.Hex()] = 0
-							}
-							if _, ok := allTokens[log.Event.Token1.Hex()]; !ok {
-								allTokens[log.Event.Token1.Hex()] = 0
-							}
-							allTokens[log.Event.Token0.Hex()]++
-							allTokens[log.Event.Token1.Hex()]++
-						}
-					} else {
-						logs.Error("Error fetching all tokens from uniswap factory contract: ", err)
-					}
-				}
-			} else if uniContract.Type == 3 {
-				uniV3Factory, _ := contracts.NewUniV3Factory(uniContract.ContractAddress, client)
-				for startBlockToTest := start; startBlockToTest <= currentBlockNumber; startBlockToTest += threshold {
-					end := startBlockToTest + threshold
-					if end > currentBlockNumber {
-						end = currentBlockNumber
-					}
-					options := &bind.FilterOpts{
-						Start:   startBlockToTest,
-						End:     &end,
-						Context: nil,
-					}
-					logs.Info(`v3 - start: `, startBlockToTest, ` end: `, end, ` total: `, len(allTokens), ` current block: `, currentBlockNumber, ` chainID: `, chainIDStr)
-					if log, err := uniV3Factory.FilterPoolCreated(options, nil, nil, nil); err == nil {
-						for log.Next() {
-							if log.Error() != nil {
-								continue
-							}
-							if _, ok := allTokens[log.Event.Token0.Hex()]; !ok {
-								allTokens[log.Event.Token0.Hex()] = 0
-							}
-							if _, ok := allTokens[log.Event.Token1.Hex()]; !ok {
-								allTokens[log.Event.Token1.Hex()] = 0
-							}
-							allTokens[log.Event.Token0.Hex()]++
-							allTokens[log.Event.Token1.Hex()]++
-						}
-					} else {
-						logs.Error("Error fetching all tokens from uniswap factory contract: ", err)
-					}
-				}
-			}
-			lastBlockSync[chainID] = strconv.FormatUint(currentBlockNumber, 10)
-		}
+		scan, err := scanUniswapFactories(ctx, state, metadata, chainID)
+		chainErrors.Add(chainID, err)
 
 		/**********************************************************************
 		** Transforming the output to the format that we need for the handle
 		** function
 		**********************************************************************/
-		for address, count := range allTokens {
+		for address, count := range scan.TokenCount {
 			if chains.IsTokenIgnored(chainID, common.HexToAddress(address)) {
 				continue
 			}
@@ -172,22 +85,22 @@ func fetchUniswapPairsTokenList(extra map[string]interface{}) ([]models.TokenLis
 		}
 	}
 
-	return handleUniswapPairsTokenList(tokensPerChainID), lastBlockSync
+	return handleUniswapPairsTokenList(ctx, tokensPerChainID), chainErrors.OrNil()
 }
 
-func buildUniswapPairsTokenList() {
+func buildUniswapPairsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`uniswap-pairs.json`)
 	tokenList.Name = "Uniswap Token Pairs"
 	tokenList.LogoURI = "ipfs://QmNa8mQkrNKp1WEEeGjFezDmDeodkWRevGFN8JCV7b4Xir"
 
-	tokens, lastBlockSync := fetchUniswapPairsTokenList(tokenList.Metadata)
-	if tokenList.Metadata == nil {
-		tokenList.Metadata = make(map[string]interface{})
+	state := helpers.LoadIndexerState(`uniswap-pairs`)
+	tokens, err := fetchUniswapPairsTokenList(ctx, tokenList.Metadata, state)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
 	}
-	for chainID, blockNumber := range lastBlockSync {
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		tokenList.Metadata[`lastBlockSyncFor_`+chainIDStr] = blockNumber
+	result, err := helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `uniswap-pairs.json`, helpers.SavingMethodAppend)
+	if err != nil {
+		return result, err
 	}
-
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `uniswap-pairs.json`, helpers.SavingMethodAppend)
+	return result, state.Save()
 }
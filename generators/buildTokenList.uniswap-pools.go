@@ -2,17 +2,12 @@ package main
 
 import (
 	"context"
-	"strconv"
-	"strings"
 	"sync"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/contracts"
-	"github.com/migratooor/tokenLists/generators/common/ethereum"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
-	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
@@ -27,7 +22,7 @@ var UNI_POOL_THRESHOLD_FOR_CHAINID = map[uint64]int{
 	43114: 3,
 }
 
-func handleUniswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address, allPoolsPerChainID map[uint64]map[string]string) []models.TokenListToken {
+func handleUniswapPoolsTokenList(ctx context.Context, tokensPerChainID map[uint64][]common.Address, allPoolsPerChainID map[uint64]map[string]contracts.UniV2FactoryPairCreated) []models.TokenListToken {
 	tokensForChainIDSyncMap := helpers.InitSyncMap(tokensPerChainID)
 
 	// Fetch the basic informations for all the tokens for all the chains
@@ -44,20 +39,16 @@ func handleUniswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address, a
 			** underlying tokens and use their name and symbol to build the pair name.
 			** The first step is to fetch the data for all the underlying tokens.
 			**************************************************************************/
-			underlyingTokenInfo := helpers.RetrieveBasicInformations(chainID, list)
+			underlyingTokenInfo := helpers.RetrieveBasicInformations(ctx, chainID, list)
 
 			/**************************************************************************
 			** Once we have the data for all the underlying tokens, we can loop over
-			** the pairs and build the name and symbol.
+			** the pairs and build the name and symbol. The underlying tokens, the
+			** factory and the creation block of the pair are kept in the metadata.
 			**************************************************************************/
-			for pool, tokens := range allPoolsPerChainID[chainID] {
-				splittedTokens := strings.Split(tokens, `_`)
-				if len(splittedTokens) != 2 {
-					continue
-				}
-
-				token1, ok1 := underlyingTokenInfo[helpers.ToAddress(splittedTokens[0])]
-				token2, ok2 := underlyingTokenInfo[helpers.ToAddress(splittedTokens[1])]
+			for pool, event := range allPoolsPerChainID[chainID] {
+				token1, ok1 := underlyingTokenInfo[event.Token0.Hex()]
+				token2, ok2 := underlyingTokenInfo[event.Token1.Hex()]
 				if !ok1 || !ok2 {
 					continue
 				}
@@ -68,8 +59,10 @@ func handleUniswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address, a
 					`UNI-V2 `+token1.Symbol+` + `+token2.Symbol,
 					``,
 					chainID,
-					18,
+					POOL_TOKEN_DECIMALS,
 				); err == nil {
+					newToken.Tags = []string{models.TagLPToken}
+					newToken.Metadata = pairMetadata(`uniswap`, event).ToMetadata()
 					syncMap = append(syncMap, newToken)
 					tokensForChainIDSyncMap.Store(chainID, syncMap)
 				}
@@ -81,122 +74,64 @@ func handleUniswapPoolsTokenList(tokensPerChainID map[uint64][]common.Address, a
 	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
 }
 
-func fetchUniswapPoolsTokenList(extra map[string]interface{}) ([]models.TokenListToken, map[uint64]string) {
+func fetchUniswapPoolsTokenList(ctx context.Context, metadata map[string]interface{}, state *helpers.TIndexerState) ([]models.TokenListToken, error) {
 	tokensPerChainID := make(map[uint64][]common.Address)
-	poolsPerChainID := make(map[uint64]map[string]string)
-	allTokens := make(map[string]int)
-	allPools := make(map[string]string)
-	lastBlockSync := make(map[uint64]string)
+	poolsPerChainID := make(map[uint64]map[string]contracts.UniV2FactoryPairCreated)
+	chainErrors := &helpers.TChainErrors{}
 
 	/**************************************************************************
 	** Looping through all the Uniswap contracts per chainID to read the logs
 	** and see the pairs and tokens that are being used.
 	** In order to be included, a PAIR must have tokens that are both in at
-	** least 3 different pairs.
+	** least UNI_POOL_THRESHOLD_FOR_CHAINID V2 pairs and V3 pools.
 	**************************************************************************/
-	for chainID, uniContract := range UniswapContractsPerChainID {
+	for chainID := range UniswapContractsPerChainID {
+		if ctx.Err() != nil {
+			break
+		}
 		if !chains.IsChainIDSupported(chainID) {
 			continue
 		}
 		tokensPerChainID[chainID] = []common.Address{}
-		poolsPerChainID[chainID] = make(map[string]string)
+		poolsPerChainID[chainID] = make(map[string]contracts.UniV2FactoryPairCreated)
 
-		/**********************************************************************
-		** Init the RPC and get the current block number to know where to start
-		** and end the logs fetching
-		**********************************************************************/
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		lastBlockSyncForChainID := uint64(0)
-		if sync, ok := extra[`lastBlockSyncFor_`+chainIDStr]; ok {
-			lastBlockSyncForChainID, _ = strconv.ParseUint(sync.(string), 10, 64)
-		}
-		client := ethereum.GetRPC(chainID)
-		currentBlockNumber, _ := client.BlockNumber(context.Background())
-		threshold := uint64(100_000)
-		if chainID == 56 {
-			threshold = uint64(5_000)
-		}
+		scan, err := scanUniswapFactories(ctx, state, metadata, chainID)
+		chainErrors.Add(chainID, err)
 
-		/**********************************************************************
-		** For each registered Uniswap contract, we will fetch the logs to
-		** get the pairs and tokens
-		**********************************************************************/
-		for _, uniContract := range uniContract {
-			start := uniContract.BlockNumber.Uint64()
-			if (lastBlockSyncForChainID != 0) && (lastBlockSyncForChainID > start) {
-				start = lastBlockSyncForChainID
-			}
-			if uniContract.Type == 2 {
-				uniV2Factory, _ := contracts.NewUniV2Factory(uniContract.ContractAddress, client)
-				for startBlockToTest := start; startBlockToTest <= currentBlockNumber; startBlockToTest += threshold {
-					end := startBlockToTest + threshold
-					if end > currentBlockNumber {
-						end = currentBlockNumber
-					}
-					options := &bind.FilterOpts{
-						Start:   startBlockToTest,
-						End:     &end,
-						Context: nil,
-					}
-					logs.Info(`v2 - start: `, startBlockToTest, ` end: `, end, ` total: `, len(allTokens), ` current block: `, currentBlockNumber, ` chainID: `, chainIDStr)
-					if log, err := uniV2Factory.FilterPairCreated(options, nil, nil); err == nil {
-						for log.Next() {
-							if log.Error() != nil {
-								continue
-							}
-							if _, ok := allTokens[log.Event.Token0.Hex()]; !ok {
-								allTokens[log.Event.Token0.Hex()] = 0
-							}
-							if _, ok := allTokens[log.Event.Token1.Hex()]; !ok {
-								allTokens[log.Event.Token1.Hex()] = 0
-							}
-							allTokens[log.Event.Token0.Hex()]++
-							allTokens[log.Event.Token1.Hex()]++
-							allPools[log.Event.Pair.Hex()] = log.Event.Token0.Hex() + `_` + log.Event.Token1.Hex()
-						}
-					} else {
-						logs.Error("Error fetching all tokens from uniswap factory contract: ", err)
-						startBlockToTest -= threshold
-					}
-				}
-			}
-			lastBlockSync[chainID] = strconv.FormatUint(currentBlockNumber, 10)
-		}
 		/**********************************************************************
 		** Adding the pairs that have at least UNI_POOL_THRESHOLD tokens in
 		** common
 		**********************************************************************/
 		chainThreshold := UNI_POOL_THRESHOLD_FOR_CHAINID[chainID]
-		for pool, tokensInPool := range allPools {
-			tokens := strings.Split(tokensInPool, `_`)
-			if (allTokens[tokens[0]] >= chainThreshold) && (allTokens[tokens[1]] >= chainThreshold) {
-				if chains.IsTokenIgnored(chainID, common.HexToAddress(tokens[0])) ||
-					chains.IsTokenIgnored(chainID, common.HexToAddress(tokens[1])) {
+		for pool, event := range scan.Pairs {
+			if (scan.TokenCount[event.Token0.Hex()] >= chainThreshold) && (scan.TokenCount[event.Token1.Hex()] >= chainThreshold) {
+				if chains.IsTokenIgnored(chainID, event.Token0) ||
+					chains.IsTokenIgnored(chainID, event.Token1) {
 					continue
 				}
-				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], common.HexToAddress(tokens[0]))
-				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], common.HexToAddress(tokens[1]))
-				poolsPerChainID[chainID][pool] = tokensInPool
+				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], event.Token0)
+				tokensPerChainID[chainID] = append(tokensPerChainID[chainID], event.Token1)
+				poolsPerChainID[chainID][pool] = event
 			}
 		}
 	}
 
-	return handleUniswapPoolsTokenList(tokensPerChainID, poolsPerChainID), lastBlockSync
+	return handleUniswapPoolsTokenList(ctx, tokensPerChainID, poolsPerChainID), chainErrors.OrNil()
 }
 
-func buildUniswapPoolsTokenList() {
+func buildUniswapPoolsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`uniswap-pools.json`)
 	tokenList.Name = "Uniswap Token Pools"
 	tokenList.LogoURI = "ipfs://QmNa8mQkrNKp1WEEeGjFezDmDeodkWRevGFN8JCV7b4Xir"
 
-	tokens, lastBlockSync := fetchUniswapPoolsTokenList(tokenList.Metadata)
-	if tokenList.Metadata == nil {
-		tokenList.Metadata = make(map[string]interface{})
+	state := helpers.LoadIndexerState(`uniswap-pools`)
+	tokens, err := fetchUniswapPoolsTokenList(ctx, tokenList.Metadata, state)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
 	}
-	for chainID, blockNumber := range lastBlockSync {
-		chainIDStr := strconv.FormatUint(chainID, 10)
-		tokenList.Metadata[`lastBlockSyncFor_`+chainIDStr] = blockNumber
+	result, err := helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `uniswap-pools.json`, helpers.SavingMethodAppend)
+	if err != nil {
+		return result, err
 	}
-
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `uniswap-pools.json`, helpers.SavingMethodAppend)
+	return result, state.Save()
 }
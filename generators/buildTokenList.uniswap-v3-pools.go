@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// formatFeeTier returns a fee tier of Uniswap V3, in hundredths of a bip, as a percentage
+func formatFeeTier(fee uint64) string {
+	return strconv.FormatFloat(float64(fee)/10_000, 'f', -1, 64) + `%`
+}
+
+func handleUniswapV3PoolsTokenList(
+	ctx context.Context,
+	tokensPerChainID map[uint64][]common.Address,
+	allPoolsPerChainID map[uint64]map[string]contracts.UniV3FactoryPoolCreated,
+) []models.TokenListToken {
+	tokensForChainIDSyncMap := helpers.InitSyncMap(tokensPerChainID)
+
+	// Fetch the basic informations for all the tokens for all the chains
+	perChainWG := sync.WaitGroup{}
+	perChainWG.Add(len(tokensPerChainID))
+	for chainID, list := range tokensPerChainID {
+		go func(chainID uint64, list []common.Address) {
+			defer perChainWG.Done()
+			syncMapRaw, _ := tokensForChainIDSyncMap.Load(chainID)
+			syncMap := syncMapRaw.([]models.TokenListToken)
+
+			/**************************************************************************
+			** The pools have no name or symbol to recognize them. We need to fetch the
+			** underlying tokens and use their name and symbol, along with the fee
+			** tier, to build the pool name.
+			**************************************************************************/
+			underlyingTokenInfo := helpers.RetrieveBasicInformations(ctx, chainID, list)
+
+			/**************************************************************************
+			** Once we have the data for all the underlying tokens, we can loop over
+			** the pools and build the name and symbol. A V3 pool has no shares, it
+			** keeps the decimals of the V2 pairs so both lists share their format.
+			** The underlying tokens, the fee tier and the tick spacing are kept in
+			** the metadata.
+			**************************************************************************/
+			for pool, event := range allPoolsPerChainID[chainID] {
+				token1, ok1 := underlyingTokenInfo[event.Token0.Hex()]
+				token2, ok2 := underlyingTokenInfo[event.Token1.Hex()]
+				if !ok1 || !ok2 {
+					continue
+				}
+
+				feeTier := event.Fee.Uint64()
+				if newToken, err := helpers.SetToken(
+					common.HexToAddress(pool),
+					`Uniswap V3 `+token1.Name+` + `+token2.Name+` `+formatFeeTier(feeTier),
+					`UNI-V3 `+token1.Symbol+` + `+token2.Symbol+` `+formatFeeTier(feeTier),
+					``,
+					chainID,
+					POOL_TOKEN_DECIMALS,
+				); err == nil {
+					newToken.Tags = []string{models.TagPool}
+					newToken.Metadata = v3PoolMetadata(`uniswap`, event).ToMetadata()
+					syncMap = append(syncMap, newToken)
+					tokensForChainIDSyncMap.Store(chainID, syncMap)
+				}
+			}
+		}(chainID, list)
+	}
+	perChainWG.Wait()
+
+	return helpers.ExtractSyncMap(tokensForChainIDSyncMap)
+}
+
+func fetchUniswapV3PoolsTokenList(ctx context.Context, metadata map[string]interface{}, state *helpers.TIndexerState) ([]models.TokenListToken, error) {
+	tokensPerChainID := make(map[uint64][]common.Address)
+	poolsPerChainID := make(map[uint64]map[string]contracts.UniV3FactoryPoolCreated)
+	chainErrors := &helpers.TChainErrors{}
+
+	/**************************************************************************
+	** Looping through all the Uniswap contracts per chainID to read the logs
+	** and see the pools and tokens that are being used.
+	** In order to be included, a POOL must have tokens that are both in at
+	** least UNI_POOL_THRESHOLD_FOR_CHAINID V2 pairs and V3 pools.
+	**************************************************************************/
+	for chainID := range UniswapContractsPerChainID {
+		if ctx.Err() != nil {
+			break
+		}
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		tokensPerChainID[chainID] = []common.Address{}
+		poolsPerChainID[chainID] = make(map[string]contracts.UniV3FactoryPoolCreated)
+
+		scan, err := scanUniswapFactories(ctx, state, metadata, chainID)
+		chainErrors.Add(chainID, err)
+
+		chainThreshold := UNI_POOL_THRESHOLD_FOR_CHAINID[chainID]
+		for pool, event := range scan.Pools {
+			if (scan.TokenCount[event.Token0.Hex()] < chainThreshold) || (scan.TokenCount[event.Token1.Hex()] < chainThreshold) {
+				continue
+			}
+			if chains.IsTokenIgnored(chainID, event.Token0) || chains.IsTokenIgnored(chainID, event.Token1) {
+				continue
+			}
+			tokensPerChainID[chainID] = append(tokensPerChainID[chainID], event.Token0, event.Token1)
+			poolsPerChainID[chainID][pool] = event
+		}
+	}
+
+	return handleUniswapV3PoolsTokenList(ctx, tokensPerChainID, poolsPerChainID), chainErrors.OrNil()
+}
+
+func buildUniswapV3PoolsTokenList(ctx context.Context) (helpers.TSaveResult, error) {
+	tokenList := helpers.LoadTokenListFromJsonFile(`uniswap-v3-pools.json`)
+	tokenList.Name = "Uniswap V3 Pools"
+	tokenList.LogoURI = "ipfs://QmNa8mQkrNKp1WEEeGjFezDmDeodkWRevGFN8JCV7b4Xir"
+
+	state := helpers.LoadIndexerState(`uniswap-v3-pools`)
+	tokens, err := fetchUniswapV3PoolsTokenList(ctx, tokenList.Metadata, state)
+	if tokens, err = helpers.KeepTokensOfFailedChains(tokenList, tokens, err); err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	result, err := helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `uniswap-v3-pools.json`, helpers.SavingMethodAppend)
+	if err != nil {
+		return result, err
+	}
+	return result, state.Save()
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -58,9 +59,12 @@ var UniswapContractsPerChainID = map[uint64][]TUniContracts{
 	},
 }
 
-func buildUniswapTokenList() {
+func buildUniswapTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`uniswap.json`)
-	originalTokenList := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](`https://tokens.uniswap.org`)
+	originalTokenList, err := helpers.FetchJSON[models.TokenListData[models.TokenListToken]](ctx, `https://tokens.uniswap.org`)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 	tokenList.Name = helpers.SafeString(originalTokenList.Name, `Uniswap Token List`)
 	tokenList.LogoURI = helpers.SafeString(originalTokenList.LogoURI, `ipfs://QmNa8mQkrNKp1WEEeGjFezDmDeodkWRevGFN8JCV7b4Xir"`)
 	tokenList.Keywords = originalTokenList.Keywords
@@ -76,7 +80,7 @@ func buildUniswapTokenList() {
 			continue
 		}
 
-		tokensInfo := helpers.RetrieveBasicInformations(chainID, tokensForChain)
+		tokensInfo := helpers.RetrieveBasicInformations(ctx, chainID, tokensForChain)
 		for _, existingToken := range tokensForChain {
 			if token, ok := tokensInfo[existingToken.Hex()]; ok {
 				if newToken, err := helpers.SetToken(
@@ -106,6 +110,6 @@ func buildUniswapTokenList() {
 		tokenList.NextTokensMap[key] = token
 	}
 
-	tokens := helpers.GetTokensFromList(tokenList.Tokens)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `uniswap.json`, helpers.SavingMethodStandard)
+	tokens := helpers.GetTokensFromList(ctx, tokenList.Tokens)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `uniswap.json`, helpers.SavingMethodStandard)
 }
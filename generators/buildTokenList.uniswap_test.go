@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+)
+
+/**************************************************************************************************
+** withUniswapFactory makes a new factory of the simulated chain the only V2 and V3 factory of
+** TEST_CHAIN_ID, read from its first block without confirmations, with a threshold of 3 pairs
+** and pools per token.
+**************************************************************************************************/
+func withUniswapFactory(t *testing.T) common.Address {
+	t.Helper()
+	factory, err := simulatedChain(t).DeployFactory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousContracts := UniswapContractsPerChainID[TEST_CHAIN_ID]
+	previousThreshold := UNI_POOL_THRESHOLD_FOR_CHAINID[TEST_CHAIN_ID]
+	previousConfirmations, hasConfirmations := ethereum.CONFIRMATIONS_FOR_CHAINID[TEST_CHAIN_ID]
+	UniswapContractsPerChainID[TEST_CHAIN_ID] = []TUniContracts{
+		{ContractAddress: factory, BlockNumber: big.NewInt(0), Type: 2},
+		{ContractAddress: factory, BlockNumber: big.NewInt(0), Type: 3},
+	}
+	UNI_POOL_THRESHOLD_FOR_CHAINID[TEST_CHAIN_ID] = 3
+	ethereum.CONFIRMATIONS_FOR_CHAINID[TEST_CHAIN_ID] = 0
+	t.Cleanup(func() {
+		UniswapContractsPerChainID[TEST_CHAIN_ID] = previousContracts
+		UNI_POOL_THRESHOLD_FOR_CHAINID[TEST_CHAIN_ID] = previousThreshold
+		if hasConfirmations {
+			ethereum.CONFIRMATIONS_FOR_CHAINID[TEST_CHAIN_ID] = previousConfirmations
+		} else {
+			delete(ethereum.CONFIRMATIONS_FOR_CHAINID, TEST_CHAIN_ID)
+		}
+	})
+	return factory
+}
+
+func TestUniswapThresholdCountsTheV3Pools(t *testing.T) {
+	factory := withUniswapFactory(t)
+	backend := simulatedChain(t)
+	popular := deployERC20(t, `Popular on V3`, `POP`)
+	partner := deployERC20(t, `Partner`, `PART`)
+	v2Only := deployERC20(t, `Only on V2`, `V2O`)
+	other := deployERC20(t, `Other`, `OTH`)
+
+	// popular is in 1 V2 pair and 2 V3 pools: it only passes the threshold of 3 with its V3 pools
+	pairs := [][2]common.Address{{popular, partner}, {v2Only, partner}, {v2Only, other}}
+	for index, pair := range pairs {
+		pairAddress := common.BigToAddress(big.NewInt(int64(0x2c00 + index)))
+		if _, err := backend.CreateUniV2Pair(factory, pair[0], pair[1], pairAddress, uint64(index)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sharedPool := common.HexToAddress(`0x0000000000000000000000000000000000003c00`)
+	otherPool := common.HexToAddress(`0x0000000000000000000000000000000000003c01`)
+	if _, err := backend.CreateUniV3Pool(factory, popular, partner, sharedPool, 500, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.CreateUniV3Pool(factory, popular, other, otherPool, 3000, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := fetchUniswapPairsTokenList(context.Background(), map[string]interface{}{}, helpers.LoadIndexerState(`test-uniswap-pairs`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddress := tokensByAddress(tokens)
+	for address, want := range map[common.Address]bool{popular: true, partner: true, v2Only: false, other: false} {
+		if _, ok := byAddress[address.Hex()]; ok != want {
+			t.Errorf(`%s is in the pairs list: %v, want %v`, address.Hex(), ok, want)
+		}
+	}
+
+	pools, err := fetchUniswapV3PoolsTokenList(context.Background(), map[string]interface{}{}, helpers.LoadIndexerState(`test-uniswap-v3-pools`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 1 || pools[0].Address != sharedPool.Hex() || pools[0].Name != `Uniswap V3 Popular on V3 + Partner 0.05%` {
+		t.Errorf(`got the V3 pools %+v, want the pool of popular and partner`, pools)
+	}
+}
+
+func TestUniswapCountsArePersistedAndNotDoubledByAReorg(t *testing.T) {
+	factory := withUniswapFactory(t)
+	backend := simulatedChain(t)
+	token := deployERC20(t, `Counted`, `CNT`)
+	partners := []common.Address{deployERC20(t, `First`, `FST`), deployERC20(t, `Second`, `SND`), deployERC20(t, `Third`, `THD`)}
+	createPair := func(index int) {
+		t.Helper()
+		pairAddress := common.BigToAddress(big.NewInt(int64(0x4c00 + index)))
+		if _, err := backend.CreateUniV2Pair(factory, token, partners[index], pairAddress, uint64(index)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	isListed := func(state *helpers.TIndexerState) bool {
+		t.Helper()
+		tokens, err := fetchUniswapPairsTokenList(context.Background(), map[string]interface{}{}, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ok := tokensByAddress(tokens)[token.Hex()]
+		return ok
+	}
+
+	state := helpers.LoadIndexerState(`test-uniswap-counts`)
+	createPair(0)
+	createPair(1)
+	if isListed(state) {
+		t.Fatal(`the token is listed with 2 pairs, below the threshold of 3`)
+	}
+
+	// A reorg makes the indexer read the block of the second pair again, which is not counted twice
+	key := factoryIndexerKey(TEST_CHAIN_ID, UniswapContractsPerChainID[TEST_CHAIN_ID][0])
+	checkpoint, _ := state.GetCheckpoint(key)
+	state.SetCheckpoint(key, ethereum.TCheckpoint{Block: checkpoint.Block, Hash: common.HexToHash(`0x01`)})
+	if isListed(state) {
+		t.Fatal(`the token is listed after a reorg, its pair read again was counted twice`)
+	}
+	if count := state.TokenPoolCount(TEST_CHAIN_ID, token.Hex()); count != 2 {
+		t.Fatalf(`got %d pairs for the token, want 2`, count)
+	}
+
+	// The count includes the pairs of the previous runs, not only the ones since the checkpoint
+	createPair(2)
+	if !isListed(state) {
+		t.Error(`the token is not listed with its third pair`)
+	}
+}
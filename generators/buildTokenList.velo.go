@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -8,49 +10,72 @@ import (
 	"github.com/migratooor/tokenLists/generators/common/contracts"
 	"github.com/migratooor/tokenLists/generators/common/ethereum"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
-	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func handleVeloTokenList(chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddresses(chainID, tokenAddresses)
+func handleVeloTokenList(ctx context.Context, chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
+	tokenList := helpers.GetTokensFromAddresses(ctx, chainID, tokenAddresses)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	return tokenList
 }
 
-func fetchVeloLikeTokenList(chainID uint64, sugarAddress common.Address) []models.TokenListToken {
+/**************************************************************************************************
+** fetchVeloLikeTokenList lists the pools of a Velodrome like DEX, read from its Sugar contract,
+** with their underlying tokens and emission tokens. The LP token of each pool carries its
+** TPoolMetadata, for the given protocol. An error is returned when the pools could not be read.
+**************************************************************************************************/
+func fetchVeloLikeTokenList(ctx context.Context, chainID uint64, sugarAddress common.Address, protocol string) ([]models.TokenListToken, error) {
+	if !chains.IsChainIDSupported(chainID) {
+		return []models.TokenListToken{}, nil
+	}
 	client := ethereum.GetRPC(chainID)
 	veloSugar, err := contracts.NewVeloSugarV2Caller(sugarAddress, client)
 	if err != nil {
-		logs.Error(err)
-		return []models.TokenListToken{}
+		return nil, err
 	}
-	allTokens, err := veloSugar.All(nil, big.NewInt(10_000), big.NewInt(0), common.Address{})
+	allTokens, err := veloSugar.All(ethereum.CallOpts(ctx, chainID), big.NewInt(10_000), big.NewInt(0), common.Address{})
 	if err != nil {
-		logs.Error(err)
-		return []models.TokenListToken{}
+		return nil, errors.New(`failed to read the pools of ` + protocol + `: ` + err.Error())
 	}
 	addressesMap := make(map[common.Address]bool)
+	poolOfLpToken := make(map[string]models.TPoolMetadata)
 	for _, token := range allTokens {
 		addressesMap[token.Token0] = true
 		addressesMap[token.Token1] = true
 		addressesMap[token.EmissionsToken] = true
 		addressesMap[token.Lp] = true
+
+		poolType := `volatile`
+		if token.Stable {
+			poolType = `stable`
+		}
+		pool := models.NewPoolMetadata(protocol, poolType, []string{token.Token0.Hex(), token.Token1.Hex()})
+		pool.Factory = token.Factory.Hex()
+		poolOfLpToken[token.Lp.Hex()] = pool
 	}
 	// Used to remove the duplicates
 	addressesSlice := []common.Address{}
 	for address := range addressesMap {
 		addressesSlice = append(addressesSlice, address)
 	}
-	return handleVeloTokenList(chainID, addressesSlice)
+	tokenList := handleVeloTokenList(ctx, chainID, addressesSlice)
+	for i, token := range tokenList {
+		if pool, ok := poolOfLpToken[token.Address]; ok {
+			tokenList[i].Tags = []string{models.TagLPToken}
+			tokenList[i].Metadata = pool.ToMetadata()
+		}
+	}
+	return tokenList, nil
 }
 
-func buildVeloTokenList() {
+func buildVeloTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`velodrome.json`)
 	tokenList.Name = `Velodrome`
 	tokenList.LogoURI = `https://velodrome.finance/velodrome.svg`
 	tokenList.Keywords = []string{`velodrome`, `optimism`}
-	tokens := []models.TokenListToken{}
-	tokens = append(tokens, fetchVeloLikeTokenList(10, common.HexToAddress(`0x7F45F1eA57E9231f846B2b4f5F8138F94295A726`))...)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `velodrome.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchVeloLikeTokenList(ctx, 10, common.HexToAddress(`0x7F45F1eA57E9231f846B2b4f5F8138F94295A726`), `velodrome`)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `velodrome.json`, helpers.SavingMethodStandard)
 }
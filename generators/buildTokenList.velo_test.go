@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/models"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+func TestFetchVeloLikeTokenList(t *testing.T) {
+	token0 := deployERC20(t, `Velo Token 0`, `VT0`)
+	token1 := deployERC20(t, `Velo Token 1`, `VT1`)
+	emissions := deployERC20(t, `Velodrome`, `VELO`)
+	lp := deployERC20(t, `VolatileV2 AMM - VT0/VT1`, `vAMMV2-VT0/VT1`)
+	sugar, err := simulatedChain(t).DeployVeloSugar([]contracts.Struct0{
+		simulated.NewVeloLp(lp, token0, token1, emissions),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := fetchVeloLikeTokenList(context.Background(), TEST_CHAIN_ID, sugar, `velodrome`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := tokensByAddress(list)
+	for _, address := range []string{token0.Hex(), token1.Hex(), emissions.Hex()} {
+		if token, ok := tokens[address]; !ok || len(token.Tags) != 0 {
+			t.Errorf(`%s is missing from the list, or tagged`, address)
+		}
+	}
+	lpToken, ok := tokens[lp.Hex()]
+	if !ok {
+		t.Fatal(`the LP token is missing from the list`)
+	}
+	if len(lpToken.Tags) != 1 || lpToken.Tags[0] != models.TagLPToken {
+		t.Errorf(`got the tags %v for the LP token`, lpToken.Tags)
+	}
+	metadata := lpToken.Metadata
+	if metadata[`protocol`] != `velodrome` || metadata[`poolType`] != `volatile` || metadata[`token0`] != token0.Hex() || metadata[`token1`] != token1.Hex() {
+		t.Errorf(`got the pool metadata %v`, metadata)
+	}
+
+	// A sugar which cannot be read fails the list, rather than giving an empty list
+	if list, err := fetchVeloLikeTokenList(context.Background(), TEST_CHAIN_ID, common.HexToAddress(`0xdead`), `velodrome`); err == nil {
+		t.Errorf(`got the tokens %v and no error without a sugar`, list)
+	}
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func fetchYearnMinTokenList() []models.TokenListToken {
-	list := helpers.FetchJSON[map[uint64]map[string]TYearnTokenData](`https://ydaemon.yearn.fi/tokens/all`)
+func fetchYearnMinTokenList(ctx context.Context) ([]models.TokenListToken, error) {
+	list, err := helpers.FetchJSON[map[uint64]map[string]TYearnTokenData](ctx, `https://ydaemon.yearn.fi/tokens/all`)
+	if err != nil {
+		return nil, err
+	}
 	listPerChainID := []models.TokenListToken{}
 
 	for chainID, listPerChain := range list {
@@ -46,15 +50,18 @@ func fetchYearnMinTokenList() []models.TokenListToken {
 		}
 	}
 
-	return helpers.GetTokensFromList(listPerChainID)
+	return setYearnTags(helpers.GetTokensFromList(ctx, listPerChainID), list), nil
 }
 
-func buildYearnMinimalTokenList() {
+func buildYearnMinimalTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`yearn-min.json`)
 	tokenList.Name = `Yearn Minimal Token List`
 	tokenList.LogoURI = `https://assets.smold.app/api/token/1/0x0bc529c00C6401aEF6D220BE8C6Ea1667F6Ad93e/logo.svg`
 	tokenList.Keywords = []string{`yearn`, `yfi`, `yvault`, `ytoken`, `ycurve`, `yprotocol`, `vaults`}
-	tokens := fetchYearnMinTokenList()
+	tokens, err := fetchYearnMinTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `yearn-min.json`, helpers.SavingMethodStandard)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `yearn-min.json`, helpers.SavingMethodStandard)
 }
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"strconv"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
@@ -60,8 +62,55 @@ type TYearnTokenData struct {
 	Decimals                  uint64     `json:"decimals"`
 }
 
-func fetchYearnTokenList() []models.TokenListToken {
-	list := helpers.FetchJSON[map[uint64]map[string]TYearnTokenData](`https://ydevmon.ycorpo.com/tokens/all`)
+// isYearnVault returns true if the token of the Yearn API is the share of a vault
+func isYearnVault(token TYearnTokenData) bool {
+	switch token.Type {
+	case TokenTypeStandardVault, TokenTypeLegagyStandardVault,
+		TokenTypeExperimentalVault, TokenTypeLegacyExperimentalVault,
+		TokenTypeAutomatedVault, TokenTypeLegacyAutomatedVault:
+		return true
+	}
+	return token.Category == `yVault`
+}
+
+/**************************************************************************************************
+** setYearnTags tags the tokens read from the Yearn API: the shares of the vaults are tagged as
+** vaults, with their underlying token in their extensions when they have only one, and the Curve
+** LP tokens as LP tokens. The tokens are matched by chainID and address, as GetTokensFromList
+** only keeps the fields read on-chain.
+**************************************************************************************************/
+func setYearnTags(tokens []models.TokenListToken, list map[uint64]map[string]TYearnTokenData) []models.TokenListToken {
+	yearnTokens := make(map[string]TYearnTokenData)
+	for chainID, listPerChain := range list {
+		for _, yearnToken := range listPerChain {
+			yearnTokens[helpers.GetKey(chainID, common.HexToAddress(yearnToken.Address))] = yearnToken
+		}
+	}
+
+	for i, token := range tokens {
+		yearnToken, ok := yearnTokens[helpers.GetKey(token.ChainID, common.HexToAddress(token.Address))]
+		switch {
+		case !ok:
+			continue
+		case isYearnVault(yearnToken):
+			tokens[i].Tags = []string{models.TagVault}
+			if len(yearnToken.UnderlyingTokensAddresses) == 1 {
+				tokens[i].Extensions = map[string]interface{}{
+					`underlyingToken`: common.HexToAddress(yearnToken.UnderlyingTokensAddresses[0]).Hex(),
+				}
+			}
+		case yearnToken.Type == TokenTypeCurveLP:
+			tokens[i].Tags = []string{models.TagLPToken}
+		}
+	}
+	return tokens
+}
+
+func fetchYearnTokenList(ctx context.Context) ([]models.TokenListToken, error) {
+	list, err := helpers.FetchJSON[map[uint64]map[string]TYearnTokenData](ctx, `https://ydevmon.ycorpo.com/tokens/all`)
+	if err != nil {
+		return nil, err
+	}
 	listPerChainID := []models.TokenListToken{}
 
 	for chainID, listPerChain := range list {
@@ -92,15 +141,18 @@ func fetchYearnTokenList() []models.TokenListToken {
 		}
 	}
 
-	return helpers.GetTokensFromList(listPerChainID)
+	return setYearnTags(helpers.GetTokensFromList(ctx, listPerChainID), list), nil
 }
 
-func buildYearnTokenList() {
+func buildYearnTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`yearn.json`)
 	tokenList.Name = `Yearn Token List`
 	tokenList.LogoURI = `https://assets.smold.app/api/token/1/0x0bc529c00C6401aEF6D220BE8C6Ea1667F6Ad93e/logo.svg`
 	tokenList.Keywords = []string{`yearn`, `yfi`, `yvault`, `ytoken`, `ycurve`, `yprotocol`, `vaults`}
-	tokens := fetchYearnTokenList()
+	tokens, err := fetchYearnTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
 
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `yearn.json`, helpers.SavingMethodStandard)
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `yearn.json`, helpers.SavingMethodStandard)
 }
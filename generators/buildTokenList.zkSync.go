@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/helpers"
@@ -8,16 +10,20 @@ import (
 )
 
 func handleZkSyncTokenList(
+	ctx context.Context,
 	chainID uint64,
 	tokenAddresses []common.Address,
 	tokenIcons map[string]string,
 ) []models.TokenListToken {
-	tokenList := helpers.GetTokensFromAddressesWithIcons(chainID, tokenAddresses, tokenIcons)
+	tokenList := helpers.GetTokensFromAddressesWithIcons(ctx, chainID, tokenAddresses, tokenIcons)
 	tokenList = append(tokenList, chains.CHAINS[chainID].Coin)
 	return tokenList
 }
 
-func fetchZkSyncTokenList() []models.TokenListToken {
+func fetchZkSyncTokenList(ctx context.Context) ([]models.TokenListToken, error) {
+	if !chains.IsChainIDSupported(324) {
+		return []models.TokenListToken{}, nil
+	}
 	type TZkSyncAPIResponse struct {
 		Items []struct {
 			Address  string `json:"l2address"`
@@ -39,7 +45,10 @@ func fetchZkSyncTokenList() []models.TokenListToken {
 	tokenAddresses := []common.Address{}
 	tokenIcons := make(map[string]string)
 	for i := 0; i < 40; i++ {
-		response := helpers.FetchJSON[TZkSyncAPIResponse](baseAPIEndpoint + nextPageURI)
+		response, err := helpers.FetchJSON[TZkSyncAPIResponse](ctx, baseAPIEndpoint+nextPageURI)
+		if err != nil {
+			return nil, err
+		}
 		for _, token := range response.Items {
 			tokenAddresses = append(tokenAddresses, common.HexToAddress(token.Address))
 			tokenIcons[common.HexToAddress(token.Address).Hex()] = token.IconURI
@@ -52,15 +61,17 @@ func fetchZkSyncTokenList() []models.TokenListToken {
 			break
 		}
 	}
-	return handleZkSyncTokenList(324, tokenAddresses, tokenIcons)
+	return handleZkSyncTokenList(ctx, 324, tokenAddresses, tokenIcons), nil
 }
 
-func buildZkSyncTokenList() {
+func buildZkSyncTokenList(ctx context.Context) (helpers.TSaveResult, error) {
 	tokenList := helpers.LoadTokenListFromJsonFile(`zksync.json`)
 	tokenList.Name = `zkSync`
 	tokenList.LogoURI = `https://assets.smold.app/api/chain/324/logo-128.png`
 	tokenList.Keywords = []string{`zksync`, `explorer`}
-	tokens := []models.TokenListToken{}
-	tokens = append(tokens, fetchZkSyncTokenList()...)
-	helpers.SaveTokenListInJsonFile(tokenList, tokens, `zksync.json`, helpers.SavingMethodStandard)
+	tokens, err := fetchZkSyncTokenList(ctx)
+	if err != nil {
+		return helpers.NotSavedResult(tokenList), err
+	}
+	return helpers.SaveTokenListInJsonFile(ctx, tokenList, tokens, `zksync.json`, helpers.SavingMethodStandard)
 }
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/fixtures"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+const (
+	exitSuccess = 0 // Everything went fine
+	exitFailure = 1 // The command ran but something required failed
+	exitUsage   = 2 // The command line is invalid
+)
+
+// TCommand is a subcommand of the generators binary
+type TCommand struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(ctx context.Context, fs *flag.FlagSet, args []string) int // fs only has the help set up, the command adds its flags
+}
+
+// COMMANDS is the list of the available subcommands, in the order they are displayed in the help
+var COMMANDS = []TCommand{
+	{
+		Name:        `generate`,
+		Usage:       `generate [names...] [--chains 1,10] [--type token|pool] [--exclude name,...] [--dry-run] [--fixtures record|replay]`,
+		Description: `Run the generators, then build the aggregated lists and the summary`,
+		Run:         runGenerateCommand,
+	},
+	{
+		Name:        `list-generators`,
+		Usage:       `list-generators [--type token|pool]`,
+		Description: `Print the available generators`,
+		Run:         runListGeneratorsCommand,
+	},
+	{
+		Name:        `aggregate`,
+		Usage:       `aggregate [--chains 1,10] [--dry-run]`,
+		Description: `Build the aggregated lists (tokenlistooor and popular) from the existing lists`,
+		Run:         runAggregateCommand,
+	},
+	{
+		Name:        `explain`,
+		Usage:       `explain <address> [--chains 1,10]`,
+		Description: `Explain why a token is or is not in the aggregated lists, from the existing lists`,
+		Run:         runExplainCommand,
+	},
+	{
+		Name:        `summary`,
+		Usage:       `summary`,
+		Description: `Build the summary.json file from the existing lists`,
+		Run:         runSummaryCommand,
+	},
+	{
+		Name:        `validate`,
+		Usage:       `validate [files...]`,
+		Description: `Check the lists in the lists folder, or only the given files, against the schema and the semantic rules`,
+		Run:         runValidateCommand,
+	},
+}
+
+// findCommand returns the command with the given name
+func findCommand(name string) (TCommand, bool) {
+	for _, command := range COMMANDS {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return TCommand{}, false
+}
+
+// printUsage prints the list of the available commands
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: go run ./generators <command> [arguments]`)
+	fmt.Fprintln(os.Stderr, ``)
+	fmt.Fprintln(os.Stderr, `Commands:`)
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, command := range COMMANDS {
+		fmt.Fprintf(w, "  %s\t%s\n", command.Name, command.Description)
+	}
+	w.Flush()
+	fmt.Fprintln(os.Stderr, ``)
+	fmt.Fprintln(os.Stderr, `Run 'go run ./generators <command> --help' for the arguments of a command.`)
+}
+
+// newFlagSet creates the flag set of a command, with a help message built from its usage
+func newFlagSet(command TCommand) *flag.FlagSet {
+	fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: go run ./generators `+command.Usage)
+		fmt.Fprintln(fs.Output(), ``)
+		fmt.Fprintln(fs.Output(), command.Description)
+		fmt.Fprintln(fs.Output(), ``)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+/**************************************************************************************************
+** parseArgs parses the flags of a command, allowing them to be placed before, after or between
+** the positional arguments (e.g. `generate uniswap --chains 1 curve`), which the standard flag
+** package does not support on its own.
+**************************************************************************************************/
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseChainIDs parses a comma separated list of chainIDs
+func parseChainIDs(value string) ([]uint64, error) {
+	chainIDs := []uint64{}
+	for _, element := range splitList(value) {
+		chainID, err := strconv.ParseUint(element, 10, 64)
+		if err != nil {
+			return nil, errors.New(`invalid chainID ` + element)
+		}
+		chainIDs = append(chainIDs, chainID)
+	}
+	return chainIDs, nil
+}
+
+// splitList splits a comma separated list, ignoring the empty elements
+func splitList(value string) []string {
+	elements := []string{}
+	for _, element := range strings.Split(value, `,`) {
+		if element = strings.TrimSpace(element); element != `` {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// parseGeneratorType parses the value of the --type flag. An empty value means all the types.
+func parseGeneratorType(value string) (TGeneratorType, error) {
+	switch strings.ToLower(value) {
+	case ``:
+		return ``, nil
+	case `token`, `tokens`:
+		return GeneratorToken, nil
+	case `pool`, `pools`:
+		return GeneratorPool, nil
+	}
+	return ``, errors.New(`invalid type ` + value + `, expected token or pool`)
+}
+
+/**************************************************************************************************
+** selectGenerators returns the names of the generators to run. With no names, all the generators
+** are selected. The selection is then narrowed to the given type and the excluded generators are
+** removed. An error listing all the unknown names is returned if any name does not match a
+** generator.
+**************************************************************************************************/
+func selectGenerators(names []string, generatorType TGeneratorType, excluded []string) ([]string, error) {
+	unknown := []string{}
+	for _, name := range append(append([]string{}, names...), excluded...) {
+		if _, ok := GENERATORS[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, errors.New(`unknown generators: ` + strings.Join(unknown, `, `) + ` (see list-generators)`)
+	}
+
+	if len(names) == 0 {
+		names = generatorNames()
+	}
+	isExcluded := make(map[string]bool)
+	for _, name := range excluded {
+		isExcluded[name] = true
+	}
+
+	selected := []string{}
+	for _, name := range names {
+		if isExcluded[name] {
+			continue
+		}
+		if generatorType != `` && GENERATORS[name].GeneratorType != generatorType {
+			continue
+		}
+		isExcluded[name] = true // Avoid running the same generator twice
+		selected = append(selected, name)
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+/**************************************************************************************************
+** prepareRun applies the chain filter and initializes everything the generators rely on: the RPC
+** clients and the block each chain is read at, the SmolDapp assets and the logos already known in
+** the existing lists.
+**************************************************************************************************/
+func prepareRun(ctx context.Context, chainIDs []uint64, logAssetsError bool) error {
+	if err := chains.SetChainFilter(chainIDs); err != nil {
+		return err
+	}
+	ethereum.Init(ctx)
+	ethereum.PinBlocks(ctx)
+	helpers.InitIcons(ctx, logAssetsError)
+	loadAllTokenLogoURI()
+	return nil
+}
+
+// recoverPendingWrites completes the writes interrupted during a previous run, before any list is read
+func recoverPendingWrites() bool {
+	if err := helpers.RecoverPendingWrites(); err != nil {
+		logs.Error(`Failed to recover the interrupted writes: ` + err.Error())
+		return false
+	}
+	return true
+}
+
+// finishRun writes the run report and returns the exit code matching the results
+func finishRun(start time.Time, results []TGeneratorResult) int {
+	helpers.FlushMetadata()
+	if err := writeRunReport(start, results); err != nil {
+		logs.Error(err)
+	}
+	if hasBlockingFailure(results) {
+		logs.Error(`At least one required generator failed, see run-report.json`)
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// runAggregators builds the aggregated lists, after the generators are done
+func runAggregators(ctx context.Context, options TSchedulerOptions) []TGeneratorResult {
+	results := []TGeneratorResult{}
+	for _, name := range []string{`tokenlistooor`, `popular`} {
+		result := runGenerator(ctx, name, AGGREGATORS[name], options)
+		logGeneratorResult(result)
+		results = append(results, result)
+	}
+	return results
+}
+
+func runGenerateCommand(ctx context.Context, fs *flag.FlagSet, args []string) int {
+	options := defaultSchedulerOptions()
+	chainsFlag := fs.String(`chains`, ``, `comma separated list of chainIDs to process (default all)`)
+	typeFlag := fs.String(`type`, ``, `only run the generators of this type: token or pool`)
+	excludeFlag := fs.String(`exclude`, ``, `comma separated list of generators to skip`)
+	fs.IntVar(&options.Concurrency, `concurrency`, options.Concurrency, `maximum number of generators running at the same time`)
+	fs.DurationVar(&options.Timeout, `timeout`, options.Timeout, `default deadline of a generator`)
+	logAssetsError := fs.Bool(`log-assets-error`, false, `print the tokens without an icon in the SmolDapp assets`)
+	fs.BoolVar(&helpers.DRY_RUN, `dry-run`, false, `print the changes and the version bumps instead of writing the lists`)
+	fixturesFlag := fs.String(`fixtures`, os.Getenv(`HTTP_FIXTURES`), `record the HTTP responses in testdata/fixtures, or replay them without network: record or replay`)
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if err := fixtures.SetMode(*fixturesFlag); err != nil {
+		return usageError(fs, err)
+	}
+
+	chainIDs, err := parseChainIDs(*chainsFlag)
+	if err != nil {
+		return usageError(fs, err)
+	}
+	generatorType, err := parseGeneratorType(*typeFlag)
+	if err != nil {
+		return usageError(fs, err)
+	}
+	names, err = selectGenerators(names, generatorType, splitList(*excludeFlag))
+	if err != nil {
+		return usageError(fs, err)
+	}
+	if !recoverPendingWrites() {
+		return exitFailure
+	}
+	if err := prepareRun(ctx, chainIDs, *logAssetsError); err != nil {
+		return usageError(fs, err)
+	}
+
+	start := time.Now()
+	names, dependentNames := splitDependentGenerators(names)
+	results := runGenerators(ctx, names, options)
+	for _, result := range results {
+		if result.Status != GeneratorStatusSuccess {
+			logs.Warning(`Generator`, result.Name, `did not complete:`, string(result.Status))
+		}
+	}
+
+	if ctx.Err() != nil {
+		logs.Error(`Run cancelled, skipping the aggregated lists`)
+	} else {
+		results = append(results, runAggregators(ctx, options)...)
+	}
+	results = append(results, runDependentGenerators(ctx, dependentNames, results, options)...)
+	if ctx.Err() == nil && !helpers.DRY_RUN {
+		buildSummary()
+	}
+	return finishRun(start, results)
+}
+
+func runListGeneratorsCommand(ctx context.Context, fs *flag.FlagSet, args []string) int {
+	typeFlag := fs.String(`type`, ``, `only list the generators of this type: token or pool`)
+	if _, err := parseArgs(fs, args); err != nil {
+		return exitCodeForParseError(err)
+	}
+	generatorType, err := parseGeneratorType(*typeFlag)
+	if err != nil {
+		return usageError(fs, err)
+	}
+	names, _ := selectGenerators(nil, generatorType, nil)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tMETHOD\tREQUIRED\tDESCRIPTION")
+	for _, name := range names {
+		generator := GENERATORS[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", name, generator.GeneratorType, generator.GenerationMethod, generator.Required, generator.Description)
+	}
+	w.Flush()
+	return exitSuccess
+}
+
+func runAggregateCommand(ctx context.Context, fs *flag.FlagSet, args []string) int {
+	options := defaultSchedulerOptions()
+	chainsFlag := fs.String(`chains`, ``, `comma separated list of chainIDs to process (default all)`)
+	fs.DurationVar(&options.Timeout, `timeout`, options.Timeout, `deadline of each aggregated list`)
+	logAssetsError := fs.Bool(`log-assets-error`, false, `print the tokens without an icon in the SmolDapp assets`)
+	fs.BoolVar(&helpers.DRY_RUN, `dry-run`, false, `print the changes and the version bumps instead of writing the lists`)
+	if _, err := parseArgs(fs, args); err != nil {
+		return exitCodeForParseError(err)
+	}
+	chainIDs, err := parseChainIDs(*chainsFlag)
+	if err != nil {
+		return usageError(fs, err)
+	}
+	if !recoverPendingWrites() {
+		return exitFailure
+	}
+	if err := prepareRun(ctx, chainIDs, *logAssetsError); err != nil {
+		return usageError(fs, err)
+	}
+
+	start := time.Now()
+	return finishRun(start, runAggregators(ctx, options))
+}
+
+func runExplainCommand(ctx context.Context, fs *flag.FlagSet, args []string) int {
+	chainsFlag := fs.String(`chains`, ``, `comma separated list of chainIDs to explain (default all)`)
+	addresses, err := parseArgs(fs, args)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(addresses) != 1 || !common.IsHexAddress(addresses[0]) {
+		return usageError(fs, errors.New(`expected one token address`))
+	}
+	chainIDs, err := parseChainIDs(*chainsFlag)
+	if err != nil {
+		return usageError(fs, err)
+	}
+	if err := chains.SetChainFilter(chainIDs); err != nil {
+		return usageError(fs, err)
+	}
+	if !recoverPendingWrites() {
+		return exitFailure
+	}
+
+	address := common.HexToAddress(addresses[0]).Hex()
+	found := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LIST\tCHAIN\tINCLUDED\tSCORE\tTHRESHOLD\tSOURCES\tADJUSTMENTS\tREASON")
+	for _, name := range []string{`tokenlistooor`, `popular`} {
+		explanationsOfList, err := loadExplanations(name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				logs.Warning(`No explanations saved for ` + name + `, run the aggregate command first`)
+			} else {
+				logs.Error(`Failed to read the explanations of ` + name + `: ` + err.Error())
+			}
+			continue
+		}
+		chainIDsOfToken := []uint64{}
+		for chainID, explanations := range explanationsOfList {
+			if _, ok := explanations[address]; ok && chains.IsChainIDSupported(chainID) {
+				chainIDsOfToken = append(chainIDsOfToken, chainID)
+			}
+		}
+		sort.Slice(chainIDsOfToken, func(i, j int) bool { return chainIDsOfToken[i] < chainIDsOfToken[j] })
+
+		for _, chainID := range chainIDsOfToken {
+			found = true
+			explanation := explanationsOfList[chainID][address]
+			adjustments := []string{}
+			for adjustment, score := range explanation.Adjustments {
+				adjustments = append(adjustments, adjustment+`=`+strconv.FormatFloat(score, 'f', -1, 64))
+			}
+			sort.Strings(adjustments)
+			fmt.Fprintf(w, "%s\t%d\t%t\t%s\t%s\t%s\t%s\t%s\n",
+				name,
+				chainID,
+				explanation.Included,
+				strconv.FormatFloat(explanation.Score, 'f', -1, 64),
+				strconv.FormatFloat(explanation.Threshold, 'f', -1, 64),
+				strings.Join(explanation.Sources, `,`),
+				strings.Join(adjustments, `,`),
+				explanation.Reason,
+			)
+		}
+	}
+	if !found {
+		logs.Warning(address + ` is not in any of the lists`)
+		return exitFailure
+	}
+	w.Flush()
+	return exitSuccess
+}
+
+func runSummaryCommand(ctx context.Context, fs *flag.FlagSet, args []string) int {
+	if _, err := parseArgs(fs, args); err != nil {
+		return exitCodeForParseError(err)
+	}
+	if !recoverPendingWrites() {
+		return exitFailure
+	}
+	buildSummary()
+	return exitSuccess
+}
+
+func runValidateCommand(ctx context.Context, fs *flag.FlagSet, args []string) int {
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if !recoverPendingWrites() {
+		return exitFailure
+	}
+	if len(files) == 0 {
+		if files, err = helpers.ListTokenListFiles(); err != nil {
+			logs.Error(err)
+			return exitFailure
+		}
+	}
+
+	invalidCount, legacyCount, legacyFileCount := 0, 0, 0
+	for _, file := range files {
+		errs, legacyErrs := helpers.ValidateTokenListFile(file)
+		if len(legacyErrs) > 0 {
+			legacyCount += len(legacyErrs)
+			legacyFileCount++
+		}
+		if len(errs) == 0 {
+			continue
+		}
+		invalidCount++
+		logs.Error(file + ` is invalid:`)
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, ` - `+err.Error())
+		}
+	}
+	if legacyCount > 0 {
+		logs.Warning(strconv.Itoa(legacyCount) + ` problems of ` + strconv.Itoa(legacyFileCount) + ` lists were already in the last commit and are left as they are`)
+	}
+	if invalidCount > 0 {
+		logs.Error(strconv.Itoa(invalidCount) + ` of ` + strconv.Itoa(len(files)) + ` lists are invalid`)
+		return exitFailure
+	}
+	logs.Success(`All the ` + strconv.Itoa(len(files)) + ` lists are valid`)
+	return exitSuccess
+}
+
+// exitCodeForParseError returns the exit code for an error returned by the flag parsing
+func exitCodeForParseError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	return exitUsage
+}
+
+// usageError prints an invalid argument error followed by the usage of the command
+func usageError(fs *flag.FlagSet, err error) int {
+	fmt.Fprintln(fs.Output(), `Error: `+err.Error())
+	fs.Usage()
+	return exitUsage
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgsAcceptsInterleavedFlags(t *testing.T) {
+	for _, test := range []struct {
+		args       []string
+		positional []string
+		chains     string
+		dryRun     bool
+	}{
+		{[]string{}, []string{}, ``, false},
+		{[]string{`uniswap`, `curve`}, []string{`uniswap`, `curve`}, ``, false},
+		{[]string{`--chains`, `1,10`, `uniswap`}, []string{`uniswap`}, `1,10`, false},
+		{[]string{`uniswap`, `--chains`, `1`, `curve`, `--dry-run`, `yearn`}, []string{`uniswap`, `curve`, `yearn`}, `1`, true},
+		{[]string{`uniswap`, `-chains=10`, `--dry-run`}, []string{`uniswap`}, `10`, true},
+	} {
+		fs := flag.NewFlagSet(`test`, flag.ContinueOnError)
+		chains := fs.String(`chains`, ``, ``)
+		dryRun := fs.Bool(`dry-run`, false, ``)
+		positional, err := parseArgs(fs, test.args)
+		if err != nil || !reflect.DeepEqual(positional, test.positional) || *chains != test.chains || *dryRun != test.dryRun {
+			t.Errorf(`got %v, %q, %v and %v for %v`, positional, *chains, *dryRun, err, test.args)
+		}
+	}
+
+	fs := flag.NewFlagSet(`test`, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := parseArgs(fs, []string{`uniswap`, `--unknown`}); err == nil {
+		t.Error(`got no error for an unknown flag`)
+	}
+}
+
+func TestSelectGenerators(t *testing.T) {
+	withTestGenerators(t, map[string]TGenerators{
+		`test-token`: {GeneratorType: GeneratorToken},
+		`test-pool`:  {GeneratorType: GeneratorPool},
+	})
+
+	for _, test := range []struct {
+		names         []string
+		generatorType TGeneratorType
+		excluded      []string
+		selected      []string
+	}{
+		{[]string{`test-token`, `test-pool`}, ``, nil, []string{`test-pool`, `test-token`}},
+		{[]string{`test-token`, `test-pool`, `test-token`}, ``, nil, []string{`test-pool`, `test-token`}},
+		{[]string{`test-token`, `test-pool`}, GeneratorPool, nil, []string{`test-pool`}},
+		{[]string{`test-token`, `test-pool`}, ``, []string{`test-pool`}, []string{`test-token`}},
+	} {
+		selected, err := selectGenerators(test.names, test.generatorType, test.excluded)
+		if err != nil || !reflect.DeepEqual(selected, test.selected) {
+			t.Errorf(`got %v and %v for %v, want %v`, selected, err, test.names, test.selected)
+		}
+	}
+
+	all, err := selectGenerators(nil, GeneratorPool, nil)
+	if err != nil || len(all) < 2 {
+		t.Fatalf(`got %v and %v for all the pool generators`, all, err)
+	}
+	for _, name := range all {
+		if GENERATORS[name].GeneratorType != GeneratorPool {
+			t.Errorf(`%s is not a pool generator`, name)
+		}
+	}
+
+	_, err = selectGenerators([]string{`test-token`, `test-unknown`}, ``, []string{`test-excluded`})
+	if err == nil || !strings.Contains(err.Error(), `unknown generators: test-unknown, test-excluded`) {
+		t.Errorf(`got the error %v for unknown generators`, err)
+	}
+}
+
+func TestGenerateCommandRefusesUnknownGenerators(t *testing.T) {
+	command, _ := findCommand(`generate`)
+	for _, test := range []struct {
+		args     []string
+		exitCode int
+	}{
+		{[]string{`test-unknown`}, exitUsage},
+		{[]string{`--exclude`, `test-unknown`}, exitUsage},
+		{[]string{`--type`, `nft`}, exitUsage},
+		{[]string{`--chains`, `mainnet`}, exitUsage},
+		{[]string{`--help`}, exitSuccess},
+	} {
+		fs := newFlagSet(command)
+		fs.SetOutput(io.Discard)
+		if exitCode := command.Run(context.Background(), fs, test.args); exitCode != test.exitCode {
+			t.Errorf(`got the exit code %d for %v, want %d`, exitCode, test.args, test.exitCode)
+		}
+	}
+}
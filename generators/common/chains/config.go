@@ -1,6 +1,10 @@
 package chains
 
 import (
+	"errors"
+	"sort"
+	"strconv"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
@@ -54,20 +58,66 @@ var CHAINS = map[uint64]TChain{
 	7777777: ZORA,
 }
 
+// SUPPORTED_CHAIN_IDS is the list of chainIDs the program works with. It contains all the chains
+// from CHAINS, unless a filter was set with SetChainFilter.
 var SUPPORTED_CHAIN_IDS = []uint64{}
 
+// chainFilter contains the chainIDs selected with SetChainFilter. Empty means all chains.
+var chainFilter = map[uint64]bool{}
+
 func init() {
 	for k := range CHAINS {
 		SUPPORTED_CHAIN_IDS = append(SUPPORTED_CHAIN_IDS, k)
 	}
+	sort.Slice(SUPPORTED_CHAIN_IDS, func(i, j int) bool {
+		return SUPPORTED_CHAIN_IDS[i] < SUPPORTED_CHAIN_IDS[j]
+	})
 }
 
-// IsChainIDSupported returns true if the chainID is supported by our program
-func IsChainIDSupported(chainID uint64) bool {
+/**************************************************************************************************
+** SetChainFilter limits the program to the given chainIDs. Once set, SUPPORTED_CHAIN_IDS only
+** contains these chains and IsChainIDSupported returns false for the others, which makes every
+** generator skip them. The tokens of the other chains already in a list are kept as they are.
+** Calling it with an empty list removes the filter.
+** It must be called before anything is read from the chains, usually right after parsing the
+** command line.
+**************************************************************************************************/
+func SetChainFilter(chainIDs []uint64) error {
+	filter := map[uint64]bool{}
+	for _, chainID := range chainIDs {
+		if !IsChainIDKnown(chainID) {
+			return errors.New(`unknown chainID ` + strconv.FormatUint(chainID, 10))
+		}
+		filter[chainID] = true
+	}
+
+	chainFilter = filter
+	SUPPORTED_CHAIN_IDS = []uint64{}
+	for chainID := range CHAINS {
+		if IsChainIDSupported(chainID) {
+			SUPPORTED_CHAIN_IDS = append(SUPPORTED_CHAIN_IDS, chainID)
+		}
+	}
+	sort.Slice(SUPPORTED_CHAIN_IDS, func(i, j int) bool {
+		return SUPPORTED_CHAIN_IDS[i] < SUPPORTED_CHAIN_IDS[j]
+	})
+	return nil
+}
+
+// IsChainIDKnown returns true if the chainID is in CHAINS, whether it is filtered out or not
+func IsChainIDKnown(chainID uint64) bool {
 	_, ok := CHAINS[chainID]
 	return ok
 }
 
+// IsChainIDSupported returns true if the chainID is supported by our program and not filtered out
+func IsChainIDSupported(chainID uint64) bool {
+	if !IsChainIDKnown(chainID) {
+		return false
+	}
+	return len(chainFilter) == 0 || chainFilter[chainID]
+}
+
 func IsTokenIgnored(chainId uint64, address common.Address) bool {
 	if address.Hex() == common.HexToAddress("0x0000000000000000000000000000000000000000").Hex() {
 		return true
@@ -0,0 +1,102 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// PINNED_BLOCKS holds the block all the reads of a chain are made at during the run, see PinBlocks
+var PINNED_BLOCKS = map[uint64]uint64{}
+
+// pinnedBlocksMutex guards PINNED_BLOCKS
+var pinnedBlocksMutex = sync.RWMutex{}
+
+/**************************************************************************************************
+** findSafeBlock returns the finalized block of a chain. The chains whose RPC does not know the
+** finalized tag fall back to the head minus the confirmations of the indexer, so the block cannot
+** be removed by a reorg either.
+**************************************************************************************************/
+func findSafeBlock(ctx context.Context, chainID uint64, client TRPCClient) (uint64, error) {
+	header, err := client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err == nil && header != nil && header.Number != nil && header.Number.Sign() > 0 {
+		return header.Number.Uint64(), nil
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if confirmations := ConfirmationsFor(chainID); head > confirmations {
+		return head - confirmations, nil
+	}
+	return head, nil
+}
+
+/**************************************************************************************************
+** PinBlocks chooses, at the start of a run, the block every chain is read at: the multicalls, the
+** calls of the contract bindings through CallOpts and the upper bound of the log scans. All the
+** lists of a run thus describe the same state of each chain, recorded in their metadata. A chain
+** whose block could not be chosen is read at its latest block.
+**************************************************************************************************/
+func PinBlocks(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, chainID := range chains.SUPPORTED_CHAIN_IDS {
+		client := GetRPC(chainID)
+		if client == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(chainID uint64, client TRPCClient) {
+			defer wg.Done()
+			block, err := findSafeBlock(ctx, chainID, client)
+			if err != nil {
+				logs.Warning(`Failed to pin a block for chain ` + strconv.FormatUint(chainID, 10) + `, reading its latest block: ` + err.Error())
+				return
+			}
+			PinBlock(chainID, block)
+		}(chainID, client)
+	}
+	wg.Wait()
+}
+
+// PinBlock sets the block the reads of a chain are made at, for instance on a simulated chain
+func PinBlock(chainID uint64, block uint64) {
+	pinnedBlocksMutex.Lock()
+	defer pinnedBlocksMutex.Unlock()
+	PINNED_BLOCKS[chainID] = block
+}
+
+// GetPinnedBlockNumber returns the block the reads of a chain are made at, if one was pinned
+func GetPinnedBlockNumber(chainID uint64) (uint64, bool) {
+	pinnedBlocksMutex.RLock()
+	defer pinnedBlocksMutex.RUnlock()
+	block, ok := PINNED_BLOCKS[chainID]
+	return block, ok
+}
+
+// GetPinnedBlock returns the block the reads of a chain are made at, or nil for the latest one
+func GetPinnedBlock(chainID uint64) *big.Int {
+	if block, ok := GetPinnedBlockNumber(chainID); ok {
+		return new(big.Int).SetUint64(block)
+	}
+	return nil
+}
+
+// GetHead returns the pinned block of a chain, or its latest block if none was pinned
+func GetHead(ctx context.Context, chainID uint64, client TRPCClient) (uint64, error) {
+	if block, ok := GetPinnedBlockNumber(chainID); ok {
+		return block, nil
+	}
+	return client.BlockNumber(ctx)
+}
+
+// CallOpts returns the options of the calls of the contract bindings, reading the pinned block
+func CallOpts(ctx context.Context, chainID uint64) *bind.CallOpts {
+	return &bind.CallOpts{Context: ctx, BlockNumber: GetPinnedBlock(chainID)}
+}
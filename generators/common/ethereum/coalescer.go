@@ -0,0 +1,291 @@
+package ethereum
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/stats"
+)
+
+// COALESCER_WINDOW is the time the coalescer of a chain waits for the requests of the other
+// generators before sending its first batch
+var COALESCER_WINDOW = 50 * time.Millisecond
+
+// COALESCER_MAX_BATCH_SIZE is the number of calls sent in each multicall, unless the chain sets a
+// lower MaxBatchSize
+var COALESCER_MAX_BATCH_SIZE = uint64(1000)
+
+// COALESCER_CALLS_IN_FLIGHT is the number of calls a chain runs at the same time: the smaller the
+// batches of a chain, the more of them are sent at the same time, up to COALESCER_MAX_CONCURRENCY
+var COALESCER_CALLS_IN_FLIGHT = uint64(4000)
+var COALESCER_MAX_CONCURRENCY = 8
+
+// BASIC_INFORMATIONS_CALLS is the number of calls fetchBasicInformations sends for each token
+const BASIC_INFORMATIONS_CALLS = 5
+
+// tBasicInformationsFuture is the pending lookup of a token, resolved once its batch is done, with
+// the contexts of the generators waiting for it until then
+type tBasicInformationsFuture struct {
+	done    chan struct{}
+	result  *TERC20
+	waiters []context.Context
+}
+
+// tQueuedLookup is a token waiting for its batch, with the context of the first generator asking
+// for it, which the calls are counted against
+type tQueuedLookup struct {
+	ctx     context.Context
+	address common.Address
+}
+
+/**************************************************************************************************
+** tChainCoalescer gathers the lookups of all the generators for a chain. Each token is only asked
+** once per run: the generators asking for a token already queued share its future, and the ones
+** asking for a token already fetched get its answer from results. The futures are dropped once
+** their batch is done, along with the contexts waiting for them. A batch failing, or cancelled as
+** all the generators waiting for it are done, keeps no answer, so the next lookup asks again.
+**************************************************************************************************/
+type tChainCoalescer struct {
+	chainID   uint64
+	mutex     sync.Mutex
+	futures   map[common.Address]*tBasicInformationsFuture
+	results   map[common.Address]*TERC20
+	queue     []tQueuedLookup
+	isRunning bool
+}
+
+var coalescers = map[uint64]*tChainCoalescer{}
+var coalescersMutex = sync.Mutex{}
+
+func getCoalescer(chainID uint64) *tChainCoalescer {
+	coalescersMutex.Lock()
+	defer coalescersMutex.Unlock()
+	if _, ok := coalescers[chainID]; !ok {
+		coalescers[chainID] = &tChainCoalescer{
+			chainID: chainID,
+			futures: make(map[common.Address]*tBasicInformationsFuture),
+			results: make(map[common.Address]*TERC20),
+		}
+	}
+	return coalescers[chainID]
+}
+
+// resetCoalescer forgets the lookups of a chain, whose answers came from a client now replaced
+func resetCoalescer(chainID uint64) {
+	coalescersMutex.Lock()
+	defer coalescersMutex.Unlock()
+	delete(coalescers, chainID)
+}
+
+// multicallTuning returns the number of calls sent in each multicall of a chain, from its
+// MaxBatchSize, and the number of multicalls sent at the same time
+func multicallTuning(chainID uint64) (uint64, int) {
+	batchSize := COALESCER_MAX_BATCH_SIZE
+	if maxBatchSize := chains.CHAINS[chainID].MaxBatchSize; maxBatchSize > 0 && maxBatchSize < batchSize {
+		batchSize = maxBatchSize
+	}
+	if batchSize < BASIC_INFORMATIONS_CALLS {
+		batchSize = BASIC_INFORMATIONS_CALLS
+	}
+	concurrency := int(COALESCER_CALLS_IN_FLIGHT / batchSize)
+	if concurrency < 1 {
+		concurrency = 1
+	} else if concurrency > COALESCER_MAX_CONCURRENCY {
+		concurrency = COALESCER_MAX_CONCURRENCY
+	}
+	return batchSize, concurrency
+}
+
+// resolvedFuture returns a future already resolved with the result
+func resolvedFuture(result *TERC20) *tBasicInformationsFuture {
+	future := &tBasicInformationsFuture{done: make(chan struct{}), result: result}
+	close(future.done)
+	return future
+}
+
+// submit returns the futures of the tokens, queuing the ones not asked yet in this run
+func (c *tChainCoalescer) submit(ctx context.Context, tokens []common.Address) map[common.Address]*tBasicInformationsFuture {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	futures := make(map[common.Address]*tBasicInformationsFuture)
+	for _, token := range tokens {
+		if result, ok := c.results[token]; ok {
+			futures[token] = resolvedFuture(result)
+			continue
+		}
+		future, ok := c.futures[token]
+		if !ok {
+			future = &tBasicInformationsFuture{done: make(chan struct{})}
+			c.futures[token] = future
+			c.queue = append(c.queue, tQueuedLookup{ctx: ctx, address: token})
+		}
+		if !hasContext(future.waiters, ctx) {
+			future.waiters = append(future.waiters, ctx)
+		}
+		futures[token] = future
+	}
+	if len(c.queue) > 0 && !c.isRunning {
+		c.isRunning = true
+		go c.run()
+	}
+	return futures
+}
+
+/**************************************************************************************************
+** run sends the queued tokens by batches, see multicallTuning, until the queue is empty. It waits
+** for COALESCER_WINDOW first, and the tokens queued while the batches are running are sent with
+** the next ones, so the lookups of the generators running together share their multicalls.
+**************************************************************************************************/
+func (c *tChainCoalescer) run() {
+	time.Sleep(COALESCER_WINDOW)
+	batchSize, concurrency := multicallTuning(c.chainID)
+	tokensPerBatch := int(batchSize / BASIC_INFORMATIONS_CALLS)
+	semaphore := make(chan struct{}, concurrency)
+
+	for {
+		semaphore <- struct{}{}
+		c.mutex.Lock()
+		if len(c.queue) == 0 {
+			c.isRunning = false
+			c.mutex.Unlock()
+			<-semaphore
+			return
+		}
+		size := tokensPerBatch
+		if size > len(c.queue) {
+			size = len(c.queue)
+		}
+		batch := c.queue[:size]
+		c.queue = c.queue[size:]
+		c.mutex.Unlock()
+
+		go func(batch []tQueuedLookup) {
+			defer func() { <-semaphore }()
+			c.fetch(batch, batchSize)
+		}(batch)
+	}
+}
+
+// hasContext returns true if ctx is one of the contexts
+func hasContext(contexts []context.Context, ctx context.Context) bool {
+	for _, item := range contexts {
+		if item == ctx {
+			return true
+		}
+	}
+	return false
+}
+
+// waitersOf returns the contexts waiting for the futures. The caller must hold the mutex of the
+// coalescer.
+func waitersOf(futures []*tBasicInformationsFuture) []context.Context {
+	waiters := []context.Context{}
+	for _, future := range futures {
+		for _, waiter := range future.waiters {
+			if !hasContext(waiters, waiter) {
+				waiters = append(waiters, waiter)
+			}
+		}
+	}
+	return waiters
+}
+
+/**************************************************************************************************
+** cancelWhenAbandoned cancels the context of a batch once the contexts of all the generators
+** waiting for its tokens are done, including the ones which joined the batch while it was running.
+** It returns when the batch is over.
+**************************************************************************************************/
+func (c *tChainCoalescer) cancelWhenAbandoned(batchCtx context.Context, cancel context.CancelFunc, futures []*tBasicInformationsFuture) {
+	for {
+		c.mutex.Lock()
+		waiters := waitersOf(futures)
+		c.mutex.Unlock()
+
+		for _, waiter := range waiters {
+			select {
+			case <-waiter.Done():
+			case <-batchCtx.Done():
+				return
+			}
+		}
+
+		c.mutex.Lock()
+		if len(waitersOf(futures)) == len(waiters) {
+			cancel()
+			c.mutex.Unlock()
+			return
+		}
+		c.mutex.Unlock()
+	}
+}
+
+/**************************************************************************************************
+** fetch reads the basic information of a batch of tokens and resolves their futures. The calls
+** are counted against the context of the first generator asking for the tokens, see stats.Detach,
+** and are cancelled once all the generators waiting for them are done, see cancelWhenAbandoned.
+**************************************************************************************************/
+func (c *tChainCoalescer) fetch(batch []tQueuedLookup, batchSize uint64) {
+	addresses := make([]common.Address, 0, len(batch))
+	futures := make([]*tBasicInformationsFuture, 0, len(batch))
+	c.mutex.Lock()
+	for _, lookup := range batch {
+		addresses = append(addresses, lookup.address)
+		futures = append(futures, c.futures[lookup.address])
+	}
+	c.mutex.Unlock()
+	batchCtx, cancel := context.WithCancel(stats.Detach(batch[0].ctx))
+	defer cancel()
+	go c.cancelWhenAbandoned(batchCtx, cancel, futures)
+
+	tokens, err := fetchBasicInformations(batchCtx, c.chainID, addresses, batchSize)
+	if err != nil && batchCtx.Err() == nil {
+		logs.Error(`Failed to fetch the basic informations of ` + strconv.Itoa(len(addresses)) + ` tokens on chain ` + strconv.FormatUint(c.chainID, 10) + `: ` + err.Error())
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, address := range addresses {
+		future := futures[i]
+		if err == nil {
+			future.result = tokens[address.Hex()]
+			c.results[address] = future.result
+		}
+		delete(c.futures, address)
+		future.waiters = nil
+		close(future.done)
+	}
+}
+
+/**************************************************************************************************
+** FetchBasicInformations returns the name, the symbol and the decimals of the tokens of a chain.
+** The lookups of all the generators go through the coalescer of the chain, see tChainCoalescer,
+** which asks the chain once per token and per run, in shared multicalls.
+**
+** The tokens whose batch failed, or which were not fetched before ctx was done, are missing from
+** the result, while a token with an empty field was checked and did not answer.
+**************************************************************************************************/
+func FetchBasicInformations(ctx context.Context, chainID uint64, tokens []common.Address) map[string]*TERC20 {
+	tokenList := make(map[string]*TERC20)
+	if len(tokens) == 0 {
+		return tokenList
+	}
+
+	futures := getCoalescer(chainID).submit(ctx, tokens)
+	for address, future := range futures {
+		select {
+		case <-future.done:
+		case <-ctx.Done():
+			return tokenList
+		}
+		if future.result != nil {
+			token := *future.result
+			tokenList[address.Hex()] = &token
+		}
+	}
+	return tokenList
+}
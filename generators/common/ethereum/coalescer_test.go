@@ -0,0 +1,95 @@
+package ethereum_test
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// tStallingBackend is a simulated chain whose calls only return once their context is done
+type tStallingBackend struct {
+	*simulated.TBackend
+	cancelledAt chan time.Time
+}
+
+func (b tStallingBackend) CallContract(ctx context.Context, call goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	<-ctx.Done()
+	select {
+	case b.cancelledAt <- time.Now():
+	default:
+	}
+	return nil, ctx.Err()
+}
+
+func TestFetchBasicInformationsCancelsTheAbandonedBatches(t *testing.T) {
+	backend, multicall := newSimulatedChain(t)
+	token, err := backend.DeployERC20(`Wrapped Ether`, `WETH`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stalling := tStallingBackend{TBackend: backend, cancelledAt: make(chan time.Time, 1)}
+	ethereum.SetRPC(TEST_CHAIN_ID, stalling, multicall)
+
+	start := time.Now()
+	lastCtx, cancelLast := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelLast()
+	go ethereum.FetchBasicInformations(lastCtx, TEST_CHAIN_ID, []common.Address{token})
+	firstCtx, cancelFirst := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFirst()
+	if tokens := ethereum.FetchBasicInformations(firstCtx, TEST_CHAIN_ID, []common.Address{token}); len(tokens) != 0 {
+		t.Fatalf(`got %v from a lookup which timed out`, tokens)
+	}
+
+	select {
+	case cancelledAt := <-stalling.cancelledAt:
+		if cancelledAt.Sub(start) < 400*time.Millisecond {
+			t.Errorf(`the batch was cancelled after %s, while a generator was still waiting for it`, cancelledAt.Sub(start))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal(`the batch kept running after all the generators waiting for it were done`)
+	}
+}
+
+// tCallCounter is a simulated chain counting its calls
+type tCallCounter struct {
+	*simulated.TBackend
+	calls *atomic.Int32
+}
+
+func (c tCallCounter) CallContract(ctx context.Context, call goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls.Add(1)
+	return c.TBackend.CallContract(ctx, call, blockNumber)
+}
+
+func TestFetchBasicInformationsAsksEachTokenOnce(t *testing.T) {
+	backend, multicall := newSimulatedChain(t)
+	token, err := backend.DeployERC20(`Wrapped Ether`, `WETH`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := &atomic.Int32{}
+	ethereum.SetRPC(TEST_CHAIN_ID, tCallCounter{TBackend: backend, calls: calls}, multicall)
+
+	first := ethereum.FetchBasicInformations(context.Background(), TEST_CHAIN_ID, []common.Address{token})
+	if first[token.Hex()] == nil || first[token.Hex()].Symbol != `WETH` {
+		t.Fatalf(`got %v for the first lookup`, first)
+	}
+	callsOfTheFirstLookup := calls.Load()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	second := ethereum.FetchBasicInformations(ctx, TEST_CHAIN_ID, []common.Address{token})
+	if second[token.Hex()] == nil || second[token.Hex()].Symbol != `WETH` {
+		t.Fatalf(`got %v for the second lookup`, second)
+	}
+	if calls.Load() != callsOfTheFirstLookup {
+		t.Errorf(`the token already fetched was asked again`)
+	}
+}
@@ -0,0 +1,88 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+)
+
+// CODE_CHECK_BATCH_SIZE is the number of addresses whose code is checked by each call
+var CODE_CHECK_BATCH_SIZE = 500
+
+/**************************************************************************************************
+** CODE_SIZE_READER_CODE is put at CODE_SIZE_READER while the code of many addresses is checked in
+** one call. It is SLOT_READER_CODE with EXTCODESIZE instead of SLOAD, and returns the code size of
+** each address of its calldata, 32 bytes each:
+**   PUSH1 0
+**   loop: JUMPDEST CALLDATASIZE DUP2 LT ISZERO PUSH1 end JUMPI
+**         DUP1 CALLDATALOAD EXTCODESIZE DUP2 MSTORE PUSH1 32 ADD PUSH1 loop JUMP
+**   end:  JUMPDEST CALLDATASIZE PUSH1 0 RETURN
+**************************************************************************************************/
+var CODE_SIZE_READER_CODE = common.FromHex(`0x60005b3681101560155780353b81526020016002565b366000f3`)
+
+// CODE_SIZE_READER is the address given CODE_SIZE_READER_CODE by the state overrides
+var CODE_SIZE_READER = common.HexToAddress(`0x00000000000000000000000000000000c0de512e`)
+
+// readCodeSizes returns whether each address has code at the block, in one call
+func readCodeSizes(ctx context.Context, client TStateClient, addresses []common.Address, blockNumber *big.Int) (map[string]bool, error) {
+	calldata := make([]byte, 0, len(addresses)*32)
+	for _, address := range addresses {
+		calldata = append(calldata, common.LeftPadBytes(address.Bytes(), 32)...)
+	}
+	result, err := client.CallContractWithOverrides(
+		ctx,
+		goEthereum.CallMsg{To: &CODE_SIZE_READER, Data: calldata},
+		blockNumber,
+		map[common.Address]gethclient.OverrideAccount{CODE_SIZE_READER: {Code: CODE_SIZE_READER_CODE}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != len(calldata) {
+		return nil, errors.New(`the code size reader answered ` + strconv.Itoa(len(result)) + ` bytes for ` + strconv.Itoa(len(addresses)) + ` addresses`)
+	}
+	hasCode := make(map[string]bool, len(addresses))
+	for index, address := range addresses {
+		hasCode[address.Hex()] = new(big.Int).SetBytes(result[index*32:(index+1)*32]).Sign() != 0
+	}
+	return hasCode, nil
+}
+
+/**************************************************************************************************
+** HasCode returns whether each address has code at the block. With a client which can override the
+** state, the addresses are checked by batches of CODE_CHECK_BATCH_SIZE in one eth_call each.
+** Otherwise, or when a batch fails, its addresses are asked one by one with eth_getCode. The
+** addresses whose code could not be read are missing from the result.
+**************************************************************************************************/
+func HasCode(ctx context.Context, client TRPCClient, addresses []common.Address, blockNumber *big.Int) map[string]bool {
+	hasCode := make(map[string]bool, len(addresses))
+	stateClient, canOverride := client.(TStateClient)
+	for start := 0; start < len(addresses); start += CODE_CHECK_BATCH_SIZE {
+		if ctx.Err() != nil {
+			break
+		}
+		end := start + CODE_CHECK_BATCH_SIZE
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		if canOverride {
+			if batch, err := readCodeSizes(ctx, stateClient, addresses[start:end], blockNumber); err == nil {
+				for key, value := range batch {
+					hasCode[key] = value
+				}
+				continue
+			}
+		}
+		for _, address := range addresses[start:end] {
+			if code, err := client.CodeAt(ctx, address, blockNumber); err == nil {
+				hasCode[address.Hex()] = len(code) > 0
+			}
+		}
+	}
+	return hasCode
+}
@@ -0,0 +1,75 @@
+package ethereum_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// tCodeAtCounter is a simulated chain counting the calls to eth_getCode
+type tCodeAtCounter struct {
+	*simulated.TBackend
+	calls *int
+}
+
+func (c tCodeAtCounter) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	*c.calls++
+	return c.TBackend.CodeAt(ctx, contract, blockNumber)
+}
+
+// tPlainClient hides the state overrides of the simulated chain
+type tPlainClient struct {
+	ethereum.TRPCClient
+}
+
+func TestHasCode(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	deployed, err := backend.DeployERC20(`Wrapped Ether`, `WETH`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := backend.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployedAfter, err := backend.DeployERC20(`Dai Stablecoin`, `DAI`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := common.HexToAddress(`0x000000000000000000000000000000000000dead`)
+	addresses := []common.Address{deployed, empty, deployedAfter}
+
+	calls := 0
+	for _, test := range []struct {
+		name          string
+		client        ethereum.TRPCClient
+		codeAtCalls   int
+		blockNumber   *big.Int
+		deployedAfter bool
+	}{
+		{`batched at the head`, tCodeAtCounter{backend, &calls}, 0, nil, true},
+		{`batched at a past block`, tCodeAtCounter{backend, &calls}, 0, new(big.Int).SetUint64(head), false},
+		{`one by one without overrides`, tPlainClient{tCodeAtCounter{backend, &calls}}, 3, new(big.Int).SetUint64(head), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			calls = 0
+			hasCode := ethereum.HasCode(context.Background(), test.client, addresses, test.blockNumber)
+			want := map[string]bool{deployed.Hex(): true, empty.Hex(): false, deployedAfter.Hex(): test.deployedAfter}
+			if len(hasCode) != len(want) {
+				t.Fatalf(`got %v, want %v`, hasCode, want)
+			}
+			for key, value := range want {
+				if hasCode[key] != value {
+					t.Errorf(`got %v for %s, want %v`, hasCode[key], key, value)
+				}
+			}
+			if calls != test.codeAtCalls {
+				t.Errorf(`got %d calls to eth_getCode, want %d`, calls, test.codeAtCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+/**************************************************************************************************
+** TIndexerSettings holds the settings of the event indexer.
+** - InitialRange is the number of blocks asked in the first request, unless the chain has its own
+**   in INDEXER_RANGE_FOR_CHAINID. The range is halved when a request fails and doubled, up to
+**   MaxRange, when it succeeds. When the node rejects a range as too large, the range never grows
+**   back above its half for the rest of the run, as it is the limit of the provider.
+** - MaxAttempts is the number of failures in a row after which the indexing stops. The ranges
+**   rejected as too large are retried right away and do not count.
+** - Confirmations is the number of blocks the indexer stays behind the head of the chain, so the
+**   events it reads cannot be removed by a reorg, unless the chain has its own in
+**   CONFIRMATIONS_FOR_CHAINID.
+**************************************************************************************************/
+type TIndexerSettings struct {
+	InitialRange  uint64
+	MaxRange      uint64
+	MaxAttempts   int
+	Confirmations uint64
+}
+
+// INDEXER_SETTINGS are the settings used by IndexEvents
+var INDEXER_SETTINGS = TIndexerSettings{
+	InitialRange:  100_000,
+	MaxRange:      1_000_000,
+	MaxAttempts:   8,
+	Confirmations: 64,
+}
+
+// INDEXER_RANGE_FOR_CHAINID overrides INDEXER_SETTINGS.InitialRange for the chains whose nodes
+// limit the range of the log requests
+var INDEXER_RANGE_FOR_CHAINID = map[uint64]uint64{
+	56: 5_000,
+}
+
+// CONFIRMATIONS_FOR_CHAINID overrides INDEXER_SETTINGS.Confirmations for the chains with deeper
+// reorgs
+var CONFIRMATIONS_FOR_CHAINID = map[uint64]uint64{
+	137: 256,
+}
+
+// ConfirmationsFor returns the number of blocks the reads of a chain stay behind its head, which is
+// also how far back the indexer goes after a reorg
+func ConfirmationsFor(chainID uint64) uint64 {
+	if chainConfirmations, ok := CONFIRMATIONS_FOR_CHAINID[chainID]; ok {
+		return chainConfirmations
+	}
+	return INDEXER_SETTINGS.Confirmations
+}
+
+// TCheckpoint is the last block indexed for a TIndexer, with its hash to detect a reorg
+type TCheckpoint struct {
+	Block uint64      `json:"block"`
+	Hash  common.Hash `json:"hash"`
+}
+
+// TCheckpointStore keeps the checkpoints of the indexers between two runs
+type TCheckpointStore interface {
+	GetCheckpoint(key string) (TCheckpoint, bool)
+	SetCheckpoint(key string, checkpoint TCheckpoint)
+}
+
+/**************************************************************************************************
+** TIndexer describes the events to index: the Event of the ABI emitted by Contract on ChainID,
+** from StartBlock. StartBlock is only used when Store has no checkpoint for the indexer, see Key.
+** Without a Store, the events are read from StartBlock on every run.
+**************************************************************************************************/
+type TIndexer struct {
+	ChainID    uint64
+	Contract   common.Address
+	ABI        *abi.ABI
+	Event      string
+	StartBlock uint64
+	Store      TCheckpointStore
+}
+
+// Key identifies the checkpoint of the indexer in its store
+func (indexer TIndexer) Key() string {
+	return strconv.FormatUint(indexer.ChainID, 10) + `/` + indexer.Contract.Hex() + `/` + indexer.Event
+}
+
+// isRangeLimitError returns true if a log request failed because its range or its result is too
+// large for the node
+func isRangeLimitError(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, `query returned more than`) ||
+		strings.Contains(message, `block range`) ||
+		strings.Contains(message, `range is too large`) ||
+		strings.Contains(message, `response size`) ||
+		strings.Contains(message, `too many blocks`)
+}
+
+/**************************************************************************************************
+** IndexEvents reads the events of an indexer, range by range, decodes each of them in a T, the
+** binding of the event generated by abigen, and passes it to the handler with its log. The events
+** are handled in the order of the chain.
+** The indexer starts after its checkpoint and stops at the block pinned for the run, see
+** PinBlocks, or at the head of the chain minus the confirmations if none was pinned. The checkpoint is moved forward after each range, so the store must only be
+** saved once the output of the handler is saved. If the hash of the checkpoint does not match the
+** chain anymore, the indexer goes back by the confirmations before starting: the events of these
+** blocks handled by the previous run are passed to the handler again, which must not count them
+** twice.
+** An error of the handler, or MaxAttempts failures in a row, stops the indexing with an error; the
+** checkpoint then covers the ranges handled until then.
+**************************************************************************************************/
+func IndexEvents[T any](ctx context.Context, indexer TIndexer, handler func(event *T, log types.Log) error) error {
+	client := GetRPC(indexer.ChainID)
+	if client == nil {
+		return errors.New(`no RPC for chain ` + strconv.FormatUint(indexer.ChainID, 10))
+	}
+	event, ok := indexer.ABI.Events[indexer.Event]
+	if !ok {
+		return errors.New(`unknown event ` + indexer.Event)
+	}
+	contract := bind.NewBoundContract(indexer.Contract, *indexer.ABI, nil, nil, nil)
+
+	confirmations := ConfirmationsFor(indexer.ChainID)
+	head, isPinned := GetPinnedBlockNumber(indexer.ChainID)
+	if !isPinned {
+		latest, err := client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		if latest < confirmations {
+			return nil
+		}
+		head = latest - confirmations
+	}
+
+	/**********************************************************************************************
+	** Resume after the checkpoint, unless the block it points to is not part of the chain anymore.
+	**********************************************************************************************/
+	start := indexer.StartBlock
+	var checkpoint TCheckpoint
+	hasCheckpoint := false
+	if indexer.Store != nil {
+		checkpoint, hasCheckpoint = indexer.Store.GetCheckpoint(indexer.Key())
+	}
+	if hasCheckpoint {
+		start = checkpoint.Block + 1
+		if checkpoint.Hash != (common.Hash{}) {
+			header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(checkpoint.Block))
+			if err != nil {
+				return err
+			}
+			if header.Hash() != checkpoint.Hash {
+				logs.Warning(`Reorg detected at block ` + strconv.FormatUint(checkpoint.Block, 10) + ` for ` + indexer.Key() + `, going back ` + strconv.FormatUint(confirmations, 10) + ` blocks`)
+				start = indexer.StartBlock
+				if checkpoint.Block > indexer.StartBlock+confirmations {
+					start = checkpoint.Block - confirmations
+				}
+			}
+		}
+	}
+
+	/**********************************************************************************************
+	** Read the logs range by range, adapting the range to what the node accepts.
+	**********************************************************************************************/
+	blockRange := INDEXER_SETTINGS.InitialRange
+	if chainRange, ok := INDEXER_RANGE_FOR_CHAINID[indexer.ChainID]; ok {
+		blockRange = chainRange
+	}
+	maxRange := INDEXER_SETTINGS.MaxRange
+	lastIndexed, hasIndexed := uint64(0), false
+	failures := 0
+	var indexingErr error
+	for from := start; from <= head; {
+		if err := ctx.Err(); err != nil {
+			indexingErr = err
+			break
+		}
+		to := from + blockRange - 1
+		if to > head {
+			to = head
+		}
+
+		eventLogs, err := client.FilterLogs(ctx, goEthereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{indexer.Contract},
+			Topics:    [][]common.Hash{{event.ID}},
+		})
+		if err != nil {
+			if isRangeLimitError(err) && blockRange > 1 {
+				blockRange /= 2
+				maxRange = blockRange
+				continue
+			}
+			failures++
+			if failures >= INDEXER_SETTINGS.MaxAttempts || ctx.Err() != nil {
+				indexingErr = errors.New(`failed to read the logs of ` + indexer.Key() + ` from block ` + strconv.FormatUint(from, 10) + `: ` + err.Error())
+				break
+			}
+			if blockRange > 1 {
+				blockRange /= 2
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(failures) * time.Second):
+			}
+			continue
+		}
+		failures = 0
+
+		logs.Info(indexer.Event+` - start: `, from, ` end: `, to, ` head: `, head, ` events: `, len(eventLogs), ` chainID: `, indexer.ChainID)
+		for _, log := range eventLogs {
+			if log.Removed {
+				continue
+			}
+			decoded := new(T)
+			if err := contract.UnpackLog(decoded, indexer.Event, log); err != nil {
+				logs.Warning(`Skipping an undecodable ` + indexer.Event + ` event in tx ` + log.TxHash.Hex() + `: ` + err.Error())
+				continue
+			}
+			if err := handler(decoded, log); err != nil {
+				indexingErr = err
+				break
+			}
+		}
+		if indexingErr != nil {
+			break
+		}
+
+		lastIndexed, hasIndexed = to, true
+		if indexer.Store != nil {
+			indexer.Store.SetCheckpoint(indexer.Key(), TCheckpoint{Block: lastIndexed})
+		}
+		from = to + 1
+		if blockRange*2 <= maxRange {
+			blockRange *= 2
+		}
+	}
+
+	/**********************************************************************************************
+	** Record the hash of the last block indexed, checked by the next run.
+	**********************************************************************************************/
+	if indexer.Store != nil && hasIndexed {
+		if header, err := client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(lastIndexed)); err == nil {
+			indexer.Store.SetCheckpoint(indexer.Key(), TCheckpoint{Block: lastIndexed, Hash: header.Hash()})
+		}
+	}
+	return indexingErr
+}
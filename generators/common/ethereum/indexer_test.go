@@ -0,0 +1,165 @@
+package ethereum_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+var uniV2FactoryABI, _ = contracts.UniV2FactoryMetaData.GetAbi()
+var uniV3FactoryABI, _ = contracts.UniV3FactoryMetaData.GetAbi()
+
+// tMemoryStore is a TCheckpointStore kept in memory
+type tMemoryStore map[string]ethereum.TCheckpoint
+
+func (s tMemoryStore) GetCheckpoint(key string) (ethereum.TCheckpoint, bool) {
+	checkpoint, ok := s[key]
+	return checkpoint, ok
+}
+
+func (s tMemoryStore) SetCheckpoint(key string, checkpoint ethereum.TCheckpoint) {
+	s[key] = checkpoint
+}
+
+// withoutConfirmations makes the indexer read the simulated chain up to its head
+func withoutConfirmations(t *testing.T) {
+	previous, hasPrevious := ethereum.CONFIRMATIONS_FOR_CHAINID[TEST_CHAIN_ID]
+	ethereum.CONFIRMATIONS_FOR_CHAINID[TEST_CHAIN_ID] = 0
+	t.Cleanup(func() {
+		if hasPrevious {
+			ethereum.CONFIRMATIONS_FOR_CHAINID[TEST_CHAIN_ID] = previous
+		} else {
+			delete(ethereum.CONFIRMATIONS_FOR_CHAINID, TEST_CHAIN_ID)
+		}
+	})
+}
+
+// indexPairs returns the pairs created by the factory since the checkpoint of the store
+func indexPairs(t *testing.T, factory common.Address, store ethereum.TCheckpointStore) []contracts.UniV2FactoryPairCreated {
+	t.Helper()
+	pairs := []contracts.UniV2FactoryPairCreated{}
+	err := ethereum.IndexEvents(context.Background(), ethereum.TIndexer{
+		ChainID:  TEST_CHAIN_ID,
+		Contract: factory,
+		ABI:      uniV2FactoryABI,
+		Event:    `PairCreated`,
+		Store:    store,
+	}, func(event *contracts.UniV2FactoryPairCreated, log types.Log) error {
+		event.Raw = log
+		pairs = append(pairs, *event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pairs
+}
+
+func TestIndexEventsResumesAfterItsCheckpoint(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	withoutConfirmations(t)
+	factory, err := backend.DeployFactory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token0 := common.HexToAddress(`0x0000000000000000000000000000000000000a00`)
+	token1 := common.HexToAddress(`0x0000000000000000000000000000000000000b00`)
+	pairAt := func(index uint64) common.Address {
+		return common.BigToAddress(new(big.Int).SetUint64(0xc00 + index))
+	}
+	blocks := []uint64{}
+	for index := uint64(0); index < 3; index++ {
+		block, err := backend.CreateUniV2Pair(factory, token0, token1, pairAt(index), index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	store := tMemoryStore{}
+	pairs := indexPairs(t, factory, store)
+	if len(pairs) != 3 {
+		t.Fatalf(`got %d pairs, want 3`, len(pairs))
+	}
+	for index, pair := range pairs {
+		if pair.Token0 != token0 || pair.Token1 != token1 || pair.Pair != pairAt(uint64(index)) || pair.Raw.BlockNumber != blocks[index] {
+			t.Errorf(`pair %d: got %s %s %s at block %d`, index, pair.Token0.Hex(), pair.Token1.Hex(), pair.Pair.Hex(), pair.Raw.BlockNumber)
+		}
+	}
+	head, _ := backend.BlockNumber(context.Background())
+	key := ethereum.TIndexer{ChainID: TEST_CHAIN_ID, Contract: factory, Event: `PairCreated`}.Key()
+	if checkpoint := store[key]; checkpoint.Block != head || checkpoint.Hash == (common.Hash{}) {
+		t.Errorf(`got the checkpoint %d %s, want the head %d`, checkpoint.Block, checkpoint.Hash.Hex(), head)
+	}
+
+	// The next run only reads the pairs created since
+	if _, err := backend.CreateUniV2Pair(factory, token0, token1, pairAt(3), 3); err != nil {
+		t.Fatal(err)
+	}
+	pairs = indexPairs(t, factory, store)
+	if len(pairs) != 1 || pairs[0].Pair != pairAt(3) {
+		t.Fatalf(`got %d pairs after the checkpoint, want the last one`, len(pairs))
+	}
+}
+
+func TestIndexEventsGoesBackOnAReorg(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	withoutConfirmations(t)
+	factory, err := backend.DeployFactory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token0 := common.HexToAddress(`0x0000000000000000000000000000000000000a00`)
+	token1 := common.HexToAddress(`0x0000000000000000000000000000000000000b00`)
+	block, err := backend.CreateUniV2Pair(factory, token0, token1, common.HexToAddress(`0x0c00`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A checkpoint on the block of the pair, with a hash the chain does not know
+	key := ethereum.TIndexer{ChainID: TEST_CHAIN_ID, Contract: factory, Event: `PairCreated`}.Key()
+	store := tMemoryStore{key: {Block: block, Hash: common.HexToHash(`0x01`)}}
+	if pairs := indexPairs(t, factory, store); len(pairs) != 1 {
+		t.Fatalf(`got %d pairs after the reorg, want the pair read again`, len(pairs))
+	}
+}
+
+func TestIndexEventsReadsUniV3Pools(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	withoutConfirmations(t)
+	factory, err := backend.DeployFactory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token0 := common.HexToAddress(`0x0000000000000000000000000000000000000a00`)
+	token1 := common.HexToAddress(`0x0000000000000000000000000000000000000b00`)
+	pool := common.HexToAddress(`0x0000000000000000000000000000000000000c00`)
+	if _, err := backend.CreateUniV3Pool(factory, token0, token1, pool, 3000, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	pools := []contracts.UniV3FactoryPoolCreated{}
+	err = ethereum.IndexEvents(context.Background(), ethereum.TIndexer{
+		ChainID:  TEST_CHAIN_ID,
+		Contract: factory,
+		ABI:      uniV3FactoryABI,
+		Event:    `PoolCreated`,
+	}, func(event *contracts.UniV3FactoryPoolCreated, log types.Log) error {
+		pools = append(pools, *event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 1 {
+		t.Fatalf(`got %d pools, want 1`, len(pools))
+	}
+	if pools[0].Pool != pool || pools[0].Fee.Uint64() != 3000 || pools[0].TickSpacing.Int64() != 60 {
+		t.Errorf(`got the pool %s with the fee %s and the tick spacing %s`, pools[0].Pool.Hex(), pools[0].Fee, pools[0].TickSpacing)
+	}
+}
@@ -4,76 +4,92 @@ import (
 	"context"
 	"math"
 	"math/big"
-	"os"
+	"net/http"
 	"strconv"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/joho/godotenv"
 	"github.com/migratooor/tokenLists/generators/common/chains"
 	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/stats"
 )
 
-// RPC contains the ethclient.Client for a specific chainID
-var RPC = map[uint64]*ethclient.Client{}
+/**************************************************************************************************
+** TRPCClient is the part of a node client used by the generators: the contract bindings, the
+** multicall and the log scans. It is implemented by ethclient.Client and by the simulated backend
+** of the simulated package, so the code relying on it can run without a network.
+**************************************************************************************************/
+type TRPCClient interface {
+	bind.ContractBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// RPC contains the client for a specific chainID
+var RPC = map[uint64]TRPCClient{}
 
 // MulticallClientForChainID holds the multicall client for a specific chainID
 var MulticallClientForChainID = make(map[uint64]TEthMultiCaller)
 
-// RPC_ENDPOINTS contains the node endpoints to connect the blockchains
-var RPC_ENDPOINTS = map[uint64]string{}
+// RPC_POOLS contains the endpoints pool of each chain, see TRPCPool
+var RPC_POOLS = map[uint64]*TRPCPool{}
 
-func Init() {
+/**************************************************************************************************
+** Init builds, for every supported chain, the pool of endpoints listed in RPC_URI_FOR_<chainID>
+** followed by the default RPC of the chain, and the multicall client using it. The health checks
+** of the pools run until ctx is done.
+**************************************************************************************************/
+func Init(ctx context.Context) {
 	godotenv.Load(`.env`)
 
-	// Load the RPC_ENDPOINTS from the env variables
-	for _, chainID := range chains.SUPPORTED_CHAIN_IDS {
-		RPC_ENDPOINTS[chainID] = useEnv(`RPC_URI_FOR_`+strconv.FormatUint(chainID, 10), RPC_ENDPOINTS[chainID])
-	}
-
-	// Init the RPC clients
 	for _, chainID := range chains.SUPPORTED_CHAIN_IDS {
-		client, err := ethclient.Dial(GetRPCURI(chainID))
-		if err != nil {
-			os.Setenv(`RPC_URI_FOR_`+strconv.FormatUint(chainID, 10), chains.CHAINS[chainID].RpcURI)
-			RPC_ENDPOINTS[chainID] = useEnv(`RPC_URI_FOR_`+strconv.FormatUint(chainID, 10), RPC_ENDPOINTS[chainID])
-			client, err = ethclient.Dial(RPC_ENDPOINTS[chainID])
-			if err != nil {
-				logs.Error(err)
-				continue
-			}
+		pool := NewRPCPool(chainID, chains.CHAINS[chainID].RpcURI)
+		if pool == nil {
+			logs.Error(`No RPC available for chain ` + strconv.FormatUint(chainID, 10))
+			continue
 		}
-		RPC[chainID] = client
+		RPC_POOLS[chainID] = pool
+		SetRPC(chainID, pool, chains.CHAINS[chainID].MulticallContract.Address)
 	}
 
-	// Create the multicall client for all the chains supported by yDaemon
-	for _, chainID := range chains.SUPPORTED_CHAIN_IDS {
-		MulticallClientForChainID[chainID] = NewMulticall(
-			GetRPCURI(chainID),
-			chains.CHAINS[chainID].MulticallContract.Address,
-		)
+	wg := sync.WaitGroup{}
+	for _, pool := range RPC_POOLS {
+		wg.Add(1)
+		go func(pool *TRPCPool) {
+			defer wg.Done()
+			pool.StartHealthChecks(ctx)
+		}(pool)
 	}
+	wg.Wait()
 }
 
-// useEnv returns the value of the environment variable envName or fallback if it is not set
-func useEnv(envName string, fallback string) string {
-	envValue := os.Getenv(envName)
-	if envValue == "" {
-		os.Setenv(envName, fallback)
-		return fallback
+// dialRPC connects to a node. Every request sent through the returned client is counted against
+// the counters of the context of the call.
+func dialRPC(rpcURI string) (*ethclient.Client, error) {
+	httpClient := &http.Client{Transport: stats.CountingTransport{Kind: stats.CallRPC}}
+	client, err := rpc.DialOptions(context.Background(), rpcURI, rpc.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
 	}
-	return os.Getenv(envName)
+	return ethclient.NewClient(client), nil
 }
 
 // GetRPC returns the current connection for a specific chain
-func GetRPC(chainID uint64) *ethclient.Client {
+func GetRPC(chainID uint64) TRPCClient {
 	return RPC[chainID]
 }
 
-// GetRPCURI returns the URI to use to connect to the node for a specific chainID
-func GetRPCURI(chainID uint64) string {
-	return RPC_ENDPOINTS[chainID]
+// SetRPC replaces the client and the multicall client of a chain, for instance with a simulated one.
+// The lookups already made on the chain are forgotten, see FetchBasicInformations.
+func SetRPC(chainID uint64, client TRPCClient, multicallAddress common.Address) {
+	RPC[chainID] = client
+	MulticallClientForChainID[chainID] = NewMulticallWithClient(client, multicallAddress)
+	resetCoalescer(chainID)
 }
 
 func randomSigner() *bind.TransactOpts {
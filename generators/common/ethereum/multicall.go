@@ -2,7 +2,6 @@ package ethereum
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"math/big"
 	"strconv"
@@ -14,62 +13,31 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
 	"github.com/migratooor/tokenLists/generators/common/contracts"
 	"github.com/migratooor/tokenLists/generators/common/logs"
 )
 
 const SHOULD_LOG_WARNINGS = true
 
-type Call struct {
-	Name     string         `json:"name"`
-	Method   string         `json:"method"`
-	Version  string         `json:"version"`
-	Abi      *abi.ABI       `json:"abi"`
-	Target   common.Address `json:"target"`
-	CallData []byte         `json:"call_data"`
-}
-
-type CallResponse struct {
-	Success    bool   `json:"success"`
-	ReturnData []byte `json:"returnData"`
-}
+var multicallABI, _ = contracts.Multicall3MetaData.GetAbi()
 
 type TEthMultiCaller struct {
 	Signer          *bind.TransactOpts
-	Client          *ethclient.Client
+	Client          TRPCClient
 	Abi             *abi.ABI
 	ContractAddress common.Address
 }
 
-func (call Call) GetMultiCall() contracts.Multicall3Call {
-	return contracts.Multicall3Call{
-		Target:   call.Target,
-		CallData: call.CallData,
-	}
-}
-
-// NewMulticall creates a new instance of a TEthMultiCaller. This is the instance we
+// NewMulticallWithClient creates a new instance of a TEthMultiCaller. This is the instance we
 // will later use to perform multiple ethereum calls batched in the same transaction.
 // For performance reason, this should be initialized once and then reused.
-func NewMulticall(rpcURI string, multicallAddress common.Address) TEthMultiCaller {
-	if rpcURI == "" {
-		logs.Error("No rpcURI provided.")
-		return TEthMultiCaller{}
-	}
-	client, err := ethclient.Dial(rpcURI)
-	if err != nil {
-		logs.Error(err)
-		time.Sleep(time.Second)
-		return NewMulticall(rpcURI, multicallAddress)
-	}
-
+func NewMulticallWithClient(client TRPCClient, multicallAddress common.Address) TEthMultiCaller {
 	// Load Multicall abi for later use
 	mcAbi, err := contracts.Multicall3MetaData.GetAbi()
 	if err != nil {
 		logs.Error(err)
-		time.Sleep(time.Second)
-		return NewMulticall(rpcURI, multicallAddress)
+		return TEthMultiCaller{}
 	}
 
 	return TEthMultiCaller{
@@ -81,6 +49,7 @@ func NewMulticall(rpcURI string, multicallAddress common.Address) TEthMultiCalle
 }
 
 func (caller *TEthMultiCaller) execute(
+	ctx context.Context,
 	multiCallGroup []contracts.Multicall3Call,
 	blockNumber *big.Int,
 ) ([]byte, error) {
@@ -92,7 +61,7 @@ func (caller *TEthMultiCaller) execute(
 
 	// Perform multicall
 	resp, err := caller.Client.CallContract(
-		context.Background(),
+		ctx,
 		ethereum.CallMsg{
 			To:   &caller.ContractAddress,
 			Data: callData,
@@ -109,44 +78,33 @@ func (caller *TEthMultiCaller) execute(
 
 // ExecuteByBatch will take a group of calls, split them in fixed-size group to
 // avoid the gasLimit error, and execute as many transactions as required to get
-// the results
+// the results, in the order of the calls. It fails if any group could not be
+// executed, see TMulticall for the decoding of the results.
 func (caller *TEthMultiCaller) ExecuteByBatch(
-	calls []Call,
+	ctx context.Context,
+	calls []contracts.Multicall3Call,
 	batchSize uint64,
 	blockNumber *big.Int,
-) map[string][]interface{} {
+) ([]contracts.Multicall3Result, error) {
 	if caller.Client == nil {
-		logs.Error("No client provided.")
-		return nil
+		return nil, errors.New("No client provided.")
 	}
 	var initialBatchSize = batchSize
-	var responses []CallResponse
-	// Create mapping for results. Be aware that we sometimes get two empty results initially, unsure why
-	results := make(map[string][]interface{})
-
-	// Add calls to multicall structure for the contract
-	var multiCalls = make([]contracts.Multicall3Call, 0, len(calls))
-	var rawCalls = make([]Call, 0, len(calls))
-	for _, call := range calls {
-		multiCalls = append(multiCalls, call.GetMultiCall())
-		rawCalls = append(rawCalls, call)
-	}
+	var responses = make([]contracts.Multicall3Result, 0, len(calls))
+
+	for i := uint64(0); i < uint64(len(calls)); {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.New(`Multicall aborted: ` + err.Error())
+		}
 
-	for i := uint64(0); i < uint64(len(multiCalls)); {
 		var group []contracts.Multicall3Call
-		var rawCallsGroup []Call
-		if i > uint64(len(multiCalls)) {
-			break
-		} else if (i + batchSize) > uint64(len(multiCalls)) {
-			group = multiCalls[i:]
-			rawCallsGroup = rawCalls[i:]
+		if (i + batchSize) > uint64(len(calls)) {
+			group = calls[i:]
 		} else {
-			group = multiCalls[i : i+batchSize]
-			rawCallsGroup = rawCalls[i : i+batchSize]
+			group = calls[i : i+batchSize]
 		}
-		_ = rawCallsGroup
 
-		tempPackedResp, err := caller.execute(group, blockNumber)
+		tempPackedResp, err := caller.execute(ctx, group, blockNumber)
 		if err != nil {
 			LIMIT_ERROR := strings.Contains(strings.ToLower(err.Error()), "call retuned result on length") && strings.Contains(strings.ToLower(err.Error()), "exceeding limit")
 			SIZE_ERROR := strings.Contains(strings.ToLower(err.Error()), "request entity too large")
@@ -183,10 +141,7 @@ func (caller *TEthMultiCaller) ExecuteByBatch(
 					chainIDStr = strconv.Itoa(int(chainID.Int64()))
 				}
 				if batchSize <= 1 {
-					if SHOULD_LOG_WARNINGS {
-						logs.Error(`Multicall failed on chain ` + chainIDStr + `! See error: ` + err.Error())
-					}
-					return nil
+					return nil, errors.New(`Multicall failed on chain ` + chainIDStr + `! See error: ` + err.Error())
 				}
 				if isAssumingOutOfGas && SHOULD_LOG_WARNINGS {
 					logs.Error(`Multicall failed on chain ` + chainIDStr + `! See error: ` + err.Error())
@@ -195,8 +150,11 @@ func (caller *TEthMultiCaller) ExecuteByBatch(
 				continue
 			} else {
 				logs.Error(err)
-				//sleep a few ms and retry
-				time.Sleep(2000 * time.Millisecond)
+				//sleep a few ms and retry, unless the run is being cancelled
+				select {
+				case <-ctx.Done():
+				case <-time.After(2000 * time.Millisecond):
+				}
 				if SHOULD_LOG_WARNINGS {
 					logs.Warning(`Retrying with initial batch size of ` + strconv.FormatUint(initialBatchSize, 10))
 				}
@@ -205,45 +163,68 @@ func (caller *TEthMultiCaller) ExecuteByBatch(
 		}
 
 		// Unpack results
-		unpackedResp, err := caller.Abi.Unpack("tryAggregate", tempPackedResp)
-		if err != nil {
-			logs.Error(err)
-			continue
+		if len(tempPackedResp) == 0 {
+			return nil, errors.New(`no multicall at ` + caller.ContractAddress.Hex())
 		}
-
-		a, err := json.Marshal(unpackedResp[0])
+		unpackedResp, err := caller.Abi.Unpack("tryAggregate", tempPackedResp)
 		if err != nil {
-			logs.Error(err)
-			continue
+			return nil, err
 		}
-
-		// Unpack results
-		var tempResp []CallResponse
-		if err := json.Unmarshal(a, &tempResp); err != nil {
-			logs.Error(err)
-			continue
+		tempResp := *abi.ConvertType(unpackedResp[0], new([]contracts.Multicall3Result)).(*[]contracts.Multicall3Result)
+		if len(tempResp) != len(group) {
+			return nil, errors.New(`the multicall answered ` + strconv.Itoa(len(tempResp)) + ` results for ` + strconv.Itoa(len(group)) + ` calls`)
 		}
 
 		responses = append(responses, tempResp...)
-		i += batchSize
+		i += uint64(len(group))
 		if batchSize != initialBatchSize {
 			batchSize = initialBatchSize
 		}
 	}
+	return responses, nil
+}
 
-	for i, response := range responses {
-		if response.ReturnData != nil {
-			unpacked, err := calls[i].Abi.Unpack(calls[i].Method, response.ReturnData)
-			if err != nil {
-				// logs.Warning("Failed to unpack method " + calls[i].Method + " for " + calls[i].Name + " : " + err.Error())
-				results[calls[i].Name+calls[i].Method] = nil
-			} else {
-				results[calls[i].Name+calls[i].Method] = unpacked
-			}
-		} else {
-			results[calls[i].Name+calls[i].Method] = nil
+/**************************************************************************************************
+** tryAggregate runs the calls through the tryAggregate method of the Multicall3 at multicall, at
+** the block, and returns the result of each of them. The overrides, if any, need a client
+** implementing TStateClient.
+**************************************************************************************************/
+func tryAggregate(
+	ctx context.Context,
+	client TRPCClient,
+	multicall common.Address,
+	calls []contracts.Multicall3Call,
+	blockNumber *big.Int,
+	overrides map[common.Address]gethclient.OverrideAccount,
+) ([]contracts.Multicall3Result, error) {
+	callData, err := multicallABI.Pack(`tryAggregate`, false, calls)
+	if err != nil {
+		return nil, err
+	}
+	msg := ethereum.CallMsg{To: &multicall, Data: callData}
+	var response []byte
+	if len(overrides) > 0 {
+		stateClient, ok := client.(TStateClient)
+		if !ok {
+			return nil, errors.New(`the client cannot override the state`)
 		}
+		response, err = stateClient.CallContractWithOverrides(ctx, msg, blockNumber, overrides)
+	} else {
+		response, err = client.CallContract(ctx, msg, blockNumber)
 	}
-
-	return results
+	if err != nil {
+		return nil, err
+	}
+	if len(response) == 0 {
+		return nil, errors.New(`no multicall at ` + multicall.Hex())
+	}
+	unpacked, err := multicallABI.Unpack(`tryAggregate`, response)
+	if err != nil {
+		return nil, err
+	}
+	results := *abi.ConvertType(unpacked[0], new([]contracts.Multicall3Result)).(*[]contracts.Multicall3Result)
+	if len(results) != len(calls) {
+		return nil, errors.New(`the multicall answered ` + strconv.Itoa(len(results)) + ` results for ` + strconv.Itoa(len(calls)) + ` calls`)
+	}
+	return results, nil
 }
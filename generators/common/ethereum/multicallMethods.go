@@ -1,113 +1,24 @@
 package ethereum
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/logs"
 )
 
-/**************************************************************************************************
-** The multicall require a specific format for the call data. The following functions are helpers
-** used to build them for some specific methods.
-**************************************************************************************************/
-
+// The ABIs of the ERC20 tokens, with the string or the bytes32 name and symbol
 var ERC20ABI, _ = contracts.ERC20MetaData.GetAbi()
 var ERC20ALTABI, _ = contracts.Erc20AltMetaData.GetAbi()
 
-func getName(name string, contractAddress common.Address) Call {
-	parsedData, err := ERC20ABI.Pack("name")
-	if err != nil {
-		return Call{
-			Target:   contractAddress,
-			Abi:      ERC20ABI,
-			Method:   `name`,
-			CallData: nil,
-			Name:     name,
-		}
-	}
-	return Call{
-		Target:   contractAddress,
-		Abi:      ERC20ABI,
-		Method:   `name`,
-		CallData: parsedData,
-		Name:     name,
-	}
-}
-func getBytes32Name(name string, contractAddress common.Address) Call {
-	parsedData, err := ERC20ALTABI.Pack("name")
-	if err != nil {
-		return Call{
-			Target:   contractAddress,
-			Abi:      ERC20ALTABI,
-			Method:   `name`,
-			CallData: nil,
-			Name:     name,
-		}
-	}
-	return Call{
-		Target:   contractAddress,
-		Abi:      ERC20ALTABI,
-		Method:   `name`,
-		CallData: parsedData,
-		Name:     name,
-	}
-}
-func getSymbol(name string, contractAddress common.Address) Call {
-	parsedData, err := ERC20ABI.Pack("symbol")
-	if err != nil {
-		return Call{
-			Target:   contractAddress,
-			Abi:      ERC20ABI,
-			Method:   `symbol`,
-			CallData: nil,
-			Name:     name,
-		}
-	}
-	return Call{
-		Target:   contractAddress,
-		Abi:      ERC20ABI,
-		Method:   `symbol`,
-		CallData: parsedData,
-		Name:     name,
-	}
-}
-func getBytes32Symbol(name string, contractAddress common.Address) Call {
-	parsedData, err := ERC20ALTABI.Pack("symbol")
-	if err != nil {
-		return Call{
-			Target:   contractAddress,
-			Abi:      ERC20ALTABI,
-			Method:   `symbol`,
-			CallData: nil,
-			Name:     name,
-		}
-	}
-	return Call{
-		Target:   contractAddress,
-		Abi:      ERC20ALTABI,
-		Method:   `symbol`,
-		CallData: parsedData,
-		Name:     name,
-	}
-}
-
-func getDecimals(name string, contractAddress common.Address) Call {
-	parsedData, err := ERC20ABI.Pack("decimals")
-	if err != nil {
-		return Call{
-			Target:   contractAddress,
-			Abi:      ERC20ABI,
-			Method:   `decimals`,
-			CallData: nil,
-			Name:     name,
-		}
-	}
-	return Call{
-		Target:   contractAddress,
-		Abi:      ERC20ABI,
-		Method:   `decimals`,
-		CallData: parsedData,
-		Name:     name,
-	}
+// TERC20 is the basic information of a token: its name, its symbol and its decimals
+type TERC20 struct {
+	Address  common.Address
+	Name     string
+	Symbol   string
+	ChainID  uint64
+	Decimals uint64
 }
 
 /**************************************************************************************************
@@ -117,77 +28,70 @@ func getDecimals(name string, contractAddress common.Address) Call {
 ** Arguments:
 ** - chainID: the chain ID of the network we are working on
 ** - tokens: a list of addresses of the tokens we want to fetch the information for
+** - batchSize: the number of calls sent in each multicall, see multicallTuning
 **
 ** Returns:
-** - a list of TERC20Token containing the basic information for the tokens
+** - a list of TERC20Token containing the basic information for the tokens, or an error when the
+**   multicall failed. A token with an empty field was checked and did not answer.
 **************************************************************************************************/
-type TERC20 struct {
-	Address  common.Address
-	Name     string
-	Symbol   string
-	ChainID  uint64
-	Decimals uint64
-}
-
-func FetchBasicInformations(chainID uint64, tokens []common.Address) map[string]*TERC20 {
+func fetchBasicInformations(ctx context.Context, chainID uint64, tokens []common.Address, batchSize uint64) (map[string]*TERC20, error) {
 	/**********************************************************************************************
 	** The first step is to prepare the multicall, connecting to the multicall instance and
-	** preparing the array of calls to send. All calls for all tokens will be send in a single
-	** multicall and will later be accessible via a concatened string `tokenAddress + methodName`.
+	** preparing the calls to send. All calls for all tokens will be send in a single multicall,
+	** and each call returns the slot its decoded result will be accessible in.
 	**********************************************************************************************/
+	type tBasicCalls struct {
+		name          *TCallResult[string]
+		bytes32Name   *TCallResult[[32]byte]
+		symbol        *TCallResult[string]
+		bytes32Symbol *TCallResult[[32]byte]
+		decimals      *TCallResult[uint64]
+	}
 	caller := MulticallClientForChainID[chainID]
-	calls := []Call{}
+	multicall := &TMulticall{}
+	calls := make(map[common.Address]tBasicCalls)
 	for _, token := range tokens {
-		calls = append(calls, getName(token.String(), token))
-		calls = append(calls, getBytes32Name(token.String()+`name_bytes_32`, token))
-		calls = append(calls, getSymbol(token.String(), token))
-		calls = append(calls, getBytes32Symbol(token.String()+`symbol_bytes_32`, token))
-		calls = append(calls, getDecimals(token.String(), token))
-	}
-
-	/**********************************************************************************************
-	** Regular fix for some RPC, which limit the number of calls in a multicall to a very low
-	** number.
-	**********************************************************************************************/
-	maxBatch := uint64(420)
-	if chainID == 250 || chainID == 56 || chainID == 137 {
-		maxBatch = 420
+		calls[token] = tBasicCalls{
+			name:          AddCall[string](multicall, token, ERC20ABI, `name`),
+			bytes32Name:   AddCall[[32]byte](multicall, token, ERC20ALTABI, `name`),
+			symbol:        AddCall[string](multicall, token, ERC20ABI, `symbol`),
+			bytes32Symbol: AddCall[[32]byte](multicall, token, ERC20ALTABI, `symbol`),
+			decimals:      AddCall[uint64](multicall, token, ERC20ABI, `decimals`),
+		}
 	}
 
 	/**********************************************************************************************
 	** Then we can proceed the responses.
 	**********************************************************************************************/
 	tokenList := make(map[string]*TERC20)
-	response := caller.ExecuteByBatch(calls, maxBatch, nil)
+	if err := multicall.Execute(ctx, caller, batchSize, GetPinnedBlock(chainID)); err != nil {
+		return nil, err
+	}
 	for _, token := range tokens {
-		rawName := response[token.String()+`name`]
-		rawBytes32Name := response[token.String()+`name_bytes_32`+`name`]
-		rawSymbol := response[token.String()+`symbol`]
-		rawBytes32Symbol := response[token.String()+`symbol_bytes_32`+`symbol`]
-		rawDecimals := response[token.String()+`decimals`]
-
+		call := calls[token]
+		bytes32Symbol := DecodeBytes32(call.bytes32Symbol.OrElse([32]byte{}), ``)
 		newToken := &TERC20{
 			Address:  token,
-			Name:     DecodeString(rawName, DecodeHex(rawBytes32Name, DecodeHex(rawBytes32Symbol, ``))),
-			Symbol:   DecodeString(rawSymbol, DecodeHex(rawBytes32Symbol, ``)),
-			Decimals: DecodeUint64(rawDecimals, 0),
+			Name:     call.name.OrElse(DecodeBytes32(call.bytes32Name.OrElse([32]byte{}), bytes32Symbol)),
+			Symbol:   call.symbol.OrElse(bytes32Symbol),
+			Decimals: call.decimals.OrElse(0),
 		}
 		tokenList[token.Hex()] = newToken
 	}
 
-	return tokenList
+	return tokenList, nil
 }
 
-func FetchNames(chainID uint64, tokens []common.Address) map[string]string {
+func FetchNames(ctx context.Context, chainID uint64, tokens []common.Address) map[string]string {
 	/**********************************************************************************************
 	** The first step is to prepare the multicall, connecting to the multicall instance and
-	** preparing the array of calls to send. All calls for all tokens will be send in a single
-	** multicall and will later be accessible via a concatened string `tokenAddress + methodName`.
+	** preparing the calls to send, each returning the slot of its decoded result.
 	**********************************************************************************************/
 	caller := MulticallClientForChainID[chainID]
-	calls := []Call{}
+	multicall := &TMulticall{}
+	names := make(map[common.Address]*TCallResult[string])
 	for _, token := range tokens {
-		calls = append(calls, getName(token.String(), token))
+		names[token] = AddCall[string](multicall, token, ERC20ABI, `name`)
 	}
 
 	/**********************************************************************************************
@@ -203,25 +107,26 @@ func FetchNames(chainID uint64, tokens []common.Address) map[string]string {
 	** Then we can proceed the responses.
 	**********************************************************************************************/
 	nameList := make(map[string]string)
-	response := caller.ExecuteByBatch(calls, maxBatch, nil)
+	if err := multicall.Execute(ctx, caller, maxBatch, GetPinnedBlock(chainID)); err != nil {
+		logs.Error(err)
+	}
 	for _, token := range tokens {
-		rawName := response[token.String()+`name`]
-		nameList[token.Hex()] = DecodeString(rawName, ``)
+		nameList[token.Hex()] = names[token].OrElse(``)
 	}
 
 	return nameList
 }
 
-func FetchDecimals(chainID uint64, tokens []common.Address) map[string]uint64 {
+func FetchDecimals(ctx context.Context, chainID uint64, tokens []common.Address) map[string]uint64 {
 	/**********************************************************************************************
 	** The first step is to prepare the multicall, connecting to the multicall instance and
-	** preparing the array of calls to send. All calls for all tokens will be send in a single
-	** multicall and will later be accessible via a concatened string `tokenAddress + methodName`.
+	** preparing the calls to send, each returning the slot of its decoded result.
 	**********************************************************************************************/
 	caller := MulticallClientForChainID[chainID]
-	calls := []Call{}
+	multicall := &TMulticall{}
+	decimals := make(map[common.Address]*TCallResult[uint64])
 	for _, token := range tokens {
-		calls = append(calls, getDecimals(token.String(), token))
+		decimals[token] = AddCall[uint64](multicall, token, ERC20ABI, `decimals`)
 	}
 
 	/**********************************************************************************************
@@ -237,10 +142,11 @@ func FetchDecimals(chainID uint64, tokens []common.Address) map[string]uint64 {
 	** Then we can proceed the responses.
 	**********************************************************************************************/
 	decimalsList := make(map[string]uint64)
-	response := caller.ExecuteByBatch(calls, maxBatch, nil)
+	if err := multicall.Execute(ctx, caller, maxBatch, GetPinnedBlock(chainID)); err != nil {
+		logs.Error(err)
+	}
 	for _, token := range tokens {
-		rawDecimals := response[token.String()+`decimals`]
-		decimalsList[token.Hex()] = DecodeUint64(rawDecimals, 0)
+		decimalsList[token.Hex()] = decimals[token].OrElse(0)
 	}
 
 	return decimalsList
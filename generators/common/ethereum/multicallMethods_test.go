@@ -0,0 +1,134 @@
+package ethereum_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// TEST_CHAIN_ID is the chain the simulated backends of the tests are installed on
+const TEST_CHAIN_ID = uint64(1)
+
+// newSimulatedChain returns a new simulated chain, with its Multicall3, installed on TEST_CHAIN_ID
+func newSimulatedChain(t *testing.T) (*simulated.TBackend, common.Address) {
+	t.Helper()
+	backend, err := simulated.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	multicall, err := backend.DeployMulticall3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Install(TEST_CHAIN_ID, multicall)
+	return backend, multicall
+}
+
+func TestFetchBasicInformations(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	deploy := func(address common.Address, err error) common.Address {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+	stringToken := deploy(backend.DeployERC20(`Wrapped Ether`, `WETH`))
+	bytes32Token := deploy(backend.DeployBytes32ERC20(`Maker`, `MKR`, 18))
+	withoutDecimals := deploy(backend.DeployERC20WithoutDecimals(`No Decimals`, `NODEC`))
+	reverting := deploy(backend.DeployRevertingERC20())
+
+	tokens := ethereum.FetchBasicInformations(context.Background(), TEST_CHAIN_ID, []common.Address{
+		stringToken, bytes32Token, withoutDecimals, reverting,
+	})
+	for address, expected := range map[common.Address]ethereum.TERC20{
+		stringToken:     {Name: `Wrapped Ether`, Symbol: `WETH`, Decimals: 18},
+		bytes32Token:    {Name: `Maker`, Symbol: `MKR`, Decimals: 18},
+		withoutDecimals: {Name: `No Decimals`, Symbol: `NODEC`},
+		reverting:       {},
+	} {
+		token, ok := tokens[address.Hex()]
+		if !ok {
+			t.Errorf(`%s is missing from the result`, address.Hex())
+			continue
+		}
+		if token.Address != address || token.Name != expected.Name || token.Symbol != expected.Symbol || token.Decimals != expected.Decimals {
+			t.Errorf(`%s: got %q %q %d, want %q %q %d`, address.Hex(), token.Name, token.Symbol, token.Decimals, expected.Name, expected.Symbol, expected.Decimals)
+		}
+	}
+}
+
+func TestExecuteByBatchHalvesTheRejectedBatches(t *testing.T) {
+	backend, multicall := newSimulatedChain(t)
+	token, err := backend.DeployERC20(`Wrapped Ether`, `WETH`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbol := ethereum.ERC20ABI.Methods[`symbol`].ID
+	calls := []contracts.Multicall3Call{}
+	for i := 0; i < 16; i++ {
+		calls = append(calls, contracts.Multicall3Call{Target: token, CallData: symbol})
+	}
+
+	// Each call takes 160 bytes of calldata: the batches of 16 and 8 calls are rejected
+	client := &simulated.TLimitedClient{TRPCClient: backend, MaxCallDataSize: 1000}
+	caller := ethereum.NewMulticallWithClient(client, multicall)
+	results, err := caller.ExecuteByBatch(context.Background(), calls, 16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf(`got %d results for %d calls`, len(results), len(calls))
+	}
+	for i, result := range results {
+		values, err := ethereum.ERC20ABI.Unpack(`symbol`, result.ReturnData)
+		if !result.Success || err != nil || values[0] != `WETH` {
+			t.Errorf(`result %d: got %v %x`, i, result.Success, result.ReturnData)
+		}
+	}
+	// The batches of 16 and 8 are rejected before each of the first 3 batches of 4, then the last 4
+	// calls fit in the batch of 16 restored after each success
+	if calls := client.Calls.Load(); calls != 10 {
+		t.Errorf(`got %d calls to the client, want 10`, calls)
+	}
+}
+
+func TestExecuteByBatchFailsWhenASingleCallIsRejected(t *testing.T) {
+	backend, multicall := newSimulatedChain(t)
+	client := &simulated.TLimitedClient{TRPCClient: backend, MaxCallDataSize: 10}
+	caller := ethereum.NewMulticallWithClient(client, multicall)
+	calls := []contracts.Multicall3Call{{Target: multicall, CallData: ethereum.ERC20ABI.Methods[`symbol`].ID}}
+	if _, err := caller.ExecuteByBatch(context.Background(), calls, 4, nil); err == nil {
+		t.Fatal(`expected an error`)
+	}
+}
+
+func TestTypedMulticall(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	token, err := backend.DeployERC20WithoutDecimals(`No Decimals`, `NODEC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	multicall := &ethereum.TMulticall{}
+	symbol := ethereum.AddCall[string](multicall, token, ethereum.ERC20ABI, `symbol`)
+	decimals := ethereum.AddCall[uint64](multicall, token, ethereum.ERC20ABI, `decimals`)
+	wrongType := ethereum.AddCall[bool](multicall, token, ethereum.ERC20ABI, `name`)
+	if err := multicall.Execute(context.Background(), ethereum.MulticallClientForChainID[TEST_CHAIN_ID], 10, nil); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, _ := symbol.Get(); !ok || value != `NODEC` {
+		t.Errorf(`symbol: got %q %v`, value, ok)
+	}
+	if decimals.Ok || decimals.Err == nil || decimals.OrElse(6) != 6 {
+		t.Errorf(`decimals: got %v %v`, decimals.Ok, decimals.Err)
+	}
+	if wrongType.Ok || wrongType.Err == nil {
+		t.Errorf(`name as a bool: got %v %v`, wrongType.Ok, wrongType.Err)
+	}
+}
@@ -0,0 +1,195 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+)
+
+/**************************************************************************************************
+** TCallResult is the slot holding the result of a call added to a TMulticall with AddCall, filled
+** by TMulticall.Execute. Ok is only true when the call succeeded and its result was decoded as T.
+** Otherwise Err tells why, and RevertReason holds the reason given by the contract if it reverted
+** with one.
+**************************************************************************************************/
+type TCallResult[T any] struct {
+	Value        T
+	Ok           bool
+	RevertReason string
+	Err          error
+}
+
+// Get returns the value of the call, whether it was decoded and the revert reason of the call
+func (r *TCallResult[T]) Get() (T, bool, string) {
+	return r.Value, r.Ok, r.RevertReason
+}
+
+// OrElse returns the value of the call, or the fallback if the call failed
+func (r *TCallResult[T]) OrElse(fallback T) T {
+	if !r.Ok {
+		return fallback
+	}
+	return r.Value
+}
+
+// tPendingCall is a call of a TMulticall, with the function filling its slot
+type tPendingCall struct {
+	call    contracts.Multicall3Call
+	resolve func(result contracts.Multicall3Result, err error)
+}
+
+// TMulticall is a set of calls executed together through the multicall, see AddCall
+type TMulticall struct {
+	calls []tPendingCall
+}
+
+/**************************************************************************************************
+** AddCall adds to the multicall a call of the method of the contract at target, and returns the
+** slot its result will be decoded in, as a T. The method must return at least one value, and the
+** first one is decoded: a T of the ABI type is taken as is, an integer is converted to any integer
+** type it fits in, and any other mismatch fails the call instead of panicking.
+**
+** The slot of a call whose arguments cannot be packed is failed right away, and the call is not
+** sent.
+**************************************************************************************************/
+func AddCall[T any](
+	multicall *TMulticall,
+	target common.Address,
+	contractABI *abi.ABI,
+	method string,
+	args ...interface{},
+) *TCallResult[T] {
+	slot := &TCallResult[T]{}
+	callData, err := contractABI.Pack(method, args...)
+	if err != nil {
+		slot.Err = err
+		return slot
+	}
+	multicall.calls = append(multicall.calls, tPendingCall{
+		call: contracts.Multicall3Call{Target: target, CallData: callData},
+		resolve: func(result contracts.Multicall3Result, err error) {
+			slot.resolve(contractABI, method, result, err)
+		},
+	})
+	return slot
+}
+
+// Len returns the number of calls of the multicall
+func (m *TMulticall) Len() int {
+	return len(m.calls)
+}
+
+/**************************************************************************************************
+** Execute sends the calls of the multicall with the caller, by batches of batchSize calls, and
+** fills their slots. When it fails, the error is returned and set in the slots.
+**************************************************************************************************/
+func (m *TMulticall) Execute(ctx context.Context, caller TEthMultiCaller, batchSize uint64, blockNumber *big.Int) error {
+	if len(m.calls) == 0 {
+		return nil
+	}
+	calls := make([]contracts.Multicall3Call, 0, len(m.calls))
+	for _, pending := range m.calls {
+		calls = append(calls, pending.call)
+	}
+	results, err := caller.ExecuteByBatch(ctx, calls, batchSize, blockNumber)
+	if err != nil {
+		for _, pending := range m.calls {
+			pending.resolve(contracts.Multicall3Result{}, err)
+		}
+		return err
+	}
+	for i, pending := range m.calls {
+		pending.resolve(results[i], nil)
+	}
+	return nil
+}
+
+// resolve decodes the result of the call of the method in the slot, unless the multicall failed
+func (r *TCallResult[T]) resolve(contractABI *abi.ABI, method string, result contracts.Multicall3Result, err error) {
+	if err != nil {
+		r.Err = err
+		return
+	}
+	if !result.Success {
+		if reason, err := abi.UnpackRevert(result.ReturnData); err == nil {
+			r.RevertReason = reason
+			r.Err = errors.New(method + ` reverted: ` + reason)
+		} else {
+			r.Err = errors.New(method + ` reverted`)
+		}
+		return
+	}
+	values, err := contractABI.Unpack(method, result.ReturnData)
+	if err != nil {
+		r.Err = errors.New(`failed to unpack ` + method + `: ` + err.Error())
+		return
+	}
+	if len(values) == 0 {
+		r.Err = errors.New(method + ` returned nothing`)
+		return
+	}
+	value, err := decodeValue[T](values[0])
+	if err != nil {
+		r.Err = errors.New(`failed to decode ` + method + `: ` + err.Error())
+		return
+	}
+	r.Value, r.Ok = value, true
+}
+
+// asBigInt returns the value as a big.Int, if it is an integer
+func asBigInt(value reflect.Value) (*big.Int, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(value.Uint()), true
+	}
+	if bigValue, ok := value.Interface().(*big.Int); ok && bigValue != nil {
+		return bigValue, true
+	}
+	return nil, false
+}
+
+/**************************************************************************************************
+** decodeValue returns the value unpacked from a call as a T. The integers, including the *big.Int
+** of the large ABI integers, are converted to the integer type of T as long as they fit in it.
+**************************************************************************************************/
+func decodeValue[T any](value interface{}) (T, error) {
+	var decoded T
+	if asT, ok := value.(T); ok {
+		return asT, nil
+	}
+	target := reflect.ValueOf(&decoded).Elem()
+	if value == nil {
+		return decoded, errors.New(`nil is not a ` + target.Type().String())
+	}
+	mismatch := errors.New(reflect.TypeOf(value).String() + ` is not a ` + target.Type().String())
+	integer, ok := asBigInt(reflect.ValueOf(value))
+	if !ok {
+		return decoded, mismatch
+	}
+
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !integer.IsInt64() || target.OverflowInt(integer.Int64()) {
+			return decoded, errors.New(integer.String() + ` overflows ` + target.Type().String())
+		}
+		target.SetInt(integer.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !integer.IsUint64() || target.OverflowUint(integer.Uint64()) {
+			return decoded, errors.New(integer.String() + ` overflows ` + target.Type().String())
+		}
+		target.SetUint(integer.Uint64())
+	default:
+		if _, isBigInt := target.Interface().(*big.Int); !isBigInt {
+			return decoded, mismatch
+		}
+		target.Set(reflect.ValueOf(new(big.Int).Set(integer)))
+	}
+	return decoded, nil
+}
@@ -0,0 +1,500 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// TRPCPoolSettings holds the settings of the circuit breakers and of the health checks
+type TRPCPoolSettings struct {
+	FailureThreshold    int           // Consecutive failures opening the circuit of an endpoint
+	BaseCooldown        time.Duration // First cooldown of an open circuit, doubled each time it opens again
+	MaxCooldown         time.Duration // Maximum cooldown of an open circuit
+	HealthCheckInterval time.Duration // Delay between two probes of the endpoints
+	HealthCheckTimeout  time.Duration // Deadline of a probe
+	MaxBlockLag         uint64        // Blocks an endpoint can be behind the others before it is skipped
+}
+
+// RPC_POOL_SETTINGS are the settings shared by the pools of all the chains
+var RPC_POOL_SETTINGS = TRPCPoolSettings{
+	FailureThreshold:    3,
+	BaseCooldown:        30 * time.Second,
+	MaxCooldown:         10 * time.Minute,
+	HealthCheckInterval: time.Minute,
+	HealthCheckTimeout:  10 * time.Second,
+	MaxBlockLag:         50,
+}
+
+/**************************************************************************************************
+** TRPCEndpoint is a node of a pool. Its circuit is open, and the endpoint skipped, until
+** openUntil: it opens after FailureThreshold failures in a row, or right away when the node rate
+** limits us. Once the cooldown is over, the next call is a trial: a success closes the circuit,
+** a failure opens it again for twice as long.
+**************************************************************************************************/
+type TRPCEndpoint struct {
+	URI       string
+	Weight    float64
+	IsDefault bool // The default RPC of the chain, tried after the other available endpoints
+	client    *ethclient.Client
+
+	mutex     sync.Mutex
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	latency   time.Duration // Moving average of the latency of the probes, 0 until the first one
+	head      uint64        // Last block number returned by a probe
+}
+
+// TRPCEndpointStatus is a snapshot of the state of an endpoint. The URI is stripped of its path.
+type TRPCEndpointStatus struct {
+	URI       string    `json:"uri"`
+	Weight    float64   `json:"weight"`
+	Open      bool      `json:"open"`
+	Failures  int       `json:"failures"`
+	LatencyMs int64     `json:"latencyMs"`
+	Head      uint64    `json:"head"`
+	OpenUntil time.Time `json:"openUntil"`
+}
+
+func (e *TRPCEndpoint) isOpen(now time.Time) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return now.Before(e.openUntil)
+}
+
+func (e *TRPCEndpoint) recordSuccess() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.failures = 0
+	e.cooldown = 0
+	e.openUntil = time.Time{}
+}
+
+// recordFailure counts a failure and opens the circuit if needed. It returns true if it did.
+func (e *TRPCEndpoint) recordFailure(rateLimited bool) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.failures++
+	if !rateLimited && e.failures < RPC_POOL_SETTINGS.FailureThreshold {
+		return false
+	}
+	if e.cooldown == 0 {
+		e.cooldown = RPC_POOL_SETTINGS.BaseCooldown
+	} else {
+		e.cooldown *= 2
+	}
+	if e.cooldown > RPC_POOL_SETTINGS.MaxCooldown {
+		e.cooldown = RPC_POOL_SETTINGS.MaxCooldown
+	}
+	e.openUntil = time.Now().Add(e.cooldown)
+	return true
+}
+
+func (e *TRPCEndpoint) recordProbe(latency time.Duration, head uint64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = (e.latency*7 + latency*3) / 10
+	}
+	e.head = head
+}
+
+// score is the share of the calls sent to the endpoint: its weight divided by its latency
+func (e *TRPCEndpoint) score() float64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	latency := e.latency
+	if latency <= 0 {
+		latency = time.Second
+	}
+	return e.Weight / latency.Seconds()
+}
+
+func (e *TRPCEndpoint) status() TRPCEndpointStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return TRPCEndpointStatus{
+		URI:       redactURI(e.URI),
+		Weight:    e.Weight,
+		Open:      time.Now().Before(e.openUntil),
+		Failures:  e.failures,
+		LatencyMs: e.latency.Milliseconds(),
+		Head:      e.head,
+		OpenUntil: e.openUntil,
+	}
+}
+
+/**************************************************************************************************
+** TRPCPool implements TRPCClient over several endpoints of the same chain. Each call goes to an
+** endpoint picked at random according to its score, among the ones whose circuit is closed and
+** which are not lagging behind, then fails over to the next ones if the endpoint is down or rate
+** limited. The default RPC of the chain comes after the other available endpoints, whatever its
+** score. The errors coming from the chain itself, like a reverted call, are returned as is.
+** If every circuit is open, the endpoints are still tried, the closest to the end of its cooldown
+** first, so a pool never refuses a call.
+**************************************************************************************************/
+type TRPCPool struct {
+	chainID   uint64
+	Endpoints []*TRPCEndpoint
+}
+
+/**************************************************************************************************
+** parseEndpoints reads a list of endpoints from a comma separated string, where each endpoint can
+** have a weight after a pipe: `https://a.com|3,https://b.com`. The weight defaults to 1.
+**************************************************************************************************/
+func parseEndpoints(value string) []*TRPCEndpoint {
+	endpoints := []*TRPCEndpoint{}
+	for _, item := range strings.Split(value, `,`) {
+		item = strings.TrimSpace(item)
+		if item == `` {
+			continue
+		}
+		endpoint := &TRPCEndpoint{URI: item, Weight: 1}
+		if index := strings.LastIndex(item, `|`); index > 0 {
+			if weight, err := strconv.ParseFloat(item[index+1:], 64); err == nil && weight > 0 {
+				endpoint.URI = item[:index]
+				endpoint.Weight = weight
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+/**************************************************************************************************
+** NewRPCPool builds the pool of a chain from the RPC_URI_FOR_<chainID> env variable, followed by
+** the default RPC of the chain if it is not already listed. The endpoints which cannot be dialed
+** are left out. Nil is returned if there is none left.
+**************************************************************************************************/
+func NewRPCPool(chainID uint64, defaultURI string) *TRPCPool {
+	endpoints := parseEndpoints(os.Getenv(`RPC_URI_FOR_` + strconv.FormatUint(chainID, 10)))
+	if defaultURI != `` {
+		isListed := false
+		for _, endpoint := range endpoints {
+			isListed = isListed || endpoint.URI == defaultURI
+		}
+		if !isListed {
+			endpoints = append(endpoints, &TRPCEndpoint{URI: defaultURI, Weight: 1, IsDefault: true})
+		}
+	}
+
+	pool := &TRPCPool{chainID: chainID}
+	for _, endpoint := range endpoints {
+		client, err := dialRPC(endpoint.URI)
+		if err != nil {
+			logs.Error(`Could not dial an RPC for chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			continue
+		}
+		endpoint.client = client
+		pool.Endpoints = append(pool.Endpoints, endpoint)
+	}
+	if len(pool.Endpoints) == 0 {
+		return nil
+	}
+	return pool
+}
+
+// Status returns a snapshot of the state of the endpoints of the pool
+func (p *TRPCPool) Status() []TRPCEndpointStatus {
+	statuses := []TRPCEndpointStatus{}
+	for _, endpoint := range p.Endpoints {
+		statuses = append(statuses, endpoint.status())
+	}
+	return statuses
+}
+
+// maxHead returns the highest block number seen by the probes of the pool
+func (p *TRPCPool) maxHead() uint64 {
+	head := uint64(0)
+	for _, endpoint := range p.Endpoints {
+		endpoint.mutex.Lock()
+		if endpoint.head > head {
+			head = endpoint.head
+		}
+		endpoint.mutex.Unlock()
+	}
+	return head
+}
+
+// order returns the endpoints in the order they should be tried for the next call
+func (p *TRPCPool) order() []*TRPCEndpoint {
+	now := time.Now()
+	maxHead := p.maxHead()
+	available := []*TRPCEndpoint{}
+	defaults := []*TRPCEndpoint{}
+	fallbacks := []*TRPCEndpoint{}
+	for _, endpoint := range p.Endpoints {
+		endpoint.mutex.Lock()
+		isLagging := endpoint.head > 0 && endpoint.head+RPC_POOL_SETTINGS.MaxBlockLag < maxHead
+		endpoint.mutex.Unlock()
+		if endpoint.isOpen(now) || isLagging {
+			fallbacks = append(fallbacks, endpoint)
+		} else if endpoint.IsDefault {
+			defaults = append(defaults, endpoint)
+		} else {
+			available = append(available, endpoint)
+		}
+	}
+
+	// Weighted shuffle of the available endpoints: the best scores come first more often
+	ordered := []*TRPCEndpoint{}
+	for len(available) > 0 {
+		total := 0.0
+		for _, endpoint := range available {
+			total += endpoint.score()
+		}
+		pick := rand.Float64() * total
+		index := 0
+		for index < len(available)-1 {
+			pick -= available[index].score()
+			if pick < 0 {
+				break
+			}
+			index++
+		}
+		ordered = append(ordered, available[index])
+		available = append(available[:index], available[index+1:]...)
+	}
+
+	sort.SliceStable(fallbacks, func(i, j int) bool {
+		fallbacks[i].mutex.Lock()
+		openUntilI := fallbacks[i].openUntil
+		fallbacks[i].mutex.Unlock()
+		fallbacks[j].mutex.Lock()
+		openUntilJ := fallbacks[j].openUntil
+		fallbacks[j].mutex.Unlock()
+		return openUntilI.Before(openUntilJ)
+	})
+	return append(append(ordered, defaults...), fallbacks...)
+}
+
+/**************************************************************************************************
+** isEndpointError returns true if the error comes from the endpoint rather than from the call:
+** the network errors, the 429 and 5xx HTTP statuses and the rate limit errors of the nodes. The
+** second value is true for the rate limits, which open the circuit right away. A log request over
+** the range limit of the node is not an endpoint error, the indexer handles it.
+**************************************************************************************************/
+func isEndpointError(err error) (bool, bool) {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || isRangeLimitError(err) {
+		return false, false
+	}
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, `rate limit`) || strings.Contains(message, `too many requests`) {
+		return true, true
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == 429 {
+			return true, true
+		}
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 401 || httpErr.StatusCode == 403, false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == -32005, rpcErr.ErrorCode() == -32005 // Limit exceeded
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) {
+		return true, false
+	}
+	return strings.Contains(message, `eof`) || strings.Contains(message, `connection reset`), false
+}
+
+// call runs fn on the endpoints of the pool, failing over until one of them answers
+func call[T any](ctx context.Context, p *TRPCPool, fn func(client *ethclient.Client) (T, error)) (T, error) {
+	var result T
+	var lastErr error
+	for _, endpoint := range p.order() {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result, lastErr = fn(endpoint.client)
+		isEndpointErr, isRateLimited := isEndpointError(lastErr)
+		if !isEndpointErr {
+			if lastErr == nil {
+				endpoint.recordSuccess()
+			}
+			return result, lastErr
+		}
+		if endpoint.recordFailure(isRateLimited) {
+			logs.Warning(`RPC ` + redactURI(endpoint.URI) + ` for chain ` + strconv.FormatUint(p.chainID, 10) + ` is paused: ` + lastErr.Error())
+		}
+	}
+	return result, lastErr
+}
+
+// redactURI hides the path and the query of an RPC URI, which often contain an API key
+func redactURI(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil && parsed.Host != `` {
+		return parsed.Scheme + `://` + parsed.Host
+	}
+	return `endpoint`
+}
+
+/**************************************************************************************************
+** StartHealthChecks probes every endpoint of the pool now, then every HealthCheckInterval until
+** ctx is done. A probe asks for the block number: its latency feeds the score of the endpoint,
+** the block number tells if it is lagging behind, and a failure counts against its circuit.
+**************************************************************************************************/
+func (p *TRPCPool) StartHealthChecks(ctx context.Context) {
+	p.probe(ctx)
+	ticker := time.NewTicker(RPC_POOL_SETTINGS.HealthCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.probe(ctx)
+			}
+		}
+	}()
+}
+
+func (p *TRPCPool) probe(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, endpoint := range p.Endpoints {
+		wg.Add(1)
+		go func(endpoint *TRPCEndpoint) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, RPC_POOL_SETTINGS.HealthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			head, err := endpoint.client.BlockNumber(probeCtx)
+			if err != nil {
+				if ctx.Err() == nil {
+					_, isRateLimited := isEndpointError(err)
+					endpoint.recordFailure(isRateLimited)
+				}
+				return
+			}
+			endpoint.recordProbe(time.Since(start), head)
+			endpoint.recordSuccess()
+		}(endpoint)
+	}
+	wg.Wait()
+}
+
+// ChainID implements TRPCClient. The chainID of the pool is returned without any call.
+func (p *TRPCPool) ChainID(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(p.chainID), nil
+}
+
+// BlockNumber implements TRPCClient
+func (p *TRPCPool) BlockNumber(ctx context.Context) (uint64, error) {
+	return call(ctx, p, func(client *ethclient.Client) (uint64, error) {
+		return client.BlockNumber(ctx)
+	})
+}
+
+// CodeAt implements bind.ContractCaller
+func (p *TRPCPool) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]byte, error) {
+		return client.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+// CallContract implements bind.ContractCaller
+func (p *TRPCPool) CallContract(ctx context.Context, msg goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]byte, error) {
+		return client.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+// CallContractWithOverrides implements TStateClient
+func (p *TRPCPool) CallContractWithOverrides(ctx context.Context, msg goEthereum.CallMsg, blockNumber *big.Int, overrides map[common.Address]gethclient.OverrideAccount) ([]byte, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]byte, error) {
+		if len(overrides) == 0 {
+			return client.CallContract(ctx, msg, blockNumber)
+		}
+		return gethclient.New(client.Client()).CallContract(ctx, msg, blockNumber, &overrides)
+	})
+}
+
+// HeaderByNumber implements bind.ContractTransactor
+func (p *TRPCPool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+// PendingCodeAt implements bind.ContractTransactor
+func (p *TRPCPool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]byte, error) {
+		return client.PendingCodeAt(ctx, account)
+	})
+}
+
+// PendingNonceAt implements bind.ContractTransactor
+func (p *TRPCPool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return call(ctx, p, func(client *ethclient.Client) (uint64, error) {
+		return client.PendingNonceAt(ctx, account)
+	})
+}
+
+// SuggestGasPrice implements bind.ContractTransactor
+func (p *TRPCPool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasPrice(ctx)
+	})
+}
+
+// SuggestGasTipCap implements bind.ContractTransactor
+func (p *TRPCPool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasTipCap(ctx)
+	})
+}
+
+// EstimateGas implements bind.ContractTransactor
+func (p *TRPCPool) EstimateGas(ctx context.Context, msg goEthereum.CallMsg) (uint64, error) {
+	return call(ctx, p, func(client *ethclient.Client) (uint64, error) {
+		return client.EstimateGas(ctx, msg)
+	})
+}
+
+// SendTransaction implements bind.ContractTransactor
+func (p *TRPCPool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := call(ctx, p, func(client *ethclient.Client) (struct{}, error) {
+		return struct{}{}, client.SendTransaction(ctx, tx)
+	})
+	return err
+}
+
+// FilterLogs implements bind.ContractFilterer
+func (p *TRPCPool) FilterLogs(ctx context.Context, query goEthereum.FilterQuery) ([]types.Log, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]types.Log, error) {
+		return client.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs implements bind.ContractFilterer. A subscription is bound to the endpoint it
+// was opened on: it fails over only when it cannot be opened.
+func (p *TRPCPool) SubscribeFilterLogs(ctx context.Context, query goEthereum.FilterQuery, ch chan<- types.Log) (goEthereum.Subscription, error) {
+	return call(ctx, p, func(client *ethclient.Client) (goEthereum.Subscription, error) {
+		return client.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
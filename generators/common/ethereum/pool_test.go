@@ -0,0 +1,167 @@
+package ethereum_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// withPoolSettings replaces the settings of the pools for the duration of a test
+func withPoolSettings(t *testing.T, settings ethereum.TRPCPoolSettings) {
+	previousSettings := ethereum.RPC_POOL_SETTINGS
+	ethereum.RPC_POOL_SETTINGS = settings
+	t.Cleanup(func() { ethereum.RPC_POOL_SETTINGS = previousSettings })
+}
+
+/**************************************************************************************************
+** newTestPool serves the simulated chain on the given number of servers, and builds the pool of
+** the test chain over them. The first ones are listed in RPC_URI_FOR_<chainID>, the last one is
+** the default RPC when withDefault is true.
+**************************************************************************************************/
+func newTestPool(t *testing.T, backend *simulated.TBackend, count int, withDefault bool) (*ethereum.TRPCPool, []*simulated.TRPCServer) {
+	t.Helper()
+	servers := []*simulated.TRPCServer{}
+	uris := []string{}
+	for i := 0; i < count; i++ {
+		server, err := backend.NewRPCServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(server.Close)
+		servers = append(servers, server)
+		uris = append(uris, server.URL)
+	}
+	defaultURI := ``
+	if withDefault {
+		defaultURI, uris = uris[len(uris)-1], uris[:len(uris)-1]
+	}
+	t.Setenv(`RPC_URI_FOR_`+strconv.FormatUint(TEST_CHAIN_ID, 10), strings.Join(uris, `,`))
+	pool := ethereum.NewRPCPool(TEST_CHAIN_ID, defaultURI)
+	if pool == nil || len(pool.Endpoints) != count {
+		t.Fatalf(`got the pool %+v`, pool)
+	}
+	return pool, servers
+}
+
+// callPool asks the pool for the block number, and fails the test if no endpoint answers
+func callPool(t *testing.T, pool *ethereum.TRPCPool, times int) {
+	t.Helper()
+	for i := 0; i < times; i++ {
+		if _, err := pool.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRPCPoolTriesTheDefaultRPCLast(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	pool, servers := newTestPool(t, backend, 3, true)
+	if !pool.Endpoints[2].IsDefault || pool.Endpoints[0].IsDefault {
+		t.Fatalf(`the default RPC is not the last endpoint`)
+	}
+
+	callPool(t, pool, 20)
+	if calls := servers[2].Calls.Load(); calls != 0 {
+		t.Errorf(`the default RPC got %d calls while the other endpoints were up`, calls)
+	}
+
+	servers[0].Down.Store(true)
+	servers[1].Down.Store(true)
+	callPool(t, pool, 1)
+	if calls := servers[2].Calls.Load(); calls != 1 {
+		t.Errorf(`the default RPC got %d calls once the other endpoints were down, want 1`, calls)
+	}
+}
+
+func TestRPCPoolFailsOverAndOpensTheCircuit(t *testing.T) {
+	withPoolSettings(t, ethereum.TRPCPoolSettings{FailureThreshold: 3, BaseCooldown: time.Minute, MaxCooldown: time.Hour, MaxBlockLag: 50})
+	backend, _ := newSimulatedChain(t)
+	pool, servers := newTestPool(t, backend, 2, false)
+	servers[0].Down.Store(true)
+
+	// The calls reaching the endpoint down fail over to the other one, until its circuit opens
+	for servers[0].Calls.Load() < 3 {
+		callPool(t, pool, 1)
+	}
+	status := pool.Status()[0]
+	if !status.Open || status.Failures != 3 || time.Until(status.OpenUntil) < 59*time.Second {
+		t.Fatalf(`got the status %+v after 3 failures`, status)
+	}
+	callPool(t, pool, 20)
+	if calls := servers[0].Calls.Load(); calls != 3 {
+		t.Errorf(`the endpoint with an open circuit got %d calls, want 3`, calls)
+	}
+	if status := pool.Status()[1]; status.Open || status.Failures != 0 {
+		t.Errorf(`got the status %+v for the endpoint up`, status)
+	}
+
+	// A rate limit opens the circuit right away
+	servers[1].RateLimited.Store(true)
+	if _, err := pool.BlockNumber(context.Background()); err == nil {
+		t.Error(`got no error from a pool whose endpoints are down and rate limited`)
+	}
+	if status := pool.Status()[1]; !status.Open || status.Failures != 1 {
+		t.Errorf(`got the status %+v for the rate limited endpoint`, status)
+	}
+}
+
+func TestRPCPoolDoublesTheCooldown(t *testing.T) {
+	withPoolSettings(t, ethereum.TRPCPoolSettings{FailureThreshold: 1, BaseCooldown: 100 * time.Millisecond, MaxCooldown: 300 * time.Millisecond, MaxBlockLag: 50})
+	backend, _ := newSimulatedChain(t)
+	pool, servers := newTestPool(t, backend, 1, false)
+	servers[0].Down.Store(true)
+
+	// Every call is a trial of the only endpoint, failing once the previous cooldown is over
+	for _, cooldown := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond} {
+		start := time.Now()
+		if _, err := pool.BlockNumber(context.Background()); err == nil {
+			t.Fatal(`got no error from an endpoint down`)
+		}
+		status := pool.Status()[0]
+		if openFor := status.OpenUntil.Sub(start); !status.Open || openFor < cooldown || openFor > cooldown+50*time.Millisecond {
+			t.Fatalf(`the circuit opened for %s, want %s`, openFor, cooldown)
+		}
+		time.Sleep(time.Until(status.OpenUntil))
+	}
+
+	servers[0].Down.Store(false)
+	callPool(t, pool, 1)
+	if status := pool.Status()[0]; status.Open || status.Failures != 0 {
+		t.Errorf(`got the status %+v after a successful trial`, status)
+	}
+}
+
+func TestRPCPoolSkipsTheLaggingEndpoints(t *testing.T) {
+	withPoolSettings(t, ethereum.TRPCPoolSettings{FailureThreshold: 3, BaseCooldown: time.Minute, MaxCooldown: time.Hour, HealthCheckInterval: time.Hour, HealthCheckTimeout: time.Second, MaxBlockLag: 10})
+	backend, _ := newSimulatedChain(t)
+	for i := 0; i < 30; i++ {
+		backend.Commit()
+	}
+	pool, servers := newTestPool(t, backend, 2, false)
+	servers[0].Lag.Store(20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.StartHealthChecks(ctx)
+	head, _ := backend.BlockNumber(ctx)
+	if statuses := pool.Status(); statuses[0].Head != head-20 || statuses[1].Head != head {
+		t.Fatalf(`got the heads %d and %d, want %d and %d`, statuses[0].Head, statuses[1].Head, head-20, head)
+	}
+	probes := servers[0].Calls.Load()
+	callPool(t, pool, 20)
+	if calls := servers[0].Calls.Load() - probes; calls != 0 {
+		t.Errorf(`the lagging endpoint got %d calls`, calls)
+	}
+
+	// Once the lagging endpoint is the only one left, it is still called
+	servers[1].Down.Store(true)
+	callPool(t, pool, 1)
+	if calls := servers[0].Calls.Load() - probes; calls != 1 {
+		t.Errorf(`the lagging endpoint got %d calls once the other one was down, want 1`, calls)
+	}
+}
@@ -0,0 +1,421 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+/**************************************************************************************************
+** TStateClient is implemented by the clients able to run a call with state overrides, like
+** TRPCPool and the simulated backend. ProbeTokens and FetchProxies need it: the chains whose client
+** does not implement it are not probed.
+**************************************************************************************************/
+type TStateClient interface {
+	CallContractWithOverrides(ctx context.Context, msg goEthereum.CallMsg, blockNumber *big.Int, overrides map[common.Address]gethclient.OverrideAccount) ([]byte, error)
+}
+
+// TProbeSettings holds the settings of the behaviour probe of the tokens
+type TProbeSettings struct {
+	BatchSize    int      // Tokens probed by each call
+	Balance      *big.Int // Balance written for the multicall, which then transfers half of it
+	RebaseBlocks uint64   // Blocks between the two reads of the balance, within the state kept by full nodes
+}
+
+// PROBE_SETTINGS are the settings shared by the probes of all the chains
+var PROBE_SETTINGS = TProbeSettings{
+	BatchSize:    100,
+	Balance:      new(big.Int).Exp(big.NewInt(10), big.NewInt(27), nil),
+	RebaseBlocks: 100,
+}
+
+/**************************************************************************************************
+** BALANCE_SLOTS are the storage slots of the balances mapping tried by the probe, each with the
+** Solidity and the Vyper layouts: the first slots, used by most tokens, the slot of the upgradeable
+** ERC20 of OpenZeppelin, after the gaps of its parents, and the ERC-7201 namespace of its ERC20 v5.
+**************************************************************************************************/
+var BALANCE_SLOTS = []common.Hash{
+	common.BigToHash(big.NewInt(0)),
+	common.BigToHash(big.NewInt(1)),
+	common.BigToHash(big.NewInt(2)),
+	common.BigToHash(big.NewInt(3)),
+	common.BigToHash(big.NewInt(4)),
+	common.BigToHash(big.NewInt(5)),
+	common.BigToHash(big.NewInt(6)),
+	common.BigToHash(big.NewInt(7)),
+	common.BigToHash(big.NewInt(8)),
+	common.BigToHash(big.NewInt(9)),
+	common.BigToHash(big.NewInt(51)),
+	common.HexToHash(`0x52c63247e1f47db19d5ce0460030c497f067ca4cebf71ba98eeadabe20bace00`),
+}
+
+// BLACKLIST_GETTERS are the getters of the tokens able to block an address: USDC, USDT, and the
+// Paxos tokens
+var BLACKLIST_GETTERS = []string{
+	`isBlacklisted(address)`,
+	`isBlackListed(address)`,
+	`isFrozen(address)`,
+}
+
+// PROBE_RECIPIENT is the address receiving the simulated transfers, holding nothing on any chain
+var PROBE_RECIPIENT = common.BytesToAddress(crypto.Keccak256([]byte(`tokenLists.probe.recipient`)))
+
+/**************************************************************************************************
+** TTokenBehaviour is what the probe found out about a token. Simulated is set when the balances
+** of the token were found and a transfer simulated: FeeOnTransfer and Rebasing are only known
+** for these tokens, while the other fields are always checked.
+**************************************************************************************************/
+type TTokenBehaviour struct {
+	Simulated     bool `json:"simulated"`
+	FeeOnTransfer bool `json:"feeOnTransfer,omitempty"`
+	Rebasing      bool `json:"rebasing,omitempty"`
+	Pausable      bool `json:"pausable,omitempty"`
+	Blacklist     bool `json:"blacklist,omitempty"`
+	Proxy         bool `json:"proxy,omitempty"`
+}
+
+// Tags returns the tags of models.TAGS matching the behaviour, or nil
+func (b TTokenBehaviour) Tags() []string {
+	tags := []string{}
+	for tag, matches := range map[string]bool{
+		models.TagFeeOnTransfer: b.FeeOnTransfer,
+		models.TagRebasing:      b.Rebasing,
+		models.TagPausable:      b.Pausable,
+		models.TagBlacklist:     b.Blacklist,
+		models.TagProxy:         b.Proxy,
+	} {
+		if matches {
+			tags = append(tags, tag)
+		}
+	}
+	return models.SortTags(tags)
+}
+
+// tProbedToken is the state of a token during a probe
+type tProbedToken struct {
+	address    common.Address
+	behaviour  TTokenBehaviour
+	balance    *big.Int     // Balance of the multicall, without overrides
+	slot       *common.Hash // Slot of the balance of the multicall, once found
+	overridden *big.Int     // Balance of the multicall once the slot is overridden
+}
+
+// tProber runs the probe of the tokens of a chain, at the block head
+type tProber struct {
+	client    TRPCClient
+	multicall common.Address
+	head      *big.Int
+}
+
+// encodeCall returns the calldata of a call to signature, with arguments of 32 bytes each
+func encodeCall(signature string, args ...interface{}) []byte {
+	callData := crypto.Keccak256([]byte(signature))[:4]
+	for _, arg := range args {
+		switch value := arg.(type) {
+		case common.Address:
+			callData = append(callData, common.LeftPadBytes(value.Bytes(), 32)...)
+		case *big.Int:
+			callData = append(callData, common.LeftPadBytes(value.Bytes(), 32)...)
+		}
+	}
+	return callData
+}
+
+// decodeUint returns the uint256 answered by a call
+func decodeUint(result contracts.Multicall3Result) (*big.Int, bool) {
+	if !result.Success || len(result.ReturnData) != 32 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(result.ReturnData), true
+}
+
+// decodeBool returns true if a call answered a bool, whatever its value
+func decodeBool(result contracts.Multicall3Result) (bool, bool) {
+	value, ok := decodeUint(result)
+	if !ok || value.Cmp(big.NewInt(1)) > 0 {
+		return false, false
+	}
+	return value.Sign() == 1, true
+}
+
+// isTransferred returns true if a transfer succeeded: it did not revert and returned true or
+// nothing, like USDT
+func isTransferred(result contracts.Multicall3Result) bool {
+	if result.Success && len(result.ReturnData) == 0 {
+		return true
+	}
+	value, ok := decodeBool(result)
+	return ok && value
+}
+
+// balanceKey returns the slot of the balance of holder in the mapping at slot, with the Solidity
+// layout or the Vyper one
+func balanceKey(holder common.Address, slot common.Hash, isVyper bool) common.Hash {
+	if isVyper {
+		return crypto.Keccak256Hash(slot.Bytes(), common.LeftPadBytes(holder.Bytes(), 32))
+	}
+	return crypto.Keccak256Hash(common.LeftPadBytes(holder.Bytes(), 32), slot.Bytes())
+}
+
+// overridesFor returns the overrides writing PROBE_SETTINGS.Balance as the balance of the
+// multicall in each of the tokens, at the slot found for it
+func (p tProber) overridesFor(tokens []*tProbedToken) map[common.Address]gethclient.OverrideAccount {
+	overrides := make(map[common.Address]gethclient.OverrideAccount)
+	for _, token := range tokens {
+		overrides[token.address] = gethclient.OverrideAccount{
+			StateDiff: map[common.Hash]common.Hash{*token.slot: common.BigToHash(PROBE_SETTINGS.Balance)},
+		}
+	}
+	return overrides
+}
+
+/**************************************************************************************************
+** readGetters reads, without overrides, the balance of the multicall in each token, and checks
+** whether the tokens answer paused() and one of the BLACKLIST_GETTERS. A token answering one of
+** them can be paused or can block an address, whatever the current value. A token without a
+** balanceOf is not an ERC20 and is left out of the next steps.
+**************************************************************************************************/
+func (p tProber) readGetters(ctx context.Context, tokens []*tProbedToken) error {
+	calls := []contracts.Multicall3Call{}
+	for _, token := range tokens {
+		calls = append(calls,
+			contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`balanceOf(address)`, p.multicall)},
+			contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`paused()`)},
+		)
+		for _, getter := range BLACKLIST_GETTERS {
+			calls = append(calls, contracts.Multicall3Call{Target: token.address, CallData: encodeCall(getter, p.multicall)})
+		}
+	}
+	results, err := tryAggregate(ctx, p.client, p.multicall, calls, p.head, nil)
+	if err != nil {
+		return err
+	}
+
+	stride := 2 + len(BLACKLIST_GETTERS)
+	for i, token := range tokens {
+		tokenResults := results[i*stride : (i+1)*stride]
+		token.balance, _ = decodeUint(tokenResults[0])
+		_, token.behaviour.Pausable = decodeBool(tokenResults[1])
+		for _, result := range tokenResults[2:] {
+			if _, ok := decodeBool(result); ok {
+				token.behaviour.Blacklist = true
+			}
+		}
+	}
+	return nil
+}
+
+/**************************************************************************************************
+** findBalanceSlots looks for the slot holding the balance of the multicall in each token: the
+** candidates of BALANCE_SLOTS are overridden one after the other, for all the tokens at once,
+** until the balance of the multicall changes.
+**************************************************************************************************/
+func (p tProber) findBalanceSlots(ctx context.Context, tokens []*tProbedToken) error {
+	for _, slot := range BALANCE_SLOTS {
+		for _, isVyper := range []bool{false, true} {
+			pending := []*tProbedToken{}
+			for _, token := range tokens {
+				if token.balance != nil && token.slot == nil {
+					pending = append(pending, token)
+				}
+			}
+			if len(pending) == 0 {
+				return nil
+			}
+
+			key := balanceKey(p.multicall, slot, isVyper)
+			calls := []contracts.Multicall3Call{}
+			overrides := make(map[common.Address]gethclient.OverrideAccount)
+			for _, token := range pending {
+				calls = append(calls, contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`balanceOf(address)`, p.multicall)})
+				overrides[token.address] = gethclient.OverrideAccount{
+					StateDiff: map[common.Hash]common.Hash{key: common.BigToHash(PROBE_SETTINGS.Balance)},
+				}
+			}
+			results, err := tryAggregate(ctx, p.client, p.multicall, calls, p.head, overrides)
+			if err != nil {
+				return err
+			}
+			for i, token := range pending {
+				if value, ok := decodeUint(results[i]); ok && value.Cmp(token.balance) != 0 {
+					token.slot = &key
+					token.overridden = value
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/**************************************************************************************************
+** simulateTransfers makes the multicall send half of its overridden balance to PROBE_RECIPIENT,
+** and reads the balance of the recipient before and after: a token delivering less than the
+** amount, beyond a rounding of one billionth, takes a fee. A token whose balanceOf is not the
+** balance written in its storage holds shares of a supply growing or shrinking over time, and
+** rebases.
+**************************************************************************************************/
+func (p tProber) simulateTransfers(ctx context.Context, tokens []*tProbedToken) error {
+	calls := []contracts.Multicall3Call{}
+	amounts := []*big.Int{}
+	for _, token := range tokens {
+		amount := new(big.Int).Div(token.overridden, big.NewInt(2))
+		amounts = append(amounts, amount)
+		calls = append(calls,
+			contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`balanceOf(address)`, PROBE_RECIPIENT)},
+			contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`transfer(address,uint256)`, PROBE_RECIPIENT, amount)},
+			contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`balanceOf(address)`, PROBE_RECIPIENT)},
+		)
+	}
+	results, err := tryAggregate(ctx, p.client, p.multicall, calls, p.head, p.overridesFor(tokens))
+	if err != nil {
+		return err
+	}
+
+	for i, token := range tokens {
+		before, isBeforeKnown := decodeUint(results[3*i])
+		after, isAfterKnown := decodeUint(results[3*i+2])
+		if !isBeforeKnown || !isAfterKnown || !isTransferred(results[3*i+1]) {
+			continue
+		}
+		received := new(big.Int).Sub(after, before)
+		tolerance := new(big.Int).Div(amounts[i], big.NewInt(1_000_000_000))
+		token.behaviour.Simulated = true
+		token.behaviour.FeeOnTransfer = new(big.Int).Add(received, tolerance).Cmp(amounts[i]) < 0
+		token.behaviour.Rebasing = token.overridden.Cmp(PROBE_SETTINGS.Balance) != 0
+	}
+	return nil
+}
+
+/**************************************************************************************************
+** checkBalanceDrift reads the overridden balance of the multicall PROBE_SETTINGS.RebaseBlocks
+** before the head: the balance of a rebasing token drifts, while the one of a standard token
+** stays the balance written. The node may not have the state of this block anymore, in which case
+** the drift is not checked.
+**************************************************************************************************/
+func (p tProber) checkBalanceDrift(ctx context.Context, tokens []*tProbedToken) {
+	if p.head.Sign() == 0 {
+		return
+	}
+	past := new(big.Int).Sub(p.head, new(big.Int).SetUint64(PROBE_SETTINGS.RebaseBlocks))
+	if past.Sign() < 0 {
+		past = big.NewInt(0)
+	}
+
+	calls := []contracts.Multicall3Call{}
+	for _, token := range tokens {
+		calls = append(calls, contracts.Multicall3Call{Target: token.address, CallData: encodeCall(`balanceOf(address)`, p.multicall)})
+	}
+	results, err := tryAggregate(ctx, p.client, p.multicall, calls, past, p.overridesFor(tokens))
+	if err != nil {
+		logs.Warning(`Failed to read the balances at block ` + past.String() + `, the drift is not checked: ` + err.Error())
+		return
+	}
+	for i, token := range tokens {
+		if value, ok := decodeUint(results[i]); ok && token.behaviour.Simulated && value.Cmp(token.overridden) != 0 {
+			token.behaviour.Rebasing = true
+		}
+	}
+}
+
+// probeBatch runs all the steps of the probe on a batch of tokens
+func (p tProber) probeBatch(ctx context.Context, tokens []*tProbedToken) error {
+	if err := p.readGetters(ctx, tokens); err != nil {
+		return err
+	}
+	if err := p.findBalanceSlots(ctx, tokens); err != nil {
+		return err
+	}
+
+	withSlot := []*tProbedToken{}
+	for _, token := range tokens {
+		if token.slot != nil {
+			withSlot = append(withSlot, token)
+		}
+	}
+	if len(withSlot) > 0 {
+		if err := p.simulateTransfers(ctx, withSlot); err != nil {
+			return err
+		}
+		p.checkBalanceDrift(ctx, withSlot)
+	}
+	addresses := []common.Address{}
+	for _, token := range tokens {
+		addresses = append(addresses, token.address)
+	}
+	proxies, err := fetchProxies(ctx, p.client, p.multicall, addresses, p.head)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		token.behaviour.Proxy = proxies[token.address.Hex()].IsProxy()
+	}
+	return nil
+}
+
+/**************************************************************************************************
+** ProbeTokens finds out the non-standard behaviours of the tokens of a chain, at its head, from
+** eth_call with state overrides run through the Multicall3 of the chain:
+** - the balance of the multicall is written in the storage of the token, then the multicall
+**   transfers half of it, to detect the fees on transfer,
+** - the balance written is read back, at the head and some blocks before, to detect the tokens
+**   holding shares whose value changes without any transfer,
+** - the pause and blacklist getters are called, and the slots of the proxies read, see FetchProxies.
+**
+** The tokens of a batch whose calls failed are missing from the result. A token the probe could
+** not simulate a transfer of, because its balances were not found or its transfer reverted, is in
+** the result without Simulated.
+**************************************************************************************************/
+func ProbeTokens(ctx context.Context, chainID uint64, tokens []common.Address) map[string]TTokenBehaviour {
+	behaviours := make(map[string]TTokenBehaviour)
+	client := GetRPC(chainID)
+	if _, ok := client.(TStateClient); !ok {
+		logs.Warning(`The client of chain ` + strconv.FormatUint(chainID, 10) + ` cannot override the state, its tokens are not probed`)
+		return behaviours
+	}
+	head, err := GetHead(ctx, chainID, client)
+	if err != nil {
+		logs.Error(`Failed to read the head of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+		return behaviours
+	}
+	prober := tProber{
+		client:    client,
+		multicall: MulticallClientForChainID[chainID].ContractAddress,
+		head:      new(big.Int).SetUint64(head),
+	}
+
+	probedTokens := []*tProbedToken{}
+	seen := make(map[common.Address]bool)
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			probedTokens = append(probedTokens, &tProbedToken{address: token})
+		}
+	}
+
+	for start := 0; start < len(probedTokens); start += PROBE_SETTINGS.BatchSize {
+		if ctx.Err() != nil {
+			break
+		}
+		end := start + PROBE_SETTINGS.BatchSize
+		if end > len(probedTokens) {
+			end = len(probedTokens)
+		}
+		batch := probedTokens[start:end]
+		if err := prober.probeBatch(ctx, batch); err != nil {
+			logs.Warning(`Failed to probe ` + strconv.Itoa(len(batch)) + ` tokens on chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			continue
+		}
+		for _, token := range batch {
+			behaviours[token.address.Hex()] = token.behaviour
+		}
+	}
+	return behaviours
+}
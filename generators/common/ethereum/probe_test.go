@@ -0,0 +1,49 @@
+package ethereum_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// withRebaseBlocks reads the drift of the balances the given number of blocks before the head, as
+// the simulated chain only has a few blocks
+func withRebaseBlocks(t *testing.T, blocks uint64) {
+	previous := ethereum.PROBE_SETTINGS.RebaseBlocks
+	ethereum.PROBE_SETTINGS.RebaseBlocks = blocks
+	t.Cleanup(func() { ethereum.PROBE_SETTINGS.RebaseBlocks = previous })
+}
+
+func TestProbeTokens(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	withRebaseBlocks(t, 1)
+	deploy := func(token simulated.TNonStandardERC20) common.Address {
+		t.Helper()
+		token.Name, token.Symbol, token.Decimals = `Probed Token`, `PRB`, 18
+		address, err := backend.DeployNonStandardERC20(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+	standard := deploy(simulated.TNonStandardERC20{})
+	fee := deploy(simulated.TNonStandardERC20{FeeBps: 100})
+	rebasing := deploy(simulated.TNonStandardERC20{Rebasing: true})
+	guarded := deploy(simulated.TNonStandardERC20{Pausable: true, Blacklist: true, Proxy: true})
+	deploy(simulated.TNonStandardERC20{}) // Mines a block after the rebasing token, to read its drift
+
+	behaviours := ethereum.ProbeTokens(context.Background(), TEST_CHAIN_ID, []common.Address{standard, fee, rebasing, guarded})
+	for address, want := range map[common.Address]ethereum.TTokenBehaviour{
+		standard: {Simulated: true},
+		fee:      {Simulated: true, FeeOnTransfer: true},
+		rebasing: {Simulated: true, Rebasing: true},
+		guarded:  {Simulated: true, Pausable: true, Blacklist: true, Proxy: true},
+	} {
+		if got, ok := behaviours[address.Hex()]; !ok || got != want {
+			t.Errorf(`%s: got %+v, want %+v`, address.Hex(), got, want)
+		}
+	}
+}
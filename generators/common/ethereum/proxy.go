@@ -0,0 +1,224 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// TProxyKind is the standard followed by a proxy
+type TProxyKind string
+
+const (
+	// ProxyEIP1967 is a proxy keeping its implementation and its admin in the EIP-1967 slots
+	ProxyEIP1967 TProxyKind = "eip1967"
+	// ProxyBeacon is an EIP-1967 proxy reading its implementation from a beacon, owned by its admin
+	ProxyBeacon TProxyKind = "beacon"
+	// ProxyEIP1822 is a UUPS proxy, upgraded through its implementation
+	ProxyEIP1822 TProxyKind = "eip1822"
+	// ProxyZeppelinOS is a proxy of the first OpenZeppelin releases, like USDC
+	ProxyZeppelinOS TProxyKind = "zeppelinos"
+)
+
+// The slots holding the implementation and the admin of the proxies
+var (
+	EIP1967_IMPLEMENTATION_SLOT    = common.HexToHash(`0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc`)
+	EIP1967_ADMIN_SLOT             = common.HexToHash(`0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103`)
+	EIP1967_BEACON_SLOT            = common.HexToHash(`0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50`)
+	EIP1822_PROXIABLE_SLOT         = common.HexToHash(`0xc5f16f0fcc639fa48a6947836d9850f504798523bf8c9a3a87d5876cf622bcf7`)
+	ZEPPELINOS_IMPLEMENTATION_SLOT = crypto.Keccak256Hash([]byte(`org.zeppelinos.proxy.implementation`))
+	ZEPPELINOS_ADMIN_SLOT          = crypto.Keccak256Hash([]byte(`org.zeppelinos.proxy.admin`))
+)
+
+// PROXY_SLOTS are the slots read for each token, in this order
+var PROXY_SLOTS = []common.Hash{
+	EIP1967_IMPLEMENTATION_SLOT,
+	EIP1967_ADMIN_SLOT,
+	EIP1967_BEACON_SLOT,
+	EIP1822_PROXIABLE_SLOT,
+	ZEPPELINOS_IMPLEMENTATION_SLOT,
+	ZEPPELINOS_ADMIN_SLOT,
+}
+
+// PROXIES_BATCH_SIZE is the number of tokens whose slots are read by each call
+var PROXIES_BATCH_SIZE = 200
+
+/**************************************************************************************************
+** SLOT_READER_CODE replaces the code of the tokens while their slots are read, so the multicall
+** can read the storage of many contracts in one call. It returns the value of each slot of its
+** calldata, 32 bytes each:
+**   PUSH1 0
+**   loop: JUMPDEST CALLDATASIZE DUP2 LT ISZERO PUSH1 end JUMPI
+**         DUP1 CALLDATALOAD SLOAD DUP2 MSTORE PUSH1 32 ADD PUSH1 loop JUMP
+**   end:  JUMPDEST CALLDATASIZE PUSH1 0 RETURN
+**************************************************************************************************/
+var SLOT_READER_CODE = common.FromHex(`0x60005b3681101560155780355481526020016002565b366000f3`)
+
+// TProxy is the proxy of a token. Kind is empty when the token is not a proxy.
+type TProxy struct {
+	Kind           TProxyKind
+	Implementation common.Address
+	Admin          common.Address
+}
+
+// IsProxy returns true if the token is a proxy
+func (p TProxy) IsProxy() bool {
+	return p.Kind != ``
+}
+
+// decodeAddress returns the address held by a word, if it is one and is set
+func decodeAddress(word []byte) (common.Address, bool) {
+	value := new(big.Int).SetBytes(word)
+	if value.Sign() == 0 || value.BitLen() > 160 {
+		return common.Address{}, false
+	}
+	return common.BigToAddress(value), true
+}
+
+/**************************************************************************************************
+** readProxySlots reads the PROXY_SLOTS of the tokens through the multicall, with the code of each
+** token replaced by SLOT_READER_CODE, and returns the proxy each token is. The beacons are not
+** resolved yet: their address is the implementation.
+**************************************************************************************************/
+func readProxySlots(
+	ctx context.Context,
+	client TRPCClient,
+	multicall common.Address,
+	tokens []common.Address,
+	blockNumber *big.Int,
+) (map[string]TProxy, error) {
+	callData := []byte{}
+	for _, slot := range PROXY_SLOTS {
+		callData = append(callData, slot.Bytes()...)
+	}
+	calls := []contracts.Multicall3Call{}
+	overrides := make(map[common.Address]gethclient.OverrideAccount)
+	for _, token := range tokens {
+		calls = append(calls, contracts.Multicall3Call{Target: token, CallData: callData})
+		overrides[token] = gethclient.OverrideAccount{Code: SLOT_READER_CODE}
+	}
+	results, err := tryAggregate(ctx, client, multicall, calls, blockNumber, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies := make(map[string]TProxy)
+	for i, token := range tokens {
+		if !results[i].Success || len(results[i].ReturnData) != 32*len(PROXY_SLOTS) {
+			continue
+		}
+		slots := make(map[common.Hash]common.Address)
+		for j, slot := range PROXY_SLOTS {
+			if address, ok := decodeAddress(results[i].ReturnData[32*j : 32*(j+1)]); ok {
+				slots[slot] = address
+			}
+		}
+
+		proxy := TProxy{}
+		switch {
+		case slots[EIP1967_IMPLEMENTATION_SLOT] != (common.Address{}):
+			proxy = TProxy{Kind: ProxyEIP1967, Implementation: slots[EIP1967_IMPLEMENTATION_SLOT], Admin: slots[EIP1967_ADMIN_SLOT]}
+		case slots[EIP1967_BEACON_SLOT] != (common.Address{}):
+			proxy = TProxy{Kind: ProxyBeacon, Implementation: slots[EIP1967_BEACON_SLOT]}
+		case slots[EIP1822_PROXIABLE_SLOT] != (common.Address{}):
+			proxy = TProxy{Kind: ProxyEIP1822, Implementation: slots[EIP1822_PROXIABLE_SLOT]}
+		case slots[ZEPPELINOS_IMPLEMENTATION_SLOT] != (common.Address{}):
+			proxy = TProxy{Kind: ProxyZeppelinOS, Implementation: slots[ZEPPELINOS_IMPLEMENTATION_SLOT], Admin: slots[ZEPPELINOS_ADMIN_SLOT]}
+		}
+		proxies[token.Hex()] = proxy
+	}
+	return proxies, nil
+}
+
+/**************************************************************************************************
+** resolveBeacons replaces the beacon of the beacon proxies by the implementation it points to, and
+** sets their admin to the owner of the beacon, who can upgrade them all. A beacon which does not
+** answer is left as the implementation.
+**************************************************************************************************/
+func resolveBeacons(ctx context.Context, client TRPCClient, multicall common.Address, proxies map[string]TProxy, blockNumber *big.Int) error {
+	keys := []string{}
+	calls := []contracts.Multicall3Call{}
+	for key, proxy := range proxies {
+		if proxy.Kind != ProxyBeacon {
+			continue
+		}
+		keys = append(keys, key)
+		calls = append(calls,
+			contracts.Multicall3Call{Target: proxy.Implementation, CallData: encodeCall(`implementation()`)},
+			contracts.Multicall3Call{Target: proxy.Implementation, CallData: encodeCall(`owner()`)},
+		)
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+	results, err := tryAggregate(ctx, client, multicall, calls, blockNumber, nil)
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		proxy := proxies[key]
+		if results[2*i].Success && len(results[2*i].ReturnData) == 32 {
+			if implementation, ok := decodeAddress(results[2*i].ReturnData); ok {
+				proxy.Implementation = implementation
+			}
+		}
+		if results[2*i+1].Success && len(results[2*i+1].ReturnData) == 32 {
+			proxy.Admin, _ = decodeAddress(results[2*i+1].ReturnData)
+		}
+		proxies[key] = proxy
+	}
+	return nil
+}
+
+// fetchProxies reads the proxies of the tokens at the block
+func fetchProxies(ctx context.Context, client TRPCClient, multicall common.Address, tokens []common.Address, blockNumber *big.Int) (map[string]TProxy, error) {
+	proxies, err := readProxySlots(ctx, client, multicall, tokens, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveBeacons(ctx, client, multicall, proxies, blockNumber); err != nil {
+		return nil, err
+	}
+	return proxies, nil
+}
+
+/**************************************************************************************************
+** FetchProxies reads, for each token of a chain, the slots of the proxy standards: the EIP-1967
+** implementation, admin and beacon slots, the EIP-1822 slot and the slots of the ZeppelinOS
+** proxies. The tokens which are not proxies are in the result with an empty Kind, while the tokens
+** which could not be read are missing from it.
+**************************************************************************************************/
+func FetchProxies(ctx context.Context, chainID uint64, tokens []common.Address) map[string]TProxy {
+	proxies := make(map[string]TProxy)
+	client := GetRPC(chainID)
+	if _, ok := client.(TStateClient); !ok {
+		logs.Warning(`The client of chain ` + strconv.FormatUint(chainID, 10) + ` cannot override the state, its proxies are not read`)
+		return proxies
+	}
+
+	multicall := MulticallClientForChainID[chainID].ContractAddress
+	for start := 0; start < len(tokens); start += PROXIES_BATCH_SIZE {
+		if ctx.Err() != nil {
+			break
+		}
+		end := start + PROXIES_BATCH_SIZE
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		batch, err := fetchProxies(ctx, client, multicall, tokens[start:end], GetPinnedBlock(chainID))
+		if err != nil {
+			logs.Warning(`Failed to read the proxies of ` + strconv.Itoa(end-start) + ` tokens on chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			continue
+		}
+		for key, proxy := range batch {
+			proxies[key] = proxy
+		}
+	}
+	return proxies
+}
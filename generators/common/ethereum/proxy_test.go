@@ -0,0 +1,94 @@
+package ethereum_test
+
+import (
+	"context"
+	"testing"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+func TestSlotReaderCode(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	first, second := common.HexToHash(`0x01`), ethereum.EIP1967_IMPLEMENTATION_SLOT
+	contract, err := backend.DeployRuntimeWithStorage([]byte{0x00}, map[common.Hash]common.Hash{
+		first:  common.HexToHash(`0x2a`),
+		second: common.HexToHash(`0x1234`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides := map[common.Address]gethclient.OverrideAccount{contract: {Code: ethereum.SLOT_READER_CODE}}
+
+	for _, test := range []struct {
+		name  string
+		slots []common.Hash
+		want  []common.Hash
+	}{
+		{`no slot`, nil, nil},
+		{`one slot`, []common.Hash{first}, []common.Hash{common.HexToHash(`0x2a`)}},
+		{`slots in order`, []common.Hash{second, common.HexToHash(`0x02`), first}, []common.Hash{common.HexToHash(`0x1234`), {}, common.HexToHash(`0x2a`)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			callData := []byte{}
+			for _, slot := range test.slots {
+				callData = append(callData, slot.Bytes()...)
+			}
+			result, err := backend.CallContractWithOverrides(context.Background(), goEthereum.CallMsg{To: &contract, Data: callData}, nil, overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []byte{}
+			for _, value := range test.want {
+				want = append(want, value.Bytes()...)
+			}
+			if common.Bytes2Hex(result) != common.Bytes2Hex(want) {
+				t.Errorf(`got %x, want %x`, result, want)
+			}
+		})
+	}
+}
+
+func TestFetchProxies(t *testing.T) {
+	backend, _ := newSimulatedChain(t)
+	deploy := func(address common.Address, err error) common.Address {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+	implementation := deploy(backend.DeployBytes32ERC20(`Proxied`, `PRX`, 18))
+	admin, owner := common.HexToAddress(`0xad`), common.HexToAddress(`0x0e`)
+	beacon := deploy(backend.DeployBeacon(implementation, owner))
+
+	token := deploy(backend.DeployBytes32ERC20(`Not a proxy`, `NOPRX`, 18))
+	eip1967 := deploy(backend.DeployProxy(ethereum.ProxyEIP1967, implementation, admin))
+	beaconProxy := deploy(backend.DeployProxy(ethereum.ProxyBeacon, beacon, common.Address{}))
+	uups := deploy(backend.DeployProxy(ethereum.ProxyEIP1822, implementation, common.Address{}))
+	zeppelinOS := deploy(backend.DeployProxy(ethereum.ProxyZeppelinOS, implementation, admin))
+
+	// The proxies are real: they answer the calls of the implementation
+	symbol := ethereum.ERC20ABI.Methods[`symbol`].ID
+	for _, proxy := range []common.Address{eip1967, beaconProxy, uups, zeppelinOS} {
+		result, err := backend.CallContract(context.Background(), goEthereum.CallMsg{To: &proxy, Data: symbol}, nil)
+		if err != nil || len(result) != 32 || string(result[:3]) != `PRX` {
+			t.Fatalf(`%s does not delegate to its implementation: %x %v`, proxy.Hex(), result, err)
+		}
+	}
+
+	proxies := ethereum.FetchProxies(context.Background(), TEST_CHAIN_ID, []common.Address{token, eip1967, beaconProxy, uups, zeppelinOS})
+	for address, want := range map[common.Address]ethereum.TProxy{
+		token:       {},
+		eip1967:     {Kind: ethereum.ProxyEIP1967, Implementation: implementation, Admin: admin},
+		beaconProxy: {Kind: ethereum.ProxyBeacon, Implementation: implementation, Admin: owner},
+		uups:        {Kind: ethereum.ProxyEIP1822, Implementation: implementation},
+		zeppelinOS:  {Kind: ethereum.ProxyZeppelinOS, Implementation: implementation, Admin: admin},
+	} {
+		if got, ok := proxies[address.Hex()]; !ok || got != want {
+			t.Errorf(`%s: got %+v, want %+v`, address.Hex(), got, want)
+		}
+	}
+}
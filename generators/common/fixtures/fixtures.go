@@ -0,0 +1,218 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// TMode is the way the HTTP calls are handled by a TTransport
+type TMode string
+
+const (
+	// ModeOff sends the requests to the network
+	ModeOff TMode = ""
+	// ModeRecord sends the requests to the network and saves every response as a fixture
+	ModeRecord TMode = "record"
+	// ModeReplay answers the requests with the saved fixtures, without any network access
+	ModeReplay TMode = "replay"
+)
+
+// SHARED_FIXTURES is the folder of the fixtures recorded outside of a generator, like the icons
+const SHARED_FIXTURES = `_shared`
+
+// MODE is the current mode, set with SetMode
+var MODE = ModeOff
+
+// ErrMissingFixture is returned in replay mode when no fixture was recorded for a request
+var ErrMissingFixture = errors.New(`no fixture recorded for this request`)
+
+type contextKey struct{}
+
+// TFixture is a recorded response, saved as testdata/fixtures/<generator>/<key>.json
+type TFixture struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	RequestBody string `json:"requestBody,omitempty"`
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+	Base64      bool   `json:"base64,omitempty"` // The body is not valid UTF-8 and is base64 encoded
+}
+
+// SetMode changes the current mode. It fails if the mode is unknown.
+func SetMode(mode string) error {
+	switch TMode(mode) {
+	case ModeOff, ModeRecord, ModeReplay:
+		MODE = TMode(mode)
+		return nil
+	}
+	return errors.New(`unknown fixtures mode ` + mode + `, expected record or replay`)
+}
+
+// WithGenerator returns a copy of ctx whose HTTP calls are recorded under the given generator
+func WithGenerator(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// GeneratorFromContext returns the generator set by WithGenerator, or SHARED_FIXTURES
+func GeneratorFromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(contextKey{}).(string); ok && name != `` {
+		return name
+	}
+	return SHARED_FIXTURES
+}
+
+/**************************************************************************************************
+** TTransport is an http.RoundTripper recording and replaying the responses of its Base transport,
+** depending on MODE. The fixtures are saved in Path/<generator>/, the generator being read from the
+** request context, and named after the method, the URL and the body of the request so the same
+** request always gets the same fixture, whatever the order in which the requests are sent.
+** Only the status, the content type and the body of the responses are kept. It is only plugged in
+** the HTTP client of the APIs: the calls to the RPC nodes are not recorded.
+**************************************************************************************************/
+type TTransport struct {
+	Path string
+	Base http.RoundTripper
+}
+
+// writeMutex avoids two requests writing the same fixture at the same time
+var writeMutex = sync.Mutex{}
+
+// RoundTrip implements http.RoundTripper
+func (t TTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if MODE == ModeOff {
+		return base.RoundTrip(req)
+	}
+
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	fixturePath := t.getFixturePath(req, requestBody)
+
+	if MODE == ModeReplay {
+		content, err := os.ReadFile(fixturePath)
+		if err != nil {
+			return nil, errors.New(ErrMissingFixture.Error() + `: ` + req.Method + ` ` + req.URL.String() + ` (` + fixturePath + `)`)
+		}
+		fixture := TFixture{}
+		if err := json.Unmarshal(content, &fixture); err != nil {
+			return nil, errors.New(`invalid fixture ` + fixturePath + `: ` + err.Error())
+		}
+		return fixture.toResponse(req)
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fixture := TFixture{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(requestBody),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get(`Content-Type`),
+		Body:        string(body),
+	}
+	if !utf8.Valid(body) {
+		fixture.Body = base64.StdEncoding.EncodeToString(body)
+		fixture.Base64 = true
+	}
+	if err := writeFixture(fixturePath, fixture); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// readRequestBody reads the body of the request and puts it back so it can still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+/**************************************************************************************************
+** getFixturePath returns the file of the fixture of a request: the host followed by the first
+** bytes of the hash of the method, the URL and the body, so the files of a host are grouped.
+**************************************************************************************************/
+func (t TTransport) getFixturePath(req *http.Request, requestBody []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + ` ` + req.URL.String() + "\n"))
+	hash.Write(requestBody)
+	name := strings.ReplaceAll(req.URL.Hostname(), `:`, `_`) + `-` + hex.EncodeToString(hash.Sum(nil))[:16] + `.json`
+	return filepath.Join(t.Path, GeneratorFromContext(req.Context()), name)
+}
+
+func writeFixture(fixturePath string, fixture TFixture) error {
+	jsonData, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	writeMutex.Lock()
+	defer writeMutex.Unlock()
+	if err := os.MkdirAll(filepath.Dir(fixturePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fixturePath, jsonData, 0644)
+}
+
+// toResponse builds the response of req from the fixture
+func (fixture TFixture) toResponse(req *http.Request) (*http.Response, error) {
+	body := []byte(fixture.Body)
+	if fixture.Base64 {
+		decoded, err := base64.StdEncoding.DecodeString(fixture.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+	header := http.Header{}
+	if fixture.ContentType != `` {
+		header.Set(`Content-Type`, fixture.ContentType)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(fixture.StatusCode) + ` ` + http.StatusText(fixture.StatusCode),
+		StatusCode:    fixture.StatusCode,
+		Proto:         `HTTP/1.1`,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
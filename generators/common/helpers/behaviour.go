@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// BEHAVIOUR_PATH is the folder of the behaviour store, with one file per chain
+var BEHAVIOUR_PATH = BASE_PATH + `/data/behaviour`
+
+// BEHAVIOUR_TTL is the time after which a token is probed again, as an upgrade of its contract
+// can change its behaviour
+var BEHAVIOUR_TTL = 30 * 24 * time.Hour
+
+// TStoredBehaviour is the entry of a token in the behaviour store, see ethereum.ProbeTokens
+type TStoredBehaviour struct {
+	ethereum.TTokenBehaviour
+	ProbedAt int64 `json:"probedAt"`
+}
+
+// behaviourStore holds the files of the behaviour store already loaded, per chainID
+var behaviourStore = map[uint64]map[string]TStoredBehaviour{}
+
+// behaviourStoreMutex guards behaviourStore and the files of the store
+var behaviourStoreMutex = sync.Mutex{}
+
+func getBehaviourPath(chainID uint64) string {
+	return BEHAVIOUR_PATH + `/` + strconv.FormatUint(chainID, 10) + `.json`
+}
+
+// loadBehaviourForChain returns the behaviour store of a chain, reading its file on the first
+// call. The caller must hold behaviourStoreMutex.
+func loadBehaviourForChain(chainID uint64) map[string]TStoredBehaviour {
+	if tokens, ok := behaviourStore[chainID]; ok {
+		return tokens
+	}
+
+	tokens := map[string]TStoredBehaviour{}
+	content, err := os.ReadFile(getBehaviourPath(chainID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logs.Warning(`Failed to read the behaviour store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+	} else if err == nil {
+		if err := json.Unmarshal(content, &tokens); err != nil {
+			logs.Warning(`Ignoring the invalid behaviour store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			tokens = map[string]TStoredBehaviour{}
+		}
+	}
+	behaviourStore[chainID] = tokens
+	return tokens
+}
+
+/**************************************************************************************************
+** RetrieveTokenBehaviours returns the behaviour of the tokens from the behaviour store, and only
+** probes the tokens which are new or whose entry is older than BEHAVIOUR_TTL. The tokens the probe
+** could not check at all are not stored, and are missing from the result.
+**************************************************************************************************/
+func RetrieveTokenBehaviours(ctx context.Context, chainID uint64, addresses []common.Address) map[string]ethereum.TTokenBehaviour {
+	now := time.Now()
+	result := map[string]ethereum.TTokenBehaviour{}
+	missingAddresses := []common.Address{}
+
+	behaviourStoreMutex.Lock()
+	tokens := loadBehaviourForChain(chainID)
+	for _, address := range addresses {
+		if stored, ok := tokens[address.Hex()]; ok && now.Sub(time.Unix(stored.ProbedAt, 0)) < BEHAVIOUR_TTL {
+			result[address.Hex()] = stored.TTokenBehaviour
+		} else {
+			missingAddresses = append(missingAddresses, address)
+		}
+	}
+	behaviourStoreMutex.Unlock()
+
+	if len(missingAddresses) == 0 {
+		return result
+	}
+	probed := ethereum.ProbeTokens(ctx, chainID, missingAddresses)
+	if len(probed) == 0 {
+		return result
+	}
+
+	behaviourStoreMutex.Lock()
+	defer behaviourStoreMutex.Unlock()
+	tokens = loadBehaviourForChain(chainID)
+	for key, behaviour := range probed {
+		tokens[key] = TStoredBehaviour{TTokenBehaviour: behaviour, ProbedAt: now.Unix()}
+		result[key] = behaviour
+	}
+	if !DRY_RUN {
+		if err := SaveJSONFile(getBehaviourPath(chainID), tokens); err != nil {
+			logs.Error(`Failed to save the behaviour store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// TTokenChangeType is the kind of change recorded for a token in a changelog entry
+type TTokenChangeType string
+
+const (
+	// TokenChangeAdd indicates that the token was added to the list
+	TokenChangeAdd TTokenChangeType = "add"
+	// TokenChangeRemove indicates that the token was removed from the list
+	TokenChangeRemove TTokenChangeType = "remove"
+	// TokenChangeModify indicates that some fields of the token changed
+	TokenChangeModify TTokenChangeType = "modify"
+)
+
+// TFieldChange holds the previous and the new value of a token field
+type TFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// TTokenChange is the change of a single token in a changelog entry
+type TTokenChange struct {
+	Type    TTokenChangeType        `json:"type"`
+	ChainID uint64                  `json:"chainId"`
+	Address string                  `json:"address"`
+	Token   *models.TokenListToken  `json:"token,omitempty"`  // The token added or removed
+	Fields  map[string]TFieldChange `json:"fields,omitempty"` // The fields modified, by JSON name
+}
+
+// TChangelogEntry is a line of a changelog file, written every time the version of a list is bumped
+type TChangelogEntry struct {
+	Version   models.TTokenListVersion `json:"version"`
+	Previous  models.TTokenListVersion `json:"previousVersion"`
+	Bump      TVersionBump             `json:"bump"`
+	Timestamp string                   `json:"timestamp"`
+	Changes   []TTokenChange           `json:"changes"`
+}
+
+/**************************************************************************************************
+** diffTokenFields returns the fields that differ between two versions of a token, indexed by
+** their JSON name. The fields which are not exported in the lists are ignored.
+**************************************************************************************************/
+func diffTokenFields(before, after models.TokenListToken) map[string]TFieldChange {
+	fields := make(map[string]TFieldChange)
+	beforeValue := reflect.ValueOf(before)
+	afterValue := reflect.ValueOf(after)
+	tokenType := beforeValue.Type()
+	for i := 0; i < tokenType.NumField(); i++ {
+		name := strings.Split(tokenType.Field(i).Tag.Get(`json`), `,`)[0]
+		if name == `` || name == `-` {
+			continue
+		}
+		previous := beforeValue.Field(i).Interface()
+		next := afterValue.Field(i).Interface()
+		if !reflect.DeepEqual(previous, next) {
+			fields[name] = TFieldChange{Before: previous, After: next}
+		}
+	}
+	return fields
+}
+
+// sortedFieldNames returns the names of the modified fields in alphabetical order
+func sortedFieldNames(fields map[string]TFieldChange) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**************************************************************************************************
+** newChangelogEntry converts a diff into a changelog entry. The changes are ordered by chainID,
+** then removals, additions and modifications, each sorted by address.
+**************************************************************************************************/
+func newChangelogEntry(diff TTokenListDiff, before, after models.TTokenListVersion, timestamp string) TChangelogEntry {
+	entry := TChangelogEntry{
+		Version:   after,
+		Previous:  before,
+		Bump:      diff.Bump,
+		Timestamp: timestamp,
+		Changes:   []TTokenChange{},
+	}
+	for _, chainID := range diff.chainIDs() {
+		for _, token := range diff.Removed[chainID] {
+			token := token
+			entry.Changes = append(entry.Changes, TTokenChange{
+				Type:    TokenChangeRemove,
+				ChainID: chainID,
+				Address: token.Address,
+				Token:   &token,
+			})
+		}
+		for _, token := range diff.Added[chainID] {
+			token := token
+			entry.Changes = append(entry.Changes, TTokenChange{
+				Type:    TokenChangeAdd,
+				ChainID: chainID,
+				Address: token.Address,
+				Token:   &token,
+			})
+		}
+		for _, modification := range diff.Modified[chainID] {
+			entry.Changes = append(entry.Changes, TTokenChange{
+				Type:    TokenChangeModify,
+				ChainID: chainID,
+				Address: modification.After.Address,
+				Fields:  diffTokenFields(modification.Before, modification.After),
+			})
+		}
+	}
+	return entry
+}
+
+// getChangelogPath returns the path, relative to the lists folder, of the changelog of a list
+func getChangelogPath(filePath string) string {
+	return `changelogs/` + strings.TrimSuffix(filePath, path.Ext(filePath)) + `.jsonl`
+}
+
+/**************************************************************************************************
+** appendChangelogEntry returns the content of the changelog of a list, lists/changelogs/<list>.jsonl,
+** with the entry added at the end. The file contains one JSON object per line, oldest first.
+** Nothing is written: the changelog is saved along with the list it belongs to.
+**************************************************************************************************/
+func appendChangelogEntry(filePath string, entry TChangelogEntry) ([]byte, error) {
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(BASE_PATH + `/lists/` + getChangelogPath(filePath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	return append(append(content, jsonData...), '\n'), nil
+}
@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+// readChangelog returns the entries of the changelog of test.json, oldest first
+func readChangelog(t *testing.T, basePath string) []TChangelogEntry {
+	t.Helper()
+	content, err := os.ReadFile(basePath + `/lists/` + getChangelogPath(`test.json`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []TChangelogEntry{}
+	for _, line := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
+		entry := TChangelogEntry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestChangelogEntryIsAppendedOnEveryBump(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saveTestList(t, REPRODUCIBLE_TOKENS[:4])
+	if entries := readChangelog(t, basePath); len(entries) != 1 || entries[0].Bump != VersionBumpMinor || len(entries[0].Changes) != 4 {
+		t.Fatalf(`got the changelog %+v after the first version`, entries)
+	}
+
+	// Saving the same tokens does not bump the version, so nothing is appended
+	timeNow = func() time.Time { return time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC) }
+	if result := saveTestList(t, REPRODUCIBLE_TOKENS[:4]); result.Changed {
+		t.Fatalf(`the list changed without any change of its tokens: %+v`, result.Diff)
+	}
+	if entries := readChangelog(t, basePath); len(entries) != 1 {
+		t.Fatalf(`got %d changelog entries without a bump, want 1`, len(entries))
+	}
+
+	renamedTokens := append(REPRODUCIBLE_TOKENS[1:4:4], REPRODUCIBLE_TOKENS[4])
+	renamedTokens[0].Name = `USD Coin v2`
+	result := saveTestList(t, renamedTokens)
+	entries := readChangelog(t, basePath)
+	if len(entries) != 2 {
+		t.Fatalf(`got %d changelog entries after a bump, want 2`, len(entries))
+	}
+	entry := entries[1]
+	if entry.Bump != VersionBumpMajor || entry.Version != result.VersionAfter || entry.Previous != result.VersionBefore || entry.Timestamp != `2024-02-03T04:05:06Z` {
+		t.Errorf(`got the entry %+v for the result %+v`, entry, result)
+	}
+
+	// The changes are ordered by chain, then removals, additions and modifications
+	want := []struct {
+		changeType TTokenChangeType
+		chainID    uint64
+		address    string
+	}{
+		{TokenChangeRemove, 1, REPRODUCIBLE_TOKENS[0].Address},
+		{TokenChangeModify, 1, REPRODUCIBLE_TOKENS[1].Address},
+		{TokenChangeAdd, 10, REPRODUCIBLE_TOKENS[4].Address},
+	}
+	if len(entry.Changes) != len(want) {
+		t.Fatalf(`got the changes %+v`, entry.Changes)
+	}
+	for i, change := range entry.Changes {
+		if change.Type != want[i].changeType || change.ChainID != want[i].chainID || change.Address != want[i].address {
+			t.Errorf(`got the change %+v at %d, want %+v`, change, i, want[i])
+		}
+	}
+	if fields := entry.Changes[1].Fields; len(fields) != 1 || fields[`name`].Before != `USD Coin` || fields[`name`].After != `USD Coin v2` {
+		t.Errorf(`got the modified fields %+v`, fields)
+	}
+	if token := entry.Changes[0].Token; token == nil || token.Symbol != `DAI` {
+		t.Errorf(`got the removed token %+v`, token)
+	}
+}
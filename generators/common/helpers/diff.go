@@ -0,0 +1,168 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// TVersionBump is the semver bump resulting from the changes in a token list
+type TVersionBump string
+
+const (
+	// VersionBumpNone indicates that the list did not change
+	VersionBumpNone TVersionBump = "none"
+	// VersionBumpPatch indicates that at least one token was modified
+	VersionBumpPatch TVersionBump = "patch"
+	// VersionBumpMinor indicates that at least one token was added
+	VersionBumpMinor TVersionBump = "minor"
+	// VersionBumpMajor indicates that at least one token was removed
+	VersionBumpMajor TVersionBump = "major"
+)
+
+// TTokenModification holds the two versions of a token present in both the old and the new list
+type TTokenModification struct {
+	Before models.TokenListToken
+	After  models.TokenListToken
+}
+
+// TTokenListDiff contains the tokens added, removed and modified in a token list, per chainID
+type TTokenListDiff struct {
+	Added    map[uint64][]models.TokenListToken
+	Removed  map[uint64][]models.TokenListToken
+	Modified map[uint64][]TTokenModification
+	Bump     TVersionBump
+}
+
+// DRY_RUN disables every write to the lists folder. The changes are printed instead.
+var DRY_RUN = false
+
+// diffPrintMutex avoids mixing the diffs of generators running in parallel
+var diffPrintMutex = sync.Mutex{}
+
+/**************************************************************************************************
+** computeTokenListDiff compares the tokens of the previous version of a list with the new ones.
+** Both maps are indexed by GetKey. A removed token bumps the major version, an added token the
+** minor version and a modified token the patch version. Only the fields written in the lists are
+** compared: a change in an internal field, like Occurrence, does not bump the version.
+** The entries are sorted by address so the diff is the same from one run to another.
+**************************************************************************************************/
+func computeTokenListDiff(previous, next map[string]models.TokenListToken) TTokenListDiff {
+	diff := TTokenListDiff{
+		Added:    make(map[uint64][]models.TokenListToken),
+		Removed:  make(map[uint64][]models.TokenListToken),
+		Modified: make(map[uint64][]TTokenModification),
+		Bump:     VersionBumpNone,
+	}
+	for key, token := range next {
+		previousToken, ok := previous[key]
+		if !ok {
+			diff.Added[token.ChainID] = append(diff.Added[token.ChainID], token)
+		} else if len(diffTokenFields(previousToken, token)) > 0 {
+			diff.Modified[token.ChainID] = append(diff.Modified[token.ChainID], TTokenModification{
+				Before: previousToken,
+				After:  token,
+			})
+		}
+	}
+	for key, token := range previous {
+		if _, ok := next[key]; !ok {
+			diff.Removed[token.ChainID] = append(diff.Removed[token.ChainID], token)
+		}
+	}
+
+	for _, tokens := range diff.Added {
+		sort.Slice(tokens, func(i, j int) bool { return tokens[i].Address < tokens[j].Address })
+	}
+	for _, tokens := range diff.Removed {
+		sort.Slice(tokens, func(i, j int) bool { return tokens[i].Address < tokens[j].Address })
+	}
+	for _, modifications := range diff.Modified {
+		sort.Slice(modifications, func(i, j int) bool { return modifications[i].After.Address < modifications[j].After.Address })
+	}
+
+	if len(diff.Removed) > 0 {
+		diff.Bump = VersionBumpMajor
+	} else if len(diff.Added) > 0 {
+		diff.Bump = VersionBumpMinor
+	} else if len(diff.Modified) > 0 {
+		diff.Bump = VersionBumpPatch
+	}
+	return diff
+}
+
+// bumpVersion returns the version following version for the given bump
+func bumpVersion(version models.TTokenListVersion, bump TVersionBump) models.TTokenListVersion {
+	switch bump {
+	case VersionBumpMajor:
+		version.Major++
+		version.Minor = 0
+		version.Patch = 0
+	case VersionBumpMinor:
+		version.Minor++
+		version.Patch = 0
+	case VersionBumpPatch:
+		version.Patch++
+	}
+	return version
+}
+
+// chainIDs returns the chainIDs with at least one change, in ascending order
+func (diff TTokenListDiff) chainIDs() []uint64 {
+	seen := make(map[uint64]bool)
+	for chainID := range diff.Added {
+		seen[chainID] = true
+	}
+	for chainID := range diff.Removed {
+		seen[chainID] = true
+	}
+	for chainID := range diff.Modified {
+		seen[chainID] = true
+	}
+	chainIDs := []uint64{}
+	for chainID := range seen {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+	return chainIDs
+}
+
+/**************************************************************************************************
+** printTokenListDiff prints the changes of a list, chain by chain, followed by the version bump.
+** It is used in dry-run mode to review the result of a generator without writing the lists.
+**************************************************************************************************/
+func printTokenListDiff(filePath string, diff TTokenListDiff, before, after models.TTokenListVersion) {
+	formatToken := func(token models.TokenListToken) string {
+		return token.Address + ` ` + token.Symbol + ` (` + token.Name + `)`
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(`[dry-run] ` + filePath + `: ` + string(diff.Bump) + ` bump, ` + before.String() + ` -> ` + after.String() + "\n")
+	for _, chainID := range diff.chainIDs() {
+		builder.WriteString(fmt.Sprintf(
+			"  chain %s: %d added, %d removed, %d modified\n",
+			strconv.FormatUint(chainID, 10),
+			len(diff.Added[chainID]),
+			len(diff.Removed[chainID]),
+			len(diff.Modified[chainID]),
+		))
+		for _, token := range diff.Added[chainID] {
+			builder.WriteString(`    + ` + formatToken(token) + "\n")
+		}
+		for _, token := range diff.Removed[chainID] {
+			builder.WriteString(`    - ` + formatToken(token) + "\n")
+		}
+		for _, modification := range diff.Modified[chainID] {
+			fields := sortedFieldNames(diffTokenFields(modification.Before, modification.After))
+			builder.WriteString(`    ~ ` + formatToken(modification.After) + ` [` + strings.Join(fields, `, `) + `]` + "\n")
+		}
+	}
+
+	diffPrintMutex.Lock()
+	defer diffPrintMutex.Unlock()
+	fmt.Print(builder.String())
+}
@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// tokensByKey indexes tokens by GetKey, like the maps compared by computeTokenListDiff
+func tokensByKey(tokens ...models.TokenListToken) map[string]models.TokenListToken {
+	tokensMap := make(map[string]models.TokenListToken)
+	for _, token := range tokens {
+		tokensMap[GetKey(token.ChainID, common.HexToAddress(token.Address))] = token
+	}
+	return tokensMap
+}
+
+func TestComputeTokenListDiff(t *testing.T) {
+	dai, usdc, weth, usdt := REPRODUCIBLE_TOKENS[0], REPRODUCIBLE_TOKENS[1], REPRODUCIBLE_TOKENS[2], REPRODUCIBLE_TOKENS[3]
+	crv := REPRODUCIBLE_TOKENS[4]
+	renamedUSDC := usdc
+	renamedUSDC.Name = `USD Coin v2`
+	countedUSDC := usdc
+	countedUSDC.Occurrence = 12
+
+	for _, test := range []struct {
+		name                     string
+		previous, next           map[string]models.TokenListToken
+		added, removed, modified int
+		bump                     TVersionBump
+	}{
+		{`same tokens`, tokensByKey(dai, usdc), tokensByKey(usdc, dai), 0, 0, 0, VersionBumpNone},
+		{`internal field changed`, tokensByKey(dai, usdc), tokensByKey(dai, countedUSDC), 0, 0, 0, VersionBumpNone},
+		{`token modified`, tokensByKey(dai, usdc), tokensByKey(dai, renamedUSDC), 0, 0, 1, VersionBumpPatch},
+		{`tokens added`, tokensByKey(dai), tokensByKey(dai, weth, usdt, crv), 3, 0, 0, VersionBumpMinor},
+		{`token added and modified`, tokensByKey(usdc), tokensByKey(renamedUSDC, weth), 1, 0, 1, VersionBumpMinor},
+		{`token removed`, tokensByKey(dai, usdc), tokensByKey(dai), 0, 1, 0, VersionBumpMajor},
+		{`token removed and added`, tokensByKey(dai, usdc), tokensByKey(dai, weth, renamedUSDC), 1, 0, 1, VersionBumpMinor},
+		{`everything changed`, tokensByKey(dai, usdc), tokensByKey(renamedUSDC, weth), 1, 1, 1, VersionBumpMajor},
+		{`first version`, tokensByKey(), tokensByKey(dai), 1, 0, 0, VersionBumpMinor},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			diff := computeTokenListDiff(test.previous, test.next)
+			added, removed, modified := 0, 0, 0
+			for _, tokens := range diff.Added {
+				added += len(tokens)
+			}
+			for _, tokens := range diff.Removed {
+				removed += len(tokens)
+			}
+			for _, modifications := range diff.Modified {
+				modified += len(modifications)
+			}
+			if added != test.added || removed != test.removed || modified != test.modified || diff.Bump != test.bump {
+				t.Errorf(`got %d added, %d removed, %d modified and a %s bump, want %d, %d, %d and %s`,
+					added, removed, modified, diff.Bump, test.added, test.removed, test.modified, test.bump)
+			}
+		})
+	}
+
+	// The entries are sorted by chain and address, whatever the order of the maps
+	diff := computeTokenListDiff(tokensByKey(), tokensByKey(weth, usdt, dai, crv, usdc))
+	addresses := []string{}
+	for _, token := range diff.Added[1] {
+		addresses = append(addresses, token.Address)
+	}
+	if len(diff.Added[10]) != 1 || len(addresses) != 4 || addresses[0] != dai.Address || addresses[3] != usdt.Address {
+		t.Errorf(`got the tokens added %v`, diff.Added)
+	}
+	if chainIDs := diff.chainIDs(); len(chainIDs) != 2 || chainIDs[0] != 1 || chainIDs[1] != 10 {
+		t.Errorf(`got the chains %v`, chainIDs)
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	version := models.TTokenListVersion{Major: 1, Minor: 2, Patch: 3}
+	for bump, want := range map[TVersionBump]models.TTokenListVersion{
+		VersionBumpNone:  {Major: 1, Minor: 2, Patch: 3},
+		VersionBumpPatch: {Major: 1, Minor: 2, Patch: 4},
+		VersionBumpMinor: {Major: 1, Minor: 3},
+		VersionBumpMajor: {Major: 2},
+	} {
+		if got := bumpVersion(version, bump); got != want {
+			t.Errorf(`got %s for a %s bump, want %s`, got.String(), bump, want.String())
+		}
+	}
+}
+
+func TestDryRunWritesNothing(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := saveTestList(t, REPRODUCIBLE_TOKENS[:5])
+	previousFiles := readLists(t, basePath)
+
+	DRY_RUN = true
+	t.Cleanup(func() { DRY_RUN = false })
+	timeNow = func() time.Time { return time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC) }
+	result := saveTestList(t, REPRODUCIBLE_TOKENS[1:])
+	if !result.Changed || result.Diff.Bump != VersionBumpMajor || result.VersionBefore != first.VersionAfter || result.VersionAfter != bumpVersion(first.VersionAfter, VersionBumpMajor) {
+		t.Errorf(`got the result %+v after %+v`, result, first)
+	}
+	assertSameFiles(t, readLists(t, basePath), previousFiles)
+
+	// Even a list which does not exist yet is not created
+	tokenList := LoadTokenListFromJsonFile(`new.json`)
+	tokenList.Name = `New`
+	tokenList.LogoURI = `https://raw.githubusercontent.com/smoldapp/tokenLists/main/.github/tokenlistooor.svg`
+	tokenList.Keywords = []string{`test`}
+	if _, err := SaveTokenListInJsonFile(context.Background(), tokenList, REPRODUCIBLE_TOKENS, `new.json`, SavingMethodStandard); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readLists(t, basePath), previousFiles)
+}
@@ -1,44 +1,141 @@
 package helpers
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"io"
 	"net/http"
-	"strings"
+	"sort"
+	"strconv"
 
 	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
 )
 
-func FetchJSON[T any](uri string) (data T) {
-	var resp *http.Response
-	var err error
+// errNotReplayable is returned when a request with a body cannot be sent again
+var errNotReplayable = errors.New(`request body cannot be replayed`)
 
-	if strings.Contains(uri, `api.portals.fi`) ||
-		strings.Contains(uri, `api.1inch.io`) {
-		req, _ := http.NewRequest("GET", uri, nil)
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36")
-		resp, err = http.DefaultClient.Do(req)
-	} else {
-		resp, err = http.Get(uri)
+// THTTPError is returned when a server answers with a non-2xx status
+type THTTPError struct {
+	URI        string
+	StatusCode int
+	Body       string
+}
+
+func (e *THTTPError) Error() string {
+	return `request to ` + e.URI + ` failed with status ` + strconv.Itoa(e.StatusCode) + `: ` + e.Body
+}
+
+/**************************************************************************************************
+** FetchBody sends a GET request to uri with the shared HTTP client and returns the body of the
+** response. An error is returned if the request fails after all the retries or if the server
+** answers with a non-2xx status: a source which is down is never mistaken for an empty source.
+**************************************************************************************************/
+func FetchBody(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
 	}
+	resp, err := HTTP_CLIENT.Do(req)
 	if err != nil {
-		logs.Error(err)
-		return data
+		return nil, err
 	}
 	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		logs.Error(`Error reading body for URI ` + uri + `: ` + err.Error())
-		return data
+		return nil, errors.New(`error reading body for URI ` + uri + `: ` + err.Error())
 	}
 
 	if (resp.StatusCode < 200) || (resp.StatusCode > 299) {
-		return data
+		if len(body) > 256 {
+			body = body[:256]
+		}
+		return nil, &THTTPError{URI: uri, StatusCode: resp.StatusCode, Body: string(body)}
 	}
+	return body, nil
+}
 
+// FetchJSON fetches uri with FetchBody and decodes the JSON response into T
+func FetchJSON[T any](ctx context.Context, uri string) (data T, err error) {
+	body, err := FetchBody(ctx, uri)
+	if err != nil {
+		return data, err
+	}
 	if err := json.Unmarshal(body, &data); err != nil {
-		logs.Error(`Error unmarshal body for URI ` + uri + `: ` + err.Error())
-		return data
+		return data, errors.New(`error unmarshal body for URI ` + uri + `: ` + err.Error())
+	}
+	return data, nil
+}
+
+/**************************************************************************************************
+** TChainErrors is returned by the sources fetched chain by chain when some chains failed. Total is
+** the number of chains the source was fetched for.
+**************************************************************************************************/
+type TChainErrors struct {
+	Errors map[uint64]error
+	Total  int
+}
+
+func (e *TChainErrors) Error() string {
+	message := strconv.Itoa(len(e.Errors)) + ` of ` + strconv.Itoa(e.Total) + ` chains failed`
+	for _, chainID := range sortedChainIDs(e.Errors) {
+		message += `; ` + strconv.FormatUint(chainID, 10) + `: ` + e.Errors[chainID].Error()
+	}
+	return message
+}
+
+// Add records the error of a chain, if any, and counts the chain
+func (e *TChainErrors) Add(chainID uint64, err error) {
+	if e.Errors == nil {
+		e.Errors = make(map[uint64]error)
+	}
+	e.Total++
+	if err != nil {
+		e.Errors[chainID] = err
+	}
+}
+
+// OrNil returns the errors as an error, or nil if no chain failed
+func (e *TChainErrors) OrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func sortedChainIDs(errs map[uint64]error) []uint64 {
+	chainIDs := make([]uint64, 0, len(errs))
+	for chainID := range errs {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+	return chainIDs
+}
+
+/**************************************************************************************************
+** KeepTokensOfFailedChains handles the error returned by a source. When only some chains failed,
+** the tokens those chains had in the previous version of the list are added back to tokens, so a
+** source temporarily down on one chain does not remove all its tokens, and nil is returned.
+** Any other error, or a failure of every chain, is returned as is.
+**************************************************************************************************/
+func KeepTokensOfFailedChains(
+	tokenList models.TokenListData[models.TokenListToken],
+	tokens []models.TokenListToken,
+	err error,
+) ([]models.TokenListToken, error) {
+	chainErrors := &TChainErrors{}
+	if err == nil || !errors.As(err, &chainErrors) || len(chainErrors.Errors) >= chainErrors.Total {
+		return tokens, err
+	}
+
+	for chainID, chainErr := range chainErrors.Errors {
+		logs.Warning(`Keeping the previous tokens of chain ` + strconv.FormatUint(chainID, 10) + ` for ` + tokenList.Name + `: ` + chainErr.Error())
+	}
+	for _, token := range tokenList.PreviousTokensMap {
+		if _, ok := chainErrors.Errors[token.ChainID]; ok {
+			tokens = append(tokens, token)
+		}
 	}
-	return data
+	return tokens, nil
 }
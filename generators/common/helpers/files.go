@@ -1,12 +1,12 @@
 package helpers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"os"
 	"path"
 	"path/filepath"
-	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
 	"github.com/migratooor/tokenLists/generators/common/logs"
 	"github.com/migratooor/tokenLists/generators/common/models"
 )
@@ -26,9 +27,27 @@ const (
 	SavingMethodAppend   JSONSaveTokensMethods = "SavingMethodAppend"
 )
 
+// TSaveResult describes what SaveTokenListInJsonFile did with a list
+type TSaveResult struct {
+	Changed            bool                     // Whether the content of the list changed
+	Diff               TTokenListDiff           // Tokens added, removed and modified, per chainID
+	VersionBefore      models.TTokenListVersion // Version of the list before the run
+	VersionAfter       models.TTokenListVersion // Version of the list after the run
+	TokenCountPerChain map[uint64]int           // Number of tokens in the list for each chainID
+}
+
+// NotSavedResult is the result of a generator failing before it saved its list: the version of the
+// list stays the one loaded
+func NotSavedResult(tokenList models.TokenListData[models.TokenListToken]) TSaveResult {
+	return TSaveResult{VersionBefore: tokenList.Version, VersionAfter: tokenList.Version}
+}
+
 // BASE_PATH is the base path to access the data informations
 var BASE_PATH, _ = filepath.Abs(getCurrentPath() + `../../../../`)
 
+// timeNow returns the time the lists are saved at, fixed by the tests to compare the saved files
+var timeNow = time.Now
+
 func getCurrentPath() string {
 	_, filename, _, _ := runtime.Caller(1)
 
@@ -43,45 +62,202 @@ func CreateFile(p string) error {
 	return nil
 }
 
-// LoadTokenListFromJsonFile loads a token list from a json file.
+// LoadTokenListFromJsonFile loads a token list from a json file. A missing file is created empty,
+// unless DRY_RUN is set, so the next run finds it.
 func LoadTokenListFromJsonFile(filePath string) models.TokenListData[models.TokenListToken] {
-	var tokenList models.TokenListData[models.TokenListToken]
-	content, err := os.ReadFile(BASE_PATH + `/lists/` + filePath)
+	tokenList, err := ReadTokenListFromJsonFile(filePath)
 	if err != nil {
 		logs.Error(err)
-		if errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, os.ErrNotExist) && !DRY_RUN {
 			os.WriteFile(BASE_PATH+`/lists/`+filePath, []byte(`{}`), 0644)
 		}
-		return models.InitTokenList()
+	}
+	return tokenList
+}
+
+// ReadTokenListFromJsonFile reads a token list from a json file, without changing any file. The
+// list is empty when the file is missing or invalid.
+func ReadTokenListFromJsonFile(filePath string) (models.TokenListData[models.TokenListToken], error) {
+	var tokenList models.TokenListData[models.TokenListToken]
+	content, err := os.ReadFile(BASE_PATH + `/lists/` + filePath)
+	if err != nil {
+		return models.InitTokenList(), err
 	}
 	if err = json.Unmarshal(content, &tokenList); err != nil {
-		logs.Error(err)
-		return models.InitTokenList()
+		return models.InitTokenList(), err
 	}
 
 	tokenList.PreviousTokensMap = make(map[string]models.TokenListToken)
 	for _, token := range tokenList.Tokens {
-		if !chains.IsChainIDSupported(token.ChainID) {
+		if !chains.IsChainIDKnown(token.ChainID) {
 			continue
 		}
 		key := GetKey(token.ChainID, common.HexToAddress(token.Address))
 		tokenList.PreviousTokensMap[key] = token
 	}
 	tokenList.NextTokensMap = make(map[string]models.TokenListToken)
-	return tokenList
+	return tokenList, nil
+}
+
+// isListable returns true if the token can be written in a list: it has a name, decimals and a
+// symbol passing validateSymbol, and is not ignored
+func isListable(token models.TokenListToken) bool {
+	if token.Name == `` || token.Decimals == 0 || validateSymbol(token.Symbol) != nil {
+		return false
+	}
+	return !chains.IsTokenIgnored(token.ChainID, common.HexToAddress(token.Address))
+}
+
+/**************************************************************************************************
+** marshalTokenList returns the content of the files of a list: the unified list, with the tokens of
+** NextTokensMap sorted by chainID then address, and one file per chainID with its tokens. The
+** tags of each file define the tags its tokens use. The popular list is then sorted by
+** occurrence, the ties keeping the order of the addresses. The content only depends on the list,
+** never on the order of a map, so the same list always gives the same bytes.
+**************************************************************************************************/
+func marshalTokenList(tokenList models.TokenListData[models.TokenListToken], filePath string) (map[string][]byte, error) {
+	tokenListPerChainID := make(map[uint64][]models.TokenListToken)
+	keys := make([]string, 0, len(tokenList.NextTokensMap))
+	for k := range tokenList.NextTokensMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tokenList.Tokens = []models.TokenListToken{}
+	for _, k := range keys {
+		token := tokenList.NextTokensMap[k]
+		tokenList.Tokens = append(tokenList.Tokens, token)
+		tokenListPerChainID[token.ChainID] = append(tokenListPerChainID[token.ChainID], token)
+	}
+
+	tokenList.Tags = models.TagsOf(tokenList.Tokens)
+	if tokenList.Keywords == nil {
+		tokenList.Keywords = []string{} // The schema expects an array
+	}
+
+	occurrence := func(p1, p2 *models.TokenListToken) bool {
+		return p1.Occurrence > p2.Occurrence
+	}
+	if filePath == `popular.json` {
+		By(occurrence).Sort(tokenList.Tokens)
+	}
+
+	/**************************************************************************
+	** Then we will just save the unified token list in a json file as well as
+	** each individual token list per chainID.
+	** All the files are prepared first and then written together, so the
+	** unified list and its splits always share the same version.
+	**************************************************************************/
+	files := make(map[string][]byte)
+	jsonData, err := json.MarshalIndent(tokenList, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[filePath] = jsonData
+
+	for chainID, tokens := range tokenListPerChainID {
+		if !chains.IsChainIDSupported(chainID) {
+			continue
+		}
+		chainIDStr := strconv.FormatUint(chainID, 10)
+
+		if len(tokens) <= len(chains.CHAINS[chainID].ExtraTokens)+1 {
+			continue //If we have as much tokens as the extra tokens, we don't need to save the list, this is the default list
+		}
+
+		if filePath == `popular.json` {
+			By(occurrence).Sort(tokens)
+		}
+		tokenList.Tokens = tokens
+		tokenList.Tags = models.TagsOf(tokens)
+		jsonData, err := json.MarshalIndent(tokenList, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files[chainIDStr+`/`+filePath] = jsonData
+	}
+	return files, nil
+}
+
+// toJSONMap returns the map as it is read back from a list, so a value set by a generator, like an
+// int or a struct, compares equal to the same value loaded from the previous version of the list
+func toJSONMap(value map[string]interface{}) map[string]interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return value
+	}
+	return result
+}
+
+/**************************************************************************************************
+** withPinnedBlocks returns the metadata of a list with, in `blocks`, the block each chain of its
+** tokens was read at during the run, see ethereum.PinBlocks. The chains not processed by the run
+** keep the block of their previous version, while the chains read at their latest block have none.
+**************************************************************************************************/
+func withPinnedBlocks(metadata map[string]interface{}, tokens map[string]models.TokenListToken) map[string]interface{} {
+	blocks := map[string]interface{}{}
+	if previous, ok := metadata[`blocks`].(map[string]interface{}); ok {
+		for chainID, block := range previous {
+			blocks[chainID] = block
+		}
+	}
+	for _, token := range tokens {
+		if !chains.IsChainIDSupported(token.ChainID) {
+			continue
+		}
+		chainID := strconv.FormatUint(token.ChainID, 10)
+		if block, ok := ethereum.GetPinnedBlockNumber(token.ChainID); ok {
+			blocks[chainID] = block
+		} else {
+			delete(blocks, chainID)
+		}
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range metadata {
+		result[key] = value
+	}
+	delete(result, `blocks`)
+	if len(blocks) > 0 {
+		result[`blocks`] = blocks
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
 }
 
-// SaveTokenListInJsonFile saves a token list in a json file
+// SaveTokenListInJsonFile saves a token list in a json file. Nothing is written if the context
+// is done, when the save starts or right before the files are written, so a generator that ran
+// past its deadline cannot overwrite a list.
+// The returned result describes the list even when it did not change.
 func SaveTokenListInJsonFile(
+	ctx context.Context,
 	tokenList models.TokenListData[models.TokenListToken],
 	tokensMaybeDuplicates []models.TokenListToken,
 	filePath string,
 	method JSONSaveTokensMethods,
-) error {
+) (TSaveResult, error) {
+	result := TSaveResult{
+		VersionBefore:      tokenList.Version,
+		VersionAfter:       tokenList.Version,
+		TokenCountPerChain: make(map[uint64]int),
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
 	tokens := []models.TokenListToken{}
 	addresses := make(map[string]bool)
 	for _, token := range tokensMaybeDuplicates {
-		key := token.Address + strconv.FormatUint(token.ChainID, 10)
+		key := GetKey(token.ChainID, common.HexToAddress(token.Address))
 		if _, ok := addresses[key]; !ok {
 			addresses[key] = true
 			tokens = append(tokens, token)
@@ -115,10 +291,26 @@ func SaveTokenListInJsonFile(
 				continue
 			}
 			newToken.Occurrence = token.Occurrence
+			newToken.Tags = models.SortTags(token.Tags)
+			newToken.Extensions = toJSONMap(token.Extensions)
+			newToken.Metadata = toJSONMap(token.Metadata)
 			tokenList.NextTokensMap[GetKey(token.ChainID, common.HexToAddress(token.Address))] = newToken
 		}
 	}
 
+	/**************************************************************************
+	** The chains filtered out with chains.SetChainFilter were not processed
+	** by the generator: their tokens are kept, only trimming their symbol as
+	** SetToken does, as the lists written before the validation may have
+	** spaces around them.
+	**************************************************************************/
+	for key, token := range tokenList.PreviousTokensMap {
+		if !chains.IsChainIDSupported(token.ChainID) {
+			token.Symbol = strings.TrimSpace(token.Symbol)
+			tokenList.NextTokensMap[key] = token
+		}
+	}
+
 	for _, token := range tokens {
 		if !chains.IsChainIDSupported(token.ChainID) {
 			continue
@@ -136,14 +328,46 @@ func SaveTokenListInJsonFile(
 			continue
 		}
 		newToken.Occurrence = token.Occurrence
+		newToken.Tags = models.SortTags(token.Tags)
+		newToken.Extensions = toJSONMap(token.Extensions)
+		newToken.Metadata = toJSONMap(token.Metadata)
 		tokenList.NextTokensMap[GetKey(token.ChainID, common.HexToAddress(token.Address))] = newToken
 	}
 
+	/**************************************************************************
+	** The tokens known as proxies get the isProxy, implementation and admin
+	** extensions from the proxies store, see RetrieveProxies.
+	**************************************************************************/
+	for key, token := range tokenList.NextTokensMap {
+		if chains.IsChainIDSupported(token.ChainID) {
+			tokenList.NextTokensMap[key] = setProxyExtensions(token)
+		}
+	}
+
+	/**************************************************************************
+	** The tokens which cannot be listed, without a name or decimals, with a
+	** symbol failing validateSymbol or ignored, are removed once here, before
+	** the diff, so the diff, the counts and the files agree.
+	**************************************************************************/
+	for key, token := range tokenList.NextTokensMap {
+		if !isListable(token) {
+			delete(tokenList.NextTokensMap, key)
+		}
+	}
+
+	/**************************************************************************
+	** The spam filters remove the tokens impersonating another one and tag
+	** the suspicious ones of the chains processed by the run, see
+	** SPAM_FILTERS. Their decisions are written in the quarantine report of
+	** the list, to be reviewed, along with the files of the list.
+	**************************************************************************/
+	quarantine := filterSpamTokens(tokenList.NextTokensMap)
+
 	/**************************************************************************
 	** If the list is empty, we skip
 	**************************************************************************/
 	if len(tokenList.NextTokensMap) == 0 {
-		return errors.New(`token list is empty`)
+		return result, errors.New(`token list is empty`)
 	}
 
 	/**************************************************************************
@@ -164,129 +388,98 @@ func SaveTokenListInJsonFile(
 	}
 
 	if len(tokenList.NextTokensMap) <= baseCoinCount {
-		return errors.New(`token list is empty`)
+		return result, errors.New(`token list is empty`)
 	}
 
-	tokenList.Timestamp = time.Now().Format(time.RFC3339)
-	tokenList.Tokens = []models.TokenListToken{}
+	for _, token := range tokenList.NextTokensMap {
+		result.TokenCountPerChain[token.ChainID]++
+	}
 
 	/**************************************************************************
 	** Detect the changes in the token list.
 	** If a token is removed, the major version is bumped.
 	** If a token is added, the minor version is bumped.
 	** If a token is modified, the patch version is bumped.
-	** Skip if we are not using the standard method.
 	**************************************************************************/
-	shouldBumpMajor := false
-	shouldBumpMinor := false
-	shouldBumpPatch := false
-	for _, token := range tokenList.NextTokensMap {
-		key := GetKey(token.ChainID, common.HexToAddress(token.Address))
-		if _, ok := tokenList.PreviousTokensMap[key]; !ok {
-			shouldBumpMinor = true
-		} else if !reflect.DeepEqual(token, tokenList.PreviousTokensMap[key]) {
-			shouldBumpPatch = true
-		}
-		tokenList.Tokens = append(tokenList.Tokens, token)
-		delete(tokenList.PreviousTokensMap, key)
-	}
-	if len(tokenList.PreviousTokensMap) > 0 {
-		shouldBumpMajor = true
-	}
+	diff := computeTokenListDiff(tokenList.PreviousTokensMap, tokenList.NextTokensMap)
+	result.Diff = diff
 
 	/**************************************************************************
-	** If there are no changes, we will just return.
+	** If there are no changes, we will just write the quarantine report, as
+	** the spam filters may have decided on tokens which are not in the list,
+	** and return. The timestamp is only moved with the version, so an
+	** unchanged list keeps the same content.
 	**************************************************************************/
-	if !shouldBumpMajor && !shouldBumpMinor && !shouldBumpPatch {
-		return nil
+	if diff.Bump == VersionBumpNone {
+		if DRY_RUN {
+			return result, nil
+		}
+		return result, writeQuarantineReport(ctx, filePath, quarantine)
 	}
 
-	if shouldBumpMajor {
-		tokenList.Version.Major++
-		tokenList.Version.Minor = 0
-		tokenList.Version.Patch = 0
-	} else if shouldBumpMinor {
-		tokenList.Version.Minor++
-		tokenList.Version.Patch = 0
-	} else if shouldBumpPatch {
-		tokenList.Version.Patch++
-	}
+	tokenList.Timestamp = timeNow().UTC().Format(time.RFC3339)
+	tokenList.Version = bumpVersion(tokenList.Version, diff.Bump)
+	tokenList.Metadata = withPinnedBlocks(tokenList.Metadata, tokenList.NextTokensMap)
+	result.VersionAfter = tokenList.Version
+	result.Changed = true
 
 	/**************************************************************************
-	** To make it easy to work with the list, we will sort the token by their
-	** chainID in ascending order.
+	** The files are checked against the schema and the semantic rules of
+	** ValidateTokenList before anything is written: a list failing them is
+	** not saved, and the previous version stays in place.
 	**************************************************************************/
-	tokenListPerChainID := make(map[uint64][]models.TokenListToken)
-	keys := make([]string, 0, len(tokenList.NextTokensMap))
-	for k := range tokenList.NextTokensMap {
-		keys = append(keys, k)
+	files, err := marshalTokenList(tokenList, filePath)
+	if err != nil {
+		logs.Error(err)
+		return result, err
 	}
-	sort.Strings(keys)
-	for i, k := range keys {
-		chainID, _ := strconv.ParseUint(strings.Split(k, `_`)[0], 10, 64)
-		if _, ok := tokenListPerChainID[chainID]; !ok {
-			tokenListPerChainID[chainID] = []models.TokenListToken{}
-		}
-
-		token := tokenList.NextTokensMap[k]
-		if (token.Name == `` || token.Symbol == `` || token.Decimals == 0) || chains.IsTokenIgnored(token.ChainID, common.HexToAddress(token.Address)) {
-			continue
-		}
-		tokenList.Tokens[i] = tokenList.NextTokensMap[k]
-		tokenListPerChainID[chainID] = append(tokenListPerChainID[chainID], tokenList.NextTokensMap[k])
+	if err := validateTokenListFiles(filePath, files); err != nil {
+		logs.Error(err)
+		return result, err
 	}
 
-	if filePath == `popular.json` {
-		occurrence := func(p1, p2 *models.TokenListToken) bool {
-			return p1.Occurrence > p2.Occurrence
+	/**************************************************************************
+	** In dry-run mode, we stop here and only print what would have changed.
+	**************************************************************************/
+	if DRY_RUN {
+		printTokenListDiff(filePath, diff, result.VersionBefore, result.VersionAfter)
+		if len(quarantine) > 0 {
+			logs.Info(`[dry-run] ` + filePath + `: ` + strconv.Itoa(len(quarantine)) + ` tokens quarantined`)
 		}
-		By(occurrence).Sort(tokenList.Tokens)
+		return result, nil
 	}
 
 	/**************************************************************************
-	** Then we will just save the unified token list in a json file as well as
-	** each individual token list per chainID.
+	** We also keep track of what changed in this version in the changelog of
+	** the list, so integrators can sync without diffing the whole files. The
+	** quarantine report is written with them, so a dry-run writes nothing.
 	**************************************************************************/
-	jsonData, err := json.MarshalIndent(tokenList, "", "  ")
+	entry := newChangelogEntry(diff, result.VersionBefore, result.VersionAfter, tokenList.Timestamp)
+	changelogData, err := appendChangelogEntry(filePath, entry)
 	if err != nil {
-		return err
+		logs.Error(err)
+		return result, err
 	}
-	if err = os.WriteFile(BASE_PATH+`/lists/`+filePath, jsonData, 0644); err != nil {
-		return err
+	files[getChangelogPath(filePath)] = changelogData
+	reportPath, reportData, err := marshalQuarantineReport(filePath, quarantine)
+	if err != nil {
+		logs.Error(err)
+		return result, err
 	}
+	files[reportPath] = reportData
 
-	for chainID, tokens := range tokenListPerChainID {
-		if !chains.IsChainIDSupported(chainID) {
-			continue
-		}
-		chainIDStr := strconv.FormatUint(chainID, 10)
-
-		if len(tokens) <= len(chains.CHAINS[chainID].ExtraTokens)+1 {
-			continue //If we have as much tokens as the extra tokens, we don't need to save the list, this is the default list
-		}
-
-		if filePath == `popular.json` {
-			occurrence := func(p1, p2 *models.TokenListToken) bool {
-				return p1.Occurrence > p2.Occurrence
-			}
-			By(occurrence).Sort(tokens)
-		}
-		tokenList.Tokens = tokens
-		jsonData, err := json.MarshalIndent(tokenList, "", "  ")
-		if err != nil {
-			logs.Error(err)
-			return err
-		}
-		if err := CreateFile(BASE_PATH + `/lists/` + chainIDStr); err != nil {
-			logs.Error(err)
-			return err
-		}
-
-		if err = os.WriteFile(BASE_PATH+`/lists/`+chainIDStr+`/`+filePath, jsonData, 0644); err != nil {
-			logs.Error(err)
-			return err
-		}
+	/**************************************************************************
+	** The generator may have been reported as timed out while it was saving
+	** the list: the context is checked again right before writing, so a list
+	** is never written after the scheduler gave up on its generator.
+	**************************************************************************/
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if err := writeFilesAtomically(filePath, files); err != nil {
+		logs.Error(err)
+		return result, err
 	}
 
-	return nil
+	return result, nil
 }
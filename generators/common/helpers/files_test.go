@@ -0,0 +1,191 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+var REPRODUCIBLE_TOKENS = []models.TokenListToken{
+	{Address: `0x6B175474E89094C44Da98b954EedeAC495271d0F`, Name: `Dai Stablecoin`, Symbol: `DAI`, Decimals: 18, ChainID: 1},
+	{Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`, Decimals: 6, ChainID: 1},
+	{Address: `0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2`, Name: `Wrapped Ether`, Symbol: `WETH`, Decimals: 18, ChainID: 1},
+	{Address: `0xdAC17F958D2ee523a2206206994597C13D831ec7`, Name: `Tether USD`, Symbol: `USDT`, Decimals: 6, ChainID: 1},
+	{
+		Address: `0x0994206dfE8De6Ec6920FF4D779B0d950605Fb53`, Name: `Curve DAO Token`, Symbol: `CRV`, Decimals: 18, ChainID: 10,
+		Metadata: map[string]interface{}{`protocol`: `curve`, `token0`: `0x1`, `token1`: `0x2`, `poolType`: `stable`},
+	},
+	{
+		Address: `0x4200000000000000000000000000000000000006`, Name: `Wrapped Ether`, Symbol: `WETH`, Decimals: 18, ChainID: 10,
+		Extensions: map[string]interface{}{`isProxy`: false, `bridgeInfo`: map[string]interface{}{`1`: `0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2`}},
+	},
+}
+
+// withTempBasePath moves the lists and the stores written by SaveTokenListInJsonFile to a temporary
+// folder, and saves the lists at the given time, until the end of the test
+func withTempBasePath(t *testing.T, now time.Time) string {
+	t.Helper()
+	basePath, previousBasePath := t.TempDir(), BASE_PATH
+	previousProxiesPath, previousQuarantinePath, previousTimeNow := PROXIES_PATH, QUARANTINE_PATH, timeNow
+	BASE_PATH, PROXIES_PATH, QUARANTINE_PATH = basePath, basePath+`/data/proxies`, basePath+`/data/quarantine`
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() {
+		BASE_PATH, PROXIES_PATH, QUARANTINE_PATH, timeNow = previousBasePath, previousProxiesPath, previousQuarantinePath, previousTimeNow
+	})
+	if err := os.MkdirAll(basePath+`/lists`, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return basePath
+}
+
+// readLists returns the content of the files of the lists folder, per path relative to the folder
+func readLists(t *testing.T, basePath string) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	err := filepath.Walk(basePath+`/lists`, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(filePath)
+		relativePath, _ := filepath.Rel(basePath+`/lists`, filePath)
+		files[relativePath] = content
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func assertSameFiles(t *testing.T, got, want map[string][]byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf(`got %d files, want %d`, len(got), len(want))
+	}
+	for name, content := range want {
+		if !bytes.Equal(got[name], content) {
+			t.Errorf("%s differs:\n%s\nwant:\n%s", name, got[name], content)
+		}
+	}
+}
+
+// saveTestList saves the tokens in test.json, after loading the previous version of the list
+func saveTestList(t *testing.T, tokens []models.TokenListToken) TSaveResult {
+	t.Helper()
+	tokenList := LoadTokenListFromJsonFile(`test.json`)
+	tokenList.Name = `Test`
+	tokenList.LogoURI = `https://raw.githubusercontent.com/smoldapp/tokenLists/main/.github/tokenlistooor.svg`
+	tokenList.Keywords = []string{`test`}
+	result, err := SaveTokenListInJsonFile(context.Background(), tokenList, tokens, `test.json`, SavingMethodStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func shuffled(tokens []models.TokenListToken, seed int64) []models.TokenListToken {
+	result := append([]models.TokenListToken{}, tokens...)
+	rand.New(rand.NewSource(seed)).Shuffle(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+	return result
+}
+
+func TestSaveTokenListInJsonFileIsReproducible(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	savedFiles := []map[string][]byte{}
+	for seed := int64(1); seed <= 3; seed++ {
+		basePath := withTempBasePath(t, now)
+		os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644)
+		if result := saveTestList(t, shuffled(REPRODUCIBLE_TOKENS, seed)); !result.Changed {
+			t.Fatal(`the list was not saved`)
+		}
+		savedFiles = append(savedFiles, readLists(t, basePath))
+	}
+	if _, ok := savedFiles[0][`test.json`]; !ok {
+		t.Fatalf(`test.json was not written, got %d files`, len(savedFiles[0]))
+	}
+	for _, files := range savedFiles[1:] {
+		assertSameFiles(t, files, savedFiles[0])
+	}
+}
+
+func TestSaveTokenListInJsonFileOnlyMovesTheTimestampWithTheContent(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644)
+	saveTestList(t, REPRODUCIBLE_TOKENS)
+	firstFiles := readLists(t, basePath)
+
+	timeNow = func() time.Time { return time.Date(2024, 2, 2, 3, 4, 5, 0, time.UTC) }
+	if result := saveTestList(t, shuffled(REPRODUCIBLE_TOKENS, 42)); result.Changed {
+		t.Errorf(`the same tokens changed the list: %+v`, result.Diff)
+	}
+	assertSameFiles(t, readLists(t, basePath), firstFiles)
+
+	modifiedTokens := append([]models.TokenListToken{}, REPRODUCIBLE_TOKENS...)
+	modifiedTokens[0].Name = `Dai`
+	if result := saveTestList(t, modifiedTokens); !result.Changed {
+		t.Fatal(`the modified token did not change the list`)
+	}
+	tokenList := LoadTokenListFromJsonFile(`test.json`)
+	if tokenList.Timestamp != `2024-02-02T03:04:05Z` || tokenList.Version.Patch != 1 {
+		t.Errorf(`got the timestamp %s and the version %+v`, tokenList.Timestamp, tokenList.Version)
+	}
+}
+
+func TestExtractSyncMapSortsTheChains(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		syncMap := InitSyncMap(map[uint64]bool{1: true, 10: true, 137: true, 42161: true})
+		for _, chainID := range []uint64{42161, 1, 137, 10} {
+			syncMap.Store(chainID, []models.TokenListToken{{ChainID: chainID, Name: `first`}, {ChainID: chainID, Name: `second`}})
+		}
+		tokens := ExtractSyncMap(syncMap)
+		if len(tokens) != 8 {
+			t.Fatalf(`got %d tokens, want 8`, len(tokens))
+		}
+		for j := 1; j < len(tokens); j++ {
+			previous, token := tokens[j-1], tokens[j]
+			if previous.ChainID > token.ChainID || (previous.ChainID == token.ChainID && previous.Name != `first`) {
+				t.Fatalf(`got the tokens out of order: %+v`, tokens)
+			}
+		}
+	}
+}
+
+func TestSaveTokenListInJsonFileDropsTheInvalidSymbolsBeforeTheDiff(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	previousList := `{"name": "Test", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [
+		{"address": "0x6B175474E89094C44Da98b954EedeAC495271d0F", "name": "Dai Stablecoin", "symbol": "DAI", "decimals": 18, "chainId": 1},
+		{"address": "0x4200000000000000000000000000000000000006", "name": "Wrapped Ether", "symbol": "WETH ", "decimals": 18, "chainId": 10},
+		{"address": "0x0994206dfE8De6Ec6920FF4D779B0d950605Fb53", "name": "Curve DAO Token", "symbol": "` + strings.Repeat(`CRV`, 30) + `", "decimals": 18, "chainId": 10}
+	]}`
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(previousList), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := chains.SetChainFilter([]uint64{1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { chains.SetChainFilter(nil) })
+
+	result := saveTestList(t, REPRODUCIBLE_TOKENS[:4])
+	if result.TokenCountPerChain[10] != 1 {
+		t.Errorf(`got %d tokens on chain 10, want 1`, result.TokenCountPerChain[10])
+	}
+	if len(result.Diff.Removed[10]) != 1 || result.Diff.Removed[10][0].Address != `0x0994206dfE8De6Ec6920FF4D779B0d950605Fb53` {
+		t.Errorf(`got the removed tokens %+v`, result.Diff.Removed)
+	}
+	if len(result.Diff.Modified[10]) != 1 || result.VersionAfter.Major != 2 {
+		t.Errorf(`got the modified tokens %+v and the version %+v`, result.Diff.Modified, result.VersionAfter)
+	}
+
+	tokenList := LoadTokenListFromJsonFile(`test.json`)
+	if len(tokenList.Tokens) != 5 || tokenList.Tokens[4].Symbol != `WETH` {
+		t.Errorf(`got the tokens %+v`, tokenList.Tokens)
+	}
+}
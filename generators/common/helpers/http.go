@@ -0,0 +1,359 @@
+package helpers
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/fixtures"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/stats"
+)
+
+const browserUserAgent = `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36`
+
+/**************************************************************************************************
+** THostConfig holds the settings applied to every request sent to a host.
+** - RequestsPerSecond limits the rate of the requests, 0 means no limit.
+** - Headers are added to every request.
+** - APIKeyEnv is the env variable containing the API key of the host, if any. When it is set, the
+**   key is sent in the APIKeyHeader header, or in the APIKeyQuery query parameter.
+**************************************************************************************************/
+type THostConfig struct {
+	RequestsPerSecond float64
+	Headers           map[string]string
+	APIKeyEnv         string
+	APIKeyHeader      string
+	APIKeyQuery       string
+}
+
+// HOST_CONFIGS contains the settings of the hosts with specific needs. The key is the hostname.
+var HOST_CONFIGS = map[string]THostConfig{
+	`api.portals.fi`: {
+		RequestsPerSecond: 2,
+		Headers:           map[string]string{`User-Agent`: browserUserAgent},
+	},
+	`api.1inch.io`: {
+		RequestsPerSecond: 1,
+		Headers:           map[string]string{`User-Agent`: browserUserAgent},
+	},
+	`api.coingecko.com`: {
+		RequestsPerSecond: 0.2,
+		APIKeyEnv:         `COINGECKO_API_KEY`,
+		APIKeyHeader:      `x-cg-demo-api-key`,
+	},
+	`data.messari.io`: {
+		RequestsPerSecond: 0.3,
+		APIKeyEnv:         `MESSARI_API_KEY`,
+		APIKeyHeader:      `x-messari-api-key`,
+	},
+	`apiv5.paraswap.io`: {
+		RequestsPerSecond: 1,
+	},
+	`api.routescan.io`: {
+		RequestsPerSecond: 2,
+	},
+	`block-explorer-api.mainnet.zksync.io`: {
+		RequestsPerSecond: 2,
+	},
+}
+
+// THTTPSettings holds the retry settings of the shared HTTP client
+type THTTPSettings struct {
+	MaxRetries     int           // Number of retries after the first attempt
+	BaseBackoff    time.Duration // Delay before the first retry, doubled at each retry
+	MaxBackoff     time.Duration // Maximum delay between two retries
+	RequestTimeout time.Duration // Deadline of a single attempt
+}
+
+/**************************************************************************************************
+** HTTP_SETTINGS are the retry settings of the shared HTTP client. They can be changed with the
+** HTTP_MAX_RETRIES, HTTP_BASE_BACKOFF, HTTP_MAX_BACKOFF and HTTP_REQUEST_TIMEOUT env variables.
+**************************************************************************************************/
+var HTTP_SETTINGS = THTTPSettings{
+	MaxRetries:     envInt(`HTTP_MAX_RETRIES`, 4),
+	BaseBackoff:    envDuration(`HTTP_BASE_BACKOFF`, time.Second),
+	MaxBackoff:     envDuration(`HTTP_MAX_BACKOFF`, time.Minute),
+	RequestTimeout: envDuration(`HTTP_REQUEST_TIMEOUT`, 30*time.Second),
+}
+
+// FIXTURES_PATH is the folder where the HTTP responses are recorded and replayed from
+var FIXTURES_PATH = BASE_PATH + `/testdata/fixtures`
+
+/**************************************************************************************************
+** HTTP_CLIENT is the client shared by all the API calls. It applies the host settings, the rate
+** limits and the retries, and counts every attempt against the counters of the request context.
+** In record and replay modes, the final responses are saved to, or read from, FIXTURES_PATH: a
+** replayed request never reaches the network, so it is neither rate limited nor counted.
+**************************************************************************************************/
+var HTTP_CLIENT = &http.Client{
+	Transport: fixtures.TTransport{
+		Path: FIXTURES_PATH,
+		Base: &TRetryTransport{
+			Base: stats.CountingTransport{Kind: stats.CallHTTP},
+		},
+	},
+}
+
+// contextTransport attaches a context to the requests built without one
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// ContextTransport returns the transport of HTTP_CLIENT bound to ctx. It is used by the clients
+// which do not accept a context, like colly, so their calls are counted, retried and recorded.
+func ContextTransport(ctx context.Context) http.RoundTripper {
+	return contextTransport{ctx: ctx, base: HTTP_CLIENT.Transport}
+}
+
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+/**************************************************************************************************
+** hostLimiter spaces the requests sent to a host so there are at most RequestsPerSecond of them.
+** The requests waiting for their turn are released in order, or as soon as their context is done.
+**************************************************************************************************/
+type hostLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *hostLimiter) wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mutex.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+var hostLimiters = map[string]*hostLimiter{}
+var hostLimitersMutex = sync.Mutex{}
+
+func getHostLimiter(host string) *hostLimiter {
+	config, ok := HOST_CONFIGS[host]
+	if !ok || config.RequestsPerSecond <= 0 {
+		return nil
+	}
+	hostLimitersMutex.Lock()
+	defer hostLimitersMutex.Unlock()
+	if _, ok := hostLimiters[host]; !ok {
+		hostLimiters[host] = &hostLimiter{interval: time.Duration(float64(time.Second) / config.RequestsPerSecond)}
+	}
+	return hostLimiters[host]
+}
+
+/**************************************************************************************************
+** TRetryTransport is the http.RoundTripper of the shared client. For every request, it:
+** - adds the headers and the API key configured for the host in HOST_CONFIGS,
+** - waits for the rate limit of the host,
+** - gives each attempt its own deadline,
+** - retries the network errors, the 429 and the 5xx responses with an exponential backoff,
+**   honouring the Retry-After header when the server sends one. A server asking to wait for more
+**   than MaxBackoff is not retried: the run would stall on it for longer than it is worth.
+** The response of the last attempt is returned, whatever its status.
+**************************************************************************************************/
+type TRetryTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	req = withHostConfig(req)
+	limiter := getHostLimiter(req.URL.Hostname())
+
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptReq, cancel, err := newAttempt(req)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := base.RoundTrip(attemptReq)
+		retry := shouldRetry(resp, err) && attempt < HTTP_SETTINGS.MaxRetries && req.Context().Err() == nil
+		delay, canWait := backoffDelay(attempt, resp)
+		if retry && !canWait {
+			logs.Warning(`Request to ` + req.URL.Host + ` asks to retry in ` + delay.String() + `, more than ` + HTTP_SETTINGS.MaxBackoff.String() + `: giving up`)
+			retry = false
+		}
+		if !retry {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		if err != nil {
+			logs.Warning(`Request to ` + req.URL.Host + ` failed (` + err.Error() + `), retrying in ` + delay.String())
+		} else {
+			logs.Warning(`Request to ` + req.URL.Host + ` returned ` + strconv.Itoa(resp.StatusCode) + `, retrying in ` + delay.String())
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		cancel()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// withHostConfig returns a copy of the request with the headers and the API key of its host
+func withHostConfig(req *http.Request) *http.Request {
+	config, ok := HOST_CONFIGS[req.URL.Hostname()]
+	if !ok {
+		return req
+	}
+	req = req.Clone(req.Context())
+	for key, value := range config.Headers {
+		if req.Header.Get(key) == `` {
+			req.Header.Set(key, value)
+		}
+	}
+	if config.APIKeyEnv == `` {
+		return req
+	}
+	apiKey := os.Getenv(config.APIKeyEnv)
+	if apiKey == `` {
+		return req
+	}
+	if config.APIKeyHeader != `` {
+		req.Header.Set(config.APIKeyHeader, apiKey)
+	}
+	if config.APIKeyQuery != `` {
+		query := req.URL.Query()
+		query.Set(config.APIKeyQuery, apiKey)
+		req.URL = cloneURL(req.URL)
+		req.URL.RawQuery = query.Encode()
+	}
+	return req
+}
+
+func cloneURL(u *url.URL) *url.URL {
+	clone := *u
+	return &clone
+}
+
+// newAttempt returns a copy of the request with its own deadline and a fresh body
+func newAttempt(req *http.Request) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), HTTP_SETTINGS.RequestTimeout)
+	attemptReq := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			cancel()
+			return nil, nil, errNotReplayable
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		attemptReq.Body = body
+	}
+	return attemptReq, cancel, nil
+}
+
+// shouldRetry returns true for the failures that may succeed later
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+/**************************************************************************************************
+** backoffDelay returns the delay before the next attempt: BaseBackoff doubled at each attempt with
+** up to 20% of jitter, capped to MaxBackoff, or the Retry-After of the response if any. A
+** Retry-After longer than MaxBackoff is returned as is, with false, as it cannot be waited for.
+**************************************************************************************************/
+func backoffDelay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get(`Retry-After`)); ok {
+			return retryAfter, retryAfter <= HTTP_SETTINGS.MaxBackoff
+		}
+	}
+	delay := HTTP_SETTINGS.BaseBackoff << attempt
+	delay += time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	if delay > HTTP_SETTINGS.MaxBackoff || delay < 0 {
+		delay = HTTP_SETTINGS.MaxBackoff
+	}
+	return delay, true
+}
+
+// parseRetryAfter parses a Retry-After header, expressed either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == `` {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// cancelOnCloseBody releases the deadline of an attempt once its response body is closed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
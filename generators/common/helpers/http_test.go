@@ -0,0 +1,189 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withHTTPSettings replaces the retry settings for the duration of a test
+func withHTTPSettings(t *testing.T, settings THTTPSettings) {
+	previousSettings := HTTP_SETTINGS
+	HTTP_SETTINGS = settings
+	t.Cleanup(func() { HTTP_SETTINGS = previousSettings })
+}
+
+// newTestServer starts a server answering each request with the status returned by respond for
+// its attempt, counting from 0
+func newTestServer(t *testing.T, respond func(attempt int, w http.ResponseWriter, r *http.Request) int) (*httptest.Server, *int32) {
+	attempts := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(respond(int(atomic.AddInt32(attempts, 1)-1), w, r))
+	}))
+	t.Cleanup(server.Close)
+	return server, attempts
+}
+
+func getWithRetries(t *testing.T, rawURL string) *http.Response {
+	t.Helper()
+	resp, err := (&http.Client{Transport: &TRetryTransport{}}).Get(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestRetryTransportRetries(t *testing.T) {
+	withHTTPSettings(t, THTTPSettings{MaxRetries: 3, BaseBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, RequestTimeout: time.Second})
+	for _, test := range []struct {
+		name     string
+		statuses []int
+		status   int
+		attempts int32
+	}{
+		{`success`, []int{200}, 200, 1},
+		{`not found`, []int{404, 200}, 404, 1},
+		{`server errors`, []int{503, 500, 200}, 200, 3},
+		{`too many requests`, []int{429, 200}, 200, 2},
+		{`out of retries`, []int{502, 502, 502, 502, 200}, 502, 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server, attempts := newTestServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) int {
+				return test.statuses[attempt]
+			})
+			if resp := getWithRetries(t, server.URL); resp.StatusCode != test.status || *attempts != test.attempts {
+				t.Errorf(`got the status %d after %d attempts, want %d after %d`, resp.StatusCode, *attempts, test.status, test.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryTransportHonoursRetryAfter(t *testing.T) {
+	withHTTPSettings(t, THTTPSettings{MaxRetries: 3, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Second, RequestTimeout: time.Second})
+	server, attempts := newTestServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) int {
+		if attempt == 0 {
+			w.Header().Set(`Retry-After`, `1`)
+			return http.StatusTooManyRequests
+		}
+		return http.StatusOK
+	})
+
+	start := time.Now()
+	if resp := getWithRetries(t, server.URL); resp.StatusCode != http.StatusOK || *attempts != 2 {
+		t.Fatalf(`got the status %d after %d attempts`, resp.StatusCode, *attempts)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf(`retried after %s, before the Retry-After`, elapsed)
+	}
+}
+
+func TestRetryTransportGivesUpOnALongRetryAfter(t *testing.T) {
+	withHTTPSettings(t, THTTPSettings{MaxRetries: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Second, RequestTimeout: time.Second})
+	server, attempts := newTestServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) int {
+		w.Header().Set(`Retry-After`, `120`)
+		return http.StatusTooManyRequests
+	})
+
+	start := time.Now()
+	if resp := getWithRetries(t, server.URL); resp.StatusCode != http.StatusTooManyRequests || *attempts != 1 {
+		t.Errorf(`got the status %d after %d attempts, want a single attempt`, resp.StatusCode, *attempts)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf(`gave up after %s`, elapsed)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	withHTTPSettings(t, THTTPSettings{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	withRetryAfter := func(value string) *http.Response {
+		return &http.Response{Header: http.Header{`Retry-After`: []string{value}}}
+	}
+	for _, test := range []struct {
+		name     string
+		attempt  int
+		resp     *http.Response
+		min, max time.Duration
+		canWait  bool
+	}{
+		{`first retry`, 0, nil, time.Second, 1200 * time.Millisecond, true},
+		{`third retry`, 2, &http.Response{Header: http.Header{}}, 4 * time.Second, 4800 * time.Millisecond, true},
+		{`capped`, 5, nil, 10 * time.Second, 10 * time.Second, true},
+		{`Retry-After`, 0, withRetryAfter(`5`), 5 * time.Second, 5 * time.Second, true},
+		{`Retry-After shorter than the backoff`, 3, withRetryAfter(`0`), 0, 0, true},
+		{`Retry-After longer than the maximum`, 0, withRetryAfter(`60`), time.Minute, time.Minute, false},
+		{`invalid Retry-After`, 0, withRetryAfter(`soon`), time.Second, 1200 * time.Millisecond, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			delay, canWait := backoffDelay(test.attempt, test.resp)
+			if delay < test.min || delay > test.max || canWait != test.canWait {
+				t.Errorf(`got %s and %v, want between %s and %s and %v`, delay, canWait, test.min, test.max, test.canWait)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		min, max time.Duration
+		ok       bool
+	}{
+		{`120`, 2 * time.Minute, 2 * time.Minute, true},
+		{` 3 `, 3 * time.Second, 3 * time.Second, true},
+		{`0`, 0, 0, true},
+		{``, 0, 0, false},
+		{`-1`, 0, 0, false},
+		{`1.5`, 0, 0, false},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute, true},
+		{`Wed, 21 Oct 2015 07:28:00 GMT`, 0, 0, true},
+	} {
+		delay, ok := parseRetryAfter(test.value)
+		if delay < test.min || delay > test.max || ok != test.ok {
+			t.Errorf(`got %s and %v for %q, want between %s and %s and %v`, delay, ok, test.value, test.min, test.max, test.ok)
+		}
+	}
+}
+
+func TestRetryTransportAppliesTheHostConfig(t *testing.T) {
+	withHTTPSettings(t, THTTPSettings{RequestTimeout: time.Second})
+	server, attempts := newTestServer(t, func(attempt int, w http.ResponseWriter, r *http.Request) int {
+		if r.Header.Get(`x-api-key`) != `secret` || r.URL.Query().Get(`key`) != `secret` || r.URL.Query().Get(`page`) != strconv.Itoa(attempt) {
+			return http.StatusUnauthorized
+		}
+		if r.Header.Get(`User-Agent`) != browserUserAgent {
+			return http.StatusForbidden
+		}
+		return http.StatusOK
+	})
+	serverURL, _ := url.Parse(server.URL)
+	HOST_CONFIGS[serverURL.Hostname()] = THostConfig{
+		RequestsPerSecond: 20,
+		Headers:           map[string]string{`User-Agent`: browserUserAgent},
+		APIKeyEnv:         `TEST_API_KEY`,
+		APIKeyHeader:      `x-api-key`,
+		APIKeyQuery:       `key`,
+	}
+	t.Setenv(`TEST_API_KEY`, `secret`)
+	t.Cleanup(func() {
+		delete(HOST_CONFIGS, serverURL.Hostname())
+		hostLimitersMutex.Lock()
+		delete(hostLimiters, serverURL.Hostname())
+		hostLimitersMutex.Unlock()
+	})
+
+	start := time.Now()
+	for page := 0; page < 4; page++ {
+		if resp := getWithRetries(t, server.URL+`?page=`+strconv.Itoa(page)); resp.StatusCode != http.StatusOK {
+			t.Fatalf(`got the status %d for the page %d`, resp.StatusCode, page)
+		}
+	}
+	// The first request is sent right away, the 3 others 50ms after the previous one
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || *attempts != 4 {
+		t.Errorf(`sent %d requests in %s, faster than 20 per second`, *attempts, elapsed)
+	}
+}
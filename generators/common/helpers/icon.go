@@ -1,7 +1,7 @@
 package helpers
 
 import (
-	"os"
+	"context"
 	"strconv"
 	"strings"
 
@@ -27,17 +27,29 @@ type TSmolAssetsList struct {
 	Tokens []string `json:"tokens"`
 }
 
-func init() {
+/**************************************************************************************************
+** InitIcons loads the list of assets available in the SmolDapp tokenAssets repository for every
+** supported chain. It must be called once, after the chain filter is set and before any token is
+** built. When logAssetsError is set, a message is printed for every token without an icon.
+**************************************************************************************************/
+func InitIcons(ctx context.Context, logAssetsError bool) {
 	basePath := `https://raw.githubusercontent.com/SmolDapp/tokenAssets/main/tokens/`
 	for _, chainID := range chains.SUPPORTED_CHAIN_IDS {
-		smoldAssetsPerChain[chainID] = FetchJSON[TSmolAssetsList](basePath + strconv.FormatUint(chainID, 10) + `/list.json`).Tokens
-	}
-	cmdArgs := os.Args
-	for _, arg := range cmdArgs {
-		if arg == "--log-assets-error" {
-			shouldLogAssetError = true
+		list, err := FetchJSON[TSmolAssetsList](ctx, basePath+strconv.FormatUint(chainID, 10)+`/list.json`)
+		if err != nil {
+			logs.Warning(`Could not load the SmolDapp assets for chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
 		}
+		smoldAssetsPerChain[chainID] = list.Tokens
 	}
+	shouldLogAssetError = logAssetsError
+}
+
+// HasIcon returns true if the logoURI is set and is not one of the placeholders of the providers
+func HasIcon(logoURI string) bool {
+	return logoURI != `` &&
+		logoURI != DEFAULT_SMOL_NOT_FOUND &&
+		logoURI != DEFAULT_PARASWAP_NOT_FOUND &&
+		logoURI != DEFAULT_ETHERSCAN_NOT_FOUND
 }
 
 func GetSmolAssetsPerChain(chainID uint64) []string {
@@ -0,0 +1,156 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// INDEXER_STATE_PATH is the folder of the state files of the indexers, one per generator
+var INDEXER_STATE_PATH = BASE_PATH + `/data/indexer`
+
+/**************************************************************************************************
+** TPoolCounts is the number of pools each token of a chain is part of, across the whole history of
+** the factories indexed by a generator. RecentPools holds the pools counted close to the
+** checkpoints, with their creation block: after a reorg the indexer reads these blocks again, see
+** ethereum.IndexEvents, and CountPool does not count their pools twice.
+**************************************************************************************************/
+type TPoolCounts struct {
+	Tokens      map[string]int    `json:"tokens"`
+	RecentPools map[string]uint64 `json:"recentPools"`
+}
+
+/**************************************************************************************************
+** TIndexerState is the ethereum.TCheckpointStore of a generator, kept in
+** data/indexer/<name>.json. The checkpoints and the pool counts set during the run stay in memory
+** until Save, which the generator calls once its list is saved, so a failed run starts again from
+** the same blocks with the same counts.
+**************************************************************************************************/
+type TIndexerState struct {
+	name        string
+	mutex       sync.Mutex
+	Checkpoints map[string]ethereum.TCheckpoint `json:"checkpoints"`
+	PoolCounts  map[uint64]*TPoolCounts         `json:"poolCounts,omitempty"`
+}
+
+func getIndexerStatePath(name string) string {
+	return INDEXER_STATE_PATH + `/` + name + `.json`
+}
+
+// LoadIndexerState reads the state of the indexers of a generator, or returns an empty state
+func LoadIndexerState(name string) *TIndexerState {
+	state := &TIndexerState{name: name, Checkpoints: map[string]ethereum.TCheckpoint{}}
+	content, err := os.ReadFile(getIndexerStatePath(name))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logs.Warning(`Failed to read the indexer state of ` + name + `: ` + err.Error())
+		}
+		return state
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		logs.Warning(`Ignoring the invalid indexer state of ` + name + `: ` + err.Error())
+		state.Checkpoints = map[string]ethereum.TCheckpoint{}
+	}
+	if state.Checkpoints == nil {
+		state.Checkpoints = map[string]ethereum.TCheckpoint{}
+	}
+	return state
+}
+
+// GetCheckpoint implements ethereum.TCheckpointStore
+func (s *TIndexerState) GetCheckpoint(key string) (ethereum.TCheckpoint, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	checkpoint, ok := s.Checkpoints[key]
+	return checkpoint, ok
+}
+
+// SetCheckpoint implements ethereum.TCheckpointStore
+func (s *TIndexerState) SetCheckpoint(key string, checkpoint ethereum.TCheckpoint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Checkpoints[key] = checkpoint
+}
+
+// HasPoolCounts returns true if the pools of the chain are counted, see ResetPoolCounts
+func (s *TIndexerState) HasPoolCounts(chainID uint64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.PoolCounts[chainID]
+	return ok
+}
+
+// ResetPoolCounts starts counting the pools of a chain from zero, and drops the checkpoints of the
+// indexers so they read the factories again from their deployment
+func (s *TIndexerState) ResetPoolCounts(chainID uint64, keys []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.PoolCounts == nil {
+		s.PoolCounts = make(map[uint64]*TPoolCounts)
+	}
+	s.PoolCounts[chainID] = &TPoolCounts{Tokens: map[string]int{}, RecentPools: map[string]uint64{}}
+	for _, key := range keys {
+		delete(s.Checkpoints, key)
+	}
+}
+
+// CountPool counts a pool created at the block for each of its tokens. It returns false, without
+// counting it, if the pool was already counted.
+func (s *TIndexerState) CountPool(chainID uint64, pool string, block uint64, tokens ...string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	counts := s.PoolCounts[chainID]
+	if _, ok := counts.RecentPools[pool]; ok {
+		return false
+	}
+	counts.RecentPools[pool] = block
+	for _, token := range tokens {
+		counts.Tokens[token]++
+	}
+	return true
+}
+
+// TokenPoolCount returns the number of pools counted for the token
+func (s *TIndexerState) TokenPoolCount(chainID uint64, token string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if counts, ok := s.PoolCounts[chainID]; ok {
+		return counts.Tokens[token]
+	}
+	return 0
+}
+
+// ForgetPoolsBefore drops the recent pools of the chain created before the block, which a reorg
+// cannot make the indexer read again
+func (s *TIndexerState) ForgetPoolsBefore(chainID uint64, block uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if counts, ok := s.PoolCounts[chainID]; ok {
+		for pool, poolBlock := range counts.RecentPools {
+			if poolBlock < block {
+				delete(counts.RecentPools, pool)
+			}
+		}
+	}
+}
+
+// Save writes the state in its file, unless this is a dry run
+func (s *TIndexerState) Save() error {
+	if DRY_RUN {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := CreateFile(INDEXER_STATE_PATH); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(s, ``, "\t")
+	if err != nil {
+		return err
+	}
+	return replaceFile(getIndexerStatePath(s.name), content)
+}
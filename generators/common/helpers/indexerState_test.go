@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+func TestIndexerStateKeepsThePoolCounts(t *testing.T) {
+	INDEXER_STATE_PATH = t.TempDir()
+	state := LoadIndexerState(`test`)
+	state.SetCheckpoint(`1/factory`, ethereum.TCheckpoint{Block: 10})
+	state.ResetPoolCounts(1, []string{`1/factory`})
+	if _, ok := state.GetCheckpoint(`1/factory`); ok || !state.HasPoolCounts(1) || state.HasPoolCounts(10) {
+		t.Fatal(`the reset did not drop the checkpoint of the chain or start its counts`)
+	}
+
+	if !state.CountPool(1, `old`, 5, `a`, `b`) || !state.CountPool(1, `recent`, 20, `a`, `c`) {
+		t.Fatal(`a new pool was not counted`)
+	}
+	if state.CountPool(1, `recent`, 20, `a`, `c`) {
+		t.Error(`a pool was counted twice`)
+	}
+	state.ForgetPoolsBefore(1, 10)
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := LoadIndexerState(`test`)
+	for token, want := range map[string]int{`a`: 2, `b`: 1, `c`: 1, `d`: 0} {
+		if count := loaded.TokenPoolCount(1, token); count != want {
+			t.Errorf(`got %d pools for %s, want %d`, count, token, want)
+		}
+	}
+	if counts := loaded.PoolCounts[1].RecentPools; len(counts) != 1 || counts[`recent`] != 20 {
+		t.Errorf(`got the recent pools %v, want only the pool after the block 10`, counts)
+	}
+}
@@ -0,0 +1,211 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// PENDING_WRITES_PATH is the folder, relative to the lists folder, containing the journals of the
+// writes in progress. It starts with an underscore so it is not considered as a list folder.
+const PENDING_WRITES_PATH = `_pending`
+
+// TEMP_FILE_SUFFIX is added to the name of a file while its new content is being staged
+const TEMP_FILE_SUFFIX = `.tmp`
+
+// TWriteJournal lists the files, relative to the lists folder, moved in place or removed by an
+// atomic write
+type TWriteJournal struct {
+	Files   []string `json:"files"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// journalMutex avoids a recovery running while a write is in progress
+var journalMutex = sync.Mutex{}
+
+func getJournalPath(name string) string {
+	return BASE_PATH + `/lists/` + PENDING_WRITES_PATH + `/` + strings.ReplaceAll(name, `/`, `_`)
+}
+
+// writeAndSync writes a file and flushes it to the disk before returning
+func writeAndSync(filePath string, data []byte) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// replaceFile writes a file through a temporary file, so a stopped run never leaves it half written
+func replaceFile(filePath string, data []byte) error {
+	if err := writeAndSync(filePath+TEMP_FILE_SUFFIX, data); err != nil {
+		os.Remove(filePath + TEMP_FILE_SUFFIX)
+		return err
+	}
+	return os.Rename(filePath+TEMP_FILE_SUFFIX, filePath)
+}
+
+// SaveJSONFile writes the value, as indented JSON, in a file outside of the lists folder, creating
+// its folder if needed. The file is replaced like in replaceFile.
+func SaveJSONFile(filePath string, value interface{}) error {
+	if err := CreateFile(filepath.Dir(filePath)); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(value, ``, "\t")
+	if err != nil {
+		return err
+	}
+	return replaceFile(filePath, content)
+}
+
+/**************************************************************************************************
+** writeFilesAtomically replaces a set of files in the lists folder as a single unit. It is used
+** to save a list along with its per-chain splits, its changelog and its quarantine report. The
+** files are given relative to the lists folder, including the ones outside of it, see
+** getListsRelativePath. A nil content removes the file.
+** 1. Every new content is written and synced to a temporary file next to its destination. If
+**    this fails, the temporary files are removed and the previous files are left untouched.
+** 2. A journal listing the files is written in lists/_pending. From there, the write is
+**    considered done: if the process stops, RecoverPendingWrites finishes it on the next run.
+** 3. The temporary files are renamed to their destination, then the journal is removed.
+** The name identifies the journal and must be unique among the writes running at the same time.
+**************************************************************************************************/
+func writeFilesAtomically(name string, files map[string][]byte) error {
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+
+	journal := TWriteJournal{Files: []string{}}
+	for filePath, content := range files {
+		if content == nil {
+			journal.Removed = append(journal.Removed, filePath)
+		} else {
+			journal.Files = append(journal.Files, filePath)
+		}
+	}
+	sort.Strings(journal.Files)
+	sort.Strings(journal.Removed)
+
+	removeTempFiles := func() {
+		for _, filePath := range journal.Files {
+			os.Remove(BASE_PATH + `/lists/` + filePath + TEMP_FILE_SUFFIX)
+		}
+	}
+	for _, filePath := range journal.Files {
+		fullPath := BASE_PATH + `/lists/` + filePath
+		if err := CreateFile(filepath.Dir(fullPath)); err != nil {
+			removeTempFiles()
+			return err
+		}
+		if err := writeAndSync(fullPath+TEMP_FILE_SUFFIX, files[filePath]); err != nil {
+			removeTempFiles()
+			return err
+		}
+	}
+
+	journalData, err := json.Marshal(journal)
+	if err != nil {
+		removeTempFiles()
+		return err
+	}
+	if err := CreateFile(BASE_PATH + `/lists/` + PENDING_WRITES_PATH); err != nil {
+		removeTempFiles()
+		return err
+	}
+	if err := writeAndSync(getJournalPath(name), journalData); err != nil {
+		os.Remove(getJournalPath(name))
+		removeTempFiles()
+		return err
+	}
+
+	return commitJournal(getJournalPath(name), journal)
+}
+
+// commitJournal moves the staged files of a journal in place and removes the files it removes, then
+// removes the journal
+func commitJournal(journalPath string, journal TWriteJournal) error {
+	for _, filePath := range journal.Files {
+		fullPath := BASE_PATH + `/lists/` + filePath
+		if _, err := os.Stat(fullPath + TEMP_FILE_SUFFIX); errors.Is(err, os.ErrNotExist) {
+			continue // Already moved before the process stopped
+		}
+		if err := os.Rename(fullPath+TEMP_FILE_SUFFIX, fullPath); err != nil {
+			return err
+		}
+	}
+	for _, filePath := range journal.Removed {
+		if err := os.Remove(BASE_PATH + `/lists/` + filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Remove(journalPath)
+}
+
+// getListsRelativePath returns the path of a file relative to the lists folder, to write a file
+// outside of it, like a quarantine report, with writeFilesAtomically
+func getListsRelativePath(filePath string) (string, error) {
+	relativePath, err := filepath.Rel(BASE_PATH+`/lists`, filePath)
+	return filepath.ToSlash(relativePath), err
+}
+
+/**************************************************************************************************
+** RecoverPendingWrites must be called before any list is read. It completes the atomic writes
+** interrupted after their journal was written, and removes the temporary files of the writes
+** interrupted before, so every list is either in its previous or in its new version.
+**************************************************************************************************/
+func RecoverPendingWrites() error {
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+
+	journalPaths, _ := filepath.Glob(BASE_PATH + `/lists/` + PENDING_WRITES_PATH + `/*`)
+	for _, journalPath := range journalPaths {
+		content, err := os.ReadFile(journalPath)
+		if err != nil {
+			return err
+		}
+		journal := TWriteJournal{}
+		if err := json.Unmarshal(content, &journal); err != nil {
+			// The journal itself was not fully written: the write never started
+			logs.Warning(`Discarding incomplete write journal ` + filepath.Base(journalPath))
+			if err := os.Remove(journalPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := commitJournal(journalPath, journal); err != nil {
+			return err
+		}
+		logs.Warning(`Completed the interrupted write of ` + strconv.Itoa(len(journal.Files)) + ` files from ` + filepath.Base(journalPath))
+	}
+
+	for _, folder := range []string{BASE_PATH + `/lists`, QUARANTINE_PATH} {
+		err := filepath.WalkDir(folder, func(filePath string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), TEMP_FILE_SUFFIX) {
+				logs.Warning(`Removing the leftover temporary file ` + entry.Name())
+				return os.Remove(filePath)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestFiles writes files given relative to the lists folder, creating their folders
+func writeTestFiles(t *testing.T, basePath string, files map[string]string) {
+	t.Helper()
+	for filePath, content := range files {
+		if err := os.MkdirAll(filepath.Dir(basePath+`/lists/`+filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(basePath+`/lists/`+filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertListsContent checks the files of the lists folder are exactly the ones given
+func assertListsContent(t *testing.T, basePath string, files map[string]string) {
+	t.Helper()
+	got := readLists(t, basePath)
+	if len(got) != len(files) {
+		t.Errorf(`got the files %v, want %v`, got, files)
+	}
+	for filePath, content := range files {
+		if string(got[filepath.FromSlash(filePath)]) != content {
+			t.Errorf(`got %q in %s, want %q`, got[filepath.FromSlash(filePath)], filePath, content)
+		}
+	}
+}
+
+func TestRecoverPendingWritesCompletesAJournaledWrite(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	// The process stopped after the journal was written, and after test.json was moved in place
+	writeTestFiles(t, basePath, map[string]string{
+		`test.json`:          `new`,
+		`1/test.json`:        `old`,
+		`1/test.json.tmp`:    `new`,
+		`10/test.json`:       `removed`,
+		`_pending/test.json`: `{"files": ["1/test.json", "test.json"], "removed": ["10/test.json"]}`,
+	})
+
+	if err := RecoverPendingWrites(); err != nil {
+		t.Fatal(err)
+	}
+	assertListsContent(t, basePath, map[string]string{
+		`test.json`:   `new`,
+		`1/test.json`: `new`,
+	})
+}
+
+func TestRecoverPendingWritesRemovesTheTemporaryFilesWithoutJournal(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	// The process stopped while the temporary files were written, before the journal
+	writeTestFiles(t, basePath, map[string]string{
+		`test.json`:       `old`,
+		`test.json.tmp`:   `new`,
+		`1/test.json`:     `old`,
+		`1/test.json.tmp`: `ne`,
+	})
+	if err := os.MkdirAll(QUARANTINE_PATH, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(QUARANTINE_PATH+`/test.json.tmp`, []byte(`new`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecoverPendingWrites(); err != nil {
+		t.Fatal(err)
+	}
+	assertListsContent(t, basePath, map[string]string{
+		`test.json`:   `old`,
+		`1/test.json`: `old`,
+	})
+	if _, err := os.Stat(QUARANTINE_PATH + `/test.json.tmp`); !os.IsNotExist(err) {
+		t.Errorf(`the temporary quarantine report was kept: %v`, err)
+	}
+}
+
+func TestRecoverPendingWritesDiscardsATruncatedJournal(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	// The process stopped while the journal was written: the write never started
+	writeTestFiles(t, basePath, map[string]string{
+		`test.json`:          `old`,
+		`test.json.tmp`:      `new`,
+		`_pending/test.json`: `{"files": ["test.js`,
+	})
+
+	if err := RecoverPendingWrites(); err != nil {
+		t.Fatal(err)
+	}
+	assertListsContent(t, basePath, map[string]string{`test.json`: `old`})
+}
+
+func TestWriteFilesAtomicallyRemovesTheNilContents(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	writeTestFiles(t, basePath, map[string]string{
+		`test.json`:   `old`,
+		`1/test.json`: `old`,
+	})
+
+	err := writeFilesAtomically(`test.json`, map[string][]byte{
+		`test.json`:    []byte(`new`),
+		`1/test.json`:  nil,
+		`10/test.json`: nil, // Removing a file which does not exist is not an error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertListsContent(t, basePath, map[string]string{`test.json`: `new`})
+}
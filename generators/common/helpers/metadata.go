@@ -0,0 +1,195 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+)
+
+// METADATA_PATH is the folder of the metadata store, with one file per chain. The store is
+// committed with the lists by the workflows: removing an entry, or a file, makes the next run read
+// the tokens again.
+var METADATA_PATH = BASE_PATH + `/data/metadata`
+
+// METADATA_FAILURE_TTL is the time during which a token whose metadata could not be read is not
+// asked again
+var METADATA_FAILURE_TTL = 30 * 24 * time.Hour
+
+/**************************************************************************************************
+** TTokenMetadata is the entry of a token in the metadata store. Block is the head of the chain
+** when the metadata was read, FetchedAt the time it was read. Failed is set when the token did
+** not answer one of name, symbol or decimals: the fields it answered are kept, and the token is
+** asked again once METADATA_FAILURE_TTL is over. The other entries never expire, as the metadata
+** of a token does not change.
+**************************************************************************************************/
+type TTokenMetadata struct {
+	Name      string `json:"name,omitempty"`
+	Symbol    string `json:"symbol,omitempty"`
+	Decimals  uint64 `json:"decimals,omitempty"`
+	Block     uint64 `json:"block"`
+	FetchedAt int64  `json:"fetchedAt"`
+	Failed    bool   `json:"failed,omitempty"`
+}
+
+// isExpired returns true if the token should be asked again for its metadata
+func (m TTokenMetadata) isExpired(now time.Time) bool {
+	return m.Failed && now.Sub(time.Unix(m.FetchedAt, 0)) > METADATA_FAILURE_TTL
+}
+
+// metadataStore holds the files of the metadata store already loaded, per chainID
+var metadataStore = map[uint64]map[string]TTokenMetadata{}
+
+// metadataDirty holds the chains whose store changed since it was last written, see FlushMetadata
+var metadataDirty = map[uint64]bool{}
+
+// metadataStoreMutex guards metadataStore, metadataDirty and the files of the store
+var metadataStoreMutex = sync.Mutex{}
+
+func getMetadataPath(chainID uint64) string {
+	return METADATA_PATH + `/` + strconv.FormatUint(chainID, 10) + `.json`
+}
+
+// loadMetadataForChain returns the metadata store of a chain, reading its file on the first call.
+// The caller must hold metadataStoreMutex.
+func loadMetadataForChain(chainID uint64) map[string]TTokenMetadata {
+	if tokens, ok := metadataStore[chainID]; ok {
+		return tokens
+	}
+
+	tokens := map[string]TTokenMetadata{}
+	content, err := os.ReadFile(getMetadataPath(chainID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logs.Warning(`Failed to read the metadata store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+	} else if err == nil {
+		if err := json.Unmarshal(content, &tokens); err != nil {
+			logs.Warning(`Ignoring the invalid metadata store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			tokens = map[string]TTokenMetadata{}
+		}
+	}
+	metadataStore[chainID] = tokens
+	return tokens
+}
+
+// saveMetadataForChain replaces the file of the metadata store of a chain. The caller must hold
+// metadataStoreMutex.
+func saveMetadataForChain(chainID uint64) error {
+	if DRY_RUN {
+		return nil
+	}
+	if err := CreateFile(METADATA_PATH); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(metadataStore[chainID], ``, "\t")
+	if err != nil {
+		return err
+	}
+	return replaceFile(getMetadataPath(chainID), content)
+}
+
+// GetStoredMetadata returns the entry of a token in the metadata store, without asking the chain
+func GetStoredMetadata(chainID uint64, address common.Address) (TTokenMetadata, bool) {
+	metadataStoreMutex.Lock()
+	defer metadataStoreMutex.Unlock()
+	metadata, ok := loadMetadataForChain(chainID)[address.Hex()]
+	return metadata, ok
+}
+
+/**************************************************************************************************
+** FlushMetadata writes the files of the chains whose metadata store changed since the last flush.
+** It is called once each generator is done and at the end of the run, including a cancelled one,
+** so a run stopped halfway keeps what it read without rewriting the store after every batch.
+**************************************************************************************************/
+func FlushMetadata() {
+	metadataStoreMutex.Lock()
+	defer metadataStoreMutex.Unlock()
+	for chainID := range metadataDirty {
+		if err := saveMetadataForChain(chainID); err != nil {
+			logs.Error(`Failed to save the metadata store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			continue
+		}
+		delete(metadataDirty, chainID)
+	}
+}
+
+/**************************************************************************************************
+** fetchMetadata returns the metadata of the tokens from the metadata store, and only asks the
+** chain for the tokens which are new or whose entry expired. The answers are added to the store,
+** written by the next FlushMetadata.
+** The tokens the multicall could not check at all are not stored, and are missing from the result.
+** A token failing without code at the pinned block, see ethereum.PinBlocks, was deployed after it:
+** its failure is returned but not stored, as is the failure of a token whose code could not be
+** read, so the next run asks for it again. The code of the failing tokens is checked in batches,
+** see ethereum.HasCode.
+**************************************************************************************************/
+func fetchMetadata(ctx context.Context, chainID uint64, addresses []common.Address) map[string]TTokenMetadata {
+	now := time.Now()
+	result := map[string]TTokenMetadata{}
+	missingAddresses := []common.Address{}
+
+	metadataStoreMutex.Lock()
+	tokens := loadMetadataForChain(chainID)
+	for _, address := range addresses {
+		if metadata, ok := tokens[address.Hex()]; ok && !metadata.isExpired(now) {
+			result[address.Hex()] = metadata
+		} else {
+			missingAddresses = append(missingAddresses, address)
+		}
+	}
+	metadataStoreMutex.Unlock()
+
+	if len(missingAddresses) == 0 {
+		return result
+	}
+
+	blockNumber := uint64(0)
+	client := ethereum.GetRPC(chainID)
+	if client != nil {
+		blockNumber, _ = ethereum.GetHead(ctx, chainID, client)
+	}
+	erc20FromChain := ethereum.FetchBasicInformations(ctx, chainID, missingAddresses)
+	if len(erc20FromChain) == 0 {
+		return result
+	}
+
+	notDeployed := map[string]bool{}
+	if pinnedBlock := ethereum.GetPinnedBlock(chainID); pinnedBlock != nil && client != nil {
+		failedAddresses := []common.Address{}
+		for key, token := range erc20FromChain {
+			if token.Name == `` || token.Symbol == `` || token.Decimals == 0 {
+				failedAddresses = append(failedAddresses, common.HexToAddress(key))
+			}
+		}
+		hasCode := ethereum.HasCode(ctx, client, failedAddresses, pinnedBlock)
+		for _, address := range failedAddresses {
+			notDeployed[address.Hex()] = !hasCode[address.Hex()]
+		}
+	}
+
+	metadataStoreMutex.Lock()
+	defer metadataStoreMutex.Unlock()
+	tokens = loadMetadataForChain(chainID)
+	for key, token := range erc20FromChain {
+		metadata := TTokenMetadata{
+			Name:      token.Name,
+			Symbol:    token.Symbol,
+			Decimals:  token.Decimals,
+			Block:     blockNumber,
+			FetchedAt: now.Unix(),
+			Failed:    token.Name == `` || token.Symbol == `` || token.Decimals == 0,
+		}
+		result[key] = metadata
+		if !notDeployed[key] {
+			tokens[key] = metadata
+		}
+	}
+	metadataDirty[chainID] = true
+	return result
+}
@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+func TestFetchMetadataIsWrittenOnFlush(t *testing.T) {
+	backend, err := simulated.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	multicall, err := backend.DeployMulticall3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Install(1, multicall)
+	token, err := backend.DeployERC20(`Wrapped Ether`, `WETH`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	METADATA_PATH = t.TempDir()
+	delete(metadataStore, 1)
+	metadata := fetchMetadata(context.Background(), 1, []common.Address{token})
+	if entry := metadata[token.Hex()]; entry.Symbol != `WETH` || entry.Decimals != 18 || entry.Failed {
+		t.Fatalf(`got the metadata %+v`, entry)
+	}
+	if _, err := os.Stat(getMetadataPath(1)); !os.IsNotExist(err) {
+		t.Fatalf(`the store was written before the flush: %v`, err)
+	}
+
+	FlushMetadata()
+	delete(metadataStore, 1)
+	if entry, ok := GetStoredMetadata(1, token); !ok || entry.Symbol != `WETH` {
+		t.Fatalf(`got the stored metadata %+v %v after the flush`, entry, ok)
+	}
+	if metadataDirty[1] {
+		t.Error(`the store is still dirty after the flush`)
+	}
+}
+
+// tNotDeployedBackend is a simulated chain on which a token has no code, as if it was deployed
+// after the pinned block. It counts the calls to eth_getCode.
+type tNotDeployedBackend struct {
+	*simulated.TBackend
+	notDeployed common.Address
+	codeAtCalls *int
+}
+
+func (b tNotDeployedBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	*b.codeAtCalls++
+	if contract == b.notDeployed {
+		return nil, nil
+	}
+	return b.TBackend.CodeAt(ctx, contract, blockNumber)
+}
+
+func (b tNotDeployedBackend) CallContractWithOverrides(ctx context.Context, msg goEthereum.CallMsg, blockNumber *big.Int, overrides map[common.Address]gethclient.OverrideAccount) ([]byte, error) {
+	withoutCode := map[common.Address]gethclient.OverrideAccount{b.notDeployed: {Code: []byte{}}}
+	for address, account := range overrides {
+		withoutCode[address] = account
+	}
+	return b.TBackend.CallContractWithOverrides(ctx, msg, blockNumber, withoutCode)
+}
+
+func TestFetchMetadataDoesNotStoreTheTokensDeployedAfterThePinnedBlock(t *testing.T) {
+	backend, err := simulated.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	multicall, err := backend.DeployMulticall3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployed, err := backend.DeployRevertingERC20()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notDeployed, err := backend.DeployRevertingERC20()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codeAtCalls := 0
+	ethereum.SetRPC(1, tNotDeployedBackend{TBackend: backend, notDeployed: notDeployed, codeAtCalls: &codeAtCalls}, multicall)
+	head, err := backend.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethereum.PinBlock(1, head)
+	t.Cleanup(func() { delete(ethereum.PINNED_BLOCKS, 1) })
+
+	METADATA_PATH = t.TempDir()
+	delete(metadataStore, 1)
+	metadata := fetchMetadata(context.Background(), 1, []common.Address{deployed, notDeployed})
+	for _, address := range []common.Address{deployed, notDeployed} {
+		if entry, ok := metadata[address.Hex()]; !ok || !entry.Failed {
+			t.Errorf(`got the metadata %+v %v for %s, want a failure`, entry, ok, address.Hex())
+		}
+	}
+	if _, ok := GetStoredMetadata(1, deployed); !ok {
+		t.Error(`the failure of the token deployed at the pinned block is not stored`)
+	}
+	if entry, ok := GetStoredMetadata(1, notDeployed); ok {
+		t.Errorf(`the failure of the token without code at the pinned block is stored: %+v`, entry)
+	}
+
+	// The code of the failing tokens is checked in one call, without eth_getCode
+	if codeAtCalls != 0 {
+		t.Errorf(`got %d calls to eth_getCode, want the batched check`, codeAtCalls)
+	}
+}
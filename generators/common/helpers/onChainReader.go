@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -13,18 +14,24 @@ import (
 /**************************************************************************************************
 * The RetrieveBasicInformations function reads the token list and returns a list of tokens with
 * their basic informations (name, symbol, logoURI, decimals, chainID). These informations are
-* retrieved from an on-chain reader.
+* retrieved from the metadata store, which only asks the on-chain reader for the tokens it does
+* not know yet, see fetchMetadata. The proxies of the tokens are read along, once per run, see
+* RetrieveProxies.
 *************************************************************************************************/
-func RetrieveBasicInformations(chainID uint64, addresses []common.Address) map[string]*ethereum.TERC20 {
+func RetrieveBasicInformations(ctx context.Context, chainID uint64, addresses []common.Address) map[string]*ethereum.TERC20 {
 	erc20Map := make(map[string]*ethereum.TERC20)
 	missingAddresses := []common.Address{}
 
 	if !chains.IsChainIDSupported(chainID) {
 		return erc20Map
 	}
+	RetrieveProxies(ctx, chainID, addresses)
 
 	for _, v := range addresses {
-		if token, ok := ALL_EXISTING_TOKENS[chainID][v.Hex()]; ok {
+		allExistingTokensMutex.RLock()
+		token, ok := ALL_EXISTING_TOKENS[chainID][v.Hex()]
+		allExistingTokensMutex.RUnlock()
+		if ok {
 			if token.Name == `` && token.Symbol == `` && token.Decimals == 0 {
 				logs.Warning(`[ALL_EXISTING_TOKENS]: Missing name, symbol and decimals for token:`, token.Address, `on chain:`, chainID)
 			} else if token.Name == `` && token.Symbol == `` {
@@ -51,8 +58,17 @@ func RetrieveBasicInformations(chainID uint64, addresses []common.Address) map[s
 			missingAddresses = append(missingAddresses, v)
 		}
 	}
-	erc20FromChain := ethereum.FetchBasicInformations(chainID, missingAddresses)
-	for k, v := range erc20FromChain {
+	erc20FromStore := fetchMetadata(ctx, chainID, missingAddresses)
+	allExistingTokensMutex.Lock()
+	defer allExistingTokensMutex.Unlock()
+	for k, metadata := range erc20FromStore {
+		v := &ethereum.TERC20{
+			Address:  common.HexToAddress(k),
+			Name:     metadata.Name,
+			Symbol:   metadata.Symbol,
+			Decimals: metadata.Decimals,
+			ChainID:  chainID,
+		}
 		erc20Map[k] = v
 		if _, ok := ALL_EXISTING_TOKENS[chainID]; !ok {
 			ALL_EXISTING_TOKENS[chainID] = make(map[string]models.TokenListToken)
@@ -111,7 +127,7 @@ func getExistingLogo(chainID uint64, lookingFor common.Address, slice []models.T
  * basic informations (name, symbol, logoURI, decimals, chainID). These informations are retrieved
  * from an on-chain reader.
  *************************************************************************************************/
-func GetTokensFromList(tokensFromList []models.TokenListToken) []models.TokenListToken {
+func GetTokensFromList(ctx context.Context, tokensFromList []models.TokenListToken) []models.TokenListToken {
 	tokens := []models.TokenListToken{}
 	grouped := GroupByChainID(tokensFromList)
 
@@ -121,7 +137,7 @@ func GetTokensFromList(tokensFromList []models.TokenListToken) []models.TokenLis
 		}
 
 		tokensForChain = append(tokensForChain, chains.CHAINS[chainID].ExtraTokens...)
-		tokensInfo := RetrieveBasicInformations(chainID, tokensForChain)
+		tokensInfo := RetrieveBasicInformations(ctx, chainID, tokensForChain)
 		for _, existingToken := range tokensForChain {
 			if token, ok := tokensInfo[existingToken.Hex()]; ok {
 				if newToken, err := SetToken(
@@ -146,10 +162,10 @@ func GetTokensFromList(tokensFromList []models.TokenListToken) []models.TokenLis
 ** tokens with their basic informations (name, symbol, logoURI, decimals, chainID). These
 ** informations are retrieved from an on-chain reader.
 *************************************************************************************************/
-func GetTokensFromAddresses(chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
+func GetTokensFromAddresses(ctx context.Context, chainID uint64, tokenAddresses []common.Address) []models.TokenListToken {
 	tokenList := []models.TokenListToken{}
 	tokenAddresses = append(tokenAddresses, chains.CHAINS[chainID].ExtraTokens...)
-	tokensInfo := RetrieveBasicInformations(chainID, tokenAddresses)
+	tokensInfo := RetrieveBasicInformations(ctx, chainID, tokenAddresses)
 	for _, address := range tokenAddresses {
 		if token, ok := tokensInfo[address.Hex()]; ok {
 			if newToken, err := SetToken(
@@ -168,13 +184,14 @@ func GetTokensFromAddresses(chainID uint64, tokenAddresses []common.Address) []m
 }
 
 func GetTokensFromAddressesWithIcons(
+	ctx context.Context,
 	chainID uint64,
 	tokenAddresses []common.Address,
 	tokenIcons map[string]string,
 ) []models.TokenListToken {
 	tokenList := []models.TokenListToken{}
 	tokenAddresses = append(tokenAddresses, chains.CHAINS[chainID].ExtraTokens...)
-	tokensInfo := RetrieveBasicInformations(chainID, tokenAddresses)
+	tokensInfo := RetrieveBasicInformations(ctx, chainID, tokenAddresses)
 
 	for _, address := range tokenAddresses {
 		if token, ok := tokensInfo[address.Hex()]; ok {
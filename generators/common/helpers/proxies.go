@@ -0,0 +1,187 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// PROXIES_PATH is the folder of the proxies store, with one file per chain
+var PROXIES_PATH = BASE_PATH + `/data/proxies`
+
+/**************************************************************************************************
+** TStoredProxy is the entry of a token in the proxies store, which only holds the proxies. When
+** the implementation of a proxy changes between two runs, the previous one is kept in
+** PreviousImplementation, along with the time the upgrade was found in UpgradedAt.
+**************************************************************************************************/
+type TStoredProxy struct {
+	Kind                   ethereum.TProxyKind `json:"kind"`
+	Implementation         string              `json:"implementation"`
+	Admin                  string              `json:"admin,omitempty"`
+	PreviousImplementation string              `json:"previousImplementation,omitempty"`
+	UpgradedAt             int64               `json:"upgradedAt,omitempty"`
+}
+
+// proxiesStore holds the files of the proxies store already loaded, per chainID
+var proxiesStore = map[uint64]map[string]TStoredProxy{}
+
+// proxiesReadInRun holds the tokens whose proxy was read during this run, per chainID
+var proxiesReadInRun = map[uint64]map[string]bool{}
+
+// proxiesStoreMutex guards proxiesStore, proxiesReadInRun and the files of the store
+var proxiesStoreMutex = sync.Mutex{}
+
+func getProxiesPath(chainID uint64) string {
+	return PROXIES_PATH + `/` + strconv.FormatUint(chainID, 10) + `.json`
+}
+
+// loadProxiesForChain returns the proxies store of a chain, reading its file on the first call.
+// The caller must hold proxiesStoreMutex.
+func loadProxiesForChain(chainID uint64) map[string]TStoredProxy {
+	if proxies, ok := proxiesStore[chainID]; ok {
+		return proxies
+	}
+
+	proxies := map[string]TStoredProxy{}
+	content, err := os.ReadFile(getProxiesPath(chainID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logs.Warning(`Failed to read the proxies store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+	} else if err == nil {
+		if err := json.Unmarshal(content, &proxies); err != nil {
+			logs.Warning(`Ignoring the invalid proxies store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+			proxies = map[string]TStoredProxy{}
+		}
+	}
+	proxiesStore[chainID] = proxies
+	return proxies
+}
+
+// GetStoredProxy returns the entry of a token in the proxies store, without asking the chain
+func GetStoredProxy(chainID uint64, address common.Address) (TStoredProxy, bool) {
+	proxiesStoreMutex.Lock()
+	defer proxiesStoreMutex.Unlock()
+	proxy, ok := loadProxiesForChain(chainID)[address.Hex()]
+	return proxy, ok
+}
+
+// addressOrEmpty returns the address, or an empty string for the zero address
+func addressOrEmpty(address common.Address) string {
+	if address == (common.Address{}) {
+		return ``
+	}
+	return address.Hex()
+}
+
+/**************************************************************************************************
+** RetrieveProxies reads the proxies of the tokens, once per run, see ethereum.FetchProxies, and
+** updates the proxies store with them. A proxy whose implementation changed since the last run
+** is flagged: a warning is logged and the previous implementation is kept in the store. The
+** tokens which could not be read keep their entry.
+**************************************************************************************************/
+func RetrieveProxies(ctx context.Context, chainID uint64, addresses []common.Address) {
+	missingAddresses := []common.Address{}
+	proxiesStoreMutex.Lock()
+	for _, address := range addresses {
+		if !proxiesReadInRun[chainID][address.Hex()] {
+			missingAddresses = append(missingAddresses, address)
+		}
+	}
+	proxiesStoreMutex.Unlock()
+	if len(missingAddresses) == 0 {
+		return
+	}
+
+	proxies := ethereum.FetchProxies(ctx, chainID, missingAddresses)
+
+	proxiesStoreMutex.Lock()
+	defer proxiesStoreMutex.Unlock()
+	if _, ok := proxiesReadInRun[chainID]; !ok {
+		proxiesReadInRun[chainID] = make(map[string]bool)
+	}
+	stored := loadProxiesForChain(chainID)
+	hasChanged := false
+	for key, proxy := range proxies {
+		proxiesReadInRun[chainID][key] = true
+		previous, isStored := stored[key]
+		next := TStoredProxy{
+			Kind:                   proxy.Kind,
+			Implementation:         addressOrEmpty(proxy.Implementation),
+			Admin:                  addressOrEmpty(proxy.Admin),
+			PreviousImplementation: previous.PreviousImplementation,
+			UpgradedAt:             previous.UpgradedAt,
+		}
+
+		switch {
+		case !proxy.IsProxy() && isStored:
+			logs.Warning(`[PROXY] ` + key + ` on chain ` + strconv.FormatUint(chainID, 10) + ` is not a proxy anymore`)
+			delete(stored, key)
+			hasChanged = true
+			continue
+		case !proxy.IsProxy():
+			continue
+		case isStored && previous.Implementation != next.Implementation:
+			logs.Warning(`[PROXY] The implementation of ` + key + ` on chain ` + strconv.FormatUint(chainID, 10) + ` changed from ` + previous.Implementation + ` to ` + next.Implementation)
+			next.PreviousImplementation = previous.Implementation
+			next.UpgradedAt = time.Now().Unix()
+		}
+		if !isStored || previous != next {
+			stored[key] = next
+			hasChanged = true
+		}
+	}
+	if hasChanged && !DRY_RUN {
+		if err := SaveJSONFile(getProxiesPath(chainID), stored); err != nil {
+			logs.Error(`Failed to save the proxies store of chain ` + strconv.FormatUint(chainID, 10) + `: ` + err.Error())
+		}
+	}
+}
+
+// PROXY_EXTENSIONS are the extensions set by setProxyExtensions
+var PROXY_EXTENSIONS = []string{`isProxy`, `implementation`, `admin`, `previousImplementation`, `upgradedAt`}
+
+/**************************************************************************************************
+** setProxyExtensions sets the isProxy, implementation and admin extensions of a token from the
+** proxies store, and removes them from the tokens which are not known as proxies. An upgraded proxy
+** also gets the previousImplementation and upgradedAt extensions, upgradedAt being the unix time
+** the upgrade was found at, set as a float64 to compare equal to the value read back from a list.
+** isProxy is only ever set to true: the store does not keep the tokens read which are not proxies,
+** so a token without the key is not known as a proxy, whether it was read or not.
+**************************************************************************************************/
+func setProxyExtensions(token models.TokenListToken) models.TokenListToken {
+	proxy, isProxy := GetStoredProxy(token.ChainID, common.HexToAddress(token.Address))
+	if !isProxy && token.Extensions == nil {
+		return token
+	}
+
+	extensions := map[string]interface{}{}
+	for key, value := range token.Extensions {
+		if !Includes(PROXY_EXTENSIONS, key) {
+			extensions[key] = value
+		}
+	}
+	if isProxy {
+		extensions[`isProxy`] = true
+		extensions[`implementation`] = proxy.Implementation
+		if proxy.Admin != `` {
+			extensions[`admin`] = proxy.Admin
+		}
+		if proxy.PreviousImplementation != `` {
+			extensions[`previousImplementation`] = proxy.PreviousImplementation
+			extensions[`upgradedAt`] = float64(proxy.UpgradedAt)
+		}
+	}
+	if len(extensions) == 0 {
+		extensions = nil
+	}
+	token.Extensions = extensions
+	return token
+}
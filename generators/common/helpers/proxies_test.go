@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/models"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+func TestSetProxyExtensionsShowsTheUpgrades(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644)
+	token := REPRODUCIBLE_TOKENS[0]
+	proxiesStoreMutex.Lock()
+	proxiesStore[token.ChainID] = map[string]TStoredProxy{
+		common.HexToAddress(token.Address).Hex(): {
+			Kind:                   ethereum.ProxyEIP1967,
+			Implementation:         `0x0000000000000000000000000000000000000002`,
+			PreviousImplementation: `0x0000000000000000000000000000000000000001`,
+			UpgradedAt:             1700000000,
+		},
+	}
+	proxiesStoreMutex.Unlock()
+	t.Cleanup(func() {
+		proxiesStoreMutex.Lock()
+		delete(proxiesStore, token.ChainID)
+		proxiesStoreMutex.Unlock()
+	})
+
+	extensions := setProxyExtensions(token).Extensions
+	if extensions[`previousImplementation`] != `0x0000000000000000000000000000000000000001` || extensions[`upgradedAt`] != float64(1700000000) {
+		t.Fatalf(`got the extensions %v, want the previous implementation and the time of the upgrade`, extensions)
+	}
+
+	if result := saveTestList(t, REPRODUCIBLE_TOKENS[:4]); !result.Changed {
+		t.Fatal(`the first save did not write the list`)
+	}
+	if result := saveTestList(t, REPRODUCIBLE_TOKENS[:4]); result.Changed {
+		t.Errorf(`the upgrade extensions changed the list read back: %+v`, result.Diff)
+	}
+}
+
+func TestRetrieveProxiesFlagsTheUpgrades(t *testing.T) {
+	withTempBasePath(t, time.Now())
+	backend, err := simulated.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	multicall, err := backend.DeployMulticall3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Install(1, multicall)
+	deploy := func(address common.Address, err error) common.Address {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return address
+	}
+	implementation := deploy(backend.DeployBytes32ERC20(`Proxied`, `PRX`, 18))
+	nextImplementation := deploy(backend.DeployBytes32ERC20(`Proxied`, `PRX`, 18))
+	admin := common.HexToAddress(`0xad`)
+	proxy := deploy(backend.DeployProxy(ethereum.ProxyEIP1967, implementation, admin))
+	token := deploy(backend.DeployBytes32ERC20(`Not a proxy`, `NOPRX`, 18))
+
+	// newRun forgets the proxies read and loaded, as a new run of the generators would
+	newRun := func() {
+		proxiesStoreMutex.Lock()
+		delete(proxiesStore, 1)
+		delete(proxiesReadInRun, 1)
+		proxiesStoreMutex.Unlock()
+	}
+	newRun()
+	t.Cleanup(newRun)
+
+	RetrieveProxies(context.Background(), 1, []common.Address{proxy, token})
+	newRun()
+	if stored, ok := GetStoredProxy(1, proxy); !ok || stored.Implementation != implementation.Hex() || stored.Admin != admin.Hex() || stored.PreviousImplementation != `` {
+		t.Fatalf(`got the stored proxy %+v`, stored)
+	}
+	if _, ok := GetStoredProxy(1, token); ok {
+		t.Error(`the token which is not a proxy is in the store`)
+	}
+
+	if err := backend.UpgradeProxy(proxy, nextImplementation); err != nil {
+		t.Fatal(err)
+	}
+	RetrieveProxies(context.Background(), 1, []common.Address{proxy, token})
+	newRun()
+	stored, _ := GetStoredProxy(1, proxy)
+	if stored.Implementation != nextImplementation.Hex() || stored.PreviousImplementation != implementation.Hex() || stored.UpgradedAt == 0 {
+		t.Fatalf(`got the stored proxy %+v after the upgrade`, stored)
+	}
+
+	extensions := setProxyExtensions(models.TokenListToken{Address: proxy.Hex(), ChainID: 1}).Extensions
+	if extensions[`isProxy`] != true || extensions[`implementation`] != nextImplementation.Hex() || extensions[`previousImplementation`] != implementation.Hex() {
+		t.Errorf(`got the extensions %v`, extensions)
+	}
+	if extensions := setProxyExtensions(models.TokenListToken{Address: token.Hex(), ChainID: 1}).Extensions; extensions != nil {
+		t.Errorf(`got the extensions %v for the token which is not a proxy`, extensions)
+	}
+}
@@ -0,0 +1,302 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SCHEMA_PATH is the JSON schema the lists must follow, also used by scripts/verify.mjs
+var SCHEMA_PATH = BASE_PATH + `/scripts/schema.json`
+
+/**************************************************************************************************
+** TSchema is a JSON schema, draft-07, reduced to the keywords used by scripts/schema.json: the
+** types, the properties of the objects, the items of the arrays, the bounds of the strings, the
+** numbers, the arrays and the objects, the patterns, the date-time and uri formats, anyOf, enum,
+** and the references to the definitions of the root schema. The other keywords are ignored.
+**************************************************************************************************/
+type TSchema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Pattern              string             `json:"pattern"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Items                *TSchema           `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Properties           map[string]TSchema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	PropertyNames        *TSchema           `json:"propertyNames"`
+	MaxProperties        *int               `json:"maxProperties"`
+	AnyOf                []TSchema          `json:"anyOf"`
+	Definitions          map[string]TSchema `json:"definitions"`
+
+	definitions       map[string]TSchema
+	patternRe         *regexp.Regexp
+	additional        *TSchema
+	additionalAllowed bool
+}
+
+var (
+	listSchema     *TSchema
+	listSchemaErr  error
+	listSchemaOnce sync.Once
+)
+
+// LoadListSchema returns the schema of SCHEMA_PATH, read on the first call
+func LoadListSchema() (*TSchema, error) {
+	listSchemaOnce.Do(func() {
+		content, err := os.ReadFile(SCHEMA_PATH)
+		if err != nil {
+			listSchemaErr = err
+			return
+		}
+		schema := &TSchema{}
+		if err := json.Unmarshal(content, schema); err != nil {
+			listSchemaErr = errors.New(`invalid schema ` + SCHEMA_PATH + `: ` + err.Error())
+			return
+		}
+		for _, name := range sortedKeys(schema.Definitions) {
+			definition := schema.Definitions[name]
+			if listSchemaErr = definition.compile(schema.Definitions); listSchemaErr != nil {
+				return
+			}
+			schema.Definitions[name] = definition
+		}
+		if listSchemaErr = schema.compile(schema.Definitions); listSchemaErr == nil {
+			listSchema = schema
+		}
+	})
+	return listSchema, listSchemaErr
+}
+
+// compile checks the patterns of the schema and links it to the definitions of the root schema
+func (s *TSchema) compile(definitions map[string]TSchema) error {
+	s.definitions = definitions
+	if s.Pattern != `` {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return errors.New(`invalid pattern ` + s.Pattern + `: ` + err.Error())
+		}
+		s.patternRe = re
+	}
+
+	s.additionalAllowed = true
+	if len(s.AdditionalProperties) > 0 {
+		if err := json.Unmarshal(s.AdditionalProperties, &s.additionalAllowed); err != nil {
+			s.additionalAllowed = true
+			s.additional = &TSchema{}
+			if err := json.Unmarshal(s.AdditionalProperties, s.additional); err != nil {
+				return errors.New(`invalid additionalProperties: ` + err.Error())
+			}
+		}
+	}
+
+	children := []*TSchema{s.Items, s.PropertyNames, s.additional}
+	for i := range s.AnyOf {
+		children = append(children, &s.AnyOf[i])
+	}
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		if err := child.compile(definitions); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		property := s.Properties[name]
+		if err := property.compile(definitions); err != nil {
+			return err
+		}
+		s.Properties[name] = property
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map in alphabetical order
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// typeOf returns the JSON type of a value decoded by encoding/json
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return `null`
+	case bool:
+		return `boolean`
+	case float64:
+		if v == math.Trunc(v) {
+			return `integer`
+		}
+		return `number`
+	case string:
+		return `string`
+	case []interface{}:
+		return `array`
+	case map[string]interface{}:
+		return `object`
+	}
+	return `unknown`
+}
+
+// joinPath returns the path of a property, or of an item when key is an int, in the JSON document
+func joinPath(path string, key interface{}) string {
+	switch k := key.(type) {
+	case int:
+		return path + `[` + strconv.Itoa(k) + `]`
+	case string:
+		if path == `` {
+			return k
+		}
+		return path + `.` + k
+	}
+	return path
+}
+
+/**************************************************************************************************
+** Validate checks a document, decoded by encoding/json in an interface{}, against the schema and
+** returns all the errors found, each prefixed with the path of the value in the document.
+**************************************************************************************************/
+func (s TSchema) Validate(value interface{}) []error {
+	return s.validate(value, ``)
+}
+
+func (s TSchema) validate(value interface{}, path string) []error {
+	fail := func(message string) []error {
+		if path == `` {
+			return []error{errors.New(message)}
+		}
+		return []error{errors.New(path + `: ` + message)}
+	}
+
+	if s.Ref != `` {
+		definition, ok := s.definitions[strings.TrimPrefix(s.Ref, `#/definitions/`)]
+		if !ok {
+			return fail(`unknown reference ` + s.Ref)
+		}
+		return definition.validate(value, path)
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, schema := range s.AnyOf {
+			if len(schema.validate(value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fail(`does not match any of the allowed schemas`)
+		}
+	}
+
+	if s.Type != `` {
+		valueType := typeOf(value)
+		if valueType != s.Type && !(s.Type == `number` && valueType == `integer`) {
+			return fail(`expected ` + s.Type + `, got ` + valueType)
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if allowed == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail(`not one of the allowed values`)
+		}
+	}
+
+	errs := []error{}
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = append(errs, fail(`shorter than `+strconv.Itoa(*s.MinLength)+` characters`)...)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = append(errs, fail(`longer than `+strconv.Itoa(*s.MaxLength)+` characters`)...)
+		}
+		if s.patternRe != nil && !s.patternRe.MatchString(v) {
+			errs = append(errs, fail(`does not match `+s.Pattern)...)
+		}
+		switch s.Format {
+		case `date-time`:
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				errs = append(errs, fail(`not a date-time`)...)
+			}
+		case `uri`:
+			if parsed, err := url.Parse(v); err != nil || parsed.Scheme == `` {
+				errs = append(errs, fail(`not a uri`)...)
+			}
+		}
+
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			errs = append(errs, fail(`lower than `+strconv.FormatFloat(*s.Minimum, 'f', -1, 64))...)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			errs = append(errs, fail(`greater than `+strconv.FormatFloat(*s.Maximum, 'f', -1, 64))...)
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs = append(errs, fail(`fewer than `+strconv.Itoa(*s.MinItems)+` items`)...)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs = append(errs, fail(`more than `+strconv.Itoa(*s.MaxItems)+` items`)...)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, s.Items.validate(item, joinPath(path, i))...)
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fail(`missing property `+name)...)
+			}
+		}
+		if s.MaxProperties != nil && len(v) > *s.MaxProperties {
+			errs = append(errs, fail(`more than `+strconv.Itoa(*s.MaxProperties)+` properties`)...)
+		}
+		for _, name := range sortedKeys(v) {
+			if s.PropertyNames != nil {
+				errs = append(errs, s.PropertyNames.validate(name, joinPath(path, name))...)
+			}
+			if property, ok := s.Properties[name]; ok {
+				errs = append(errs, property.validate(v[name], joinPath(path, name))...)
+			} else if !s.additionalAllowed {
+				errs = append(errs, fail(`unexpected property `+name)...)
+			} else if s.additional != nil {
+				errs = append(errs, s.additional.validate(v[name], joinPath(path, name))...)
+			}
+		}
+	}
+	return errs
+}
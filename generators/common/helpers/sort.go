@@ -13,12 +13,14 @@ type tokenSorter struct {
 
 type By func(p1, p2 *models.TokenListToken) bool
 
+// Sort sorts the tokens with the by closure. The sort is stable: the tokens by considers equal
+// keep their order.
 func (by By) Sort(tokens []models.TokenListToken) {
 	ps := &tokenSorter{
 		tokens: tokens,
 		by:     by,
 	}
-	sort.Sort(ps)
+	sort.Stable(ps)
 }
 
 // Len is part of sort.Interface.
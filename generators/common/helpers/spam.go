@@ -0,0 +1,338 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// QUARANTINE_PATH is the folder of the quarantine reports, with one file per list
+var QUARANTINE_PATH = BASE_PATH + `/data/quarantine`
+
+// SPAM_URL_REGEX matches the names and symbols advertising a website, a common pattern of the spam
+// tokens airdropped to the wallets
+var SPAM_URL_REGEX = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/|\bvisit\b|\bclaim\b)`)
+
+/**************************************************************************************************
+** CANONICAL_SYMBOLS is the registry of the symbols of the well-known tokens, per chainID: only the
+** addresses listed here may use one of these symbols on the chain. The symbols are compared in
+** upper case, once their look-alike characters are replaced, see symbolSkeleton. A token removed
+** by mistake is fixed by adding its address here, or by adjusting the filters.
+**************************************************************************************************/
+var CANONICAL_SYMBOLS = map[uint64]map[string][]common.Address{
+	1: {
+		`USDC`: {common.HexToAddress(`0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`)},
+		`USDT`: {common.HexToAddress(`0xdAC17F958D2ee523a2206206994597C13D831ec7`)},
+		`DAI`: {
+			common.HexToAddress(`0x6B175474E89094C44Da98b954EedeAC495271d0F`),
+			common.HexToAddress(`0x89d24A6b4CcB1B6fAA2625fE562bDD9a23260359`), // Single collateral Dai
+		},
+		`WETH`: {common.HexToAddress(`0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2`)},
+		`WBTC`: {common.HexToAddress(`0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599`)},
+	},
+	10: {
+		`USDC`: {
+			common.HexToAddress(`0x0b2C639c533813f4Aa9D7837CAf62653d097Ff85`),
+			common.HexToAddress(`0x7F5c764cBc14f9669B88837ca1490cCa17c31607`), // Bridged USDC
+		},
+		`USDT`: {common.HexToAddress(`0x94b008aA00579c1307B0EF2c499aD98a8ce58e58`)},
+		`DAI`:  {common.HexToAddress(`0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1`)},
+		`WETH`: {common.HexToAddress(`0x4200000000000000000000000000000000000006`)},
+		`WBTC`: {common.HexToAddress(`0x68f180fcCe6836688e9084f035309E29Bf0A2095`)},
+		`OP`:   {common.HexToAddress(`0x4200000000000000000000000000000000000042`)},
+	},
+	137: {
+		`USDC`: {
+			common.HexToAddress(`0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359`),
+			common.HexToAddress(`0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174`), // Bridged USDC, renamed USDC.e by SetToken
+		},
+		`USDC.E`: {common.HexToAddress(`0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174`)},
+		`USDT`:   {common.HexToAddress(`0xc2132D05D31c914a87C6611C10748AEb04B58e8F`)},
+		`DAI`:    {common.HexToAddress(`0x8f3Cf7ad23Cd3CaDbD9735AFf958023239c6A063`)},
+		`WETH`:   {common.HexToAddress(`0x7ceB23fD6bC0adD59E62ac25578270cFf1b9f619`)},
+		`WBTC`:   {common.HexToAddress(`0x1BFD67037B42Cf73acF2047067bd4F2C47D9BfD6`)},
+	},
+	8453: {
+		`USDC`:  {common.HexToAddress(`0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913`)},
+		`USDBC`: {common.HexToAddress(`0xd9aAEc86B65D86f6A7B5B1b0c42FFA531710b6CA`)},
+		`DAI`:   {common.HexToAddress(`0x50c5725949A6F0c72E6C4a641F24049A917DB0Cb`)},
+		`WETH`:  {common.HexToAddress(`0x4200000000000000000000000000000000000006`)},
+	},
+	42161: {
+		`USDC`: {
+			common.HexToAddress(`0xaf88d065e77c8cC2239327C5EDb3A432268e5831`),
+			common.HexToAddress(`0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8`), // Bridged USDC
+		},
+		`USDT`: {common.HexToAddress(`0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9`)},
+		`DAI`:  {common.HexToAddress(`0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1`)},
+		`WETH`: {common.HexToAddress(`0x82aF49447D8a07e3bd95BD0d56f35241523fBab1`)},
+		`WBTC`: {common.HexToAddress(`0x2f2a2543B76A4166549F7aaB2e75Bef0aefC5B0f`)},
+		`ARB`:  {common.HexToAddress(`0x912CE59144191C1204E64559FE8253a0e49E6548`)},
+	},
+}
+
+// HOMOGLYPHS are the Cyrillic and Greek letters looking like a Latin letter, with this letter
+var HOMOGLYPHS = map[rune]rune{
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C',
+	'Т': 'T', 'У': 'Y', 'Х': 'X', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J', 'а': 'a', 'е': 'e', 'о': 'o',
+	'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ј': 'j',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N',
+	'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X', 'ο': 'o', 'ν': 'v',
+}
+
+// isInvisible returns true for the zero-width and formatting characters, the combining grapheme
+// joiner and the Hangul fillers, which are not displayed
+func isInvisible(character rune) bool {
+	return unicode.Is(unicode.Cf, character) || character == '\u034F' || character == '\u115F' || character == '\u3164'
+}
+
+/**************************************************************************************************
+** symbolSkeleton returns the symbol as it looks to a user, in upper case: the invisible characters
+** are removed, the fullwidth forms are replaced by their ASCII version and the HOMOGLYPHS by the
+** Latin letter they look like. Two symbols with the same skeleton cannot be told apart.
+**************************************************************************************************/
+func symbolSkeleton(symbol string) string {
+	builder := strings.Builder{}
+	for _, character := range symbol {
+		switch {
+		case isInvisible(character):
+			continue
+		case character >= '\uFF01' && character <= '\uFF5E':
+			builder.WriteRune(character - 0xFEE0)
+		case HOMOGLYPHS[character] != 0:
+			builder.WriteRune(HOMOGLYPHS[character])
+		default:
+			builder.WriteRune(character)
+		}
+	}
+	return strings.ToUpper(builder.String())
+}
+
+// isASCII returns true if the string only has ASCII characters
+func isASCII(value string) bool {
+	for _, character := range value {
+		if character > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// isCanonicalToken returns true if the token is one of the addresses allowed to use the symbol
+func isCanonicalToken(chainID uint64, address common.Address, symbol string) bool {
+	for _, canonicalAddress := range CANONICAL_SYMBOLS[chainID][symbol] {
+		if canonicalAddress == address {
+			return true
+		}
+	}
+	return false
+}
+
+/**************************************************************************************************
+** TSpamFilter is a heuristic of the spam filters: Score is added to the spam score of the tokens
+** for which Matches returns true. A token whose score reaches SPAM_DROP_SCORE is removed from the
+** list, and a token whose score reaches SPAM_TAG_SCORE is tagged as suspicious.
+**************************************************************************************************/
+type TSpamFilter struct {
+	Name    string
+	Score   float64
+	Matches func(token models.TokenListToken) bool
+}
+
+// SPAM_DROP_SCORE is the spam score from which a token is removed from the lists
+var SPAM_DROP_SCORE = 1.0
+
+// SPAM_TAG_SCORE is the spam score from which a token is tagged as suspicious
+var SPAM_TAG_SCORE = 0.5
+
+/**************************************************************************************************
+** SPAM_FILTERS are the heuristics run on every token before a list is saved: a name or a symbol
+** advertising a website, invisible characters, a symbol of CANONICAL_SYMBOLS used by another
+** address, and a symbol written with look-alike letters to pass for a Latin one.
+**************************************************************************************************/
+var SPAM_FILTERS = []TSpamFilter{
+	{
+		Name:  `urlName`,
+		Score: 1,
+		Matches: func(token models.TokenListToken) bool {
+			return SPAM_URL_REGEX.MatchString(token.Name) || SPAM_URL_REGEX.MatchString(token.Symbol)
+		},
+	},
+	{
+		Name:  `invisibleCharacters`,
+		Score: 1,
+		Matches: func(token models.TokenListToken) bool {
+			return strings.IndexFunc(token.Name+token.Symbol, isInvisible) >= 0
+		},
+	},
+	{
+		Name:  `impersonation`,
+		Score: 1,
+		Matches: func(token models.TokenListToken) bool {
+			symbol := symbolSkeleton(token.Symbol)
+			if _, ok := CANONICAL_SYMBOLS[token.ChainID][symbol]; !ok {
+				return false
+			}
+			return !isCanonicalToken(token.ChainID, common.HexToAddress(token.Address), symbol)
+		},
+	},
+	{
+		Name:  `homoglyphs`,
+		Score: 0.5,
+		Matches: func(token models.TokenListToken) bool {
+			skeleton := symbolSkeleton(token.Symbol)
+			return skeleton != strings.ToUpper(token.Symbol) && isASCII(skeleton)
+		},
+	},
+}
+
+// TQuarantineEntry is a token the spam filters dropped or tagged, with the filters it matched
+type TQuarantineEntry struct {
+	ChainID uint64   `json:"chainId"`
+	Address string   `json:"address"`
+	Name    string   `json:"name"`
+	Symbol  string   `json:"symbol"`
+	Score   float64  `json:"score"`
+	Filters []string `json:"filters"`
+	Action  string   `json:"action"`
+}
+
+const (
+	QuarantineDropped = `dropped`
+	QuarantineTagged  = `tagged`
+)
+
+// scoreSpam returns the spam score of a token and the names of the filters it matched
+func scoreSpam(token models.TokenListToken) (float64, []string) {
+	score := 0.0
+	matched := []string{}
+	for _, filter := range SPAM_FILTERS {
+		if filter.Matches(token) {
+			score += filter.Score
+			matched = append(matched, filter.Name)
+		}
+	}
+	return score, matched
+}
+
+/**************************************************************************************************
+** filterSpamTokens runs SPAM_FILTERS on the tokens of the supported chains: the tokens reaching
+** SPAM_DROP_SCORE are removed from the map, the ones reaching SPAM_TAG_SCORE are tagged with
+** models.TagSuspicious. The decisions are returned sorted by chainID and address.
+**************************************************************************************************/
+func filterSpamTokens(tokens map[string]models.TokenListToken) []TQuarantineEntry {
+	entries := []TQuarantineEntry{}
+	for key, token := range tokens {
+		if !chains.IsChainIDSupported(token.ChainID) {
+			continue
+		}
+		score, matched := scoreSpam(token)
+		if score < SPAM_TAG_SCORE {
+			continue
+		}
+		entry := TQuarantineEntry{
+			ChainID: token.ChainID,
+			Address: token.Address,
+			Name:    token.Name,
+			Symbol:  token.Symbol,
+			Score:   score,
+			Filters: matched,
+			Action:  QuarantineTagged,
+		}
+		if score >= SPAM_DROP_SCORE {
+			entry.Action = QuarantineDropped
+			delete(tokens, key)
+		} else {
+			token.Tags = models.SortTags(append(token.Tags, models.TagSuspicious))
+			tokens[key] = token
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChainID != entries[j].ChainID {
+			return entries[i].ChainID < entries[j].ChainID
+		}
+		return entries[i].Address < entries[j].Address
+	})
+	return entries
+}
+
+/**************************************************************************************************
+** marshalQuarantineReport returns the path, relative to the lists folder, and the content of the
+** quarantine report of a list, in QUARANTINE_PATH, so the maintainers can review the decisions
+** of the spam filters. It is written along with the list by writeFilesAtomically, or alone by
+** writeQuarantineReport when the list does not change. The decisions on the chains left out of
+** the run are kept from the previous report. The content is nil when no decision is left, to
+** remove the report.
+**************************************************************************************************/
+func marshalQuarantineReport(filePath string, entries []TQuarantineEntry) (string, []byte, error) {
+	reportPath := QUARANTINE_PATH + `/` + path.Base(filePath)
+	relativePath, err := getListsRelativePath(reportPath)
+	if err != nil {
+		return ``, nil, err
+	}
+
+	report := []TQuarantineEntry{}
+	if content, err := os.ReadFile(reportPath); err == nil {
+		previousEntries := []TQuarantineEntry{}
+		if err := json.Unmarshal(content, &previousEntries); err != nil {
+			logs.Warning(`Replacing the invalid quarantine report of ` + filePath + `: ` + err.Error())
+		}
+		for _, entry := range previousEntries {
+			if !chains.IsChainIDSupported(entry.ChainID) {
+				report = append(report, entry)
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return ``, nil, err
+	}
+	report = append(report, entries...)
+	if len(report) == 0 {
+		return relativePath, nil, nil
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].ChainID != report[j].ChainID {
+			return report[i].ChainID < report[j].ChainID
+		}
+		return report[i].Address < report[j].Address
+	})
+
+	content, err := json.MarshalIndent(report, ``, "\t")
+	return relativePath, content, err
+}
+
+/**************************************************************************************************
+** writeQuarantineReport writes the quarantine report of a list whose files are not written, as its
+** version did not change. Nothing is written when the report is the one already on disk.
+**************************************************************************************************/
+func writeQuarantineReport(ctx context.Context, filePath string, entries []TQuarantineEntry) error {
+	reportPath, reportData, err := marshalQuarantineReport(filePath, entries)
+	if err != nil {
+		logs.Error(err)
+		return err
+	}
+	previousData, err := os.ReadFile(BASE_PATH + `/lists/` + reportPath)
+	if (err == nil && reportData != nil && bytes.Equal(previousData, reportData)) || (errors.Is(err, os.ErrNotExist) && reportData == nil) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := writeFilesAtomically(filePath, map[string][]byte{reportPath: reportData}); err != nil {
+		logs.Error(err)
+		return err
+	}
+	return nil
+}
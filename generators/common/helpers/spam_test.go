@@ -0,0 +1,187 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// SPAM_TOKEN claims a website in its name, which is enough to be dropped by the spam filters
+var SPAM_TOKEN = models.TokenListToken{Address: `0x1111111111111111111111111111111111111111`, Name: `Visit www.claim-rewards.com`, Symbol: `CLAIM`, Decimals: 18, ChainID: 1}
+
+func TestSpamFiltersOnlyRunOnTheProcessedChains(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	previousList := `{"name": "Test", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [
+		{"address": "0x2222222222222222222222222222222222222222", "name": "Visit www.other-chain.com", "symbol": "OTHER", "decimals": 18, "chainId": 10}
+	]}`
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(previousList), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previousReport := []TQuarantineEntry{{ChainID: 10, Address: `0x2222222222222222222222222222222222222222`, Action: QuarantineTagged}}
+	if err := SaveJSONFile(QUARANTINE_PATH+`/test.json`, previousReport); err != nil {
+		t.Fatal(err)
+	}
+	if err := chains.SetChainFilter([]uint64{1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { chains.SetChainFilter(nil) })
+
+	saveTestList(t, append([]models.TokenListToken{SPAM_TOKEN}, REPRODUCIBLE_TOKENS[:4]...))
+	tokens := LoadTokenListFromJsonFile(`test.json`).PreviousTokensMap
+	if _, ok := tokens[GetKey(10, common.HexToAddress(`0x2222222222222222222222222222222222222222`))]; !ok {
+		t.Error(`the token of the chain left out of the run was filtered`)
+	}
+	if _, ok := tokens[GetKey(1, common.HexToAddress(SPAM_TOKEN.Address))]; ok {
+		t.Error(`the spam token was not filtered`)
+	}
+
+	content, err := os.ReadFile(QUARANTINE_PATH + `/test.json`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := []TQuarantineEntry{}
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 2 || report[0].ChainID != 1 || report[0].Action != QuarantineDropped || report[1].ChainID != 10 {
+		t.Errorf(`got the quarantine report %+v`, report)
+	}
+}
+
+func TestDryRunDoesNotWriteTheQuarantineReport(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	DRY_RUN = true
+	t.Cleanup(func() { DRY_RUN = false })
+
+	saveTestList(t, append([]models.TokenListToken{SPAM_TOKEN}, REPRODUCIBLE_TOKENS...))
+	if _, err := os.Stat(QUARANTINE_PATH + `/test.json`); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf(`the quarantine report was written in dry-run: %v`, err)
+	}
+}
+
+func TestQuarantineReportIsWrittenWhenTheListDoesNotChange(t *testing.T) {
+	t.Cleanup(func() { DRY_RUN = false })
+	for _, dryRun := range []bool{false, true} {
+		basePath := withTempBasePath(t, time.Now())
+		if err := os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		saveTestList(t, REPRODUCIBLE_TOKENS)
+		DRY_RUN = dryRun
+		if result := saveTestList(t, append([]models.TokenListToken{SPAM_TOKEN}, REPRODUCIBLE_TOKENS...)); result.Changed {
+			t.Errorf(`the list changed with the spam token, dry-run %v`, dryRun)
+		}
+		DRY_RUN = false
+		_, err := os.Stat(QUARANTINE_PATH + `/test.json`)
+		if !dryRun && err != nil {
+			t.Errorf(`the quarantine report of the unchanged list was not written: %v`, err)
+		}
+		if dryRun && !errors.Is(err, os.ErrNotExist) {
+			t.Errorf(`the quarantine report was written in dry-run: %v`, err)
+		}
+	}
+}
+
+func TestQuarantineReportIsRemovedWithoutDecisions(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saveTestList(t, append([]models.TokenListToken{SPAM_TOKEN}, REPRODUCIBLE_TOKENS...))
+	if _, err := os.Stat(QUARANTINE_PATH + `/test.json`); err != nil {
+		t.Fatalf(`the quarantine report was not written: %v`, err)
+	}
+
+	saveTestList(t, REPRODUCIBLE_TOKENS[1:])
+	if _, err := os.Stat(QUARANTINE_PATH + `/test.json`); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf(`the quarantine report without decisions was kept: %v`, err)
+	}
+	if pending, _ := os.ReadDir(basePath + `/lists/` + PENDING_WRITES_PATH); len(pending) != 0 {
+		t.Errorf(`%d journals were left`, len(pending))
+	}
+}
+
+func TestSymbolSkeleton(t *testing.T) {
+	for symbol, skeleton := range map[string]string{
+		`USDC`:            `USDC`,
+		`usdc`:            `USDC`,
+		`USDС`:            `USDC`, // Cyrillic Es
+		`ՍSDC`:            `ՍSDC`, // Armenian Seh, not a known look-alike
+		`WΕΤΗ`:            `WETH`, // Greek Epsilon, Tau and Eta
+		`ＵＳＤＴ`:            `USDT`, // Fullwidth forms
+		"US\u200BDC":      `USDC`, // Zero width space
+		"\u2066DAI\u2069": `DAI`,  // Isolates
+		"\u3164WBTC":      `WBTC`, // Hangul filler
+		`USDC.e`:          `USDC.E`,
+		`ΩMEGA`:           `ΩMEGA`,
+		`Ѕhіb`:            `SHIB`, // Cyrillic Dze and I
+		`1INCH`:           `1INCH`,
+		`ｃｒｖ`:             `CRV`,
+		"D\u034FA\u115FI": `DAI`, // Combining grapheme joiner and Hangul choseong filler
+	} {
+		if got := symbolSkeleton(symbol); got != skeleton {
+			t.Errorf(`got the skeleton %q for %q, want %q`, got, symbol, skeleton)
+		}
+	}
+}
+
+func TestScoreSpam(t *testing.T) {
+	const otherAddress = `0x3333333333333333333333333333333333333333`
+	for _, test := range []struct {
+		name    string
+		token   models.TokenListToken
+		score   float64
+		filters []string
+		action  string
+	}{
+		{`canonical USDC`, models.TokenListToken{ChainID: 1, Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`}, 0, []string{}, ``},
+		{`second canonical DAI`, models.TokenListToken{ChainID: 1, Address: `0x89d24A6b4CcB1B6fAA2625fE562bDD9a23260359`, Name: `Dai Stablecoin v1.0`, Symbol: `DAI`}, 0, []string{}, ``},
+		{`bridged USDC on chain 137`, models.TokenListToken{ChainID: 137, Address: `0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174`, Name: `USD Coin (PoS)`, Symbol: `USDC`}, 0, []string{}, ``},
+		{`bridged USDC.e on chain 137`, models.TokenListToken{ChainID: 137, Address: `0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174`, Name: `USD Coin (PoS)`, Symbol: `USDC.e`}, 0, []string{}, ``},
+		{`USDC on a chain without registry`, models.TokenListToken{ChainID: 56, Address: otherAddress, Name: `USD Coin`, Symbol: `USDC`}, 0, []string{}, ``},
+		{`unrelated token`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Curve DAO Token`, Symbol: `CRV`}, 0, []string{}, ``},
+		{`non Latin symbol`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Omega`, Symbol: `ΩMEGA`}, 0, []string{}, ``},
+		{`fake USDC on chain 1`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `USD Coin`, Symbol: `USDC`}, 1, []string{`impersonation`}, QuarantineDropped},
+		{`fake USDC in lower case`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `USD Coin`, Symbol: `usdc`}, 1, []string{`impersonation`}, QuarantineDropped},
+		{`mainnet USDC address on chain 10`, models.TokenListToken{ChainID: 10, Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`}, 1, []string{`impersonation`}, QuarantineDropped},
+		{`Cyrillic USDC`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `USD Coin`, Symbol: `USDС`}, 1.5, []string{`impersonation`, `homoglyphs`}, QuarantineDropped},
+		{`Greek WETH`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Wrapped Ether`, Symbol: `WΕΤΗ`}, 1.5, []string{`impersonation`, `homoglyphs`}, QuarantineDropped},
+		{`fullwidth USDT`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Tether USD`, Symbol: `ＵＳＤＴ`}, 1.5, []string{`impersonation`, `homoglyphs`}, QuarantineDropped},
+		{`zero width USDC`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `USD Coin`, Symbol: "US\u200BDC"}, 2.5, []string{`invisibleCharacters`, `impersonation`, `homoglyphs`}, QuarantineDropped},
+		{`zero width name`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: "Curve\u200B DAO", Symbol: `CRV`}, 1, []string{`invisibleCharacters`}, QuarantineDropped},
+		{`Cyrillic symbol of an unknown token`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Shiba`, Symbol: `Ѕhіb`}, 0.5, []string{`homoglyphs`}, QuarantineTagged},
+		{`website in the name`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Visit www.claim-rewards.com`, Symbol: `REWARD`}, 1, []string{`urlName`}, QuarantineDropped},
+		{`Telegram link in the symbol`, models.TokenListToken{ChainID: 1, Address: otherAddress, Name: `Reward`, Symbol: `t.me/reward`}, 1, []string{`urlName`}, QuarantineDropped},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			score, filters := scoreSpam(test.token)
+			if score != test.score || !reflect.DeepEqual(filters, test.filters) {
+				t.Errorf(`got the score %v from %v, want %v from %v`, score, filters, test.score, test.filters)
+			}
+
+			key := GetKey(test.token.ChainID, common.HexToAddress(test.token.Address))
+			tokens := map[string]models.TokenListToken{key: test.token}
+			entries := filterSpamTokens(tokens)
+			action := ``
+			if len(entries) == 1 {
+				action = entries[0].Action
+			}
+			if action != test.action {
+				t.Errorf(`got the action %q, want %q`, action, test.action)
+			}
+			if _, kept := tokens[key]; kept != (test.action != QuarantineDropped) {
+				t.Errorf(`the token is kept: %v, with the action %q`, kept, action)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/migratooor/tokenLists/generators/common/models"
@@ -14,12 +15,21 @@ func InitSyncMap[T any](chainIDs map[uint64]T) *sync.Map {
 	return &tokensForChainIDSyncMap
 }
 
+// ExtractSyncMap returns the tokens of all the chains of the map, in the ascending order of the
+// chainIDs so the result does not depend on the order of the map
 func ExtractSyncMap(mapper *sync.Map) []models.TokenListToken {
-	tokenList := []models.TokenListToken{}
+	chainIDs := []uint64{}
 	mapper.Range(func(chainID, syncMapRaw interface{}) bool {
-		syncMap, _ := syncMapRaw.([]models.TokenListToken)
-		tokenList = append(tokenList, syncMap...)
+		chainIDs = append(chainIDs, chainID.(uint64))
 		return true
 	})
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+
+	tokenList := []models.TokenListToken{}
+	for _, chainID := range chainIDs {
+		syncMapRaw, _ := mapper.Load(chainID)
+		syncMap, _ := syncMapRaw.([]models.TokenListToken)
+		tokenList = append(tokenList, syncMap...)
+	}
 	return tokenList
 }
@@ -2,6 +2,8 @@ package helpers
 
 import (
 	"errors"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/migratooor/tokenLists/generators/common/chains"
@@ -11,6 +13,10 @@ import (
 
 var ALL_EXISTING_TOKENS = map[uint64]map[string]models.TokenListToken{}
 
+// allExistingTokensMutex guards ALL_EXISTING_TOKENS, which is shared by the generators running
+// in parallel
+var allExistingTokensMutex = sync.RWMutex{}
+
 func init() {
 	chainCoins := []models.TokenListToken{}
 
@@ -46,12 +52,16 @@ func SetToken(
 	chainID uint64, decimals int,
 ) (models.TokenListToken, error) {
 	token := models.TokenListToken{}
+	name, symbol = strings.TrimSpace(name), strings.TrimSpace(symbol)
 	if name == `` {
 		return token, errors.New(`token name is empty`)
 	}
 	if symbol == `` {
 		return token, errors.New(`token symbol is empty`)
 	}
+	if err := validateSymbol(symbol); err != nil {
+		return token, errors.New(`token ` + err.Error())
+	}
 	if decimals == 0 {
 		return token, errors.New(`token decimals is 0`)
 	}
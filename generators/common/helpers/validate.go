@@ -0,0 +1,340 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// MAX_SYMBOL_LENGTH is the length, in characters, above which a symbol is refused
+const MAX_SYMBOL_LENGTH = 64
+
+// validateSymbol returns an error if the symbol is empty, too long, has spaces around it or has
+// characters which cannot be printed
+func validateSymbol(symbol string) error {
+	switch {
+	case symbol == ``:
+		return errors.New(`missing symbol`)
+	case utf8.RuneCountInString(symbol) > MAX_SYMBOL_LENGTH:
+		return errors.New(`symbol longer than ` + strconv.Itoa(MAX_SYMBOL_LENGTH) + ` characters`)
+	case strings.TrimSpace(symbol) != symbol:
+		return errors.New(`symbol starts or ends with a space`)
+	}
+	for _, character := range symbol {
+		if !unicode.IsPrint(character) {
+			return errors.New(`symbol has a character which cannot be printed`)
+		}
+	}
+	return nil
+}
+
+/**************************************************************************************************
+** ValidateTokenList performs the semantic checks on a token list, the ones the schema cannot
+** express: the list must have a name and at least one token, and every token must have a
+** checksummed address, a chainID, a name, a valid symbol, see validateSymbol, decimals in the
+** uint8 range, tags defined in the tags of the list, and must not be listed twice for the same
+** chain.
+** All the problems found are returned, an empty slice means the list is valid.
+**************************************************************************************************/
+func ValidateTokenList(tokenList models.TokenListData[models.TokenListToken]) []error {
+	errs := []error{}
+	if strings.TrimSpace(tokenList.Name) == `` {
+		errs = append(errs, errors.New(`list has no name`))
+	}
+	if len(tokenList.Tokens) == 0 {
+		errs = append(errs, errors.New(`list has no tokens`))
+	}
+
+	seen := make(map[string]bool)
+	for i, token := range tokenList.Tokens {
+		prefix := `tokens[` + strconv.Itoa(i) + `] (` + token.Address + `): `
+		if !common.IsHexAddress(token.Address) {
+			errs = append(errs, errors.New(prefix+`invalid address`))
+		} else if common.HexToAddress(token.Address).Hex() != token.Address {
+			errs = append(errs, errors.New(prefix+`address is not checksummed`))
+		}
+		if token.ChainID == 0 {
+			errs = append(errs, errors.New(prefix+`missing chainId`))
+		}
+		if strings.TrimSpace(token.Name) == `` {
+			errs = append(errs, errors.New(prefix+`missing name`))
+		}
+		if err := validateSymbol(token.Symbol); err != nil {
+			errs = append(errs, errors.New(prefix+err.Error()))
+		}
+		if token.Decimals < 0 || token.Decimals > 255 {
+			errs = append(errs, errors.New(prefix+`decimals out of range`))
+		}
+		for _, tag := range token.Tags {
+			if _, ok := tokenList.Tags[tag]; !ok {
+				errs = append(errs, errors.New(prefix+`tag `+tag+` is not defined in the list`))
+			}
+		}
+
+		key := GetKey(token.ChainID, common.HexToAddress(token.Address))
+		if seen[key] {
+			errs = append(errs, errors.New(prefix+`duplicated on chain `+strconv.FormatUint(token.ChainID, 10)))
+		}
+		seen[key] = true
+	}
+	return errs
+}
+
+/**************************************************************************************************
+** ValidateTokenListContent checks the content of a list file against the schema of SCHEMA_PATH,
+** then with ValidateTokenList. It returns the list read from the content along with the errors.
+**************************************************************************************************/
+func ValidateTokenListContent(content []byte) (models.TokenListData[models.TokenListToken], []error) {
+	var tokenList models.TokenListData[models.TokenListToken]
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return tokenList, []error{err}
+	}
+	if err := json.Unmarshal(content, &tokenList); err != nil {
+		return tokenList, []error{err}
+	}
+
+	schema, err := LoadListSchema()
+	if err != nil {
+		return tokenList, []error{err}
+	}
+	errs := schema.Validate(document)
+	return tokenList, append(errs, ValidateTokenList(tokenList)...)
+}
+
+// getChainIDOfFile returns the chainID of a list per chainID, from its folder, and false for the
+// other lists
+func getChainIDOfFile(filePath string) (uint64, string, bool) {
+	folder, name, found := strings.Cut(filePath, `/`)
+	if !found {
+		return 0, ``, false
+	}
+	chainID, err := strconv.ParseUint(folder, 10, 64)
+	if err != nil {
+		return 0, ``, false
+	}
+	return chainID, name, true
+}
+
+/**************************************************************************************************
+** validateChainFile checks that a list per chainID only has tokens of its chain, and that each of
+** them is in the main list with the same fields.
+**************************************************************************************************/
+func validateChainFile(chainID uint64, tokenList, mainList models.TokenListData[models.TokenListToken]) []error {
+	errs := []error{}
+	mainTokens := make(map[string]models.TokenListToken)
+	for _, token := range mainList.Tokens {
+		mainTokens[GetKey(token.ChainID, common.HexToAddress(token.Address))] = token
+	}
+	for i, token := range tokenList.Tokens {
+		prefix := `tokens[` + strconv.Itoa(i) + `] (` + token.Address + `): `
+		if token.ChainID != chainID {
+			errs = append(errs, errors.New(prefix+`not on chain `+strconv.FormatUint(chainID, 10)))
+			continue
+		}
+		mainToken, ok := mainTokens[GetKey(token.ChainID, common.HexToAddress(token.Address))]
+		if !ok {
+			errs = append(errs, errors.New(prefix+`missing from the main list`))
+		} else if fields := diffTokenFields(mainToken, token); len(fields) > 0 {
+			errs = append(errs, errors.New(prefix+`differs from the main list on `+strings.Join(sortedFieldNames(fields), `, `)))
+		}
+	}
+	return errs
+}
+
+// compareVersions returns -1, 0 or 1 if a is lower than, equal to or greater than b
+func compareVersions(a, b models.TTokenListVersion) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// readHeadFile returns the content of a file of the lists folder in the last commit of the
+// repository, and false if the file is not in it or git cannot read the repository
+func readHeadFile(filePath string) ([]byte, bool) {
+	content, err := exec.Command(`git`, `-C`, BASE_PATH, `show`, `HEAD:lists/`+filePath).Output()
+	return content, err == nil
+}
+
+/**************************************************************************************************
+** validateVersionAgainstHead checks that the version of a list is not lower than the one of the
+** same file in the last commit of the repository. A file missing from the last commit, or a
+** repository git cannot read, is not checked.
+**************************************************************************************************/
+func validateVersionAgainstHead(filePath string, version models.TTokenListVersion) error {
+	content, ok := readHeadFile(filePath)
+	if !ok {
+		return nil
+	}
+	var headList models.TokenListData[models.TokenListToken]
+	if err := json.Unmarshal(content, &headList); err != nil {
+		return nil
+	}
+	if compareVersions(version, headList.Version) < 0 {
+		return errors.New(`version ` + version.String() + ` is lower than ` + headList.Version.String() + ` in HEAD`)
+	}
+	return nil
+}
+
+/**************************************************************************************************
+** validateTokenListFiles checks the files of a list prepared by marshalTokenList before they are
+** written: each of them must pass ValidateTokenListContent, and the lists per chainID must be
+** subsets of the main list, filePath.
+**************************************************************************************************/
+func validateTokenListFiles(filePath string, files map[string][]byte) error {
+	mainList, errs := ValidateTokenListContent(files[filePath])
+	for _, name := range sortedKeys(files) {
+		if name == filePath {
+			continue
+		}
+		chainID, _, ok := getChainIDOfFile(name)
+		if !ok {
+			continue
+		}
+		tokenList, chainErrs := ValidateTokenListContent(files[name])
+		chainErrs = append(chainErrs, validateChainFile(chainID, tokenList, mainList)...)
+		for _, err := range chainErrs {
+			errs = append(errs, errors.New(name+`: `+err.Error()))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return errors.New(filePath + ` fails validation and is not saved: ` + strings.Join(messages, `; `))
+}
+
+/**************************************************************************************************
+** ListTokenListFiles returns the path, relative to the lists folder, of every token list file:
+** the aggregated lists at the root and the lists per chainID in the sub-folders. The summary and
+** the folders starting with an underscore are not token lists and are skipped.
+**************************************************************************************************/
+func ListTokenListFiles() ([]string, error) {
+	root := BASE_PATH + `/lists`
+	files := []string{}
+	err := filepath.WalkDir(root, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if filePath != root && (strings.HasPrefix(entry.Name(), `_`) || strings.HasPrefix(entry.Name(), `.`)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(filePath) != `.json` || entry.Name() == `summary.json` {
+			return nil
+		}
+		relativePath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relativePath))
+		return nil
+	})
+	return files, err
+}
+
+/**************************************************************************************************
+** validateFileContent validates the content of a file of the lists folder with
+** ValidateTokenListContent. A list per chainID must also be a subset of its main list, read with
+** readFile.
+**************************************************************************************************/
+func validateFileContent(filePath string, content []byte, readFile func(filePath string) ([]byte, bool)) (models.TokenListData[models.TokenListToken], []error) {
+	tokenList, errs := ValidateTokenListContent(content)
+	if chainID, name, ok := getChainIDOfFile(filePath); ok {
+		mainContent, ok := readFile(name)
+		if !ok {
+			errs = append(errs, errors.New(`no main list `+name))
+		} else {
+			var mainList models.TokenListData[models.TokenListToken]
+			if err := json.Unmarshal(mainContent, &mainList); err != nil {
+				errs = append(errs, errors.New(`invalid main list `+name+`: `+err.Error()))
+			} else {
+				errs = append(errs, validateChainFile(chainID, tokenList, mainList)...)
+			}
+		}
+	}
+	return tokenList, errs
+}
+
+// readListFile returns the content of a file of the lists folder, and false if it cannot be read
+func readListFile(filePath string) ([]byte, bool) {
+	content, err := os.ReadFile(BASE_PATH + `/lists/` + filePath)
+	return content, err == nil
+}
+
+// TOKEN_INDEX_REGEX matches the index of a token in the errors, which changes with the tokens added
+// before it
+var TOKEN_INDEX_REGEX = regexp.MustCompile(`tokens\[\d+\]`)
+
+/**************************************************************************************************
+** splitLegacyErrors separates the errors of a list which the same file already had in the last
+** commit of the repository. Most of the lists were written before these rules existed, with
+** symbols having spaces around them or timestamps which are not RFC 3339 dates: these problems
+** are left as they are, and go away as the generators write the lists again. Any new problem,
+** like a new token with an invalid symbol, is still an error. The errors are compared without the
+** index of the token, and each error of the last commit only excuses one error of the file.
+**************************************************************************************************/
+func splitLegacyErrors(filePath string, errs []error) ([]error, []error) {
+	if len(errs) == 0 {
+		return errs, nil
+	}
+	headContent, ok := readHeadFile(filePath)
+	if !ok {
+		return errs, nil
+	}
+	_, headErrs := validateFileContent(filePath, headContent, readHeadFile)
+	headErrCount := make(map[string]int)
+	for _, err := range headErrs {
+		headErrCount[TOKEN_INDEX_REGEX.ReplaceAllString(err.Error(), `tokens[]`)]++
+	}
+
+	newErrs, legacyErrs := []error{}, []error{}
+	for _, err := range errs {
+		key := TOKEN_INDEX_REGEX.ReplaceAllString(err.Error(), `tokens[]`)
+		if headErrCount[key] > 0 {
+			headErrCount[key]--
+			legacyErrs = append(legacyErrs, err)
+		} else {
+			newErrs = append(newErrs, err)
+		}
+	}
+	return newErrs, legacyErrs
+}
+
+/**************************************************************************************************
+** ValidateTokenListFile reads a list from the lists folder and validates it with
+** validateFileContent, and checks that its version is not lower than in the last commit. The
+** errors the file already had in the last commit are returned apart, see splitLegacyErrors.
+**************************************************************************************************/
+func ValidateTokenListFile(filePath string) ([]error, []error) {
+	content, err := os.ReadFile(BASE_PATH + `/lists/` + filePath)
+	if err != nil {
+		return []error{err}, nil
+	}
+	tokenList, errs := validateFileContent(filePath, content, readListFile)
+	if err := validateVersionAgainstHead(filePath, tokenList.Version); err != nil {
+		errs = append(errs, err)
+	}
+	return splitLegacyErrors(filePath, errs)
+}
@@ -0,0 +1,211 @@
+package helpers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// commitLists commits the lists folder of the temporary base path, as the last commit the
+// validation compares the lists with
+func commitLists(t *testing.T, basePath string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{`init`, `-q`},
+		{`add`, `lists`},
+		{`-c`, `user.name=test`, `-c`, `user.email=test@localhost`, `commit`, `-q`, `-m`, `lists`},
+	} {
+		if output, err := exec.Command(`git`, append([]string{`-C`, basePath}, args...)...).CombinedOutput(); err != nil {
+			t.Skipf(`git is not available: %v %s`, err, output)
+		}
+	}
+}
+
+func TestValidateTokenListFileLeavesTheProblemsOfTheLastCommit(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	legacyList := `{"name": "Test", "logoURI": "https://example.com/logo.svg", "keywords": [], "timestamp": "27/06/2023 10:42:18", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [
+		{"address": "0x6B175474E89094C44Da98b954EedeAC495271d0F", "name": "Dai Stablecoin", "symbol": "DAI ", "decimals": 18, "chainId": 1}
+	]}`
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(legacyList), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commitLists(t, basePath)
+
+	errs, legacyErrs := ValidateTokenListFile(`test.json`)
+	if len(errs) != 0 || len(legacyErrs) != 2 {
+		t.Fatalf(`got the errors %v and the legacy errors %v`, errs, legacyErrs)
+	}
+
+	newToken := `{"address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "name": "USD Coin", "symbol": " USDC", "decimals": 6, "chainId": 1},
+		{"address": "0x6B175474E89094C44Da98b954EedeAC495271d0F"`
+	withNewToken := strings.Replace(legacyList, `{"address": "0x6B175474E89094C44Da98b954EedeAC495271d0F"`, newToken, 1)
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(withNewToken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	errs, legacyErrs = ValidateTokenListFile(`test.json`)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`) || len(legacyErrs) != 2 {
+		t.Errorf(`got the errors %v and the legacy errors %v`, errs, legacyErrs)
+	}
+}
+
+// validTestList returns a list passing ValidateTokenList, changed by the tests
+func validTestList() models.TokenListData[models.TokenListToken] {
+	return models.TokenListData[models.TokenListToken]{
+		Name:      `Test`,
+		LogoURI:   `https://example.com/logo.svg`,
+		Keywords:  []string{`test`},
+		Timestamp: `2024-01-02T03:04:05Z`,
+		Version:   models.TTokenListVersion{Major: 1},
+		Tags:      map[string]models.TTag{models.TagStablecoin: models.TAGS[models.TagStablecoin]},
+		Tokens: []models.TokenListToken{
+			{Address: `0x6B175474E89094C44Da98b954EedeAC495271d0F`, Name: `Dai Stablecoin`, Symbol: `DAI`, Decimals: 18, ChainID: 1, Tags: []string{models.TagStablecoin}},
+			{Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`, Decimals: 6, ChainID: 1},
+			{Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`, Decimals: 6, ChainID: 10},
+		},
+	}
+}
+
+func TestValidateTokenList(t *testing.T) {
+	if errs := ValidateTokenList(validTestList()); len(errs) != 0 {
+		t.Fatalf(`the valid list has the errors %v`, errs)
+	}
+	for _, test := range []struct {
+		name   string
+		change func(tokenList *models.TokenListData[models.TokenListToken])
+		error  string
+	}{
+		{`no name`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Name = ` ` }, `list has no name`},
+		{`no tokens`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens = nil }, `list has no tokens`},
+		{`invalid address`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].Address = `0xA0b8` }, `invalid address`},
+		{`address not checksummed`, func(tokenList *models.TokenListData[models.TokenListToken]) {
+			tokenList.Tokens[1].Address = strings.ToLower(tokenList.Tokens[1].Address)
+		}, `address is not checksummed`},
+		{`no chainId`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].ChainID = 0 }, `missing chainId`},
+		{`no name for a token`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].Name = `` }, `missing name`},
+		{`no symbol`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].Symbol = `` }, `missing symbol`},
+		{`symbol too long`, func(tokenList *models.TokenListData[models.TokenListToken]) {
+			tokenList.Tokens[1].Symbol = strings.Repeat(`Ü`, MAX_SYMBOL_LENGTH+1)
+		}, `symbol longer than 64 characters`},
+		{`space around the symbol`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].Symbol = `USDC ` }, `symbol starts or ends with a space`},
+		{`symbol not printable`, func(tokenList *models.TokenListData[models.TokenListToken]) {
+			tokenList.Tokens[1].Symbol = "US\u200BDC"
+		}, `symbol has a character which cannot be printed`},
+		{`decimals above 255`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].Decimals = 256 }, `decimals out of range`},
+		{`negative decimals`, func(tokenList *models.TokenListData[models.TokenListToken]) { tokenList.Tokens[1].Decimals = -1 }, `decimals out of range`},
+		{`tag not defined`, func(tokenList *models.TokenListData[models.TokenListToken]) {
+			tokenList.Tokens[1].Tags = []string{models.TagLPToken}
+		}, `tag lp_token is not defined in the list`},
+		{`duplicated token`, func(tokenList *models.TokenListData[models.TokenListToken]) {
+			tokenList.Tokens[2].ChainID = 1
+		}, `tokens[2] (0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48): duplicated on chain 1`},
+		{`duplicated token not checksummed`, func(tokenList *models.TokenListData[models.TokenListToken]) {
+			tokenList.Tokens[2] = tokenList.Tokens[0]
+			tokenList.Tokens[2].Address = strings.ToLower(tokenList.Tokens[2].Address)
+		}, `duplicated on chain 1`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tokenList := validTestList()
+			test.change(&tokenList)
+			errs := ValidateTokenList(tokenList)
+			found := false
+			for _, err := range errs {
+				found = found || strings.Contains(err.Error(), test.error)
+			}
+			if !found {
+				t.Errorf(`got the errors %v, want %q`, errs, test.error)
+			}
+		})
+	}
+}
+
+func TestValidateTokenListFileChecksTheVersionAgainstTheLastCommit(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	tokenList := validTestList()
+	tokenList.Version = models.TTokenListVersion{Major: 1, Minor: 2}
+	if err := SaveJSONFile(basePath+`/lists/test.json`, tokenList); err != nil {
+		t.Fatal(err)
+	}
+	commitLists(t, basePath)
+
+	for _, test := range []struct {
+		version models.TTokenListVersion
+		valid   bool
+	}{
+		{models.TTokenListVersion{Major: 1, Minor: 2}, true},
+		{models.TTokenListVersion{Major: 2}, true},
+		{models.TTokenListVersion{Major: 1, Minor: 1, Patch: 9}, false},
+	} {
+		tokenList.Version = test.version
+		if err := SaveJSONFile(basePath+`/lists/test.json`, tokenList); err != nil {
+			t.Fatal(err)
+		}
+		errs, _ := ValidateTokenListFile(`test.json`)
+		if test.valid && len(errs) != 0 {
+			t.Errorf(`version %s: got the errors %v`, test.version.String(), errs)
+		}
+		if !test.valid && (len(errs) != 1 || !strings.Contains(errs[0].Error(), `is lower than 1.2.0 in HEAD`)) {
+			t.Errorf(`version %s: got the errors %v`, test.version.String(), errs)
+		}
+	}
+}
+
+func TestValidateTokenListFileChecksTheListsPerChain(t *testing.T) {
+	basePath := withTempBasePath(t, time.Now())
+	mainList := validTestList()
+	if err := SaveJSONFile(basePath+`/lists/test.json`, mainList); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		tokens []models.TokenListToken
+		error  string
+	}{
+		{`subset`, mainList.Tokens[:2], ``},
+		{`token of another chain`, mainList.Tokens[1:3], `tokens[1] (0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48): not on chain 1`},
+		{`token missing from the main list`, []models.TokenListToken{
+			{Address: `0xdAC17F958D2ee523a2206206994597C13D831ec7`, Name: `Tether USD`, Symbol: `USDT`, Decimals: 6, ChainID: 1},
+		}, `missing from the main list`},
+		{`token different from the main list`, []models.TokenListToken{
+			{Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`, Decimals: 18, ChainID: 1},
+		}, `differs from the main list on decimals`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			chainList := validTestList()
+			chainList.Tokens = test.tokens
+			if err := SaveJSONFile(basePath+`/lists/1/test.json`, chainList); err != nil {
+				t.Fatal(err)
+			}
+			errs, _ := ValidateTokenListFile(`1/test.json`)
+			if test.error == `` && len(errs) != 0 {
+				t.Errorf(`got the errors %v`, errs)
+			}
+			if test.error != `` && (len(errs) != 1 || !strings.Contains(errs[0].Error(), test.error)) {
+				t.Errorf(`got the errors %v, want %q`, errs, test.error)
+			}
+		})
+	}
+}
+
+func TestSaveTokenListInJsonFileRefusesAnInvalidList(t *testing.T) {
+	basePath := withTempBasePath(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err := os.WriteFile(basePath+`/lists/test.json`, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saveTestList(t, REPRODUCIBLE_TOKENS)
+	previousFiles := readLists(t, basePath)
+
+	invalidTokens := append([]models.TokenListToken{}, REPRODUCIBLE_TOKENS...)
+	invalidTokens[0].Decimals = 256
+	tokenList := LoadTokenListFromJsonFile(`test.json`)
+	_, err := SaveTokenListInJsonFile(context.Background(), tokenList, invalidTokens, `test.json`, SavingMethodStandard)
+	if err == nil || !strings.Contains(err.Error(), `decimals out of range`) {
+		t.Errorf(`got the error %v, want the decimals out of range`, err)
+	}
+	assertSameFiles(t, readLists(t, basePath), previousFiles)
+}
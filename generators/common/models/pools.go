@@ -0,0 +1,51 @@
+package models
+
+import "encoding/json"
+
+/**************************************************************************************************
+** TPoolMetadata describes the composition of a pool, or of the LP token of a pool, in the metadata
+** of its entry in a list, so it can be resolved to its assets without parsing its name.
+** - Protocol is the DEX of the pool (uniswap, sushiswap, curve, velodrome...) and PoolType its
+**   kind for this DEX (v2, v3, stable, volatile, the Curve registry...).
+** - Token0 and Token1 are the first two underlying tokens. Tokens lists all of them, in the order
+**   of the pool, when there are more than two.
+** - Pool is the address of the pool contract when the entry is its LP token, a distinct contract.
+** - Factory and CreationBlock are the factory which deployed the pool, and the block it did, when
+**   they are known.
+** - FeeTier, in hundredths of a bip, and TickSpacing are set for the concentrated liquidity pools.
+**************************************************************************************************/
+type TPoolMetadata struct {
+	Protocol      string   `json:"protocol"`
+	PoolType      string   `json:"poolType"`
+	Token0        string   `json:"token0"`
+	Token1        string   `json:"token1"`
+	Tokens        []string `json:"tokens,omitempty"`
+	Pool          string   `json:"pool,omitempty"`
+	Factory       string   `json:"factory,omitempty"`
+	CreationBlock uint64   `json:"creationBlock,omitempty"`
+	FeeTier       uint64   `json:"feeTier,omitempty"`
+	TickSpacing   int64    `json:"tickSpacing,omitempty"`
+}
+
+// ToMetadata returns the pool as the metadata of a TokenListToken
+func (p TPoolMetadata) ToMetadata() map[string]interface{} {
+	metadata := map[string]interface{}{}
+	content, _ := json.Marshal(p)
+	json.Unmarshal(content, &metadata)
+	return metadata
+}
+
+// NewPoolMetadata returns the TPoolMetadata of a pool made of the given tokens
+func NewPoolMetadata(protocol string, poolType string, tokens []string) TPoolMetadata {
+	pool := TPoolMetadata{Protocol: protocol, PoolType: poolType}
+	if len(tokens) > 0 {
+		pool.Token0 = tokens[0]
+	}
+	if len(tokens) > 1 {
+		pool.Token1 = tokens[1]
+	}
+	if len(tokens) > 2 {
+		pool.Tokens = tokens
+	}
+	return pool
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// TTag is the definition of a tag in the tags of a list, as in the Uniswap token list schema
+type TTag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+/**************************************************************************************************
+** The tags shared by the generators. The Uniswap token list schema limits the identifier of a tag
+** to 10 word characters, its name to 20 characters and its description to 200 characters, without
+** any punctuation but dots, commas and colons.
+**************************************************************************************************/
+const (
+	TagStablecoin    = `stablecoin`
+	TagLPToken       = `lp_token`
+	TagPool          = `pool`
+	TagVault         = `vault`
+	TagWrappedNative = `wnative`
+	TagBridged       = `bridged`
+	TagSuspicious    = `suspicious`
+	TagFeeOnTransfer = `fee_on_tx`
+	TagRebasing      = `rebasing`
+	TagPausable      = `pausable`
+	TagBlacklist     = `blacklist`
+	TagProxy         = `proxy`
+)
+
+// TAGS is the vocabulary of the tags a token of a list can have
+var TAGS = map[string]TTag{
+	TagStablecoin: {
+		Name:        `Stablecoin`,
+		Description: `Token pegged to the value of a fiat currency or of a commodity`,
+	},
+	TagLPToken: {
+		Name:        `LP token`,
+		Description: `Share of the liquidity of a pool, redeemable for its underlying tokens`,
+	},
+	TagPool: {
+		Name:        `Pool`,
+		Description: `Pool contract whose liquidity has no token, like a Uniswap V3 pool`,
+	},
+	TagVault: {
+		Name:        `Vault`,
+		Description: `Share of a vault, redeemable for its underlying token`,
+	},
+	TagWrappedNative: {
+		Name:        `Wrapped native`,
+		Description: `ERC20 version of the coin of the chain`,
+	},
+	TagBridged: {
+		Name:        `Bridged`,
+		Description: `Token bridged from another chain`,
+	},
+	TagSuspicious: {
+		Name:        `Suspicious`,
+		Description: `Token matching a spam heuristic, to check before use`,
+	},
+	TagFeeOnTransfer: {
+		Name:        `Fee on transfer`,
+		Description: `Token whose transfers deliver less than the amount sent`,
+	},
+	TagRebasing: {
+		Name:        `Rebasing`,
+		Description: `Token whose balances change without any transfer`,
+	},
+	TagPausable: {
+		Name:        `Pausable`,
+		Description: `Token whose transfers can be paused by its owner`,
+	},
+	TagBlacklist: {
+		Name:        `Blacklist`,
+		Description: `Token whose owner can block the transfers of an address`,
+	},
+	TagProxy: {
+		Name:        `Upgradeable`,
+		Description: `Token behind a proxy, whose code can be replaced by its admin`,
+	},
+}
+
+// TAG_ALIASES are the other names of the tags of TAGS used by the lists the generators read
+var TAG_ALIASES = map[string]string{
+	`stable`:         TagStablecoin,
+	`stablecoins`:    TagStablecoin,
+	`stable_coin`:    TagStablecoin,
+	`lp`:             TagLPToken,
+	`lptoken`:        TagLPToken,
+	`vaults`:         TagVault,
+	`wrapped_native`: TagWrappedNative,
+	`bridge`:         TagBridged,
+	`fot`:            TagFeeOnTransfer,
+	`rebase`:         TagRebasing,
+	`upgradeable`:    TagProxy,
+}
+
+/**************************************************************************************************
+** ToTag returns the tag of TAGS matching a tag given by an external list, ignoring the case and
+** the separators, or false if it is not part of the vocabulary.
+**************************************************************************************************/
+func ToTag(tag string) (string, bool) {
+	tag = strings.NewReplacer(`-`, `_`, ` `, `_`).Replace(strings.ToLower(strings.TrimSpace(tag)))
+	if alias, ok := TAG_ALIASES[tag]; ok {
+		tag = alias
+	}
+	if _, ok := TAGS[tag]; !ok {
+		return ``, false
+	}
+	return tag, true
+}
+
+// SortTags returns the tags of TAGS, without duplicates and in alphabetical order, or nil
+func SortTags(tags []string) []string {
+	seen := make(map[string]bool)
+	sorted := []string{}
+	for _, tag := range tags {
+		if _, ok := TAGS[tag]; ok && !seen[tag] {
+			seen[tag] = true
+			sorted = append(sorted, tag)
+		}
+	}
+	if len(sorted) == 0 {
+		return nil
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// TagsOf returns the definitions of the tags used by the tokens, or nil
+func TagsOf(tokens []TokenListToken) map[string]TTag {
+	var tags map[string]TTag
+	for _, token := range tokens {
+		for _, tag := range token.Tags {
+			if tags == nil {
+				tags = make(map[string]TTag)
+			}
+			tags[tag] = TAGS[tag]
+		}
+	}
+	return tags
+}
@@ -1,32 +1,47 @@
 package models
 
-// TokenListToken is the token struct used in the default token list
+import "strconv"
+
+// TokenListToken is the token struct used in the default token list. Tags are identifiers of TAGS
+// and Extensions follows the Uniswap token list schema: at most 10 properties, whose values are
+// strings, numbers, booleans or objects of them, but no arrays.
 type TokenListToken struct {
-	Address  string                 `json:"address"`
-	Name     string                 `json:"name"`
-	Symbol   string                 `json:"symbol"`
-	LogoURI  string                 `json:"logoURI,omitempty"`
-	ChainID  uint64                 `json:"chainId"`
-	Decimals int                    `json:"decimals"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Address    string                 `json:"address"`
+	Name       string                 `json:"name"`
+	Symbol     string                 `json:"symbol"`
+	LogoURI    string                 `json:"logoURI,omitempty"`
+	ChainID    uint64                 `json:"chainId"`
+	Decimals   int                    `json:"decimals"`
+	Tags       []string               `json:"tags,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 
 	// The following fields are optional and not exported
 	Occurrence int `json:"-"` // Use for aggregation: number of time this token was found
 }
 
+// TTokenListVersion is the semver version of a token list
+type TTokenListVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// String returns the version formatted as major.minor.patch
+func (v TTokenListVersion) String() string {
+	return strconv.Itoa(v.Major) + `.` + strconv.Itoa(v.Minor) + `.` + strconv.Itoa(v.Patch)
+}
+
 // TokenListData is the token list struct used in the default token list
 // [T any](uri string) (data T) {
 type TokenListData[T any] struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Timestamp   string `json:"timestamp"`
-	Version     struct {
-		Major int `json:"major"`
-		Minor int `json:"minor"`
-		Patch int `json:"patch"`
-	} `json:"version"`
+	Name              string                    `json:"name"`
+	Description       string                    `json:"description"`
+	Timestamp         string                    `json:"timestamp"`
+	Version           TTokenListVersion         `json:"version"`
 	LogoURI           string                    `json:"logoURI"`
 	Keywords          []string                  `json:"keywords"`
+	Tags              map[string]TTag           `json:"tags,omitempty"`
 	Tokens            []T                       `json:"tokens"`
 	PreviousTokensMap map[string]TokenListToken `json:"-"`
 	NextTokensMap     map[string]TokenListToken `json:"-"`
@@ -0,0 +1,124 @@
+package simulated
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+/**************************************************************************************************
+** tAssembler builds small EVM programs without a compiler. Jump destinations and data offsets
+** are referenced by label and resolved when the bytecode is built: a label is always pushed with
+** a PUSH2, so the programs are limited to 64KB, far more than the mocks need.
+**************************************************************************************************/
+type tAssembler struct {
+	code   []byte
+	data   [][]byte
+	labels map[string]int
+	fixups map[int]string
+}
+
+func newAssembler() *tAssembler {
+	return &tAssembler{
+		labels: make(map[string]int),
+		fixups: make(map[int]string),
+	}
+}
+
+// op appends some opcodes without immediate value
+func (a *tAssembler) op(ops ...vm.OpCode) *tAssembler {
+	for _, op := range ops {
+		a.code = append(a.code, byte(op))
+	}
+	return a
+}
+
+// push appends the shortest PUSH of value. An empty value pushes 0.
+func (a *tAssembler) push(value []byte) *tAssembler {
+	for len(value) > 1 && value[0] == 0 {
+		value = value[1:]
+	}
+	if len(value) == 0 {
+		value = []byte{0}
+	}
+	a.code = append(a.code, byte(vm.PUSH1)+byte(len(value)-1))
+	a.code = append(a.code, value...)
+	return a
+}
+
+// pushUint appends the shortest PUSH of value
+func (a *tAssembler) pushUint(value uint64) *tAssembler {
+	return a.push(new(big.Int).SetUint64(value).Bytes())
+}
+
+// pushLabel appends a PUSH2 of the position of the label, resolved in bytes
+func (a *tAssembler) pushLabel(name string) *tAssembler {
+	a.code = append(a.code, byte(vm.PUSH2))
+	a.fixups[len(a.code)] = name
+	a.code = append(a.code, 0, 0)
+	return a
+}
+
+// jumpIf jumps to the label if the value at the top of the stack is not zero
+func (a *tAssembler) jumpIf(name string) *tAssembler {
+	return a.pushLabel(name).op(vm.JUMPI)
+}
+
+// label marks a jump destination
+func (a *tAssembler) label(name string) *tAssembler {
+	a.labels[name] = len(a.code)
+	return a.op(vm.JUMPDEST)
+}
+
+// dataLabel stores a constant after the code. Its position is then available as a label.
+func (a *tAssembler) dataLabel(name string, value []byte) *tAssembler {
+	a.labels[name] = -1 - len(a.data)
+	a.data = append(a.data, value)
+	return a
+}
+
+// bytes returns the code followed by the constants, with every label resolved
+func (a *tAssembler) bytes() ([]byte, error) {
+	code := append([]byte{}, a.code...)
+	dataOffsets := make([]int, len(a.data))
+	offset := len(code)
+	for i, value := range a.data {
+		dataOffsets[i] = offset
+		code = append(code, value...)
+		offset += len(value)
+	}
+
+	for position, name := range a.fixups {
+		target, ok := a.labels[name]
+		if !ok {
+			return nil, errors.New(`unknown label ` + name)
+		}
+		if target < 0 {
+			target = dataOffsets[-1-target]
+		}
+		if target > 0xffff {
+			return nil, errors.New(`label ` + name + ` is out of the PUSH2 range`)
+		}
+		code[position] = byte(target >> 8)
+		code[position+1] = byte(target)
+	}
+	return code, nil
+}
+
+/**************************************************************************************************
+** deploymentCode wraps a runtime bytecode in the init code returning it, so it can be deployed
+** with a contract creation transaction. The init code first writes the storage, if any.
+**************************************************************************************************/
+func deploymentCode(runtime []byte, storage map[common.Hash]common.Hash) ([]byte, error) {
+	init := newAssembler()
+	for key, value := range storage {
+		init.push(value.Bytes()).push(key.Bytes()).op(vm.SSTORE)
+	}
+	init.pushUint(uint64(len(runtime))).op(vm.DUP1)
+	init.pushLabel(`runtime`).push(nil).op(vm.CODECOPY)
+	init.push(nil).op(vm.RETURN)
+	init.dataLabel(`runtime`, runtime)
+	return init.bytes()
+}
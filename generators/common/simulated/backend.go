@@ -0,0 +1,214 @@
+package simulated
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+// GAS_LIMIT is the gas limit of the blocks of the simulated chain
+const GAS_LIMIT = uint64(30_000_000)
+
+/**************************************************************************************************
+** TBackend is an in-memory chain built on the simulated backend of go-ethereum. It implements
+** ethereum.TRPCClient, so it can replace the RPC of a chain with Install, and deploys the contracts
+** the generators rely on: Multicall3, the ERC20 variants and the mocks of the factories.
+** Every deployment and every transaction is mined right away, in its own block.
+**************************************************************************************************/
+type TBackend struct {
+	*backends.SimulatedBackend
+	key      *ecdsa.PrivateKey
+	Deployer *bind.TransactOpts
+}
+
+// DEPLOYER_KEY is the key of the deployer account. It is the same for every chain, so the contracts
+// deployed in the same order get the same addresses on every run of a test.
+var DEPLOYER_KEY = crypto.Keccak256([]byte(`tokenLists simulated deployer`))
+
+// NewBackend returns an empty chain with a funded deployer account
+func NewBackend() (*TBackend, error) {
+	key, err := crypto.ToECDSA(DEPLOYER_KEY)
+	if err != nil {
+		return nil, err
+	}
+	deployer, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		return nil, err
+	}
+	deployer.GasLimit = GAS_LIMIT / 2
+	balance := new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+	alloc := core.GenesisAlloc{deployer.From: {Balance: balance}}
+
+	return &TBackend{
+		SimulatedBackend: backends.NewSimulatedBackend(alloc, GAS_LIMIT),
+		key:              key,
+		Deployer:         deployer,
+	}, nil
+}
+
+// ChainID implements ethereum.TRPCClient
+func (b *TBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return b.Blockchain().Config().ChainID, nil
+}
+
+// BlockNumber implements ethereum.TRPCClient
+func (b *TBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	return b.Blockchain().CurrentBlock().Number.Uint64(), nil
+}
+
+/**************************************************************************************************
+** CallContractWithOverrides implements ethereum.TStateClient. The call runs on a copy of the state
+** of the block, the head when blockNumber is nil, with the overrides applied, like eth_call does
+** on a node.
+**************************************************************************************************/
+func (b *TBackend) CallContractWithOverrides(ctx context.Context, msg goEthereum.CallMsg, blockNumber *big.Int, overrides map[common.Address]gethclient.OverrideAccount) ([]byte, error) {
+	header := b.Blockchain().CurrentBlock()
+	if blockNumber != nil {
+		header = b.Blockchain().GetHeaderByNumber(blockNumber.Uint64())
+		if header == nil {
+			return nil, errors.New(`unknown block ` + blockNumber.String())
+		}
+	}
+	stateDB, err := b.Blockchain().StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	for address, account := range overrides {
+		if account.Nonce != 0 {
+			stateDB.SetNonce(address, account.Nonce)
+		}
+		if account.Code != nil {
+			stateDB.SetCode(address, account.Code)
+		}
+		if account.Balance != nil {
+			stateDB.SetBalance(address, account.Balance)
+		}
+		if account.State != nil {
+			stateDB.SetStorage(address, account.State)
+		}
+		for key, value := range account.StateDiff {
+			stateDB.SetState(address, key, value)
+		}
+	}
+
+	value := msg.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	message := &core.Message{
+		From:              msg.From,
+		To:                msg.To,
+		Value:             value,
+		GasLimit:          GAS_LIMIT,
+		GasPrice:          new(big.Int),
+		GasFeeCap:         new(big.Int),
+		GasTipCap:         new(big.Int),
+		Data:              msg.Data,
+		SkipAccountChecks: true,
+	}
+	evm := vm.NewEVM(
+		core.NewEVMBlockContext(header, b.Blockchain(), nil),
+		core.NewEVMTxContext(message),
+		stateDB,
+		b.Blockchain().Config(),
+		vm.Config{NoBaseFee: true},
+	)
+	result, err := core.ApplyMessage(evm, message, new(core.GasPool).AddGas(GAS_LIMIT))
+	if err != nil {
+		return nil, err
+	}
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result.Return(), nil
+}
+
+// Install makes the generators use this chain, and the Multicall3 at multicallAddress, for chainID
+func (b *TBackend) Install(chainID uint64, multicallAddress common.Address) {
+	ethereum.SetRPC(chainID, b, multicallAddress)
+}
+
+// mined commits the pending block and checks the transaction succeeded
+func (b *TBackend) mined(tx *types.Transaction) (*types.Receipt, error) {
+	b.Commit()
+	receipt, err := b.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, errors.New(`transaction ` + tx.Hash().Hex() + ` reverted`)
+	}
+	return receipt, nil
+}
+
+// DeployRuntime deploys a contract whose runtime bytecode is code
+func (b *TBackend) DeployRuntime(code []byte) (common.Address, error) {
+	return b.DeployRuntimeWithStorage(code, nil)
+}
+
+// DeployRuntimeWithStorage deploys a contract whose runtime bytecode is code, with its storage
+// initialized to storage
+func (b *TBackend) DeployRuntimeWithStorage(code []byte, storage map[common.Hash]common.Hash) (common.Address, error) {
+	creationCode, err := deploymentCode(code, storage)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address, tx, _, err := bind.DeployContract(b.Deployer, abi.ABI{}, creationCode, b)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, err := b.mined(tx); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
+
+// DeployMock deploys a mock contract answering the given calls, see TMockCall
+func (b *TBackend) DeployMock(calls ...TMockCall) (common.Address, error) {
+	code, err := mockRuntime(calls)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return b.DeployRuntime(code)
+}
+
+// Transact sends a transaction with the given calldata to a contract and mines it
+func (b *TBackend) Transact(to common.Address, callData []byte) (*types.Receipt, error) {
+	contract := bind.NewBoundContract(to, abi.ABI{}, b, b, b)
+	tx, err := contract.RawTransact(b.Deployer, callData)
+	if err != nil {
+		return nil, err
+	}
+	return b.mined(tx)
+}
+
+// EmitLog makes a mock contract emit a log, in a new block
+func (b *TBackend) EmitLog(mock common.Address, topics []common.Hash, data []byte) (*types.Receipt, error) {
+	return b.Transact(mock, emitCallData(topics, data))
+}
+
+// DeployMulticall3 deploys the real Multicall3 contract
+func (b *TBackend) DeployMulticall3() (common.Address, error) {
+	address, tx, _, err := contracts.DeployMulticall3(b.Deployer, b)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, err := b.mined(tx); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
@@ -0,0 +1,31 @@
+package simulated
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+/**************************************************************************************************
+** TLimitedClient wraps a client and rejects the calls whose data is larger than MaxCallDataSize,
+** with the error returned by the RPCs limiting the size of the requests. It reproduces the nodes
+** forcing the multicall to halve its batches. Calls counts every call received, rejected or not.
+**************************************************************************************************/
+type TLimitedClient struct {
+	ethereum.TRPCClient
+	MaxCallDataSize int
+	Calls           atomic.Uint64
+}
+
+// CallContract implements ethereum.TRPCClient
+func (c *TLimitedClient) CallContract(ctx context.Context, call goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.Calls.Add(1)
+	if c.MaxCallDataSize > 0 && len(call.Data) > c.MaxCallDataSize {
+		return nil, errors.New(`413 Request Entity Too Large`)
+	}
+	return c.TRPCClient.CallContract(ctx, call, blockNumber)
+}
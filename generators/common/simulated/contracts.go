@@ -0,0 +1,180 @@
+package simulated
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+)
+
+var erc20ABI, _ = contracts.ERC20MetaData.GetAbi()
+var erc20AltABI, _ = contracts.Erc20AltMetaData.GetAbi()
+var uniV2FactoryABI, _ = contracts.UniV2FactoryMetaData.GetAbi()
+var uniV3FactoryABI, _ = contracts.UniV3FactoryMetaData.GetAbi()
+var ajnaPoolFactoryABI, _ = contracts.AjnaPoolFactoryMetaData.GetAbi()
+var ajnaPoolABI, _ = contracts.AjnaPoolMetaData.GetAbi()
+var veloSugarABI, _ = contracts.VeloSugarV2MetaData.GetAbi()
+
+// MethodCall returns the TMockCall answering any call to a method of the ABI with the outputs
+func MethodCall(contractABI *abi.ABI, method string, outputs ...interface{}) (TMockCall, error) {
+	output, err := contractABI.Methods[method].Outputs.Pack(outputs...)
+	if err != nil {
+		return TMockCall{}, err
+	}
+	return TMockCall{Input: contractABI.Methods[method].ID, Output: output}, nil
+}
+
+// deployMethodMock deploys a mock answering the calls returned by the builders, in order
+func (b *TBackend) deployMethodMock(builders ...func() (TMockCall, error)) (common.Address, error) {
+	calls := []TMockCall{}
+	for _, builder := range builders {
+		call, err := builder()
+		if err != nil {
+			return common.Address{}, err
+		}
+		calls = append(calls, call)
+	}
+	return b.DeployMock(calls...)
+}
+
+/**************************************************************************************************
+** The ERC20 variants met in the wild, covering the fallbacks of ethereum.FetchBasicInformations:
+** - DeployERC20 deploys the OpenZeppelin ERC20, with string name and symbol and 18 decimals,
+** - DeployBytes32ERC20 a token returning its name and symbol as bytes32, like MKR,
+** - DeployERC20WithoutDecimals a token without the decimals method,
+** - DeployRevertingERC20 a contract reverting on every call.
+**************************************************************************************************/
+func (b *TBackend) DeployERC20(name, symbol string) (common.Address, error) {
+	address, tx, _, err := contracts.DeployERC20(b.Deployer, b, name, symbol)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, err := b.mined(tx); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
+
+func (b *TBackend) DeployBytes32ERC20(name, symbol string, decimals uint8) (common.Address, error) {
+	var nameBytes, symbolBytes [32]byte
+	copy(nameBytes[:], name)
+	copy(symbolBytes[:], symbol)
+	return b.deployMethodMock(
+		func() (TMockCall, error) { return MethodCall(erc20AltABI, `name`, nameBytes) },
+		func() (TMockCall, error) { return MethodCall(erc20AltABI, `symbol`, symbolBytes) },
+		func() (TMockCall, error) { return MethodCall(erc20ABI, `decimals`, decimals) },
+	)
+}
+
+func (b *TBackend) DeployERC20WithoutDecimals(name, symbol string) (common.Address, error) {
+	return b.deployMethodMock(
+		func() (TMockCall, error) { return MethodCall(erc20ABI, `name`, name) },
+		func() (TMockCall, error) { return MethodCall(erc20ABI, `symbol`, symbol) },
+	)
+}
+
+func (b *TBackend) DeployRevertingERC20() (common.Address, error) {
+	return b.DeployMock()
+}
+
+/**************************************************************************************************
+** The factories scanned by the event generators are mocks emitting their creation events on
+** demand: DeployFactory deploys an empty one, then each CreateUniV2Pair or CreateUniV3Pool call
+** mines a block with the event, so the scans can be checked against known block ranges.
+**************************************************************************************************/
+func (b *TBackend) DeployFactory() (common.Address, error) {
+	return b.DeployMock()
+}
+
+// CreateUniV2Pair emits the PairCreated event of a UniswapV2 like factory and returns its block
+func (b *TBackend) CreateUniV2Pair(factory, token0, token1, pair common.Address, pairIndex uint64) (uint64, error) {
+	event := uniV2FactoryABI.Events[`PairCreated`]
+	data, err := event.Inputs.NonIndexed().Pack(pair, new(big.Int).SetUint64(pairIndex))
+	if err != nil {
+		return 0, err
+	}
+	topics := []common.Hash{event.ID, common.BytesToHash(token0.Bytes()), common.BytesToHash(token1.Bytes())}
+	receipt, err := b.EmitLog(factory, topics, data)
+	if err != nil {
+		return 0, err
+	}
+	return receipt.BlockNumber.Uint64(), nil
+}
+
+// CreateUniV3Pool emits the PoolCreated event of a UniswapV3 like factory and returns its block
+func (b *TBackend) CreateUniV3Pool(factory, token0, token1, pool common.Address, fee uint64, tickSpacing int64) (uint64, error) {
+	event := uniV3FactoryABI.Events[`PoolCreated`]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(tickSpacing), pool)
+	if err != nil {
+		return 0, err
+	}
+	topics := []common.Hash{
+		event.ID,
+		common.BytesToHash(token0.Bytes()),
+		common.BytesToHash(token1.Bytes()),
+		common.BigToHash(new(big.Int).SetUint64(fee)),
+	}
+	receipt, err := b.EmitLog(factory, topics, data)
+	if err != nil {
+		return 0, err
+	}
+	return receipt.BlockNumber.Uint64(), nil
+}
+
+// TAjnaPool is a pool of the mocked Ajna factory
+type TAjnaPool struct {
+	Collateral common.Address
+	QuoteToken common.Address
+}
+
+// DeployAjnaFactory deploys a mock of each pool, then the factory listing them
+func (b *TBackend) DeployAjnaFactory(pools []TAjnaPool) (common.Address, error) {
+	poolAddresses := []common.Address{}
+	for _, pool := range pools {
+		pool := pool
+		poolAddress, err := b.deployMethodMock(
+			func() (TMockCall, error) { return MethodCall(ajnaPoolABI, `collateralAddress`, pool.Collateral) },
+			func() (TMockCall, error) { return MethodCall(ajnaPoolABI, `quoteTokenAddress`, pool.QuoteToken) },
+		)
+		if err != nil {
+			return common.Address{}, err
+		}
+		poolAddresses = append(poolAddresses, poolAddress)
+	}
+	return b.deployMethodMock(
+		func() (TMockCall, error) {
+			return MethodCall(ajnaPoolFactoryABI, `getDeployedPoolsList`, poolAddresses)
+		},
+	)
+}
+
+// NewVeloLp returns a pool of the VeloSugar mock, with all its amounts set to zero
+func NewVeloLp(lp, token0, token1, emissionsToken common.Address) contracts.Struct0 {
+	return contracts.Struct0{
+		Lp:               lp,
+		Token0:           token0,
+		Token1:           token1,
+		EmissionsToken:   emissionsToken,
+		TotalSupply:      big.NewInt(0),
+		Reserve0:         big.NewInt(0),
+		Claimable0:       big.NewInt(0),
+		Reserve1:         big.NewInt(0),
+		Claimable1:       big.NewInt(0),
+		GaugeTotalSupply: big.NewInt(0),
+		Emissions:        big.NewInt(0),
+		AccountBalance:   big.NewInt(0),
+		AccountEarned:    big.NewInt(0),
+		AccountStaked:    big.NewInt(0),
+		PoolFee:          big.NewInt(0),
+		Token0Fees:       big.NewInt(0),
+		Token1Fees:       big.NewInt(0),
+	}
+}
+
+// DeployVeloSugar deploys a VeloSugar mock returning the pools from `all`, whatever the arguments
+func (b *TBackend) DeployVeloSugar(lps []contracts.Struct0) (common.Address, error) {
+	return b.deployMethodMock(
+		func() (TMockCall, error) { return MethodCall(veloSugarABI, `all`, lps) },
+	)
+}
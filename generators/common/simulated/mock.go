@@ -0,0 +1,95 @@
+package simulated
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// EMIT_SELECTOR is the selector of the calls asking a mock contract to emit a log
+var EMIT_SELECTOR = []byte{0xff, 0xff, 0xff, 0xff}
+
+/**************************************************************************************************
+** TMockCall is a call a mock contract answers to. An Input of 4 bytes matches every call to this
+** selector, whatever the arguments, while a longer Input must match the calldata exactly. The
+** mock returns Output, or reverts with it when Reverts is set. The calls matching none of the
+** TMockCall revert without data.
+**************************************************************************************************/
+type TMockCall struct {
+	Input   []byte
+	Output  []byte
+	Reverts bool
+}
+
+// answerCalls appends the code answering the calls in order, see TMockCall. The calls matching
+// none of them go on with the code appended next.
+func (a *tAssembler) answerCalls(calls []TMockCall) *tAssembler {
+	for i, call := range calls {
+		next := `next_` + strconv.Itoa(i)
+		output := `output_` + strconv.Itoa(i)
+		if len(call.Input) == 4 {
+			a.push(nil).op(vm.CALLDATALOAD).pushUint(0xe0).op(vm.SHR)
+			a.push(call.Input).op(vm.EQ, vm.ISZERO).jumpIf(next)
+		} else {
+			a.op(vm.CALLDATASIZE).pushUint(uint64(len(call.Input))).op(vm.EQ, vm.ISZERO).jumpIf(next)
+			for offset := 0; offset < len(call.Input); offset += 32 {
+				word := make([]byte, 32)
+				copy(word, call.Input[offset:])
+				a.pushUint(uint64(offset)).op(vm.CALLDATALOAD).push(word).op(vm.EQ, vm.ISZERO).jumpIf(next)
+			}
+		}
+		a.pushUint(uint64(len(call.Output))).op(vm.DUP1).pushLabel(output).push(nil).op(vm.CODECOPY)
+		a.push(nil)
+		if call.Reverts {
+			a.op(vm.REVERT)
+		} else {
+			a.op(vm.RETURN)
+		}
+		a.dataLabel(output, call.Output)
+		a.label(next)
+	}
+	return a
+}
+
+/**************************************************************************************************
+** mockRuntime builds the runtime bytecode of a mock contract answering the given calls in order.
+** The mock also emits any log it is asked to through EMIT_SELECTOR, see emitCallData, so the
+** events of a factory can be reproduced from its own address.
+**************************************************************************************************/
+func mockRuntime(calls []TMockCall) ([]byte, error) {
+	a := newAssembler()
+	a.answerCalls(calls)
+
+	// Emit: selector | topics count | topics | data
+	a.push(nil).op(vm.CALLDATALOAD).pushUint(0xe0).op(vm.SHR)
+	a.push(EMIT_SELECTOR).op(vm.EQ, vm.ISZERO).jumpIf(`fallback`)
+	a.pushUint(4).op(vm.CALLDATALOAD).pushUint(32).op(vm.MUL).pushUint(36).op(vm.ADD)
+	a.op(vm.DUP1, vm.CALLDATASIZE, vm.SUB, vm.SWAP1).push(nil).op(vm.CALLDATACOPY)
+	for topics := 0; topics <= 4; topics++ {
+		a.pushUint(4).op(vm.CALLDATALOAD).pushUint(uint64(topics)).op(vm.EQ).jumpIf(`emit_` + strconv.Itoa(topics))
+	}
+	a.pushLabel(`fallback`).op(vm.JUMP)
+	for topics := 0; topics <= 4; topics++ {
+		a.label(`emit_` + strconv.Itoa(topics))
+		for j := topics - 1; j >= 0; j-- {
+			a.pushUint(uint64(36 + 32*j)).op(vm.CALLDATALOAD)
+		}
+		a.pushUint(uint64(36+32*topics)).op(vm.CALLDATASIZE, vm.SUB).push(nil)
+		a.op(vm.LOG0+vm.OpCode(topics), vm.STOP)
+	}
+
+	a.label(`fallback`).push(nil).op(vm.DUP1, vm.REVERT)
+	return a.bytes()
+}
+
+// emitCallData returns the calldata asking a mock contract to emit a log
+func emitCallData(topics []common.Hash, data []byte) []byte {
+	callData := append([]byte{}, EMIT_SELECTOR...)
+	callData = append(callData, common.LeftPadBytes(big.NewInt(int64(len(topics))).Bytes(), 32)...)
+	for _, topic := range topics {
+		callData = append(callData, topic.Bytes()...)
+	}
+	return append(callData, data...)
+}
@@ -0,0 +1,92 @@
+package simulated
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMockAnswersTheCallsInOrder(t *testing.T) {
+	backend, err := NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	selector := []byte{0x01, 0x02, 0x03, 0x04}
+	exactInput := append(append([]byte{}, selector...), common.LeftPadBytes([]byte{0x2a}, 32)...)
+	longOutput := bytes.Repeat([]byte{0xab}, 70)
+	mock, err := backend.DeployMock(
+		TMockCall{Input: exactInput, Output: []byte{0x2a}},
+		TMockCall{Input: selector, Output: longOutput},
+		TMockCall{Input: []byte{0x05, 0x06, 0x07, 0x08}, Output: []byte{0xee}, Reverts: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(callData []byte) ([]byte, error) {
+		return backend.CallContract(context.Background(), goEthereum.CallMsg{To: &mock, Data: callData}, nil)
+	}
+	if output, err := call(exactInput); err != nil || !bytes.Equal(output, []byte{0x2a}) {
+		t.Errorf(`exact input: got %x %v`, output, err)
+	}
+	otherArgument := append(append([]byte{}, selector...), common.LeftPadBytes([]byte{0x2b}, 32)...)
+	if output, err := call(otherArgument); err != nil || !bytes.Equal(output, longOutput) {
+		t.Errorf(`selector: got %x %v`, output, err)
+	}
+	if _, err := call([]byte{0x05, 0x06, 0x07, 0x08}); err == nil {
+		t.Error(`expected the reverting call to fail`)
+	}
+	if _, err := call([]byte{0x09, 0x09, 0x09, 0x09}); err == nil {
+		t.Error(`expected an unknown call to fail`)
+	}
+}
+
+func TestMockEmitsLogs(t *testing.T) {
+	backend, err := NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	mock, err := backend.DeployMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topics := []common.Hash{common.HexToHash(`0x01`), common.HexToHash(`0x02`)}
+	data := bytes.Repeat([]byte{0xcd}, 40)
+	receipt, err := backend.EmitLog(mock, topics, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipt.Logs) != 1 {
+		t.Fatalf(`got %d logs, want 1`, len(receipt.Logs))
+	}
+	log := receipt.Logs[0]
+	if log.Address != mock || len(log.Topics) != 2 || log.Topics[1] != topics[1] || !bytes.Equal(log.Data, data) {
+		t.Errorf(`got the log %+v`, log)
+	}
+}
+
+func TestNewBackendDeploysAtTheSameAddresses(t *testing.T) {
+	addresses := []common.Address{}
+	for i := 0; i < 2; i++ {
+		backend, err := NewBackend()
+		if err != nil {
+			t.Fatal(err)
+		}
+		address, err := backend.DeployMulticall3()
+		backend.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addresses = append(addresses, address)
+	}
+	if addresses[0] != addresses[1] {
+		t.Errorf(`got %s then %s`, addresses[0].Hex(), addresses[1].Hex())
+	}
+}
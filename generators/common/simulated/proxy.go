@@ -0,0 +1,91 @@
+package simulated
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+// UPGRADE_TO_SELECTOR is the selector of upgradeTo(address), answered by the proxies themselves
+var UPGRADE_TO_SELECTOR = selectorOf(`upgradeTo(address)`)
+
+// proxySlots returns the slot a proxy of the kind reads its implementation, or its beacon, from,
+// and the slot of its admin, zero when the kind has none
+func proxySlots(kind ethereum.TProxyKind) (common.Hash, common.Hash, error) {
+	switch kind {
+	case ethereum.ProxyEIP1967:
+		return ethereum.EIP1967_IMPLEMENTATION_SLOT, ethereum.EIP1967_ADMIN_SLOT, nil
+	case ethereum.ProxyBeacon:
+		return ethereum.EIP1967_BEACON_SLOT, common.Hash{}, nil
+	case ethereum.ProxyEIP1822:
+		return ethereum.EIP1822_PROXIABLE_SLOT, common.Hash{}, nil
+	case ethereum.ProxyZeppelinOS:
+		return ethereum.ZEPPELINOS_IMPLEMENTATION_SLOT, ethereum.ZEPPELINOS_ADMIN_SLOT, nil
+	}
+	return common.Hash{}, common.Hash{}, errors.New(`unknown proxy kind ` + string(kind))
+}
+
+/**************************************************************************************************
+** proxyRuntime builds the runtime bytecode of a proxy reading its target from slot. upgradeTo
+** writes the new target in the slot, whoever calls it. Any other call is delegated to the
+** implementation, which is the target, or the address returned by implementation() on the target
+** for a beacon proxy, and its result or its revert is returned as is.
+**************************************************************************************************/
+func proxyRuntime(slot common.Hash, isBeacon bool) ([]byte, error) {
+	a := newAssembler()
+	a.push(nil).op(vm.CALLDATALOAD).pushUint(0xe0).op(vm.SHR)
+	a.push(UPGRADE_TO_SELECTOR).op(vm.EQ).jumpIf(`upgradeTo`)
+
+	a.push(slot.Bytes()).op(vm.SLOAD)
+	if isBeacon {
+		a.push(selectorOf(`implementation()`)).pushUint(0xe0).op(vm.SHL).push(nil).op(vm.MSTORE)
+		a.pushUint(32).push(nil).pushUint(4).push(nil).op(vm.DUP5, vm.GAS, vm.STATICCALL, vm.ISZERO).jumpIf(`revert`)
+		a.op(vm.POP).push(nil).op(vm.MLOAD)
+	}
+	a.op(vm.CALLDATASIZE).push(nil).push(nil).op(vm.CALLDATACOPY)
+	a.push(nil).push(nil).op(vm.CALLDATASIZE).push(nil).op(vm.DUP5, vm.GAS, vm.DELEGATECALL)
+	a.op(vm.RETURNDATASIZE).push(nil).push(nil).op(vm.RETURNDATACOPY)
+	a.op(vm.ISZERO).jumpIf(`revert`)
+	a.op(vm.RETURNDATASIZE).push(nil).op(vm.RETURN)
+	a.label(`revert`).op(vm.RETURNDATASIZE).push(nil).op(vm.REVERT)
+
+	a.label(`upgradeTo`).pushUint(4).op(vm.CALLDATALOAD).push(slot.Bytes()).op(vm.SSTORE, vm.STOP)
+	return a.bytes()
+}
+
+/**************************************************************************************************
+** DeployProxy deploys a proxy of the kind delegating its calls to target, the implementation, or
+** the beacon for a ethereum.ProxyBeacon. The target, and the admin for the kinds which have one,
+** are written in the slots ethereum.FetchProxies reads. UpgradeProxy changes the target.
+**************************************************************************************************/
+func (b *TBackend) DeployProxy(kind ethereum.TProxyKind, target, admin common.Address) (common.Address, error) {
+	slot, adminSlot, err := proxySlots(kind)
+	if err != nil {
+		return common.Address{}, err
+	}
+	code, err := proxyRuntime(slot, kind == ethereum.ProxyBeacon)
+	if err != nil {
+		return common.Address{}, err
+	}
+	storage := map[common.Hash]common.Hash{slot: common.BytesToHash(target.Bytes())}
+	if adminSlot != (common.Hash{}) {
+		storage[adminSlot] = common.BytesToHash(admin.Bytes())
+	}
+	return b.DeployRuntimeWithStorage(code, storage)
+}
+
+// UpgradeProxy points a proxy deployed by DeployProxy to a new target, in a new block
+func (b *TBackend) UpgradeProxy(proxy, target common.Address) error {
+	_, err := b.Transact(proxy, append(append([]byte{}, UPGRADE_TO_SELECTOR...), common.LeftPadBytes(target.Bytes(), 32)...))
+	return err
+}
+
+// DeployBeacon deploys a beacon answering implementation() and owner(), for a beacon proxy
+func (b *TBackend) DeployBeacon(implementation, owner common.Address) (common.Address, error) {
+	return b.DeployMock(
+		TMockCall{Input: selectorOf(`implementation()`), Output: common.LeftPadBytes(implementation.Bytes(), 32)},
+		TMockCall{Input: selectorOf(`owner()`), Output: common.LeftPadBytes(owner.Bytes(), 32)},
+	)
+}
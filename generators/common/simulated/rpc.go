@@ -0,0 +1,86 @@
+package simulated
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+/**************************************************************************************************
+** TRPCServer serves the chain over JSON-RPC on a local URL, for the code dialing an RPC like
+** ethereum.NewRPCPool. It answers eth_chainId, eth_blockNumber and eth_call. Its failures are set
+** by the tests: Down answers every request with a 503, RateLimited with a 429, and Lag reports a
+** head this many blocks behind the chain. Calls counts the requests received, answered or not.
+**************************************************************************************************/
+type TRPCServer struct {
+	*httptest.Server
+	Down        atomic.Bool
+	RateLimited atomic.Bool
+	Lag         atomic.Uint64
+	Calls       atomic.Uint64
+}
+
+// TCallArgs are the arguments of eth_call sent by ethclient
+type TCallArgs struct {
+	From common.Address  `json:"from"`
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+type rpcService struct {
+	backend *TBackend
+	server  *TRPCServer
+}
+
+// ChainId answers eth_chainId
+func (s *rpcService) ChainId(ctx context.Context) (*hexutil.Big, error) {
+	chainID, err := s.backend.ChainID(ctx)
+	return (*hexutil.Big)(chainID), err
+}
+
+// BlockNumber answers eth_blockNumber, Lag blocks behind the chain
+func (s *rpcService) BlockNumber(ctx context.Context) (hexutil.Uint64, error) {
+	head, err := s.backend.BlockNumber(ctx)
+	if lag := s.server.Lag.Load(); lag < head {
+		return hexutil.Uint64(head - lag), err
+	}
+	return 0, err
+}
+
+// Call answers eth_call, on the head or on a block given by its number
+func (s *rpcService) Call(ctx context.Context, args TCallArgs, block rpc.BlockNumber) (hexutil.Bytes, error) {
+	var blockNumber *big.Int
+	if block >= 0 {
+		blockNumber = big.NewInt(block.Int64())
+	}
+	msg := goEthereum.CallMsg{From: args.From, To: args.To, Data: args.Data}
+	return s.backend.CallContractWithOverrides(ctx, msg, blockNumber, nil)
+}
+
+// NewRPCServer starts serving the chain. The server is closed with the backend by the caller.
+func (b *TBackend) NewRPCServer() (*TRPCServer, error) {
+	server := &TRPCServer{}
+	handler := rpc.NewServer()
+	if err := handler.RegisterName(`eth`, &rpcService{backend: b, server: server}); err != nil {
+		return nil, err
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.Calls.Add(1)
+		switch {
+		case server.Down.Load():
+			http.Error(w, `service unavailable`, http.StatusServiceUnavailable)
+		case server.RateLimited.Load():
+			http.Error(w, `too many requests`, http.StatusTooManyRequests)
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	}))
+	return server, nil
+}
@@ -0,0 +1,109 @@
+package simulated
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+)
+
+/**************************************************************************************************
+** TNonStandardERC20 describes a token deviating from the standard ERC20 like the ones the probe of
+** ethereum.ProbeTokens detects. The token keeps its balances in a Solidity mapping at slot 0 and
+** starts without any, so only the probe, overriding them, can move some.
+** - FeeBps is the share of each transfer the token keeps, in basis points,
+** - Rebasing makes the balances shares worth the block number each,
+** - Pausable and Blacklist add the paused() and isBlacklisted(address) getters, answering false,
+** - Proxy sets the EIP-1967 implementation and admin slots, both to the deployer.
+**************************************************************************************************/
+type TNonStandardERC20 struct {
+	Name      string
+	Symbol    string
+	Decimals  uint8
+	FeeBps    uint64
+	Rebasing  bool
+	Pausable  bool
+	Blacklist bool
+	Proxy     bool
+}
+
+// selectorOf returns the selector of a method
+func selectorOf(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+// balanceSlot replaces the address at the top of the stack with the slot of its balance
+func (a *tAssembler) balanceSlot() *tAssembler {
+	return a.push(nil).op(vm.MSTORE).pushUint(64).push(nil).op(vm.KECCAK256)
+}
+
+// returnWord returns the value at the top of the stack
+func (a *tAssembler) returnWord() *tAssembler {
+	return a.push(nil).op(vm.MSTORE).pushUint(32).push(nil).op(vm.RETURN)
+}
+
+// nonStandardERC20Runtime builds the runtime bytecode of the token
+func nonStandardERC20Runtime(token TNonStandardERC20) ([]byte, error) {
+	a := newAssembler()
+	a.push(nil).op(vm.CALLDATALOAD).pushUint(0xe0).op(vm.SHR)
+	a.op(vm.DUP1).push(erc20ABI.Methods[`balanceOf`].ID).op(vm.EQ).jumpIf(`balanceOf`)
+	a.op(vm.DUP1).push(erc20ABI.Methods[`transfer`].ID).op(vm.EQ).jumpIf(`transfer`)
+	if token.Pausable {
+		a.op(vm.DUP1).push(selectorOf(`paused()`)).op(vm.EQ).jumpIf(`false`)
+	}
+	if token.Blacklist {
+		a.op(vm.DUP1).push(selectorOf(`isBlacklisted(address)`)).op(vm.EQ).jumpIf(`false`)
+	}
+
+	calls := []TMockCall{}
+	for _, builder := range []func() (TMockCall, error){
+		func() (TMockCall, error) { return MethodCall(erc20ABI, `name`, token.Name) },
+		func() (TMockCall, error) { return MethodCall(erc20ABI, `symbol`, token.Symbol) },
+		func() (TMockCall, error) { return MethodCall(erc20ABI, `decimals`, token.Decimals) },
+	} {
+		call, err := builder()
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	a.answerCalls(calls)
+	a.label(`revert`).push(nil).op(vm.DUP1, vm.REVERT)
+
+	// balanceOf(address): shares times the block number for a rebasing token
+	a.label(`balanceOf`).pushUint(4).op(vm.CALLDATALOAD).balanceSlot().op(vm.SLOAD)
+	if token.Rebasing {
+		a.op(vm.NUMBER, vm.MUL)
+	}
+	a.returnWord()
+
+	// transfer(address,uint256): debit the amount from the caller, credit it minus the fee
+	a.label(`transfer`).op(vm.CALLER).balanceSlot().op(vm.DUP1, vm.SLOAD)
+	a.pushUint(36).op(vm.CALLDATALOAD)
+	if token.Rebasing {
+		a.op(vm.NUMBER, vm.SWAP1, vm.DIV)
+	}
+	a.op(vm.DUP1, vm.DUP3, vm.LT).jumpIf(`revert`)
+	a.op(vm.DUP1, vm.DUP3, vm.SUB, vm.DUP4, vm.SSTORE)
+	a.op(vm.DUP1).pushUint(token.FeeBps).op(vm.MUL).pushUint(10_000).op(vm.SWAP1, vm.DIV, vm.SWAP1, vm.SUB)
+	a.pushUint(4).op(vm.CALLDATALOAD).balanceSlot()
+	a.op(vm.DUP1, vm.SLOAD, vm.DUP3, vm.ADD, vm.SWAP1, vm.SSTORE)
+	a.pushUint(1).returnWord()
+
+	a.label(`false`).push(nil).returnWord()
+	return a.bytes()
+}
+
+// DeployNonStandardERC20 deploys a token with the behaviours of the TNonStandardERC20
+func (b *TBackend) DeployNonStandardERC20(token TNonStandardERC20) (common.Address, error) {
+	code, err := nonStandardERC20Runtime(token)
+	if err != nil {
+		return common.Address{}, err
+	}
+	storage := map[common.Hash]common.Hash{}
+	if token.Proxy {
+		storage[ethereum.EIP1967_IMPLEMENTATION_SLOT] = common.BytesToHash(b.Deployer.From.Bytes())
+		storage[ethereum.EIP1967_ADMIN_SLOT] = common.BytesToHash(b.Deployer.From.Bytes())
+	}
+	return b.DeployRuntimeWithStorage(code, storage)
+}
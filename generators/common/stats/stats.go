@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+// TCallCounters holds the number of network calls performed on behalf of a context
+type TCallCounters struct {
+	RPC  atomic.Uint64
+	HTTP atomic.Uint64
+}
+
+// TCallKind is the kind of call counted by a CountingTransport
+type TCallKind string
+
+const (
+	// CallRPC is a JSON-RPC call to a node
+	CallRPC TCallKind = "RPC"
+	// CallHTTP is any other HTTP call, usually to an API
+	CallHTTP TCallKind = "HTTP"
+)
+
+// WithCounters returns a copy of ctx carrying a new set of counters
+func WithCounters(ctx context.Context) (context.Context, *TCallCounters) {
+	counters := &TCallCounters{}
+	return context.WithValue(ctx, contextKey{}, counters), counters
+}
+
+// FromContext returns the counters carried by ctx, or nil if there are none
+func FromContext(ctx context.Context) *TCallCounters {
+	if ctx == nil {
+		return nil
+	}
+	counters, _ := ctx.Value(contextKey{}).(*TCallCounters)
+	return counters
+}
+
+// Count increments the counter of the given kind carried by ctx, if any
+func Count(ctx context.Context, kind TCallKind) {
+	counters := FromContext(ctx)
+	if counters == nil {
+		return
+	}
+	switch kind {
+	case CallRPC:
+		counters.RPC.Add(1)
+	case CallHTTP:
+		counters.HTTP.Add(1)
+	}
+}
+
+/**************************************************************************************************
+** CountingTransport is an http.RoundTripper counting every request against the counters found in
+** the request context. It allows to attribute the calls to the generator performing them, even
+** when the HTTP client is shared between all the generators.
+**************************************************************************************************/
+type CountingTransport struct {
+	Kind TCallKind
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t CountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	Count(req.Context(), t.Kind)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// Detach returns a context which is never cancelled, carrying the counters of ctx, so the calls
+// made on behalf of ctx by a shared worker are still counted if they outlive it
+func Detach(ctx context.Context) context.Context {
+	counters := FromContext(ctx)
+	if counters == nil {
+		return context.Background()
+	}
+	return context.WithValue(context.Background(), contextKey{}, counters)
+}
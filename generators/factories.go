@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/migratooor/tokenLists/generators/common/contracts"
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+var uniV2FactoryABI, _ = contracts.UniV2FactoryMetaData.GetAbi()
+var uniV3FactoryABI, _ = contracts.UniV3FactoryMetaData.GetAbi()
+
+/**************************************************************************************************
+** The lists built from the factories used to keep the last block they scanned in their metadata,
+** as `lastBlockSyncFor_<chainID>`. legacyLastBlockSync returns this block, to start the indexers
+** without a checkpoint from there, and dropLegacyLastBlockSync removes it once the indexers of
+** the chain have their checkpoints.
+**************************************************************************************************/
+func legacyLastBlockSync(metadata map[string]interface{}, chainID uint64) uint64 {
+	if value, ok := metadata[`lastBlockSyncFor_`+strconv.FormatUint(chainID, 10)].(string); ok {
+		block, _ := strconv.ParseUint(value, 10, 64)
+		return block
+	}
+	return 0
+}
+
+func dropLegacyLastBlockSync(metadata map[string]interface{}, chainID uint64) {
+	delete(metadata, `lastBlockSyncFor_`+strconv.FormatUint(chainID, 10))
+}
+
+// factoryStartBlock returns the block to index a factory from when it has no checkpoint yet
+func factoryStartBlock(deploymentBlock uint64, metadata map[string]interface{}, chainID uint64) uint64 {
+	if legacyBlock := legacyLastBlockSync(metadata, chainID); legacyBlock > deploymentBlock {
+		return legacyBlock
+	}
+	return deploymentBlock
+}
+
+// factoryIndexerKey returns the key of the checkpoint of the indexer of a factory, see TIndexer.Key
+func factoryIndexerKey(chainID uint64, uniContract TUniContracts) string {
+	indexer := ethereum.TIndexer{ChainID: chainID, Contract: uniContract.ContractAddress, Event: `PairCreated`}
+	if uniContract.Type == 3 {
+		indexer.Event = `PoolCreated`
+	}
+	return indexer.Key()
+}
+
+// indexPairCreated passes the pairs created by a UniswapV2 like factory to onPair, with the log of
+// their creation in Raw. The Sushiswap factories emit the same PairCreated event.
+func indexPairCreated(
+	ctx context.Context,
+	state *helpers.TIndexerState,
+	chainID uint64,
+	factory common.Address,
+	startBlock uint64,
+	onPair func(event *contracts.UniV2FactoryPairCreated),
+) error {
+	return ethereum.IndexEvents(ctx, ethereum.TIndexer{
+		ChainID:    chainID,
+		Contract:   factory,
+		ABI:        uniV2FactoryABI,
+		Event:      `PairCreated`,
+		StartBlock: startBlock,
+		Store:      state,
+	}, func(event *contracts.UniV2FactoryPairCreated, log types.Log) error {
+		event.Raw = log
+		onPair(event)
+		return nil
+	})
+}
+
+// indexPoolCreated passes the pools created by a UniswapV3 like factory to onPool, with the log of
+// their creation in Raw
+func indexPoolCreated(
+	ctx context.Context,
+	state *helpers.TIndexerState,
+	chainID uint64,
+	factory common.Address,
+	startBlock uint64,
+	onPool func(event *contracts.UniV3FactoryPoolCreated),
+) error {
+	return ethereum.IndexEvents(ctx, ethereum.TIndexer{
+		ChainID:    chainID,
+		Contract:   factory,
+		ABI:        uniV3FactoryABI,
+		Event:      `PoolCreated`,
+		StartBlock: startBlock,
+		Store:      state,
+	}, func(event *contracts.UniV3FactoryPoolCreated, log types.Log) error {
+		event.Raw = log
+		onPool(event)
+		return nil
+	})
+}
+
+/**************************************************************************************************
+** tUniswapScan holds what the Uniswap factories of a chain created since the previous run, the V2
+** pairs and the V3 pools, and for each token of these pairs and pools, the number of pairs and
+** pools it is part of across the whole history of the factories.
+**************************************************************************************************/
+type tUniswapScan struct {
+	TokenCount map[string]int
+	Pairs      map[string]contracts.UniV2FactoryPairCreated
+	Pools      map[string]contracts.UniV3FactoryPoolCreated
+}
+
+/**************************************************************************************************
+** scanUniswapFactories indexes the V2 and V3 factories of UniswapContractsPerChainID for a chain.
+** uniswap-pairs, uniswap-pools and uniswap-v3-pools each run their own scan, from the checkpoints
+** of their own indexer state, so the logs of the factories are read three times. Sharing one scan
+** would require the three lists to move their checkpoints together, including when one of them
+** fails to save.
+** The number of pairs and pools of the tokens is kept in the indexer state, see TPoolCounts, and a
+** pool read again after a reorg is not counted twice. A state without counts for the chain, from
+** a previous version, makes the factories be read again from their deployment.
+**************************************************************************************************/
+func scanUniswapFactories(
+	ctx context.Context,
+	state *helpers.TIndexerState,
+	metadata map[string]interface{},
+	chainID uint64,
+) (tUniswapScan, error) {
+	scan := tUniswapScan{
+		TokenCount: make(map[string]int),
+		Pairs:      make(map[string]contracts.UniV2FactoryPairCreated),
+		Pools:      make(map[string]contracts.UniV3FactoryPoolCreated),
+	}
+
+	keys := []string{}
+	for _, uniContract := range UniswapContractsPerChainID[chainID] {
+		keys = append(keys, factoryIndexerKey(chainID, uniContract))
+	}
+	if !state.HasPoolCounts(chainID) {
+		state.ResetPoolCounts(chainID, keys)
+	}
+
+	var chainErr error
+	for _, uniContract := range UniswapContractsPerChainID[chainID] {
+		start := uniContract.BlockNumber.Uint64()
+		var err error
+		if uniContract.Type == 2 {
+			err = indexPairCreated(ctx, state, chainID, uniContract.ContractAddress, start, func(event *contracts.UniV2FactoryPairCreated) {
+				state.CountPool(chainID, event.Pair.Hex(), event.Raw.BlockNumber, event.Token0.Hex(), event.Token1.Hex())
+				scan.Pairs[event.Pair.Hex()] = *event
+			})
+		} else if uniContract.Type == 3 {
+			err = indexPoolCreated(ctx, state, chainID, uniContract.ContractAddress, start, func(event *contracts.UniV3FactoryPoolCreated) {
+				state.CountPool(chainID, event.Pool.Hex(), event.Raw.BlockNumber, event.Token0.Hex(), event.Token1.Hex())
+				scan.Pools[event.Pool.Hex()] = *event
+			})
+		}
+		if err != nil {
+			logs.Error("Error fetching all tokens from uniswap factory contract: ", err)
+			chainErr = err
+		}
+	}
+	if chainErr == nil {
+		dropLegacyLastBlockSync(metadata, chainID)
+	}
+
+	/**********************************************************************************************
+	** The next run reads again, after a reorg, the blocks down to the confirmations before the
+	** checkpoints: only the pools of these blocks must be remembered.
+	**********************************************************************************************/
+	oldestCheckpoint, hasCheckpoint := uint64(0), false
+	for _, key := range keys {
+		if checkpoint, ok := state.GetCheckpoint(key); ok && (!hasCheckpoint || checkpoint.Block < oldestCheckpoint) {
+			oldestCheckpoint, hasCheckpoint = checkpoint.Block, true
+		}
+	}
+	if confirmations := ethereum.ConfirmationsFor(chainID); hasCheckpoint && oldestCheckpoint > confirmations {
+		state.ForgetPoolsBefore(chainID, oldestCheckpoint-confirmations)
+	}
+
+	for _, event := range scan.Pairs {
+		scan.TokenCount[event.Token0.Hex()] = state.TokenPoolCount(chainID, event.Token0.Hex())
+		scan.TokenCount[event.Token1.Hex()] = state.TokenPoolCount(chainID, event.Token1.Hex())
+	}
+	for _, event := range scan.Pools {
+		scan.TokenCount[event.Token0.Hex()] = state.TokenPoolCount(chainID, event.Token0.Hex())
+		scan.TokenCount[event.Token1.Hex()] = state.TokenPoolCount(chainID, event.Token1.Hex())
+	}
+	return scan, chainErr
+}
+
+// POOL_TOKEN_DECIMALS are the decimals of the entries of the pairs and pools lists. The LP token of
+// a UniswapV2 like pair always has 18 decimals, whatever the decimals of its underlying tokens.
+const POOL_TOKEN_DECIMALS = 18
+
+// pairMetadata returns the TPoolMetadata of a pair created by a UniswapV2 like factory
+func pairMetadata(protocol string, event contracts.UniV2FactoryPairCreated) models.TPoolMetadata {
+	pool := models.NewPoolMetadata(protocol, `v2`, []string{event.Token0.Hex(), event.Token1.Hex()})
+	pool.Factory = event.Raw.Address.Hex()
+	pool.CreationBlock = event.Raw.BlockNumber
+	return pool
+}
+
+// v3PoolMetadata returns the TPoolMetadata of a pool created by a UniswapV3 like factory
+func v3PoolMetadata(protocol string, event contracts.UniV3FactoryPoolCreated) models.TPoolMetadata {
+	pool := models.NewPoolMetadata(protocol, `v3`, []string{event.Token0.Hex(), event.Token1.Hex()})
+	pool.Factory = event.Raw.Address.Hex()
+	pool.CreationBlock = event.Raw.BlockNumber
+	pool.FeeTier = event.Fee.Uint64()
+	pool.TickSpacing = event.TickSpacing.Int64()
+	return pool
+}
@@ -1,5 +1,13 @@
 package main
 
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+)
+
 type TGenerationMethods string
 type TGeneratorType string
 
@@ -20,12 +28,15 @@ const (
 )
 
 type TGenerators struct {
-	Exec             func()
+	Exec             func(ctx context.Context) (helpers.TSaveResult, error)
 	Name             string
 	Description      string
 	GenerationMethod TGenerationMethods
 	GeneratorType    TGeneratorType
-	Tags             []string //
+	Tags             []string      //
+	Timeout          time.Duration // Overrides the scheduler default deadline when set
+	Required         bool          // The run fails if this generator does not succeed
+	DependsOn        []string      // Lists read by the generator, which then runs after the generators and the aggregated lists
 }
 
 var GENERATORS = map[string]TGenerators{
@@ -53,9 +64,10 @@ var GENERATORS = map[string]TGenerators{
 	`ajna-static`: {
 		Exec:             buildAjnaStaticTokenList,
 		Name:             `Ajna (Static)`,
-		Description:      `A list of non-rebased tokens that could work on Ajna.`,
+		Description:      `A list of tokens that could work on Ajna, without the rebasing and fee on transfer tokens.`,
 		GenerationMethod: GenerationExternalList,
 		GeneratorType:    GeneratorToken,
+		DependsOn:        AJNA_STATIC_SOURCES,
 	},
 	`bebop`: {
 		Exec:             buildBebopTokenList,
@@ -77,6 +89,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of tokens available showing in CoinGecko data agregator.`,
 		GenerationMethod: GenerationAPI,
 		GeneratorType:    GeneratorToken,
+		Required:         true,
 	},
 	`consensys`: {
 		Exec:             buildConsensysTokenList,
@@ -105,6 +118,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of tokens available in DefiLlama token service`,
 		GenerationMethod: GenerationExternalList,
 		GeneratorType:    GeneratorToken,
+		Required:         true,
 	},
 	// `ethereum-etherscan`: { // deprecated, use scan-1 instead
 	// 	Exec:             buildScanTokenList_1,
@@ -182,6 +196,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of tokens supported by Smoldapp Token Assets repository`,
 		GenerationMethod: GenerationAPI,
 		GeneratorType:    GeneratorToken,
+		Required:         true,
 	},
 	`sushiswap-pairs`: {
 		Exec:             buildSushiswapPairsTokenList,
@@ -189,6 +204,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of token used in the SushiSwap Liquidity Pools.`,
 		GenerationMethod: GenerationEvents,
 		GeneratorType:    GeneratorPool,
+		Timeout:          4 * time.Hour,
 	},
 	`sushiswap-pools`: {
 		Exec:             buildSushiswapPoolsTokenList,
@@ -196,6 +212,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of Liquidity Pool available on SushiSwap DEX.`,
 		GenerationMethod: GenerationEvents,
 		GeneratorType:    GeneratorPool,
+		Timeout:          4 * time.Hour,
 	},
 	`sushiswap`: {
 		Exec:             buildSushiswapTokenList,
@@ -210,6 +227,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of token pairs (liquidity pools) available for trading on UniSwap.`,
 		GenerationMethod: GenerationEvents,
 		GeneratorType:    GeneratorPool,
+		Timeout:          4 * time.Hour,
 	},
 	`uniswap-pools`: {
 		Exec:             buildUniswapPoolsTokenList,
@@ -217,6 +235,15 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of Liquidity Pool available on Uniswap V2 DEX.`,
 		GenerationMethod: GenerationEvents,
 		GeneratorType:    GeneratorPool,
+		Timeout:          4 * time.Hour,
+	},
+	`uniswap-v3-pools`: {
+		Exec:             buildUniswapV3PoolsTokenList,
+		Name:             `UniSwap (V3 pools)`,
+		Description:      `A list of Liquidity Pool available on Uniswap V3 DEX, with their fee tier and tick spacing.`,
+		GenerationMethod: GenerationEvents,
+		GeneratorType:    GeneratorPool,
+		Timeout:          4 * time.Hour,
 	},
 	`uniswap`: {
 		Exec:             buildUniswapTokenList,
@@ -224,6 +251,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of tokens available on UniSwap DEX.`,
 		GenerationMethod: GenerationExternalList,
 		GeneratorType:    GeneratorToken,
+		Required:         true,
 	},
 	`velodrome`: {
 		Exec:             buildVeloTokenList,
@@ -238,6 +266,7 @@ var GENERATORS = map[string]TGenerators{
 		Description:      `A list of Yearn's vaults and their underlying tokens.`,
 		GenerationMethod: GenerationAPI,
 		GeneratorType:    GeneratorToken,
+		Required:         true,
 	},
 	`yearn-min`: {
 		Exec:             buildYearnMinimalTokenList,
@@ -254,3 +283,32 @@ var GENERATORS = map[string]TGenerators{
 		GeneratorType:    GeneratorToken,
 	},
 }
+
+// generatorNames returns the names of the generators, sorted, to go through GENERATORS in the same
+// order on every run
+func generatorNames() []string {
+	names := make([]string, 0, len(GENERATORS))
+	for name := range GENERATORS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AGGREGATORS are the lists built from the output of the other generators, once they are all done
+var AGGREGATORS = map[string]TGenerators{
+	`tokenlistooor`: {
+		Exec:          buildTokenListooorList,
+		Name:          `Tokenlistooor`,
+		Description:   `A list of tokens found in at least two of the other lists.`,
+		GeneratorType: GeneratorToken,
+		Required:      true,
+	},
+	`popular`: {
+		Exec:          buildPopularList,
+		Name:          `Popular`,
+		Description:   `A list of the most popular tokens, across all the other lists.`,
+		GeneratorType: GeneratorToken,
+		Required:      true,
+	},
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/migratooor/tokenLists/generators/common/fixtures"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// updateGolden rewrites the golden files with the output of the tests, see assertGolden
+var updateGolden = flag.Bool(`update`, false, `rewrite the golden files of the tests`)
+
+// replayFixtures answers the HTTP calls of the test with the fixtures recorded for the generator,
+// under testdata/fixtures/<generator>/, and returns the context to call the generator with
+func replayFixtures(t *testing.T, generator string) context.Context {
+	t.Helper()
+	if err := fixtures.SetMode(string(fixtures.ModeReplay)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fixtures.SetMode(string(fixtures.ModeOff)) })
+	simulatedChain(t)
+	return fixtures.WithGenerator(context.Background(), generator)
+}
+
+/**************************************************************************************************
+** knownTokens adds the tokens to the ones known from the previous lists, as a run does before its
+** generators start, so their metadata is not asked to the chain. The tokens of the fixtures which
+** are not known are asked to the simulated chain, which has no contract at their address.
+**************************************************************************************************/
+func knownTokens(tokens ...models.TokenListToken) {
+	for _, token := range tokens {
+		if _, ok := helpers.ALL_EXISTING_TOKENS[token.ChainID]; !ok {
+			helpers.ALL_EXISTING_TOKENS[token.ChainID] = make(map[string]models.TokenListToken)
+		}
+		helpers.ALL_EXISTING_TOKENS[token.ChainID][token.Address] = token
+	}
+}
+
+/**************************************************************************************************
+** assertGolden compares the tokens, sorted by chainID and address, with the golden file of the
+** generator in testdata/golden/. Running the tests with -update rewrites the golden file instead.
+**************************************************************************************************/
+func assertGolden(t *testing.T, generator string, tokens []models.TokenListToken) {
+	t.Helper()
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].ChainID != tokens[j].ChainID {
+			return tokens[i].ChainID < tokens[j].ChainID
+		}
+		return tokens[i].Address < tokens[j].Address
+	})
+	content, err := json.MarshalIndent(tokens, ``, "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = append(content, '\n')
+
+	goldenPath := filepath.Join(REPO_PATH, `testdata`, `golden`, generator+`.json`)
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenPath, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf(`missing golden file, run the tests with -update: %v`, err)
+	}
+	if !bytes.Equal(content, golden) {
+		t.Errorf("the output of %s does not match %s:\n%s", generator, goldenPath, content)
+	}
+}
+
+// FIXTURE_TOKENS are the tokens of the fixtures of testdata/fixtures, known by the golden tests
+var FIXTURE_TOKENS = []models.TokenListToken{
+	{Address: `0xdAC17F958D2ee523a2206206994597C13D831ec7`, Name: `Tether USD`, Symbol: `USDT`, Decimals: 6, ChainID: 1},
+	{Address: `0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48`, Name: `USD Coin`, Symbol: `USDC`, Decimals: 6, ChainID: 1},
+	{Address: `0x6B175474E89094C44Da98b954EedeAC495271d0F`, Name: `Dai Stablecoin`, Symbol: `DAI`, Decimals: 18, ChainID: 1},
+	{Address: `0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2`, Name: `Wrapped Ether`, Symbol: `WETH`, Decimals: 18, ChainID: 1},
+	{Address: `0x111111111117dC0aa78b770fA6A738034120C302`, Name: `1INCH Token`, Symbol: `1INCH`, Decimals: 18, ChainID: 1},
+	{Address: `0xdA816459F1AB5631232FE5e97a05BBBb94970c95`, Name: `DAI yVault`, Symbol: `yvDAI`, Decimals: 18, ChainID: 1},
+	{Address: `0x6c3F90f043a72FA612cbac8115EE7e52BDe6E490`, Name: `Curve.fi DAI/USDC/USDT`, Symbol: `3Crv`, Decimals: 18, ChainID: 1},
+}
+
+// fetchEtherscanTokenList fetches the etherscan list of TEST_CHAIN_ID, the only chain of the tests
+func fetchEtherscanTokenList(ctx context.Context) ([]models.TokenListToken, error) {
+	return fetchScanTokenList(ctx, TEST_CHAIN_ID)
+}
+
+/**************************************************************************************************
+** TestGoldenTokenLists replays the fixtures of testdata/fixtures and compares the tokens built by
+** the generators with the golden files of testdata/golden, the metadata of the tokens being known
+** beforehand and the chain simulated. After a change in a parser, or a new recording, running the
+** tests with -update rewrites the golden files, whose diff shows the effect of the change.
+** The generators calling an HTTP API which are not in the map below are not covered yet: to cover
+** one, record its fixtures with `generate --fixtures record <generator>`, add it here and run the
+** tests with -update.
+**************************************************************************************************/
+func TestGoldenTokenLists(t *testing.T) {
+	knownTokens(FIXTURE_TOKENS...)
+	for generator, fetch := range map[string]func(ctx context.Context) ([]models.TokenListToken, error){
+		`1inch`:     fetch1InchTokenList,
+		`coingecko`: fetchCoingeckoTokenList,
+		`curve`:     fetchCurveTokenList,
+		`defillama`: fetchDefillamaTokenList,
+		`etherscan`: fetchEtherscanTokenList,
+		`ledger`:    fetchLedgerTokenList,
+		`messari`:   fetchMessariTokenList,
+		`paraswap`:  fetchParaswapTokenList,
+		`portals`:   fetchPortalsTokenList,
+		`tns`:       fetchTNSTokeList,
+		`yearn-min`: fetchYearnMinTokenList,
+	} {
+		generator, fetch := generator, fetch
+		t.Run(generator, func(t *testing.T) {
+			tokens, err := fetch(replayFixtures(t, generator))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, generator, tokens)
+		})
+	}
+}
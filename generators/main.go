@@ -1,50 +1,33 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
-	"strings"
-
-	"github.com/migratooor/tokenLists/generators/common/ethereum"
-	"github.com/migratooor/tokenLists/generators/common/logs"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	ethereum.Init()
-	loadAllTokenLogoURI()
-
 	if len(os.Args) < 2 {
-		for name, generator := range GENERATORS {
-			logs.Info(`Running generator:`, strings.ToTitle(name))
-			generator.Exec()
-			logs.Success(`Done!`)
-		}
-	} else if (len(os.Args) == 2) && (os.Args[1] == `tokens`) {
-		for name, generator := range GENERATORS {
-			if generator.GeneratorType == GeneratorToken {
-				logs.Info(`Running generator:`, strings.ToTitle(name))
-				generator.Exec()
-				logs.Success(`Done!`)
-			}
-		}
-	} else if (len(os.Args) == 2) && (os.Args[1] == `pools`) {
-		for name, generator := range GENERATORS {
-			if generator.GeneratorType == GeneratorPool {
-				logs.Info(`Running generator:`, strings.ToTitle(name))
-				generator.Exec()
-				logs.Success(`Done!`)
-			}
-		}
-	} else {
-		for _, arg := range os.Args[1:] {
-			if _, ok := GENERATORS[arg]; ok {
-				logs.Info(`Running generator:`, strings.ToTitle(arg))
-				GENERATORS[arg].Exec()
-				logs.Success(`Done!`)
-			}
-		}
+		printUsage()
+		os.Exit(exitUsage)
+	}
+	switch os.Args[1] {
+	case `help`, `-h`, `-help`, `--help`:
+		printUsage()
+		os.Exit(exitSuccess)
+	}
+
+	command, ok := findCommand(os.Args[1])
+	if !ok {
+		fmt.Fprintln(os.Stderr, `Error: unknown command `+os.Args[1])
+		printUsage()
+		os.Exit(exitUsage)
 	}
 
-	buildTokenListooorList()
-	buildPopularList()
-	buildSummary()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	exitCode := command.Run(ctx, newFlagSet(command), os.Args[2:])
+	stop()
+	os.Exit(exitCode)
 }
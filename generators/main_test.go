@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/chains"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/simulated"
+)
+
+// TEST_CHAIN_ID is the chain the tests run on, replaced by a simulated chain, see simulatedChain
+const TEST_CHAIN_ID = uint64(1)
+
+// REPO_PATH is the root of the repository, where the fixtures and the golden files are read from
+var REPO_PATH = helpers.BASE_PATH
+
+/**************************************************************************************************
+** TestMain runs the tests on TEST_CHAIN_ID only, with the lists and the stores in a temporary
+** folder, so the tests never change the files of the repository. The fixtures and the schema are
+** still read from the repository.
+**************************************************************************************************/
+func TestMain(m *testing.M) {
+	basePath, err := os.MkdirTemp(``, `tokenLists-`)
+	if err != nil {
+		panic(err)
+	}
+	helpers.BASE_PATH = basePath
+	helpers.METADATA_PATH = basePath + `/data/metadata`
+	helpers.PROXIES_PATH = basePath + `/data/proxies`
+	helpers.BEHAVIOUR_PATH = basePath + `/data/behaviour`
+	helpers.INDEXER_STATE_PATH = basePath + `/data/indexer`
+	helpers.QUARANTINE_PATH = basePath + `/data/quarantine`
+	if err := os.MkdirAll(basePath+`/lists`, 0755); err != nil {
+		panic(err)
+	}
+	if err := chains.SetChainFilter([]uint64{TEST_CHAIN_ID}); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(basePath)
+	os.Exit(code)
+}
+
+var simulatedChainOnce = sync.Once{}
+var sharedSimulatedChain *simulated.TBackend
+
+// simulatedChain returns the simulated chain installed on TEST_CHAIN_ID, shared by the tests as the
+// stores of the helpers keep the tokens they read in memory
+func simulatedChain(t *testing.T) *simulated.TBackend {
+	t.Helper()
+	simulatedChainOnce.Do(func() {
+		backend, err := simulated.NewBackend()
+		if err != nil {
+			t.Fatal(err)
+		}
+		multicall, err := backend.DeployMulticall3()
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.Install(TEST_CHAIN_ID, multicall)
+		sharedSimulatedChain = backend
+	})
+	if sharedSimulatedChain == nil {
+		t.Fatal(`the simulated chain could not be installed`)
+	}
+	return sharedSimulatedChain
+}
+
+// deployERC20 deploys a standard ERC20 on the simulated chain
+func deployERC20(t *testing.T, name, symbol string) common.Address {
+	t.Helper()
+	address, err := simulatedChain(t).DeployERC20(name, symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// SOURCE_ONCHAIN is the source of the fields read on-chain
+const SOURCE_ONCHAIN = `onchain`
+
+/**************************************************************************************************
+** FIELD_SOURCE_PRIORITY breaks the ties of the majority votes on the fields of a token: when two
+** values are given by as many lists, the value of the list coming first here wins. The lists
+** missing from FIELD_SOURCE_PRIORITY come after, in the order of their names.
+**************************************************************************************************/
+var FIELD_SOURCE_PRIORITY = []string{
+	`smolAssets`,
+	`yearn`,
+	`coingecko`,
+	`uniswap`,
+	`1inch`,
+	`paraswap`,
+	`cowswap`,
+	`sushiswap`,
+}
+
+// TFieldProvenance is the source of each field of a token of an aggregated list
+type TFieldProvenance struct {
+	Name     string `json:"name,omitempty"`
+	Symbol   string `json:"symbol,omitempty"`
+	LogoURI  string `json:"logoURI,omitempty"`
+	Decimals string `json:"decimals,omitempty"`
+}
+
+// TFieldConflict is a field of a token the sources disagree on: the sources giving each value,
+// and the value kept
+type TFieldConflict struct {
+	Field    string              `json:"field"`
+	Values   map[string][]string `json:"values"`
+	Resolved string              `json:"resolved"`
+}
+
+// tFieldVotes are the values of a field of a token, with the sources giving each of them
+type tFieldVotes map[string][]string
+
+// tTokenVotes are the values of the fields of a token in all the lists it is in
+type tTokenVotes struct {
+	Name     tFieldVotes
+	Symbol   tFieldVotes
+	LogoURI  tFieldVotes
+	Decimals tFieldVotes
+}
+
+func newTokenVotes() *tTokenVotes {
+	return &tTokenVotes{
+		Name:     tFieldVotes{},
+		Symbol:   tFieldVotes{},
+		LogoURI:  tFieldVotes{},
+		Decimals: tFieldVotes{},
+	}
+}
+
+// add records the fields of the token as given by the source. The empty values, the placeholder
+// icons and the decimals set to 0 are not votes.
+func (votes *tTokenVotes) add(source string, token models.TokenListToken) {
+	if token.Name != `` {
+		votes.Name[token.Name] = append(votes.Name[token.Name], source)
+	}
+	if token.Symbol != `` {
+		votes.Symbol[token.Symbol] = append(votes.Symbol[token.Symbol], source)
+	}
+	if helpers.HasIcon(token.LogoURI) {
+		votes.LogoURI[token.LogoURI] = append(votes.LogoURI[token.LogoURI], source)
+	}
+	if token.Decimals != 0 {
+		decimals := strconv.Itoa(token.Decimals)
+		votes.Decimals[decimals] = append(votes.Decimals[decimals], source)
+	}
+}
+
+// sourceRank returns the position of the source in FIELD_SOURCE_PRIORITY, or the length of the
+// list for the other sources
+func sourceRank(source string) int {
+	for i, prioritySource := range FIELD_SOURCE_PRIORITY {
+		if prioritySource == source {
+			return i
+		}
+	}
+	return len(FIELD_SOURCE_PRIORITY)
+}
+
+// bestSource returns the source with the highest priority, then the first in the order of names
+func bestSource(sources []string) string {
+	best := ``
+	for _, source := range sources {
+		if best == `` || sourceRank(source) < sourceRank(best) || (sourceRank(source) == sourceRank(best) && source < best) {
+			best = source
+		}
+	}
+	return best
+}
+
+/**************************************************************************************************
+** resolve returns the value given by the most sources, along with the source it is credited to.
+** A tie goes to the value of the source with the highest priority, see FIELD_SOURCE_PRIORITY,
+** then to the smallest value, so the result never depends on the order the lists are read in.
+**************************************************************************************************/
+func (votes tFieldVotes) resolve() (string, string) {
+	value, source := ``, ``
+	for candidate, sources := range votes {
+		candidateSource := bestSource(sources)
+		switch {
+		case value == ``,
+			len(sources) > len(votes[value]),
+			len(sources) == len(votes[value]) && sourceRank(candidateSource) < sourceRank(source),
+			len(sources) == len(votes[value]) && sourceRank(candidateSource) == sourceRank(source) && candidate < value:
+			value, source = candidate, candidateSource
+		}
+	}
+	return value, source
+}
+
+// conflict returns the conflict of the field, if the sources give more than one value
+func (votes tFieldVotes) conflict(field string, resolved string) (TFieldConflict, bool) {
+	if len(votes) < 2 {
+		return TFieldConflict{}, false
+	}
+	values := map[string][]string{}
+	for value, sources := range votes {
+		values[value] = append([]string{}, sources...)
+		sort.Strings(values[value])
+	}
+	return TFieldConflict{Field: field, Values: values, Resolved: resolved}, true
+}
+
+/**************************************************************************************************
+** resolveToken builds a token from the votes of the lists it is in. The name, the symbol and the
+** logo are decided by a majority vote. The decimals read on-chain, in the metadata store, are
+** authoritative and count as a vote of SOURCE_ONCHAIN, so a list disagreeing with the chain is
+** reported as a conflict; the majority vote is only used for the tokens never read on-chain.
+**************************************************************************************************/
+func resolveToken(chainID uint64, address string, votes *tTokenVotes) (models.TokenListToken, TFieldProvenance, []TFieldConflict) {
+	token := models.TokenListToken{Address: address, ChainID: chainID}
+	provenance := TFieldProvenance{}
+	token.Name, provenance.Name = votes.Name.resolve()
+	token.Symbol, provenance.Symbol = votes.Symbol.resolve()
+	token.LogoURI, provenance.LogoURI = votes.LogoURI.resolve()
+
+	decimals, decimalsSource := votes.Decimals.resolve()
+	if metadata, ok := helpers.GetStoredMetadata(chainID, common.HexToAddress(address)); ok && metadata.Decimals != 0 {
+		decimals, decimalsSource = strconv.FormatUint(metadata.Decimals, 10), SOURCE_ONCHAIN
+		votes.Decimals[decimals] = append(votes.Decimals[decimals], SOURCE_ONCHAIN)
+	}
+	token.Decimals, _ = strconv.Atoi(decimals)
+	token.Decimals = helpers.SafeInt(token.Decimals, 18)
+	provenance.Decimals = decimalsSource
+
+	conflicts := []TFieldConflict{}
+	for _, field := range []struct {
+		name     string
+		votes    tFieldVotes
+		resolved string
+	}{
+		{`name`, votes.Name, token.Name},
+		{`symbol`, votes.Symbol, token.Symbol},
+		{`decimals`, votes.Decimals, decimals},
+	} {
+		if conflict, ok := field.votes.conflict(field.name, field.resolved); ok {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return token, provenance, conflicts
+}
+
+/**************************************************************************************************
+** applyResolvedFields sets the name, the symbol and the logo decided by the aggregation on the
+** tokens read on-chain, whose decimals are kept, and records the source of each field in the
+** provenance key of their metadata, next to the keys they already have. The tokens the lists do
+** not know, like the coins of the chains, are left as they are.
+**************************************************************************************************/
+func (aggregation TAggregation) applyResolvedFields(tokens []models.TokenListToken) []models.TokenListToken {
+	for i, token := range tokens {
+		address := common.HexToAddress(token.Address).Hex()
+		resolvedToken, ok := aggregation.Tokens[token.ChainID][address]
+		if !ok {
+			continue
+		}
+		provenance := aggregation.Provenance[token.ChainID][address]
+		provenance.Decimals = SOURCE_ONCHAIN
+		tokens[i].Name = helpers.SafeString(resolvedToken.Name, token.Name)
+		tokens[i].Symbol = helpers.SafeString(resolvedToken.Symbol, token.Symbol)
+		if resolvedToken.LogoURI != `` {
+			tokens[i].LogoURI = resolvedToken.LogoURI
+		} else {
+			provenance.LogoURI = ``
+		}
+		metadata := make(map[string]interface{}, len(token.Metadata)+1)
+		for key, value := range token.Metadata {
+			metadata[key] = value
+		}
+		metadata[`provenance`] = provenance
+		tokens[i].Metadata = metadata
+	}
+	return tokens
+}
+
+// getConflictsReportPath returns the file listing the fields the lists disagree on
+func getConflictsReportPath() string {
+	return helpers.BASE_PATH + `/data/aggregation/conflicts.json`
+}
+
+/**************************************************************************************************
+** saveConflictsReport writes the fields the lists disagree on in getConflictsReportPath, per chainID
+** and address, so the sources giving wrong decimals or names can be found and fixed.
+**************************************************************************************************/
+func (aggregation TAggregation) saveConflictsReport() error {
+	if helpers.DRY_RUN {
+		return nil
+	}
+	return helpers.SaveJSONFile(getConflictsReportPath(), aggregation.Conflicts)
+}
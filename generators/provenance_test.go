@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+func TestApplyResolvedFieldsKeepsTheMetadata(t *testing.T) {
+	address := `0x6B175474E89094C44Da98b954EedeAC495271d0F`
+	metadata := map[string]interface{}{`protocol`: `velodrome`}
+	aggregation := TAggregation{
+		Tokens: map[uint64]map[string]models.TokenListToken{
+			TEST_CHAIN_ID: {address: {Address: address, ChainID: TEST_CHAIN_ID, Name: `Dai Stablecoin`, Symbol: `DAI`}},
+		},
+		Provenance: map[uint64]map[string]TFieldProvenance{
+			TEST_CHAIN_ID: {address: {Name: `uniswap`, Symbol: `uniswap`}},
+		},
+	}
+
+	tokens := aggregation.applyResolvedFields([]models.TokenListToken{
+		{Address: address, ChainID: TEST_CHAIN_ID, Name: `Dai`, Symbol: `DAI`, Decimals: 18, Metadata: metadata},
+	})
+	if tokens[0].Name != `Dai Stablecoin` {
+		t.Errorf(`got the name %s`, tokens[0].Name)
+	}
+	if tokens[0].Metadata[`protocol`] != `velodrome` {
+		t.Errorf(`the metadata of the token was dropped: %v`, tokens[0].Metadata)
+	}
+	if _, ok := tokens[0].Metadata[`provenance`].(TFieldProvenance); !ok {
+		t.Errorf(`the provenance is missing from %v`, tokens[0].Metadata)
+	}
+	if _, ok := metadata[`provenance`]; ok {
+		t.Error(`the metadata map of the token was modified in place`)
+	}
+}
+
+func TestResolveVotes(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		votes  tFieldVotes
+		value  string
+		source string
+	}{
+		{`majority`, tFieldVotes{`Dai`: {`paraswap`, `sushiswap`}, `Dai Stablecoin`: {`smolAssets`}}, `Dai`, `paraswap`},
+		{`credited to the source with the highest priority`, tFieldVotes{`Dai`: {`sushiswap`, `1inch`, `zkSync`}}, `Dai`, `1inch`},
+		{`tie broken by the priority`, tFieldVotes{`Dai`: {`paraswap`}, `Dai Stablecoin`: {`coingecko`}}, `Dai Stablecoin`, `coingecko`},
+		{`tie broken by the priority of the best source`, tFieldVotes{`Dai`: {`cowswap`, `yearn`}, `Dai Stablecoin`: {`coingecko`, `uniswap`}}, `Dai`, `yearn`},
+		{`tie between unknown sources broken by the smallest value`, tFieldVotes{`Dai`: {`portals`}, `DAI`: {`ledger`}}, `DAI`, `ledger`},
+		{`no votes`, tFieldVotes{}, ``, ``},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if value, source := test.votes.resolve(); value != test.value || source != test.source {
+				t.Errorf(`got %q from %q, want %q from %q`, value, source, test.value, test.source)
+			}
+		})
+	}
+}
+
+func TestResolveTokenDoesNotDependOnTheOrderOfTheLists(t *testing.T) {
+	address := `0x6B175474E89094C44Da98b954EedeAC495271d0F`
+	lists := []struct {
+		source string
+		token  models.TokenListToken
+	}{
+		{`portals`, models.TokenListToken{Name: `Dai`, Symbol: `DAI`, Decimals: 18, LogoURI: `https://portals.example/dai.png`}},
+		{`ledger`, models.TokenListToken{Name: `Dai Stablecoin`, Symbol: `dai`, Decimals: 18, LogoURI: `https://ledger.example/dai.png`}},
+		{`paraswap`, models.TokenListToken{Name: `Dai`, Symbol: `DAI`, Decimals: 18}},
+		{`coingecko`, models.TokenListToken{Name: `Dai Stablecoin`, Symbol: `DAI`, Decimals: 18, LogoURI: `https://coingecko.example/dai.png`}},
+		{`zkSync`, models.TokenListToken{Name: `Maker Dai`, Symbol: `DAI`, Decimals: 6}},
+	}
+
+	var first models.TokenListToken
+	var firstProvenance TFieldProvenance
+	for seed := int64(0); seed < 20; seed++ {
+		votes := newTokenVotes()
+		for _, i := range rand.New(rand.NewSource(seed)).Perm(len(lists)) {
+			votes.add(lists[i].source, lists[i].token)
+		}
+		token, provenance, _ := resolveToken(TEST_CHAIN_ID, address, votes)
+		if seed == 0 {
+			first, firstProvenance = token, provenance
+			continue
+		}
+		if !reflect.DeepEqual(token, first) || provenance != firstProvenance {
+			t.Fatalf(`got %+v from %+v, then %+v from %+v`, first, firstProvenance, token, provenance)
+		}
+	}
+
+	// Dai and Dai Stablecoin both have 2 votes, coingecko coming before paraswap
+	want := TFieldProvenance{Name: `coingecko`, Symbol: `coingecko`, LogoURI: `coingecko`, Decimals: `coingecko`}
+	if first.Name != `Dai Stablecoin` || first.Symbol != `DAI` || first.LogoURI != `https://coingecko.example/dai.png` || first.Decimals != 18 {
+		t.Errorf(`got the token %+v`, first)
+	}
+	if firstProvenance != want {
+		t.Errorf(`got the provenance %+v, want %+v`, firstProvenance, want)
+	}
+}
+
+func TestResolveTokenPrefersTheOnChainDecimals(t *testing.T) {
+	address, err := simulatedChain(t).DeployBytes32ERC20(`USD Coin`, `USDC`, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	helpers.RetrieveBasicInformations(context.Background(), TEST_CHAIN_ID, []common.Address{address})
+
+	votes := newTokenVotes()
+	votes.add(`coingecko`, models.TokenListToken{Name: `USD Coin`, Symbol: `USDC`, Decimals: 18})
+	votes.add(`uniswap`, models.TokenListToken{Name: `USD Coin`, Symbol: `USDC`, Decimals: 18})
+	votes.add(`portals`, models.TokenListToken{Name: `USDC`, Symbol: `USDC`, Decimals: 6})
+	token, provenance, conflicts := resolveToken(TEST_CHAIN_ID, address.Hex(), votes)
+	if token.Decimals != 6 || provenance.Decimals != SOURCE_ONCHAIN {
+		t.Errorf(`got %d decimals from %s, want 6 from the chain`, token.Decimals, provenance.Decimals)
+	}
+
+	want := []TFieldConflict{
+		{Field: `name`, Values: map[string][]string{`USD Coin`: {`coingecko`, `uniswap`}, `USDC`: {`portals`}}, Resolved: `USD Coin`},
+		{Field: `decimals`, Values: map[string][]string{`18`: {`coingecko`, `uniswap`}, `6`: {`onchain`, `portals`}}, Resolved: `6`},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf(`got the conflicts %+v, want %+v`, conflicts, want)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/ethereum"
+	"github.com/migratooor/tokenLists/generators/common/fixtures"
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/logs"
+	"github.com/migratooor/tokenLists/generators/common/models"
+	"github.com/migratooor/tokenLists/generators/common/stats"
+)
+
+type TGeneratorStatus string
+
+const (
+	// GeneratorStatusSuccess indicates that the generator returned before its deadline
+	GeneratorStatusSuccess TGeneratorStatus = "success"
+	// GeneratorStatusFailed indicates that the generator returned an error or panicked
+	GeneratorStatusFailed TGeneratorStatus = "failed"
+	// GeneratorStatusTimeout indicates that the generator did not return before its deadline
+	GeneratorStatusTimeout TGeneratorStatus = "timeout"
+	// GeneratorStatusCancelled indicates that the run was cancelled before or while the generator was running
+	GeneratorStatusCancelled TGeneratorStatus = "cancelled"
+)
+
+// TSchedulerOptions holds the settings used to run the generators
+type TSchedulerOptions struct {
+	Concurrency int           // Maximum number of generators running at the same time
+	Timeout     time.Duration // Default deadline for a single generator
+}
+
+// TGeneratorResult is the outcome of a single generator execution, as written in the run report
+type TGeneratorResult struct {
+	Name               string                   `json:"name"`
+	Status             TGeneratorStatus         `json:"status"`
+	Error              string                   `json:"error,omitempty"`
+	Required           bool                     `json:"required"`
+	Changed            bool                     `json:"changed"`
+	Bump               helpers.TVersionBump     `json:"bump,omitempty"`
+	TokenCountPerChain map[uint64]int           `json:"tokenCountPerChain"`
+	VersionBefore      models.TTokenListVersion `json:"versionBefore"`
+	VersionAfter       models.TTokenListVersion `json:"versionAfter"`
+	DurationSeconds    float64                  `json:"durationSeconds"`
+	RPCCalls           uint64                   `json:"rpcCalls"`
+	HTTPCalls          uint64                   `json:"httpCalls"`
+	Duration           time.Duration            `json:"-"`
+}
+
+// IsBlocking returns true if the result should make the whole run fail
+func (r TGeneratorResult) IsBlocking() bool {
+	return r.Required && r.Status != GeneratorStatusSuccess
+}
+
+/**************************************************************************************************
+** defaultSchedulerOptions returns the scheduler options, using the GENERATORS_CONCURRENCY and
+** GENERATORS_TIMEOUT env variables when they are set.
+**************************************************************************************************/
+func defaultSchedulerOptions() TSchedulerOptions {
+	options := TSchedulerOptions{
+		Concurrency: 4,
+		Timeout:     time.Hour,
+	}
+	if value, err := strconv.Atoi(os.Getenv(`GENERATORS_CONCURRENCY`)); err == nil && value > 0 {
+		options.Concurrency = value
+	}
+	if value, err := time.ParseDuration(os.Getenv(`GENERATORS_TIMEOUT`)); err == nil && value > 0 {
+		options.Timeout = value
+	}
+	return options
+}
+
+/**************************************************************************************************
+** withListVersion sets the versions of a result without any, like the result of a generator which
+** timed out, panicked or never started, to the version of its list, <name>.json, as no generator
+** saves its list once it failed.
+**************************************************************************************************/
+func withListVersion(result TGeneratorResult) TGeneratorResult {
+	if result.VersionBefore != (models.TTokenListVersion{}) {
+		return result
+	}
+	if tokenList, err := helpers.ReadTokenListFromJsonFile(result.Name + `.json`); err == nil {
+		result.VersionBefore = tokenList.Version
+		result.VersionAfter = tokenList.Version
+	}
+	return result
+}
+
+// GENERATOR_GRACE_PERIOD is how long a generator is waited for to return once its context is done
+var GENERATOR_GRACE_PERIOD = 10 * time.Second
+
+/**************************************************************************************************
+** runGenerator executes one generator with its own deadline. The generator is run in a separate
+** goroutine so that a generator stuck on a call that ignores the context is reported as timed out
+** instead of blocking the whole run. Once its context is done, the generator is given
+** GENERATOR_GRACE_PERIOD to return before its slot is released, so it does not keep running next
+** to the following generators, and the metadata it read is only flushed once it returned. A
+** generator still running after that cannot save its list anymore as the save helper refuses to
+** write once the context is done, and its metadata is flushed at the end of the run.
+**************************************************************************************************/
+func runGenerator(ctx context.Context, name string, generator TGenerators, options TSchedulerOptions) TGeneratorResult {
+	timeout := options.Timeout
+	if generator.Timeout > 0 {
+		timeout = generator.Timeout
+	}
+	generatorCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	generatorCtx, counters := stats.WithCounters(generatorCtx)
+	generatorCtx = fixtures.WithGenerator(generatorCtx, name)
+
+	type TExecOutcome struct {
+		saveResult helpers.TSaveResult
+		err        error
+	}
+
+	start := time.Now()
+	done := make(chan TExecOutcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- TExecOutcome{err: fmt.Errorf(`generator panicked: %v`, r)}
+			}
+		}()
+		saveResult, err := generator.Exec(generatorCtx)
+		done <- TExecOutcome{saveResult: saveResult, err: err}
+	}()
+
+	result := TGeneratorResult{
+		Name:               name,
+		Required:           generator.Required,
+		TokenCountPerChain: map[uint64]int{},
+	}
+	stopped := true
+	select {
+	case outcome := <-done:
+		result.Status = GeneratorStatusSuccess
+		result.Changed = outcome.saveResult.Changed
+		result.Bump = outcome.saveResult.Diff.Bump
+		result.VersionBefore = outcome.saveResult.VersionBefore
+		result.VersionAfter = outcome.saveResult.VersionAfter
+		if outcome.saveResult.TokenCountPerChain != nil {
+			result.TokenCountPerChain = outcome.saveResult.TokenCountPerChain
+		}
+		if outcome.err != nil {
+			result.Status = GeneratorStatusFailed
+			result.Error = outcome.err.Error()
+		}
+	case <-generatorCtx.Done():
+		result.Status = GeneratorStatusTimeout
+		result.Error = `generator did not finish within ` + timeout.String()
+		if ctx.Err() != nil {
+			result.Status = GeneratorStatusCancelled
+			result.Error = ctx.Err().Error()
+		}
+		select {
+		case outcome := <-done:
+			result.VersionBefore = outcome.saveResult.VersionBefore
+			result.VersionAfter = outcome.saveResult.VersionAfter
+		case <-time.After(GENERATOR_GRACE_PERIOD):
+			stopped = false
+			logs.Warning(`Generator`, name, `did not return within`, GENERATOR_GRACE_PERIOD.String(), `after its context was done`)
+		}
+	}
+	if result.Status != GeneratorStatusSuccess {
+		result = withListVersion(result)
+	}
+	if stopped {
+		helpers.FlushMetadata()
+	}
+	result.Duration = time.Since(start)
+	result.DurationSeconds = result.Duration.Seconds()
+	result.RPCCalls = counters.RPC.Load()
+	result.HTTPCalls = counters.HTTP.Load()
+	return result
+}
+
+/**************************************************************************************************
+** runGenerators executes the given generators in parallel, with at most options.Concurrency of
+** them running at the same time. Generators are started in alphabetical order. Once ctx is
+** cancelled, the generators not yet started are skipped and reported as cancelled.
+**************************************************************************************************/
+func runGenerators(ctx context.Context, names []string, options TSchedulerOptions) []TGeneratorResult {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	sortedNames := append([]string{}, names...)
+	sort.Strings(sortedNames)
+
+	results := make([]TGeneratorResult, len(sortedNames))
+	slots := make(chan struct{}, options.Concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, name := range sortedNames {
+		select {
+		case <-ctx.Done():
+			results[i] = withListVersion(TGeneratorResult{
+				Name:               name,
+				Status:             GeneratorStatusCancelled,
+				Error:              ctx.Err().Error(),
+				Required:           GENERATORS[name].Required,
+				TokenCountPerChain: map[uint64]int{},
+			})
+			continue
+		case slots <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			logs.Info(`Running generator:`, strings.ToTitle(name))
+			results[i] = runGenerator(ctx, name, GENERATORS[name], options)
+			logGeneratorResult(results[i])
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// splitDependentGenerators separates the generators depending on other lists, see DependsOn, from
+// the ones which can run right away
+func splitDependentGenerators(names []string) ([]string, []string) {
+	independentNames, dependentNames := []string{}, []string{}
+	for _, name := range names {
+		if len(GENERATORS[name].DependsOn) > 0 {
+			dependentNames = append(dependentNames, name)
+		} else {
+			independentNames = append(independentNames, name)
+		}
+	}
+	return independentNames, dependentNames
+}
+
+/**************************************************************************************************
+** runDependentGenerators runs the generators depending on other lists once the other generators
+** and the aggregated lists are saved, so they read the lists of this run. A generator depending
+** on a list which did not succeed in this run is not started: it is reported as failed and its
+** previous list is kept.
+**************************************************************************************************/
+func runDependentGenerators(ctx context.Context, names []string, previousResults []TGeneratorResult, options TSchedulerOptions) []TGeneratorResult {
+	statuses := make(map[string]TGeneratorStatus)
+	for _, result := range previousResults {
+		statuses[result.Name] = result.Status
+	}
+
+	results := []TGeneratorResult{}
+	runnableNames := []string{}
+	for _, name := range names {
+		failedDependency := ``
+		for _, dependency := range GENERATORS[name].DependsOn {
+			if status, ok := statuses[dependency]; ok && status != GeneratorStatusSuccess && ctx.Err() == nil {
+				failedDependency = dependency
+				break
+			}
+		}
+		if failedDependency == `` {
+			runnableNames = append(runnableNames, name)
+			continue
+		}
+		result := withListVersion(TGeneratorResult{
+			Name:               name,
+			Status:             GeneratorStatusFailed,
+			Error:              `the list ` + failedDependency + ` it depends on did not succeed`,
+			Required:           GENERATORS[name].Required,
+			TokenCountPerChain: map[uint64]int{},
+		})
+		logGeneratorResult(result)
+		results = append(results, result)
+	}
+	return append(results, runGenerators(ctx, runnableNames, options)...)
+}
+
+// logGeneratorResult prints a one line summary of a generator execution
+func logGeneratorResult(result TGeneratorResult) {
+	if result.Status == GeneratorStatusSuccess {
+		logs.Success(`Done with ` + result.Name + ` in ` + result.Duration.Round(time.Second).String())
+		return
+	}
+	logs.Error(`Generator ` + result.Name + ` ` + string(result.Status) + `: ` + result.Error)
+}
+
+/**************************************************************************************************
+** writeRunReport saves the results of the run in run-report.json, at the root of the repository,
+** so that the CI can inspect what happened without parsing the logs.
+**************************************************************************************************/
+func writeRunReport(start time.Time, results []TGeneratorResult) error {
+	report := struct {
+		StartedAt       string                                   `json:"startedAt"`
+		DurationSeconds float64                                  `json:"durationSeconds"`
+		Success         bool                                     `json:"success"`
+		DryRun          bool                                     `json:"dryRun"`
+		Generators      []TGeneratorResult                       `json:"generators"`
+		RPCEndpoints    map[uint64][]ethereum.TRPCEndpointStatus `json:"rpcEndpoints"`
+	}{
+		StartedAt:       start.UTC().Format(time.RFC3339),
+		DurationSeconds: time.Since(start).Seconds(),
+		Success:         !hasBlockingFailure(results),
+		DryRun:          helpers.DRY_RUN,
+		Generators:      results,
+		RPCEndpoints:    map[uint64][]ethereum.TRPCEndpointStatus{},
+	}
+	for chainID, pool := range ethereum.RPC_POOLS {
+		report.RPCEndpoints[chainID] = pool.Status()
+	}
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(helpers.BASE_PATH+`/run-report.json`, jsonData, 0644)
+}
+
+// hasBlockingFailure returns true if one of the required generators did not succeed
+func hasBlockingFailure(results []TGeneratorResult) bool {
+	for _, result := range results {
+		if result.IsBlocking() {
+			return true
+		}
+	}
+	return false
+}
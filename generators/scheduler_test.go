@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/migratooor/tokenLists/generators/common/helpers"
+	"github.com/migratooor/tokenLists/generators/common/models"
+)
+
+// withTestGenerators adds the generators to GENERATORS until the end of the test
+func withTestGenerators(t *testing.T, generators map[string]TGenerators) {
+	t.Helper()
+	for name, generator := range generators {
+		GENERATORS[name] = generator
+	}
+	t.Cleanup(func() {
+		for name := range generators {
+			delete(GENERATORS, name)
+		}
+	})
+}
+
+// withGracePeriod changes GENERATOR_GRACE_PERIOD until the end of the test
+func withGracePeriod(t *testing.T, gracePeriod time.Duration) {
+	t.Helper()
+	previous := GENERATOR_GRACE_PERIOD
+	GENERATOR_GRACE_PERIOD = gracePeriod
+	t.Cleanup(func() { GENERATOR_GRACE_PERIOD = previous })
+}
+
+func TestRunGeneratorDoesNotSaveAfterTheTimeout(t *testing.T) {
+	withGracePeriod(t, 10*time.Millisecond)
+	listPath := helpers.BASE_PATH + `/lists/test-slow.json`
+	t.Cleanup(func() { os.Remove(listPath) })
+
+	// The generator ignores its context and only saves once released, after its deadline
+	release, saved := make(chan struct{}), make(chan error, 1)
+	generator := TGenerators{
+		Timeout: 20 * time.Millisecond,
+		Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			<-release
+			tokenList := models.TokenListData[models.TokenListToken]{Name: `Slow`}
+			result, err := helpers.SaveTokenListInJsonFile(ctx, tokenList, []models.TokenListToken{
+				{Address: `0x6B175474E89094C44Da98b954EedeAC495271d0F`, Name: `Dai Stablecoin`, Symbol: `DAI`, Decimals: 18, ChainID: TEST_CHAIN_ID},
+			}, `test-slow.json`, helpers.SavingMethodStandard)
+			saved <- err
+			return result, err
+		},
+	}
+
+	result := runGenerator(context.Background(), `test-slow`, generator, TSchedulerOptions{Timeout: time.Hour})
+	close(release)
+	if result.Status != GeneratorStatusTimeout {
+		t.Errorf(`got the status %s, want %s`, result.Status, GeneratorStatusTimeout)
+	}
+	if err := <-saved; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`the save after the deadline returned %v`, err)
+	}
+	if _, err := os.Stat(listPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf(`the list was written after the timeout: %v`, err)
+	}
+}
+
+func TestRunGeneratorReportsTheVersionOfTheListItDidNotSave(t *testing.T) {
+	listPath := helpers.BASE_PATH + `/lists/test-unsaved.json`
+	if err := os.WriteFile(listPath, []byte(`{"name":"Unsaved","version":{"major":2,"minor":3,"patch":4},"tokens":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(listPath) })
+	version := models.TTokenListVersion{Major: 2, Minor: 3, Patch: 4}
+
+	for _, test := range []struct {
+		status TGeneratorStatus
+		exec   func(ctx context.Context) (helpers.TSaveResult, error)
+	}{
+		{GeneratorStatusTimeout, func(ctx context.Context) (helpers.TSaveResult, error) {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			return helpers.TSaveResult{}, ctx.Err()
+		}},
+		{GeneratorStatusFailed, func(ctx context.Context) (helpers.TSaveResult, error) {
+			panic(`unexpected`)
+		}},
+		{GeneratorStatusFailed, func(ctx context.Context) (helpers.TSaveResult, error) {
+			return helpers.NotSavedResult(helpers.LoadTokenListFromJsonFile(`test-unsaved.json`)), errors.New(`source unavailable`)
+		}},
+	} {
+		generator := TGenerators{Timeout: 20 * time.Millisecond, Exec: test.exec}
+		result := runGenerator(context.Background(), `test-unsaved`, generator, TSchedulerOptions{Timeout: time.Hour})
+		if result.Status != test.status || result.VersionBefore != version || result.VersionAfter != version {
+			t.Errorf(`got the status %s and the versions %s and %s, want %s and %s`,
+				result.Status, result.VersionBefore.String(), result.VersionAfter.String(), test.status, version.String())
+		}
+	}
+}
+
+func TestRunGeneratorsBoundsTheConcurrency(t *testing.T) {
+	const concurrency = 2
+	running, maxRunning := atomic.Int32{}, atomic.Int32{}
+	generators := map[string]TGenerators{}
+	names := []string{`test-a`, `test-b`, `test-c`, `test-d`, `test-e`}
+	for _, name := range names {
+		generators[name] = TGenerators{Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for previous := maxRunning.Load(); current > previous && !maxRunning.CompareAndSwap(previous, current); {
+				previous = maxRunning.Load()
+			}
+			time.Sleep(20 * time.Millisecond)
+			return helpers.TSaveResult{}, nil
+		}}
+	}
+	withTestGenerators(t, generators)
+
+	results := runGenerators(context.Background(), names, TSchedulerOptions{Concurrency: concurrency, Timeout: time.Minute})
+	for i, result := range results {
+		if result.Name != names[i] || result.Status != GeneratorStatusSuccess {
+			t.Errorf(`got %s %s at %d`, result.Name, result.Status, i)
+		}
+	}
+	if got := maxRunning.Load(); got != concurrency {
+		t.Errorf(`got up to %d generators running at the same time, want %d`, got, concurrency)
+	}
+}
+
+func TestRunGeneratorsWaitsForTheGeneratorsWhichTimedOut(t *testing.T) {
+	withGracePeriod(t, time.Minute)
+
+	// The first generator takes some time to stop once its deadline is reached
+	firstReturned, secondStartedEarly := atomic.Bool{}, atomic.Bool{}
+	withTestGenerators(t, map[string]TGenerators{
+		`test-first`: {Timeout: 20 * time.Millisecond, Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			defer firstReturned.Store(true)
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return helpers.TSaveResult{}, ctx.Err()
+		}},
+		`test-second`: {Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			secondStartedEarly.Store(!firstReturned.Load())
+			return helpers.TSaveResult{}, nil
+		}},
+	})
+
+	results := runGenerators(context.Background(), []string{`test-first`, `test-second`}, TSchedulerOptions{Concurrency: 1, Timeout: time.Minute})
+	if results[0].Status != GeneratorStatusTimeout || results[1].Status != GeneratorStatusSuccess {
+		t.Errorf(`got the statuses %s and %s`, results[0].Status, results[1].Status)
+	}
+	if secondStartedEarly.Load() {
+		t.Error(`the slot of the generator which timed out was released before it returned`)
+	}
+}
+
+func TestRunGeneratorsStopsWhenTheRunIsCancelled(t *testing.T) {
+	withGracePeriod(t, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first generator is stuck until the end of the test, whatever its context
+	started, stuck, secondRan := make(chan struct{}), make(chan struct{}), atomic.Bool{}
+	startedOnce := sync.Once{}
+	t.Cleanup(func() { close(stuck) })
+	withTestGenerators(t, map[string]TGenerators{
+		`test-first`: {Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			startedOnce.Do(func() { close(started) })
+			<-stuck
+			return helpers.TSaveResult{}, nil
+		}},
+		`test-second`: {Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			secondRan.Store(true)
+			return helpers.TSaveResult{}, nil
+		}},
+	})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	results := runGenerators(ctx, []string{`test-second`, `test-first`}, TSchedulerOptions{Concurrency: 1, Timeout: time.Minute})
+	for _, result := range results {
+		if result.Status != GeneratorStatusCancelled {
+			t.Errorf(`got the status %s for %s, want %s`, result.Status, result.Name, GeneratorStatusCancelled)
+		}
+	}
+	if secondRan.Load() {
+		t.Error(`a generator was started after the run was cancelled`)
+	}
+}
+
+func TestFinishRunReportsTheFailedGenerators(t *testing.T) {
+	reportPath := helpers.BASE_PATH + `/run-report.json`
+	t.Cleanup(func() { os.Remove(reportPath) })
+	withTestGenerators(t, map[string]TGenerators{
+		`test-required`: {Required: true, Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			return helpers.TSaveResult{}, errors.New(`source unavailable`)
+		}},
+		`test-optional`: {Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			return helpers.TSaveResult{}, errors.New(`source unavailable`)
+		}},
+		`test-success`: {Required: true, Exec: func(ctx context.Context) (helpers.TSaveResult, error) {
+			return helpers.TSaveResult{
+				Changed:            true,
+				Diff:               helpers.TTokenListDiff{Bump: helpers.VersionBumpMinor},
+				TokenCountPerChain: map[uint64]int{TEST_CHAIN_ID: 2},
+				VersionBefore:      models.TTokenListVersion{Major: 1},
+				VersionAfter:       models.TTokenListVersion{Major: 1, Minor: 1},
+			}, nil
+		}},
+	})
+
+	for _, test := range []struct {
+		names    []string
+		exitCode int
+	}{
+		{[]string{`test-optional`, `test-success`}, exitSuccess},
+		{[]string{`test-optional`, `test-required`, `test-success`}, exitFailure},
+	} {
+		results := runGenerators(context.Background(), test.names, TSchedulerOptions{Concurrency: 2, Timeout: time.Minute})
+		if exitCode := finishRun(time.Now(), results); exitCode != test.exitCode {
+			t.Errorf(`got the exit code %d for %v, want %d`, exitCode, test.names, test.exitCode)
+		}
+
+		content, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := struct {
+			Success    bool               `json:"success"`
+			Generators []TGeneratorResult `json:"generators"`
+		}{}
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Success != (test.exitCode == exitSuccess) || len(report.Generators) != len(test.names) {
+			t.Fatalf(`got the report %+v for %v`, report, test.names)
+		}
+		for _, result := range report.Generators {
+			switch result.Name {
+			case `test-success`:
+				if result.Status != GeneratorStatusSuccess || !result.Required || !result.Changed || result.Bump != helpers.VersionBumpMinor ||
+					result.TokenCountPerChain[TEST_CHAIN_ID] != 2 || result.VersionAfter.Minor != 1 {
+					t.Errorf(`got the result %+v`, result)
+				}
+			default:
+				if result.Status != GeneratorStatusFailed || result.Error != `source unavailable` || result.Required != (result.Name == `test-required`) {
+					t.Errorf(`got the result %+v`, result)
+				}
+			}
+		}
+	}
+}
@@ -2,8 +2,8 @@ package static
 
 import "github.com/ethereum/go-ethereum/common"
 
-// The Ajna tokenlist is static because no rebasing token are allowed and we currently have no way to detect them
-// This list has been manually curated by the Ajna team and added on the 2023-10-27
+// AJNA_STATIC_TOKENLIST are the tokens manually curated by the Ajna team, added on the 2023-10-27.
+// They seed the Ajna static list, completed with the tokens of other lists the probe finds safe.
 var AJNA_STATIC_TOKENLIST = map[uint64][]TStaticElement{
 	1: {
 		{common.HexToAddress(`0x583019fF0f430721aDa9cfb4fac8F06cA104d0B4`), `https://assets.coingecko.com/coins/images/32222/large/st-yETH-128px.png?1696914941`},
@@ -1714,5 +1714,5 @@ var AJNA_STATIC_TOKENLIST = map[uint64][]TStaticElement{
 	},
 	42161: {
 		{common.HexToAddress(`0x050C24dBf1eEc17babE5fc585F06116A259CC77A`), `https://dlc-public-assets.s3.amazonaws.com/dlcBTC_Token.png`},
-	}
+	},
 }